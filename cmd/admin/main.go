@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"money_share/pkg/admin"
+	"money_share/pkg/cache"
+	"money_share/pkg/config"
+	"money_share/pkg/database"
+	"money_share/pkg/logging"
+	"money_share/pkg/repository"
+	"os"
+	"time"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes an admin command and returns the process exit code
+func run(args []string) int {
+	flags := flag.NewFlagSet("admin", flag.ContinueOnError)
+	apply := flags.Bool("apply", false, "apply changes instead of printing them")
+	yes := flags.Bool("yes", false, "do not ask for confirmation when applying")
+	output := flags.String("output", "", "file export-group writes to instead of standard output")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), admin.Usage)
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load(".env")
+	if err != nil {
+		fmt.Printf("Cannot read config: %s\n", err)
+		return 1
+	}
+	// Only warnings and errors, query logs would mix with command output
+	logger := logging.New(os.Stderr, cfg.LogFormat, "warn")
+	slog.SetDefault(logger)
+	time.Local = time.UTC
+
+	db := database.Connect(cfg.DatabaseDriver, cfg.DatabaseDSN, logger)
+	defer db.Close()
+
+	a := &admin.Admin{
+		UserRepository:   repository.NewUserRepository(db.DB),
+		GroupRepository:  repository.NewGroupRepository(db.DB),
+		MemberRepository: repository.NewMemberRepository(db.DB),
+		Apply:            *apply,
+		Yes:              *yes,
+		Output:           *output,
+		In:               os.Stdin,
+		Out:              os.Stdout,
+		Clock:            time.Now,
+	}
+	if cfg.CacheEnabled {
+		// Changes invalidate the groups the API caches
+		rdb := database.NewRedisClient()
+		defer rdb.Close()
+		groupCache := cache.New(rdb.DB, cfg.CacheTTL, logger)
+		a.InvalidateGroup = func(groupID uint) error {
+			return groupCache.InvalidateGroup(context.Background(), groupID)
+		}
+	}
+	err = a.Run(flags.Args())
+	if errors.Is(err, admin.ErrUsage) {
+		fmt.Fprint(os.Stderr, admin.Usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -1,54 +1,181 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/gorilla/mux"
-	"github.com/spf13/viper"
-	"log"
+	"log/slog"
 	"money_share/pkg/auth"
+	"money_share/pkg/background"
+	"money_share/pkg/config"
 	"money_share/pkg/controller"
 	"money_share/pkg/database"
-	"money_share/pkg/middleware"
-	"money_share/pkg/repository"
-	"money_share/pkg/route"
+	"money_share/pkg/logging"
+	"money_share/pkg/metrics"
+	"money_share/pkg/migration"
+	"money_share/pkg/server"
+	"money_share/pkg/tracing"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 func main() {
 	fmt.Println("Loading config...")
-	viper.SetConfigFile(".env")
-	err := viper.ReadInConfig()
+	cfg, err := config.Load(".env")
 	if err != nil {
 		fmt.Println("Cannot read config, exiting...")
 		return
 	}
-	auth.JWTKey = []byte(viper.GetString("JWT_KEY"))
+	auth.JWTKey = []byte(cfg.JWTKey)
+
+	// Set up structured logger, JSON in production and text locally
+	logger := logging.New(os.Stdout, cfg.LogFormat, cfg.LogLevel)
+	slog.SetDefault(logger)
+
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, logger, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate-files" {
+		os.Exit(runMigrateFiles(cfg, os.Args[2:]))
+	}
 
 	// Set time zone to UTC
 	time.Local = time.UTC
 
-	fmt.Println("Connecting to database...")
-	db := database.Connect()
-	controller.UserRepository = repository.NewUserRepository(db.DB)
-	controller.GroupRepository = repository.NewGroupRepository(db.DB)
-	controller.MemberRepository = repository.NewMemberRepository(db.DB)
-	controller.ExpenseRepository = repository.NewExpenseRepository(db.DB)
-
-	database.NewRedisClient()
-
-	fmt.Println("Starting server at port 8080...")
-	r := mux.NewRouter()
-	// Set up rate limiter middleware
-	r.Use(middleware.RateLimit(50, 10))
-	// Set up routes
-	route.RegisterUserRoutes(r)
-	route.RegisterGroupRoutes(r)
-	route.RegisterMemberRoutes(r)
-	route.RegisterExpenseRoutes(r)
-	// Handle not found with custom message
-	r.HandleFunc("/", controller.HandleNotFound)
-	// Start server
-	http.Handle("/", r)
-	log.Fatal(http.ListenAndServe(":8080", r))
+	// Set up tracing, spans are exported to an OTLP collector or stdout
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  "money_share",
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		SampleRatio:  cfg.TracingSampleRatio,
+		Output:       os.Stdout,
+	})
+	if err != nil {
+		logger.Error("Cannot set up tracing", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Connecting to database...")
+	db := database.Connect(cfg.DatabaseDriver, cfg.DatabaseDSN, logger)
+	if err := db.DB.Use(tracing.GormPlugin{}); err != nil {
+		logger.Error("Cannot set up database tracing", "error", err)
+		os.Exit(1)
+	}
+	if err := checkMigrations(logger, db, cfg.MigrationsAutoApply); err != nil {
+		logger.Error("Cannot start with current database schema", "error", err)
+		os.Exit(1)
+	}
+
+	rdb := database.NewRedisClient()
+	rdb.DB.AddHook(metrics.RedisHook{})
+	rdb.DB.AddHook(tracing.RedisHook{})
+
+	// Expose connection pool statistics
+	if sqlDB, err := db.DB.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB, "money_share"); err != nil {
+			logger.Error("Cannot register database metrics", "error", err)
+		}
+	}
+
+	fileStorage, err := server.NewStorage(context.Background(), cfg)
+	if err != nil {
+		logger.Error("Cannot set up file storage", "error", err)
+		os.Exit(1)
+	}
+	// Push notifications to the devices of their users through the configured providers
+	pushSenders, err := server.NewPushSenders(cfg)
+	if err != nil {
+		logger.Error("Cannot set up push notifications", "error", err)
+		os.Exit(1)
+	}
+
+	srv, err := server.New(cfg, db.DB, rdb.DB, server.Options{Storage: fileStorage, PushSenders: pushSenders, Logger: logger})
+	if err != nil {
+		logger.Error("Cannot set up server", "error", err)
+		os.Exit(1)
+	}
+	app := srv.App
+	workers := background.NewGroup(logger)
+	srv.Start(workers)
+
+	logger.Info("Starting server", "address", cfg.ServerAddress)
+	httpServer := &http.Server{
+		Addr:     cfg.ServerAddress,
+		Handler:  srv.Handler,
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+	// Event streams never go idle, end them when shutting down
+	httpServer.RegisterOnShutdown(app.Events.Close)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- httpServer.ListenAndServe()
+	}()
+
+	// Wait for termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Server stopped unexpectedly", "error", err)
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+	}
+
+	shutdown(app, httpServer, workers, db, rdb, shutdownTracing)
+}
+
+// checkMigrations refuses to start against a database with pending migrations,
+// unless autoApply is set in which case they are applied.
+func checkMigrations(logger *slog.Logger, db *database.SQLDB, autoApply bool) error {
+	migrator, err := migration.New(db.DB)
+	if err != nil {
+		return err
+	}
+	if !autoApply {
+		if err := migrator.CheckPending(); err != nil {
+			return fmt.Errorf("%s, run `migrate up` or set MIGRATIONS_AUTO_APPLY=true", err)
+		}
+		return nil
+	}
+	applied, err := migrator.Up()
+	for _, m := range applied {
+		logger.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+// shutdown stops accepting connections, drains in-flight requests and background
+// workers within the timeout, then closes database and redis clients and flushes traces.
+func shutdown(app *controller.App, server *http.Server, workers *background.Group, db *database.SQLDB,
+	rdb *database.RedisDB, shutdownTracing func(context.Context) error) {
+	logger := app.Logger
+	logger.Info("Shutting down server...")
+	app.HealthChecker.SetDraining()
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.Config.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Error draining in-flight requests", "error", err)
+	}
+	if err := workers.Shutdown(ctx); err != nil {
+		logger.Error("Error draining background workers", "error", err)
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("Error closing database", "error", err)
+	}
+	if err := rdb.Close(); err != nil {
+		logger.Error("Error closing redis", "error", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Error flushing traces", "error", err)
+	}
+	logger.Info("Server stopped")
 }
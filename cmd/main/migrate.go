@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"money_share/pkg/config"
+	"money_share/pkg/database"
+	"money_share/pkg/migration"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `Usage: money_share migrate <command>
+
+Commands:
+  up               apply all pending migrations
+  down N           revert the last N applied migrations
+  status           list migrations and whether they are applied
+  create NAME      create an empty up/down migration pair
+`
+
+// runMigrate implements the `migrate` subcommand and returns the process exit code
+func runMigrate(cfg config.Config, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := flags.String("dir", "pkg/migration/"+cfg.DatabaseDriver, "directory to create new migration files in")
+	flags.Usage = func() { fmt.Fprint(flags.Output(), migrateUsage) }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	command := flags.Arg(0)
+	// Creating a migration doesn't need a database connection
+	if command == "create" {
+		if flags.NArg() != 2 {
+			flags.Usage()
+			return 2
+		}
+		upPath, downPath, err := migration.Create(*dir, flags.Arg(1))
+		if err != nil {
+			fmt.Printf("Cannot create migration: %s\n", err)
+			return 1
+		}
+		fmt.Printf("Created %s\nCreated %s\n", upPath, downPath)
+		return 0
+	}
+
+	db := database.Connect(cfg.DatabaseDriver, cfg.DatabaseDSN, logger)
+	defer db.Close()
+	migrator, err := migration.New(db.DB)
+	if err != nil {
+		fmt.Printf("Cannot load migrations: %s\n", err)
+		return 1
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("Applied %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		if flags.NArg() != 2 {
+			flags.Usage()
+			return 2
+		}
+		n, err := strconv.Atoi(flags.Arg(1))
+		if err != nil {
+			fmt.Printf("Cannot parse number of migrations '%s': %s\n", flags.Arg(1), err)
+			return 2
+		}
+		reverted, err := migrator.Down(n)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state = "applied"
+				appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if status.Modified {
+				state = "applied (modified since)"
+			}
+			fmt.Fprintf(tw, "%06d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		_ = tw.Flush()
+	default:
+		flags.Usage()
+		return 2
+	}
+	return 0
+}
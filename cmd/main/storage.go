@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"money_share/pkg/config"
+	"money_share/pkg/server"
+	"money_share/pkg/storage"
+	"path/filepath"
+)
+
+const migrateFilesUsage = `Usage: money_share migrate-files [-from DIR] [-prefix PREFIX]
+
+Copies files uploaded to the local file server into the configured storage backend.
+Existing objects are overwritten, so the command can be run again after a failure.
+`
+
+// runMigrateFiles implements the `migrate-files` subcommand and returns the process exit code
+func runMigrateFiles(cfg config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate-files", flag.ContinueOnError)
+	from := flags.String("from", filepath.Join("fileServer", "userProfileImage"), "directory of previously uploaded files")
+	prefix := flags.String("prefix", "userProfileImage", "key prefix of the migrated objects")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateFilesUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx := context.Background()
+	dst, err := server.NewStorage(ctx, cfg)
+	if err != nil {
+		fmt.Printf("Cannot set up storage: %s\n", err)
+		return 1
+	}
+	count, err := storage.MigrateDirectory(ctx, *from, *prefix, dst, func(key string) {
+		fmt.Printf("Copied %s\n", key)
+	})
+	if err != nil {
+		fmt.Printf("Migrated %d files before failing: %s\n", count, err)
+		return 1
+	}
+	fmt.Printf("Migrated %d files\n", count)
+	return 0
+}
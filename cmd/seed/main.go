@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"money_share/pkg/config"
+	"money_share/pkg/database"
+	"money_share/pkg/logging"
+	"money_share/pkg/migration"
+	"money_share/pkg/model"
+	"money_share/pkg/seed"
+	"os"
+	"time"
+)
+
+const usage = `Usage: seed [flags]
+
+Fills the database with generated users, groups, members and expenses. The same flags
+always generate the same data. Every seeded user can log in with the -password value.
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run seeds the database and returns the process exit code
+func run(args []string) int {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	seedConfig := seed.Config{}
+	end := ""
+	flags.Int64Var(&seedConfig.Seed, "seed", 1, "random seed")
+	flags.IntVar(&seedConfig.Users, "users", 50, "number of users")
+	flags.IntVar(&seedConfig.Groups, "groups", 10, "number of groups")
+	flags.IntVar(&seedConfig.MinMembers, "min-members", 2, "minimum number of members per group")
+	flags.IntVar(&seedConfig.MaxMembers, "max-members", 8, "maximum number of members per group")
+	flags.IntVar(&seedConfig.ExpensesPerMonth, "expenses-per-month", 20, "average number of expenses per group and month")
+	flags.IntVar(&seedConfig.Months, "months", 6, "number of months expenses are spread over")
+	flags.StringVar(&end, "end", "2024-01-01", "date the generated history ends at, YYYY-MM-DD")
+	flags.StringVar(&seedConfig.Password, "password", "password123", "password of every seeded user")
+	clearData := flags.Bool("clear", false, "delete all existing users, groups, members and expenses first")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	var err error
+	if seedConfig.End, err = time.Parse("2006-01-02", end); err != nil {
+		fmt.Printf("Cannot parse end date '%s': %s\n", end, err)
+		return 2
+	}
+
+	data, err := seed.Generate(seedConfig)
+	if err != nil {
+		fmt.Printf("Invalid seed config: %s\n", err)
+		return 2
+	}
+
+	cfg, err := config.Load(".env")
+	if err != nil {
+		fmt.Printf("Cannot read config: %s\n", err)
+		return 1
+	}
+	// Only warnings and errors, batch inserts would flood the query log
+	logger := logging.New(os.Stderr, cfg.LogFormat, "warn")
+	slog.SetDefault(logger)
+	time.Local = time.UTC
+
+	db := database.Connect(cfg.DatabaseDriver, cfg.DatabaseDSN, logger)
+	defer db.Close()
+	migrator, err := migration.New(db.DB)
+	if err != nil {
+		fmt.Printf("Cannot load migrations: %s\n", err)
+		return 1
+	}
+	if err := migrator.CheckPending(); err != nil {
+		fmt.Printf("%s, run `migrate up` first\n", err)
+		return 1
+	}
+
+	if *clearData {
+		if err := seed.Clear(db.DB); err != nil {
+			fmt.Printf("Cannot clear database: %s\n", err)
+			return 1
+		}
+	} else {
+		// Seeding twice would fail on unique usernames half way, refuse early instead
+		var users int64
+		if err := db.DB.Model(&model.User{}).Unscoped().Count(&users).Error; err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		if users > 0 {
+			fmt.Printf("Database already has %d users, run with -clear to replace them\n", users)
+			return 1
+		}
+	}
+
+	started := time.Now()
+	if err := seed.Insert(db.DB, data); err != nil {
+		fmt.Printf("Cannot seed database: %s\n", err)
+		return 1
+	}
+	members, expenses := 0, 0
+	for _, group := range data.Groups {
+		members += len(group.Members)
+		expenses += len(group.Expenses)
+	}
+	fmt.Printf("Seeded %d users, %d groups, %d members and %d expenses in %s\n",
+		len(data.Users), len(data.Groups), members, expenses, time.Since(started).Round(time.Millisecond))
+	fmt.Printf("Log in as e.g. '%s' with password '%s'\n", data.Users[0].Username, seedConfig.Password)
+	return 0
+}
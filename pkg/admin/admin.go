@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Usage = `Usage: admin [-apply] [-yes] [-output FILE] <command> [arguments]
+
+USER is a user ID or a username. Commands changing data only print what they would
+do, unless -apply is given, in which case they ask for confirmation first.
+
+Commands:
+  find-users QUERY                  find users by username, display name or email address
+  show-user USER                    show a user and the groups they belong to
+  reset-password USER               set a new random password and print it
+  disable-user USER                 block a user at once, from logging in and from using the API
+  enable-user USER                  allow a disabled user to log in again
+  list-groups USER                  list the groups of a user
+  list-members GROUP_ID             list the members of a group with their role and total
+  set-role GROUP_ID USER ROLE       change the role of a member, ROLE is member or manager
+  recompute-totals GROUP_ID         recompute group and member totals from approved expenses
+  export-group GROUP_ID             write a group with members and expenses as JSON
+`
+
+// Maximum number of users printed by find-users
+const findLimit = 50
+
+// ErrUsage is returned when a command is unknown or has the wrong arguments
+var ErrUsage = errors.New("invalid command or arguments")
+
+// ErrNotConfirmed is returned when the operator declined to apply a change
+var ErrNotConfirmed = errors.New("change not confirmed, nothing was applied")
+
+// Admin runs operator commands against the repositories
+type Admin struct {
+	UserRepository   repository.UserRepository
+	GroupRepository  repository.GroupRepository
+	MemberRepository repository.MemberRepository
+	// Apply makes changes instead of only printing them
+	Apply bool
+	// Yes skips the confirmation prompt when applying
+	Yes bool
+	// Output is the file export-group writes to, standard output when empty
+	Output string
+	// In is read for confirmation, Out receives all other output
+	In    io.Reader
+	Out   io.Writer
+	Clock func() time.Time
+	// InvalidateGroup drops what the API caches about a changed group, nil when nothing is cached
+	InvalidateGroup func(groupID uint) error
+}
+
+// change is a modification described by summary lines, applied only when confirmed
+type change struct {
+	summary []string
+	apply   func() error
+	// groupID is the group the change touches, zero for none
+	groupID uint
+}
+
+// Run executes the command named by args[0]
+func (a *Admin) Run(args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "find-users":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.findUsers(args[0])
+	case "show-user":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.showUser(args[0])
+	case "reset-password":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.resetPassword(args[0])
+	case "disable-user", "enable-user":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.setDisabled(args[0], command == "disable-user")
+	case "list-groups":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.listGroups(args[0])
+	case "list-members":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.listMembers(args[0])
+	case "set-role":
+		if len(args) != 3 {
+			return ErrUsage
+		}
+		return a.setRole(args[0], args[1], args[2])
+	case "recompute-totals":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.recomputeTotals(args[0])
+	case "export-group":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return a.exportGroup(args[0])
+	default:
+		return ErrUsage
+	}
+}
+
+// commit prints the summary of c and applies it when running with -apply and confirmed
+func (a *Admin) commit(c change) error {
+	for _, line := range c.summary {
+		fmt.Fprintln(a.Out, line)
+	}
+	if !a.Apply {
+		fmt.Fprintln(a.Out, "Dry run, nothing was changed. Run again with -apply to apply.")
+		return nil
+	}
+	if !a.Yes {
+		fmt.Fprint(a.Out, "Apply these changes? [y/N] ")
+		answer, _ := bufio.NewReader(a.In).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return ErrNotConfirmed
+		}
+	}
+	if err := c.apply(); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.Out, "Applied.")
+	if c.groupID != 0 && a.InvalidateGroup != nil {
+		// The cache TTL expires the entries eventually
+		if err := a.InvalidateGroup(c.groupID); err != nil {
+			fmt.Fprintf(a.Out, "Cannot invalidate cached group %d, the API may serve it stale until the cache expires: %s\n", c.groupID, err)
+		}
+	}
+	return nil
+}
+
+// findUser looks a user up by ID when ref is numeric, by username otherwise
+func (a *Admin) findUser(ref string) (*model.User, error) {
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		user, err := a.UserRepository.GetById(uint(id))
+		if err != nil {
+			return nil, fmt.Errorf("cannot find user with ID %d: %w", id, err)
+		}
+		return user, nil
+	}
+	user, err := a.UserRepository.GetByUsername(ref)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find user '%s': %w", ref, err)
+	}
+	return user, nil
+}
+
+func parseGroupID(ref string) (uint, error) {
+	id, err := strconv.ParseUint(ref, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid group ID '%s'", ref)
+	}
+	return uint(id), nil
+}
+
+func describeUser(user *model.User) string {
+	return fmt.Sprintf("user %d (%s)", user.ID, user.Username)
+}
@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"money_share/pkg/dto"
+	"money_share/pkg/model"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+func (a *Admin) listGroups(ref string) error {
+	user, err := a.findUser(ref)
+	if err != nil {
+		return err
+	}
+	groups, err := a.GroupRepository.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(a.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tTOTAL\tAVERAGE")
+	for _, group := range groups {
+		fmt.Fprintf(tw, "%d\t%s\t%.2f\t%.2f\n", group.ID, group.Name, group.TotalExpense, group.AverageExpense)
+	}
+	return tw.Flush()
+}
+
+func (a *Admin) listMembers(groupRef string) error {
+	groupID, err := parseGroupID(groupRef)
+	if err != nil {
+		return err
+	}
+	members, err := a.MemberRepository.GetByGroup(groupID)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(a.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "USER ID\tUSERNAME\tROLE\tTOTAL")
+	for _, member := range members {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%.2f\n", member.UserID, member.User.Username, member.Role, member.TotalExpense)
+	}
+	return tw.Flush()
+}
+
+func (a *Admin) setRole(groupRef string, userRef string, role string) error {
+	groupID, err := parseGroupID(groupRef)
+	if err != nil {
+		return err
+	}
+	if err := model.ValidateRole(role); err != nil {
+		return err
+	}
+	user, err := a.findUser(userRef)
+	if err != nil {
+		return err
+	}
+	member, err := a.MemberRepository.GetByID(user.ID, groupID)
+	if err != nil {
+		return fmt.Errorf("%s is not a member of group %d: %w", describeUser(user), groupID, err)
+	}
+	if member.Role == role {
+		fmt.Fprintf(a.Out, "%s is already %s of group %d, nothing to do.\n", describeUser(user), role, groupID)
+		return nil
+	}
+	return a.commit(change{
+		summary: []string{fmt.Sprintf("Change role of %s in group %d from %s to %s", describeUser(user), groupID, member.Role, role)},
+		apply: func() error {
+			return a.MemberRepository.UpdateRole(user.ID, groupID, role)
+		},
+		groupID: groupID,
+	})
+}
+
+func (a *Admin) recomputeTotals(groupRef string) error {
+	groupID, err := parseGroupID(groupRef)
+	if err != nil {
+		return err
+	}
+	group, err := a.GroupRepository.GetWithExpenses(groupID)
+	if err != nil {
+		return fmt.Errorf("cannot find group %d: %w", groupID, err)
+	}
+	totals := group.ComputeTotals()
+
+	var summary []string
+	if !sameAmount(group.TotalExpense, totals.Total) {
+		summary = append(summary, fmt.Sprintf("Group %d total: %.2f -> %.2f", groupID, group.TotalExpense, totals.Total))
+	}
+	if !sameAmount(group.AverageExpense, totals.Average) {
+		summary = append(summary, fmt.Sprintf("Group %d average: %.2f -> %.2f", groupID, group.AverageExpense, totals.Average))
+	}
+	members := append([]model.Member{}, group.Members...)
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	for _, member := range members {
+		if !sameAmount(member.TotalExpense, totals.Members[member.UserID]) {
+			summary = append(summary, fmt.Sprintf("Member %d (%s) total: %.2f -> %.2f",
+				member.UserID, member.User.Username, member.TotalExpense, totals.Members[member.UserID]))
+		}
+	}
+	if len(summary) == 0 {
+		fmt.Fprintf(a.Out, "Totals of group %d are correct, nothing to do.\n", groupID)
+		return nil
+	}
+	return a.commit(change{
+		summary: summary,
+		apply: func() error {
+			return a.GroupRepository.UpdateTotals(groupID, totals.Total, totals.Average, totals.Members)
+		},
+		groupID: groupID,
+	})
+}
+
+func (a *Admin) exportGroup(groupRef string) error {
+	groupID, err := parseGroupID(groupRef)
+	if err != nil {
+		return err
+	}
+	group, err := a.GroupRepository.GetWithExpenses(groupID)
+	if err != nil {
+		return fmt.Errorf("cannot find group %d: %w", groupID, err)
+	}
+
+	out := a.Out
+	if a.Output != "" {
+		file, err := os.Create(a.Output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dto.GroupToGroupDTO(*group)); err != nil {
+		return err
+	}
+	if a.Output != "" {
+		fmt.Fprintf(a.Out, "Exported group %d to %s\n", groupID, a.Output)
+	}
+	return nil
+}
+
+// sameAmount compares amounts stored as float32 up to a cent
+func sameAmount(a float32, b float32) bool {
+	return math.Abs(float64(a)-float64(b)) < 0.005
+}
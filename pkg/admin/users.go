@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"money_share/pkg/model"
+	"text/tabwriter"
+	"time"
+)
+
+// Characters of generated passwords, without look-alikes such as 0/O and 1/l
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const generatedPasswordLength = 16
+
+func (a *Admin) findUsers(query string) error {
+	users, err := a.UserRepository.Find(query, findLimit)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(a.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSERNAME\tDISPLAY NAME\tEMAIL\tSTATUS")
+	for _, user := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", user.ID, user.Username, user.DisplayName, user.EmailAddress, userStatus(user))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(users) == findLimit {
+		fmt.Fprintf(a.Out, "Showing the first %d users, refine the query to see others.\n", findLimit)
+	}
+	return nil
+}
+
+func (a *Admin) showUser(ref string) error {
+	user, err := a.findUser(ref)
+	if err != nil {
+		return err
+	}
+	groups, err := a.GroupRepository.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%d\n", user.ID)
+	fmt.Fprintf(tw, "Username\t%s\n", user.Username)
+	fmt.Fprintf(tw, "Display name\t%s\n", user.DisplayName)
+	fmt.Fprintf(tw, "Email address\t%s\n", user.EmailAddress)
+	fmt.Fprintf(tw, "Phone number\t%s\n", user.PhoneNumber)
+	fmt.Fprintf(tw, "Created at\t%s\n", user.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(tw, "Status\t%s\n", userStatus(user))
+	fmt.Fprintf(tw, "Groups\t%d\n", len(groups))
+	for _, group := range groups {
+		fmt.Fprintf(tw, "\t%d %s\n", group.ID, group.Name)
+	}
+	return tw.Flush()
+}
+
+func (a *Admin) resetPassword(ref string) error {
+	user, err := a.findUser(ref)
+	if err != nil {
+		return err
+	}
+	return a.commit(change{
+		summary: []string{fmt.Sprintf("Reset password of %s to a new random password", describeUser(user))},
+		apply: func() error {
+			password, err := generatePassword()
+			if err != nil {
+				return err
+			}
+			hashedPassword, err := model.HashPassword(password)
+			if err != nil {
+				return err
+			}
+			if _, err := a.UserRepository.Update(user.ID, map[string]interface{}{"Password": hashedPassword}); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.Out, "New password of %s: %s\n", describeUser(user), password)
+			return nil
+		},
+	})
+}
+
+func (a *Admin) setDisabled(ref string, disabled bool) error {
+	user, err := a.findUser(ref)
+	if err != nil {
+		return err
+	}
+	if user.IsDisabled() == disabled {
+		fmt.Fprintf(a.Out, "%s is already %s, nothing to do.\n", describeUser(user), userStatus(user))
+		return nil
+	}
+
+	summary := fmt.Sprintf("Enable %s", describeUser(user))
+	var disabledAt interface{}
+	if disabled {
+		// Authenticate checks the account on every request, tokens already issued stop working at once
+		summary = fmt.Sprintf("Disable %s, requests with tokens already issued are rejected and open event streams closed", describeUser(user))
+		disabledAt = a.Clock()
+	}
+	return a.commit(change{
+		summary: []string{summary},
+		apply: func() error {
+			_, err := a.UserRepository.Update(user.ID, map[string]interface{}{"DisabledAt": disabledAt})
+			return err
+		},
+	})
+}
+
+func userStatus(user *model.User) string {
+	if user.IsDisabled() {
+		return "disabled"
+	}
+	return "active"
+}
+
+func generatePassword() (string, error) {
+	password := make([]byte, generatedPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,154 @@
+// Package apperror defines the errors shared by the repositories, models and handlers. Each
+// error has a kind, which decides the HTTP status, and a stable code clients can match on.
+package apperror
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind is the category of an error
+type Kind string
+
+const (
+	KindValidation           Kind = "validation"
+	KindUnauthorized         Kind = "unauthorized"
+	KindForbidden            Kind = "forbidden"
+	KindNotFound             Kind = "not_found"
+	KindConflict             Kind = "conflict"
+	KindPayloadTooLarge      Kind = "payload_too_large"
+	KindUnsupportedMediaType Kind = "unsupported_media_type"
+	KindTooManyRequests      Kind = "too_many_requests"
+	KindUnavailable          Kind = "unavailable"
+	KindInternal             Kind = "internal"
+)
+
+// CodeValidationFailed is the code of validation errors listing failing fields
+const CodeValidationFailed = "validation_failed"
+
+// FieldError is a validation failure of a body field or a parameter
+type FieldError struct {
+	// Field is the dotted path of a body field, e.g. "members.0.role", or a parameter name
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error with a kind and a machine-readable code
+type Error struct {
+	Kind Kind
+	// Code is a stable snake_case identifier, e.g. "user_not_found"
+	Code string
+	// Message is safe to show to clients
+	Message string
+	// Fields lists the failing fields of validation errors
+	Fields []FieldError
+	// Err is the cause, it is not shown to clients
+	Err error
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if len(e.Fields) > 0 {
+		var fields []string
+		for _, field := range e.Fields {
+			fields = append(fields, field.Message)
+		}
+		message = strings.Join(fields, ", ")
+	}
+	if e.Err != nil {
+		return message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(kind Kind, code string, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap returns an error of kind caused by err
+func Wrap(err error, kind Kind, code string, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
+
+func NotFound(code string, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code string, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func Forbidden(code string, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func Unauthorized(code string, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+// Validation returns a validation error failing the fields
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: CodeValidationFailed, Message: message, Fields: fields}
+}
+
+// InvalidField returns a validation error of a single field
+func InvalidField(field string, message string) *Error {
+	return Validation(message, FieldError{Field: field, Message: message})
+}
+
+// Internal wraps an unexpected error, its details are only logged
+func Internal(err error) *Error {
+	return Wrap(err, KindInternal, "internal_error", "Internal server error")
+}
+
+// Join merges validation errors into one listing every failing field. Nil errors are
+// skipped and the first error which is not a validation error is returned as is.
+func Join(errs ...error) error {
+	var joined *Error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		appErr, ok := As(err)
+		if !ok || appErr.Kind != KindValidation {
+			return err
+		}
+		if joined == nil {
+			joined = Validation(appErr.Message)
+		}
+		joined.Fields = append(joined.Fields, appErr.Fields...)
+	}
+	if joined == nil {
+		return nil
+	}
+	if len(joined.Fields) > 1 {
+		joined.Message = "Validation failed"
+	}
+	return joined
+}
+
+// As returns the Error in the chain of err
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of err, errors of other packages are internal
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// Is reports whether err is of kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
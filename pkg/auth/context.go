@@ -0,0 +1,20 @@
+package auth
+
+import "context"
+
+type userIDKey struct{}
+
+// WithUserID returns a context carrying the authenticated user, repositories record them as
+// the actor of changes
+func WithUserID(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFrom returns the authenticated user of ctx
+func UserIDFrom(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	userID, ok := ctx.Value(userIDKey{}).(uint)
+	return userID, ok && userID != 0
+}
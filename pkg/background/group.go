@@ -0,0 +1,51 @@
+package background
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Group runs long-lived background workers and waits for them to drain on shutdown.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+func NewGroup(logger *slog.Logger) *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel, logger: logger}
+}
+
+// Go starts a worker. The context passed to fn is cancelled when Shutdown is called,
+// workers are expected to return promptly after that.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				g.logger.Error("Background worker panicked", "worker", name, "panic", r)
+			}
+		}()
+		fn(g.ctx)
+	}()
+}
+
+// Shutdown signals all workers to stop and waits until they return or ctx expires.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.cancel()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
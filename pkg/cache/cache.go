@@ -0,0 +1,195 @@
+// Package cache is a Redis read-through cache for the group reads clients poll: group details and
+// member balances. Entries belong to a group and are versioned, invalidating a group bumps its
+// version so entries written by readers racing the invalidation are never read.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"log/slog"
+	"money_share/pkg/metrics"
+	"sync"
+	"time"
+)
+
+// Interval at which instances waiting for another instance to load an entry check for it
+const lockPollInterval = 20 * time.Millisecond
+
+type Cache struct {
+	Client *redis.Client
+	Prefix string
+	// TTL bounds how long an entry is served when an invalidation is missed, e.g. after a write
+	// made outside the API
+	TTL time.Duration
+	// LockTimeout bounds how long a loader holds the lock of a missing entry, instances which
+	// don't hold it wait at most this long for the entry before loading it themselves
+	LockTimeout time.Duration
+	Logger      *slog.Logger
+
+	flight flight
+}
+
+func New(client *redis.Client, ttl time.Duration, logger *slog.Logger) *Cache {
+	return &Cache{Client: client, Prefix: "cache:", TTL: ttl, LockTimeout: time.Second, Logger: logger}
+}
+
+func (c *Cache) versionKey(groupID uint) string {
+	return fmt.Sprintf("%sgroup:%d:version", c.Prefix, groupID)
+}
+
+// InvalidateGroup drops every entry of the group
+func (c *Cache) InvalidateGroup(ctx context.Context, groupID uint) error {
+	key := c.versionKey(groupID)
+	// The version outlives every entry written with an older version, so a reset to zero
+	// cannot revive one
+	_, err := c.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, 2*c.TTL)
+		return nil
+	})
+	return err
+}
+
+// invalidate invalidates the group and logs failures, the write which made the entries stale
+// has succeeded and the TTL expires them eventually
+func (c *Cache) invalidate(ctx context.Context, groupID uint) {
+	if err := c.InvalidateGroup(ctx, groupID); err != nil {
+		c.Logger.Warn("Cannot invalidate cached group", "group_id", groupID, "error", err)
+	}
+}
+
+// Load returns the entry name of the group from the cache, or loads and caches it on a miss.
+// Concurrent misses of an entry in one instance share a single load and its value, instances
+// wait for a load in progress in another instance. When Redis fails the entry is loaded uncached.
+func Load[T any](ctx context.Context, c *Cache, cacheName string, groupID uint, name string, load func() (T, error)) (T, error) {
+	version, err := c.Client.Get(ctx, c.versionKey(groupID)).Result()
+	if errors.Is(err, redis.Nil) {
+		version, err = "0", nil
+	}
+	if err != nil {
+		c.record(cacheName, "error", err)
+		return load()
+	}
+	key := fmt.Sprintf("%sgroup:%d:v%s:%s", c.Prefix, groupID, version, name)
+
+	var value T
+	if found, err := c.get(ctx, key, &value); err != nil {
+		c.record(cacheName, "error", err)
+		return load()
+	} else if found {
+		c.record(cacheName, "hit", nil)
+		return value, nil
+	}
+	c.record(cacheName, "miss", nil)
+
+	shared, err := c.flight.do(key, func() (interface{}, error) {
+		return loadLocked(ctx, c, key, load)
+	})
+	if err != nil {
+		return value, err
+	}
+	return shared.(T), nil
+}
+
+// loadLocked loads and caches a missing entry under its lock, or waits for the instance
+// holding the lock to cache it
+func loadLocked[T any](ctx context.Context, c *Cache, key string, load func() (T, error)) (T, error) {
+	lockKey := key + ":lock"
+	locked, err := c.Client.SetNX(ctx, lockKey, 1, c.LockTimeout).Result()
+	if err == nil && !locked {
+		var value T
+		deadline := time.Now().Add(c.LockTimeout)
+		for time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return value, ctx.Err()
+			case <-time.After(lockPollInterval):
+			}
+			found, err := c.get(ctx, key, &value)
+			if found {
+				return value, nil
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	value, err := load()
+	if err != nil {
+		// Errors, e.g. not found, are not cached
+		if locked {
+			c.Client.Del(ctx, lockKey)
+		}
+		return value, err
+	}
+	if encoded, err := json.Marshal(value); err != nil {
+		c.Logger.Warn("Cannot encode cache entry", "key", key, "error", err)
+	} else if err = c.Client.Set(ctx, key, encoded, c.TTL).Err(); err != nil {
+		c.Logger.Warn("Cannot write cache entry", "key", key, "error", err)
+	}
+	if locked {
+		c.Client.Del(ctx, lockKey)
+	}
+	return value, nil
+}
+
+// get decodes the entry at key into value and reports whether it exists
+func (c *Cache) get(ctx context.Context, key string, value interface{}) (bool, error) {
+	encoded, err := c.Client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err = json.Unmarshal(encoded, value); err != nil {
+		// Treat entries of an older format as missing, they are overwritten
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c *Cache) record(cacheName string, result string, err error) {
+	metrics.CacheRequests.WithLabelValues(cacheName, result).Inc()
+	if err != nil {
+		c.Logger.Debug("Cache unavailable, reading from database", "cache", cacheName, "error", err)
+	}
+}
+
+// flight shares the result of a function between concurrent callers with the same key
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func (f *flight) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]*call)
+	}
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		<-c.done
+		return c.value, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	c.value, c.err = fn()
+	close(c.done)
+	f.mu.Lock()
+	delete(f.calls, key)
+	f.mu.Unlock()
+	return c.value, c.err
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"strconv"
+)
+
+// Names of the caches in metrics
+const (
+	groupCacheName  = "group"
+	memberCacheName = "member"
+)
+
+// GroupRepository caches group details, updates and deletes invalidate the group
+type GroupRepository struct {
+	repository.GroupRepository
+	Cache *Cache
+	ctx   context.Context
+}
+
+func NewGroupRepository(groupRepository repository.GroupRepository, cache *Cache) repository.GroupRepository {
+	return GroupRepository{groupRepository, cache, context.Background()}
+}
+
+func (repository GroupRepository) WithContext(ctx context.Context) repository.GroupRepository {
+	return GroupRepository{repository.GroupRepository.WithContext(ctx), repository.Cache, ctx}
+}
+
+func (repository GroupRepository) GetById(groupId uint) (*model.Group, error) {
+	if groupId <= 0 {
+		return repository.GroupRepository.GetById(groupId)
+	}
+	return Load(repository.ctx, repository.Cache, groupCacheName, groupId, "detail", func() (*model.Group, error) {
+		group, err := repository.GroupRepository.GetById(groupId)
+		if err == nil {
+			// Password hashes never leave the database
+			for i := range group.Members {
+				group.Members[i].User.Password = ""
+			}
+		}
+		return group, err
+	})
+}
+
+func (repository GroupRepository) Update(group *model.Group) error {
+	err := repository.GroupRepository.Update(group)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, group.ID)
+	}
+	return err
+}
+
+func (repository GroupRepository) Delete(groupId uint) error {
+	err := repository.GroupRepository.Delete(groupId)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, groupId)
+	}
+	return err
+}
+
+func (repository GroupRepository) UpdateTotals(groupID uint, total float32, average float32, memberTotals map[uint]float32) error {
+	err := repository.GroupRepository.UpdateTotals(groupID, total, average, memberTotals)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, groupID)
+	}
+	return err
+}
+
+// MemberRepository caches member balances, member changes invalidate the group
+type MemberRepository struct {
+	repository.MemberRepository
+	Cache *Cache
+	ctx   context.Context
+}
+
+func NewMemberRepository(memberRepository repository.MemberRepository, cache *Cache) repository.MemberRepository {
+	return MemberRepository{memberRepository, cache, context.Background()}
+}
+
+func (repository MemberRepository) WithContext(ctx context.Context) repository.MemberRepository {
+	return MemberRepository{repository.MemberRepository.WithContext(ctx), repository.Cache, ctx}
+}
+
+func (repository MemberRepository) GetByID(userID uint, groupID uint) (*model.Member, error) {
+	if userID <= 0 || groupID <= 0 {
+		return repository.MemberRepository.GetByID(userID, groupID)
+	}
+	name := "member:" + strconv.FormatUint(uint64(userID), 10)
+	return Load(repository.ctx, repository.Cache, memberCacheName, groupID, name, func() (*model.Member, error) {
+		member, err := repository.MemberRepository.GetByID(userID, groupID)
+		if err == nil {
+			member.User.Password = ""
+		}
+		return member, err
+	})
+}
+
+func (repository MemberRepository) AddMemberToGroup(userID uint, groupID uint) error {
+	err := repository.MemberRepository.AddMemberToGroup(userID, groupID)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, groupID)
+	}
+	return err
+}
+
+func (repository MemberRepository) RemoveMemberFromGroup(userID uint, groupID uint) error {
+	err := repository.MemberRepository.RemoveMemberFromGroup(userID, groupID)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, groupID)
+	}
+	return err
+}
+
+func (repository MemberRepository) UpdateRole(userID uint, groupID uint, role string) error {
+	err := repository.MemberRepository.UpdateRole(userID, groupID, role)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, groupID)
+	}
+	return err
+}
+
+func (repository MemberRepository) IncreaseTotalExpense(userID uint, groupID uint, updateValue float32) error {
+	err := repository.MemberRepository.IncreaseTotalExpense(userID, groupID, updateValue)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, groupID)
+	}
+	return err
+}
+
+// ExpenseRepository invalidates the group of every expense written, the expense hooks have
+// updated the totals of the group and its members by then
+type ExpenseRepository struct {
+	repository.ExpenseRepository
+	Cache *Cache
+	ctx   context.Context
+}
+
+func NewExpenseRepository(expenseRepository repository.ExpenseRepository, cache *Cache) repository.ExpenseRepository {
+	return ExpenseRepository{expenseRepository, cache, context.Background()}
+}
+
+func (repository ExpenseRepository) WithContext(ctx context.Context) repository.ExpenseRepository {
+	return ExpenseRepository{repository.ExpenseRepository.WithContext(ctx), repository.Cache, ctx}
+}
+
+func (repository ExpenseRepository) Create(expense *model.Expense) error {
+	err := repository.ExpenseRepository.Create(expense)
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, expense.GroupID)
+	}
+	return err
+}
+
+func (repository ExpenseRepository) Update(expense *model.Expense) (string, error) {
+	var action string
+	err := repository.invalidatingGroupOf(expense.ID, func() (err error) {
+		action, err = repository.ExpenseRepository.Update(expense)
+		return err
+	})
+	return action, err
+}
+
+func (repository ExpenseRepository) Delete(expenseId uint) error {
+	return repository.invalidatingGroupOf(expenseId, func() error {
+		return repository.ExpenseRepository.Delete(expenseId)
+	})
+}
+
+// invalidatingGroupOf runs write and then invalidates the group of the expense, which the
+// writes by ID don't carry
+func (repository ExpenseRepository) invalidatingGroupOf(expenseId uint, write func() error) error {
+	expense, err := repository.ExpenseRepository.GetById(expenseId)
+	if err != nil {
+		// The write reports the invalid ID or missing expense
+		return write()
+	}
+	err = write()
+	if err == nil {
+		repository.Cache.invalidate(repository.ctx, expense.GroupID)
+	}
+	return err
+}
+
+// UserRepository invalidates the groups of every user updated, cached groups and members
+// hold the display name and avatar of their users
+type UserRepository struct {
+	repository.UserRepository
+	// Groups lists the groups of the user, it must not be cached
+	Groups repository.GroupRepository
+	Cache  *Cache
+	ctx    context.Context
+}
+
+func NewUserRepository(userRepository repository.UserRepository, groups repository.GroupRepository, cache *Cache) repository.UserRepository {
+	return UserRepository{userRepository, groups, cache, context.Background()}
+}
+
+func (repository UserRepository) WithContext(ctx context.Context) repository.UserRepository {
+	return UserRepository{repository.UserRepository.WithContext(ctx), repository.Groups.WithContext(ctx), repository.Cache, ctx}
+}
+
+func (repository UserRepository) Update(userID uint, updateMap map[string]interface{}) (*model.User, error) {
+	user, err := repository.UserRepository.Update(userID, updateMap)
+	if err != nil {
+		return user, err
+	}
+	groups, err := repository.Groups.GetByUser(userID)
+	if err != nil {
+		// The update has succeeded and the TTL expires the entries eventually
+		repository.Cache.Logger.Warn("Cannot list the groups of an updated user", "user_id", userID, "error", err)
+		return user, nil
+	}
+	for _, group := range groups {
+		repository.Cache.invalidate(repository.ctx, group.ID)
+	}
+	return user, nil
+}
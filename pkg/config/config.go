@@ -0,0 +1,189 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"time"
+)
+
+// Config holds every setting of the server, read from the .env file and environment
+type Config struct {
+	ServerAddress         string
+	JWTKey                string
+	ShutdownTimeout       time.Duration
+	ReadinessCheckTimeout time.Duration
+	MigrationsAutoApply   bool
+
+	// DatabaseDriver is "postgres" or "sqlite", for SQLite DatabaseDSN is the file path
+	DatabaseDriver string
+	DatabaseDSN    string
+
+	LogFormat string
+	LogLevel  string
+
+	// CacheEnabled caches group details and member balances in redis for CacheTTL
+	CacheEnabled bool
+	CacheTTL     time.Duration
+
+	// Streams of group events send a heartbeat every StreamHeartbeatInterval, a user may have
+	// StreamMaxConnections streams open across instances
+	StreamHeartbeatInterval time.Duration
+	StreamMaxConnections    int
+
+	// Notifications are pushed to Android devices through FCM and to iOS devices through APNs
+	// when their credentials are set. New notifications are polled every PushPollInterval, a
+	// push is attempted PushMaxAttempts times waiting PushBackoff before the first retry, and at
+	// most PushMaxRetryDelay before any retry.
+	PushPollInterval   time.Duration
+	PushMaxAttempts    int
+	PushBackoff        time.Duration
+	PushMaxRetryDelay  time.Duration
+	FCMCredentialsFile string
+	APNsKeyFile        string
+	APNsKeyID          string
+	APNsTeamID         string
+	APNsTopic          string
+	APNsSandbox        bool
+
+	// Due webhook deliveries are polled every WebhookPollInterval, a delivery is attempted
+	// WebhookMaxAttempts times waiting WebhookBackoff before the first retry. A webhook is disabled
+	// once WebhookDisableAfter deliveries failed in a row. Endpoints on private networks are
+	// refused unless WebhookAllowPrivateNetworks is set.
+	WebhookPollInterval         time.Duration
+	WebhookMaxAttempts          int
+	WebhookBackoff              time.Duration
+	WebhookTimeout              time.Duration
+	WebhookDisableAfter         int
+	WebhookAllowPrivateNetworks bool
+
+	RateLimitDefault string
+	RateLimitAuth    string
+	TrustedProxies   string
+
+	TracingExporter     string
+	TracingOTLPEndpoint string
+	TracingOTLPInsecure bool
+	TracingSampleRatio  float64
+
+	// StorageBackend is "local" or "s3"
+	StorageBackend    string
+	StorageLocalDir   string
+	StoragePublicURL  string
+	StorageSigningKey string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKey       string
+	S3SecretKey       string
+	S3UseSSL          bool
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("SERVER_ADDRESS", ":8080")
+	v.SetDefault("SHUTDOWN_TIMEOUT", 15*time.Second)
+	v.SetDefault("READINESS_CHECK_TIMEOUT", 2*time.Second)
+	v.SetDefault("MIGRATIONS_AUTO_APPLY", false)
+	v.SetDefault("DATABASE_DRIVER", "postgres")
+	v.SetDefault("DATABASE_DSN", "")
+	v.SetDefault("LOG_FORMAT", "json")
+	v.SetDefault("LOG_LEVEL", "info")
+	v.SetDefault("CACHE_ENABLED", true)
+	v.SetDefault("CACHE_TTL", 5*time.Minute)
+	v.SetDefault("STREAM_HEARTBEAT_INTERVAL", 15*time.Second)
+	v.SetDefault("STREAM_MAX_CONNECTIONS", 5)
+	v.SetDefault("PUSH_POLL_INTERVAL", 2*time.Second)
+	v.SetDefault("PUSH_MAX_ATTEMPTS", 4)
+	v.SetDefault("PUSH_BACKOFF", time.Second)
+	v.SetDefault("PUSH_MAX_RETRY_DELAY", 5*time.Minute)
+	v.SetDefault("FCM_CREDENTIALS_FILE", "")
+	v.SetDefault("APNS_KEY_FILE", "")
+	v.SetDefault("APNS_SANDBOX", false)
+	v.SetDefault("WEBHOOK_POLL_INTERVAL", time.Second)
+	v.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	v.SetDefault("WEBHOOK_BACKOFF", 30*time.Second)
+	v.SetDefault("WEBHOOK_TIMEOUT", 10*time.Second)
+	v.SetDefault("WEBHOOK_DISABLE_AFTER", 5)
+	v.SetDefault("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+	v.SetDefault("RATE_LIMIT_DEFAULT", "50/10s")
+	v.SetDefault("RATE_LIMIT_AUTH", "10/1m")
+	v.SetDefault("TRUSTED_PROXIES", "")
+	v.SetDefault("TRACING_EXPORTER", "none")
+	v.SetDefault("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	v.SetDefault("TRACING_OTLP_INSECURE", true)
+	v.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	v.SetDefault("STORAGE_BACKEND", "local")
+	v.SetDefault("STORAGE_LOCAL_DIR", "./fileServer")
+	v.SetDefault("STORAGE_PUBLIC_URL", "http://localhost:8080/file")
+	v.SetDefault("S3_REGION", "us-east-1")
+	v.SetDefault("S3_BUCKET", "money-share")
+	v.SetDefault("S3_USE_SSL", true)
+}
+
+// Load reads the config file at path, environment variables take precedence over the file
+func Load(path string) (Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.AutomaticEnv()
+	setDefaults(v)
+	if err := v.ReadInConfig(); err != nil {
+		return Config{}, err
+	}
+	return fromViper(v), nil
+}
+
+// Default returns the config with default values only, used by tests
+func Default() Config {
+	v := viper.New()
+	setDefaults(v)
+	return fromViper(v)
+}
+
+func fromViper(v *viper.Viper) Config {
+	return Config{
+		ServerAddress:               v.GetString("SERVER_ADDRESS"),
+		JWTKey:                      v.GetString("JWT_KEY"),
+		ShutdownTimeout:             v.GetDuration("SHUTDOWN_TIMEOUT"),
+		ReadinessCheckTimeout:       v.GetDuration("READINESS_CHECK_TIMEOUT"),
+		MigrationsAutoApply:         v.GetBool("MIGRATIONS_AUTO_APPLY"),
+		DatabaseDriver:              v.GetString("DATABASE_DRIVER"),
+		DatabaseDSN:                 v.GetString("DATABASE_DSN"),
+		LogFormat:                   v.GetString("LOG_FORMAT"),
+		LogLevel:                    v.GetString("LOG_LEVEL"),
+		CacheEnabled:                v.GetBool("CACHE_ENABLED"),
+		CacheTTL:                    v.GetDuration("CACHE_TTL"),
+		StreamHeartbeatInterval:     v.GetDuration("STREAM_HEARTBEAT_INTERVAL"),
+		StreamMaxConnections:        v.GetInt("STREAM_MAX_CONNECTIONS"),
+		PushPollInterval:            v.GetDuration("PUSH_POLL_INTERVAL"),
+		PushMaxAttempts:             v.GetInt("PUSH_MAX_ATTEMPTS"),
+		PushBackoff:                 v.GetDuration("PUSH_BACKOFF"),
+		PushMaxRetryDelay:           v.GetDuration("PUSH_MAX_RETRY_DELAY"),
+		FCMCredentialsFile:          v.GetString("FCM_CREDENTIALS_FILE"),
+		APNsKeyFile:                 v.GetString("APNS_KEY_FILE"),
+		APNsKeyID:                   v.GetString("APNS_KEY_ID"),
+		APNsTeamID:                  v.GetString("APNS_TEAM_ID"),
+		APNsTopic:                   v.GetString("APNS_TOPIC"),
+		APNsSandbox:                 v.GetBool("APNS_SANDBOX"),
+		WebhookPollInterval:         v.GetDuration("WEBHOOK_POLL_INTERVAL"),
+		WebhookMaxAttempts:          v.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+		WebhookBackoff:              v.GetDuration("WEBHOOK_BACKOFF"),
+		WebhookTimeout:              v.GetDuration("WEBHOOK_TIMEOUT"),
+		WebhookDisableAfter:         v.GetInt("WEBHOOK_DISABLE_AFTER"),
+		WebhookAllowPrivateNetworks: v.GetBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS"),
+		RateLimitDefault:            v.GetString("RATE_LIMIT_DEFAULT"),
+		RateLimitAuth:               v.GetString("RATE_LIMIT_AUTH"),
+		TrustedProxies:              v.GetString("TRUSTED_PROXIES"),
+		TracingExporter:             v.GetString("TRACING_EXPORTER"),
+		TracingOTLPEndpoint:         v.GetString("TRACING_OTLP_ENDPOINT"),
+		TracingOTLPInsecure:         v.GetBool("TRACING_OTLP_INSECURE"),
+		TracingSampleRatio:          v.GetFloat64("TRACING_SAMPLE_RATIO"),
+		StorageBackend:              v.GetString("STORAGE_BACKEND"),
+		StorageLocalDir:             v.GetString("STORAGE_LOCAL_DIR"),
+		StoragePublicURL:            v.GetString("STORAGE_PUBLIC_URL"),
+		StorageSigningKey:           v.GetString("STORAGE_SIGNING_KEY"),
+		S3Endpoint:                  v.GetString("S3_ENDPOINT"),
+		S3Region:                    v.GetString("S3_REGION"),
+		S3Bucket:                    v.GetString("S3_BUCKET"),
+		S3AccessKey:                 v.GetString("S3_ACCESS_KEY"),
+		S3SecretKey:                 v.GetString("S3_SECRET_KEY"),
+		S3UseSSL:                    v.GetBool("S3_USE_SSL"),
+	}
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/dto"
+	"money_share/pkg/model"
+	"net/http"
+)
+
+func (app *App) GetGroupActivity(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Only members read the activity of a group
+	if _, err = app.requireMember(r, groupID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Get activities from database
+	activities, err := app.ActivityRepository.WithContext(r.Context()).ListByGroup(groupID, page)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, pageResponse(activities, func(activity *model.Activity) dto.ActivityDTO {
+		return dto.ActivityToActivityDTO(*activity)
+	}))
+}
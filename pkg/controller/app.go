@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"github.com/go-redis/redis/v9"
+	"log/slog"
+	"money_share/pkg/config"
+	"money_share/pkg/health"
+	"money_share/pkg/logging"
+	"money_share/pkg/realtime"
+	"money_share/pkg/repository"
+	"money_share/pkg/storage"
+	"net/http"
+	"time"
+)
+
+// App owns everything the HTTP handlers depend on. Handlers are methods of App so that
+// several instances with different dependencies can live in one process.
+type App struct {
+	Config config.Config
+	// Clock returns the current time, replaceable in tests
+	Clock  func() time.Time
+	Logger *slog.Logger
+	Redis  *redis.Client
+	// Storage keeps uploaded files
+	Storage storage.Storage
+
+	UserRepository         repository.UserRepository
+	GroupRepository        repository.GroupRepository
+	MemberRepository       repository.MemberRepository
+	ExpenseRepository      repository.ExpenseRepository
+	ActivityRepository     repository.ActivityRepository
+	NotificationRepository repository.NotificationRepository
+	DeviceRepository       repository.DeviceRepository
+	WebhookRepository      repository.WebhookRepository
+	PaymentRepository      repository.PaymentRepository
+	// Memberships is the uncached member repository permission checks read, so that removed
+	// and demoted members lose access at once. MemberRepository is used when it is nil.
+	Memberships repository.MemberRepository
+	// Events streams the changes of groups to their members
+	Events *realtime.Hub
+
+	HealthChecker *health.Checker
+}
+
+// Now returns the current time according to the app clock
+func (app *App) Now() time.Time {
+	if app.Clock == nil {
+		return time.Now()
+	}
+	return app.Clock()
+}
+
+// requestLogger returns the app logger annotated with the request ID of r
+func (app *App) requestLogger(r *http.Request) *slog.Logger {
+	return logging.WithContext(app.Logger, r.Context())
+}
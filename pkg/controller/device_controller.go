@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/apperror"
+	"money_share/pkg/dto"
+	"money_share/pkg/dto/request"
+	"money_share/pkg/model"
+	"net/http"
+)
+
+func (app *App) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	// Parse device data from request body
+	registrationRequest := &request.DeviceRegistrationRequest{}
+	if err := decodeBody(r, registrationRequest); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Get requester from header, devices are registered for the requester
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+	device := &model.Device{
+		UserID:     userID,
+		Platform:   registrationRequest.Platform,
+		Token:      registrationRequest.Token,
+		AppVersion: registrationRequest.AppVersion,
+	}
+
+	// Save device in database, registering a token again updates it
+	if err = app.DeviceRepository.WithContext(r.Context()).Register(device); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, dto.DeviceToDeviceDTO(*device))
+}
+
+func (app *App) GetDevices(w http.ResponseWriter, r *http.Request) {
+	// Get requester from header
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+
+	// Get devices from database
+	devices, err := app.DeviceRepository.WithContext(r.Context()).ListByUser(userID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	deviceDTOs := make([]dto.DeviceDTO, 0, len(devices))
+	for _, device := range devices {
+		deviceDTOs = append(deviceDTOs, dto.DeviceToDeviceDTO(*device))
+	}
+	app.ResponseJSON(w, deviceDTOs)
+}
+
+func (app *App) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	// Get device id from parameters
+	deviceID, err := parseID("deviceId", mux.Vars(r)["deviceId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Get requester from header, users unregister their own devices only
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+
+	// Delete device from database
+	if err = app.DeviceRepository.WithContext(r.Context()).Delete(userID, deviceID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	w.WriteHeader(http.StatusOK)
+}
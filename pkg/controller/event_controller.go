@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"money_share/pkg/apperror"
+	"money_share/pkg/realtime"
+	"net/http"
+	"time"
+)
+
+// How long opening a stream waits for the event hub
+const streamSubscribeTimeout = 5 * time.Second
+
+// Delay browsers wait before reconnecting a dropped stream, in milliseconds
+const streamRetry = 3000
+
+func (app *App) StreamGroupEvents(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Get requester from header, only members receive the events of a group
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+	// Browsers send the ID of the last event received when they reconnect
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID != "" && !realtime.ValidID(lastEventID) {
+		app.ResponseError(w, apperror.InvalidField("Last-Event-ID", "Last-Event-ID must be the ID of an event"))
+		return
+	}
+
+	if _, err = app.requireMember(r, groupID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Count the stream against the limit of the user, the lease outlives missed heartbeats
+	heartbeat := app.Config.StreamHeartbeatInterval
+	connection, ok, err := app.Events.Connect(r.Context(), userID, app.Config.StreamMaxConnections, 3*heartbeat)
+	if err != nil {
+		app.ResponseError(w, apperror.Wrap(err, apperror.KindUnavailable, "events_unavailable", "Events are unavailable"))
+		return
+	}
+	if !ok {
+		app.ResponseError(w, apperror.New(apperror.KindTooManyRequests, "too_many_streams", "Too many event streams open, close one first"))
+		return
+	}
+	defer connection.Close()
+
+	// Subscribe before reading the history so that no event falls in between
+	subscribeCtx, cancel := context.WithTimeout(r.Context(), streamSubscribeTimeout)
+	subscription, err := app.Events.Subscribe(subscribeCtx, groupID)
+	cancel()
+	if err != nil {
+		app.ResponseError(w, apperror.Wrap(err, apperror.KindUnavailable, "events_unavailable", "Events are unavailable"))
+		return
+	}
+	defer subscription.Close()
+
+	var missed []realtime.Event
+	var latestID string
+	complete := true
+	if lastEventID != "" {
+		missed, complete, latestID, err = app.Events.Since(r.Context(), groupID, lastEventID)
+		if err != nil {
+			app.ResponseError(w, apperror.Wrap(err, apperror.KindUnavailable, "events_unavailable", "Events are unavailable"))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	stream := &eventStream{w: w, flusher: http.NewResponseController(w), lastID: lastEventID}
+	stream.retry()
+	if !complete {
+		// The client resumes from the newest event after reloading the group
+		stream.lastID = latestID
+		stream.write(realtime.Event{ID: latestID, Type: realtime.EventReset, Data: []byte("{}")})
+	}
+	for _, event := range missed {
+		stream.send(event)
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for stream.err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			// Streams of accounts disabled or removed from the group since they were opened are closed
+			if app.lostAccess(r, userID, groupID) {
+				return
+			}
+			stream.heartbeat()
+			if err := connection.Refresh(r.Context()); err != nil {
+				app.Logger.Warn("Cannot refresh event stream connection", "user_id", userID, "error", err)
+			}
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// Dropped for lagging behind or shutting down, the client resumes from its last event
+				return
+			}
+			stream.send(event)
+		}
+	}
+	if !errors.Is(stream.err, context.Canceled) {
+		app.Logger.Debug("Event stream closed", "group_id", groupID, "error", stream.err)
+	}
+}
+
+// lostAccess reports whether the account of userID was disabled or deleted or left the group,
+// errors reading them keep the stream open
+func (app *App) lostAccess(r *http.Request, userID uint, groupID uint) bool {
+	user, err := app.UserRepository.WithContext(r.Context()).GetById(userID)
+	if err == nil && user.IsDisabled() {
+		return true
+	}
+	if err == nil {
+		_, err = app.requireMember(r, groupID)
+	}
+	if apperror.Is(err, apperror.KindNotFound) || apperror.Is(err, apperror.KindForbidden) {
+		return true
+	}
+	if err != nil {
+		app.Logger.Warn("Cannot check user of event stream", "user_id", userID, "error", err)
+	}
+	return false
+}
+
+// eventStream writes the events of a stream, events up to lastID have been sent. Writing
+// stops at the first error.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher *http.ResponseController
+	lastID  string
+	err     error
+}
+
+// send writes an event unless it has been sent already
+func (s *eventStream) send(event realtime.Event) {
+	// Events published while the history was read are received twice
+	if s.lastID != "" && realtime.CompareIDs(event.ID, s.lastID) <= 0 {
+		return
+	}
+	s.lastID = event.ID
+	s.write(event)
+}
+
+func (s *eventStream) write(event realtime.Event) {
+	if s.err == nil {
+		s.err = realtime.WriteEvent(s.w, event)
+		s.flush()
+	}
+}
+
+func (s *eventStream) heartbeat() {
+	if s.err == nil {
+		s.err = realtime.WriteHeartbeat(s.w)
+		s.flush()
+	}
+}
+
+func (s *eventStream) retry() {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, "retry: %d\n\n", streamRetry)
+		s.flush()
+	}
+}
+
+func (s *eventStream) flush() {
+	if s.err == nil {
+		s.err = s.flusher.Flush()
+	}
+}
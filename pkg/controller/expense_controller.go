@@ -1,250 +1,343 @@
 package controller
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
+	"money_share/pkg/apperror"
 	"money_share/pkg/dto"
+	"money_share/pkg/metrics"
+	"money_share/pkg/model"
 	"money_share/pkg/repository"
+	"money_share/pkg/util"
 	"net/http"
 	"strconv"
+	"time"
 )
 
-var ExpenseRepository repository.ExpenseRepository
-
-func GetExpenseByID(w http.ResponseWriter, r *http.Request) {
+func (app *App) GetExpenseByID(w http.ResponseWriter, r *http.Request) {
 	// Get expense id from parameters
-	params := mux.Vars(r)
-	expenseIDStr := params["expenseId"]
-	expenseID, err := strconv.ParseUint(expenseIDStr, 0, 32)
+	expenseID, err := parseID("expenseId", mux.Vars(r)["expenseId"])
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse expense id '%s': %s", expenseIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
-	// Get expense from database
-	expense, err := ExpenseRepository.GetById(uint(expenseID))
+	// Get expense from database, only members of its group read it
+	expense, err := app.ExpenseRepository.WithContext(r.Context()).GetById(expenseID)
+	if err == nil {
+		_, err = app.requireMember(r, expense.GroupID)
+	}
 	if err != nil {
-		errMsg := fmt.Sprintf("Error getting expense by id '%d': %s", expenseID, err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write to response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
 	expenseDTO := dto.ExpenseToExpenseDTO(*expense)
-	err = json.NewEncoder(w).Encode(expenseDTO)
-	if err != nil {
-		errMsg := fmt.Sprintf("Error encoding to json: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
-		return
-	}
+	app.ResponseJSON(w, expenseDTO)
 }
 
-func GetExpensesByGroup(w http.ResponseWriter, r *http.Request) {
+func (app *App) GetExpensesByGroup(w http.ResponseWriter, r *http.Request) {
 	// Get group id from parameters
-	params := mux.Vars(r)
-	groupIDStr := params["groupId"]
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group id '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
+	app.listExpenses(w, r, groupID)
+}
 
-	// Get expenses from database
-	expenses, err := ExpenseRepository.GetByGroup(uint(groupID))
-	if err != nil {
-		errMsg := fmt.Sprintf("Error getting expense by group id '%d': %s", groupID, err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+func (app *App) GetExpensesByMember(w http.ResponseWriter, r *http.Request) {
+	// Get group id from queries, the member is filtered like any other field
+	queries := r.URL.Query()
+	groupID, groupErr := parseID("groupId", queries.Get("groupId"))
+	_, memberErr := parseID("memberId", queries.Get("memberId"))
+	if err := apperror.Join(memberErr, groupErr); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
+	app.listExpenses(w, r, groupID)
+}
 
-	// Write to response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	var expenseDTOs []dto.ExpenseDTO
-	for _, expense := range expenses {
-		expenseDTOs = append(expenseDTOs, dto.ExpenseToExpenseDTO(*expense))
-	}
-	err = json.NewEncoder(w).Encode(expenseDTOs)
+func (app *App) SearchExpenses(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from header, only the groups of the user are searched
+	userID, err := parseID("userID", r.Header.Get("userID"))
 	if err != nil {
-		errMsg := fmt.Sprintf("Error encoding to json: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
 		return
 	}
-}
-
-func GetExpensesByMember(w http.ResponseWriter, r *http.Request) {
-	// Get member id and group id from queries
 	queries := r.URL.Query()
-	memberIDStr := queries.Get("memberId")
-	groupIDStr := queries.Get("groupId")
-	if len(memberIDStr) == 0 || len(groupIDStr) == 0 {
-		errMsg := fmt.Sprintf("Not enough parameters provided, required 'memberId' and 'groupId'")
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+	search := repository.ExpenseSearch{UserID: userID, Query: queries.Get("q")}
+	var groupErr error
+	if groupIDStr := queries.Get("groupId"); groupIDStr != "" {
+		search.GroupID, groupErr = parseID("groupId", groupIDStr)
+	}
+	page, pageErr := parsePage(r)
+	if err = apperror.Join(groupErr, pageErr); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
-	memberID, err := strconv.ParseUint(memberIDStr, 0, 32)
+
+	// Search expenses in database
+	matches, err := app.ExpenseRepository.WithContext(r.Context()).Search(search, page)
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse member id '%s': %s", memberIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
-	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group id '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+
+	// Write to response
+	app.ResponseJSON(w, pageResponse(matches, func(match *repository.ExpenseMatch) dto.ExpenseMatchDTO {
+		return dto.ExpenseMatchDTO{Expense: dto.ExpenseToExpenseDTO(*match.Expense), Rank: match.Rank, Snippet: match.Snippet}
+	}))
+}
+
+// listExpenses writes a page of the expenses of a group filtered and ordered by the query parameters
+func (app *App) listExpenses(w http.ResponseWriter, r *http.Request, groupID uint) {
+	filter, filterErr := parseExpenseFilter(r)
+	page, pageErr := parsePage(r)
+	if err := apperror.Join(filterErr, pageErr); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	filter.GroupID = groupID
+	if _, err := app.requireMember(r, groupID); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Get expenses from database
-	expenses, err := ExpenseRepository.GetByMember(uint(memberID), uint(groupID))
+	expenses, err := app.ExpenseRepository.WithContext(r.Context()).List(filter, page)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error getting expenses: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write to response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	var expenseDTOs []dto.ExpenseDTO
-	for _, expense := range expenses {
-		expenseDTOs = append(expenseDTOs, dto.ExpenseToExpenseDTO(*expense))
+	app.ResponseJSON(w, pageResponse(expenses, func(expense *model.Expense) dto.ExpenseDTO {
+		return dto.ExpenseToExpenseDTO(*expense)
+	}))
+}
+
+// parseExpenseFilter parses the filter and order query parameters of expense lists, every
+// invalid parameter is reported
+func parseExpenseFilter(r *http.Request) (repository.ExpenseFilter, error) {
+	queries := r.URL.Query()
+	filter := repository.ExpenseFilter{
+		Status: queries.Get("status"),
+		Sort:   repository.ExpenseSort(queries.Get("sort")),
+		// Newest first unless asked otherwise
+		Descending: queries.Get("order") != "asc",
+	}
+	var errs []error
+	if order := queries.Get("order"); order != "" && order != "asc" && order != "desc" {
+		errs = append(errs, apperror.InvalidField("order", "order must be 'asc' or 'desc'"))
+	}
+	if memberIDStr := queries.Get("memberId"); memberIDStr != "" {
+		memberID, err := parseID("memberId", memberIDStr)
+		errs = append(errs, err)
+		filter.MemberID = memberID
 	}
-	err = json.NewEncoder(w).Encode(expenseDTOs)
+	var err error
+	filter.PurchasedFrom, err = parseTimeQuery(queries.Get("from"), "from", false)
+	errs = append(errs, err)
+	filter.PurchasedTo, err = parseTimeQuery(queries.Get("to"), "to", true)
+	errs = append(errs, err)
+	filter.MinAmount, err = parseAmountQuery(queries.Get("minAmount"), "minAmount")
+	errs = append(errs, err)
+	filter.MaxAmount, err = parseAmountQuery(queries.Get("maxAmount"), "maxAmount")
+	errs = append(errs, err)
+	return filter, apperror.Join(errs...)
+}
+
+// parseTimeQuery parses a date and time or a date, which is the start of the day or with
+// endOfDay the end of the day
+func parseTimeQuery(value string, name string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(util.DateTimeLayout, value); err == nil {
+		return &parsed, nil
+	}
+	parsed, err := time.Parse(util.ShortDateLayout, value)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error encoding to json: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
-		return
+		return nil, apperror.InvalidField(name, fmt.Sprintf("%s must be a date or a date and time, e.g. '%s'", name, util.DateTimeLayout))
+	}
+	if endOfDay {
+		parsed = parsed.Add(24*time.Hour - time.Nanosecond)
 	}
+	return &parsed, nil
 }
 
-func CreateExpense(w http.ResponseWriter, r *http.Request) {
+func parseAmountQuery(value string, name string) (*float32, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return nil, apperror.InvalidField(name, fmt.Sprintf("Cannot parse %s '%s'", name, value))
+	}
+	result := float32(amount)
+	return &result, nil
+}
+
+func (app *App) CreateExpense(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	expenseDTO := &dto.ExpenseDTO{}
-	err := json.NewDecoder(r.Body).Decode(expenseDTO)
-	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse request body: %s", err), http.StatusBadRequest)
+	if err := decodeBody(r, expenseDTO); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 	// Get user ID from header
-	userIDString := r.Header.Get("userID")
-	userID, _ := strconv.ParseUint(userIDString, 0, 32)
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
 
-	// TODO: Validate fields
-	expense := expenseDTO.MapToDomain()
+	expense, err := expenseDTO.MapToDomain()
+	if err != nil {
+		app.ResponseError(w, apperror.InvalidField("purchaseTime", "Cannot parse purchase time"))
+		return
+	}
+	if err = expense.ValidateFields(); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
 
 	// Get requester role in group
-	user, err := MemberRepository.GetByID(uint(userID), expense.GroupID)
+	user, err := app.requireMember(r, expense.GroupID)
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Error validating requester role in group: %s", err),
-			http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
-	role := user.Role
-	if role == "member" {
-		expense.Status = "pending"
-		if expense.MemberID != uint(userID) {
-			ResponseError(w, "You are not a manager, you cannot add expense for another member", http.StatusBadRequest)
+	switch user.Role {
+	case model.RoleMember:
+		expense.Status = model.StatusPending
+		if expense.MemberID != userID {
+			app.ResponseError(w, apperror.Forbidden("not_group_manager", "You are not a manager, you cannot add expense for another member"))
 			return
 		}
-	} else if role == "manager" {
-		expense.Status = "approved"
+	case model.RoleManager:
+		expense.Status = model.StatusApproved
 		// Validate member of group
-		if expense.MemberID != uint(userID) {
-			_, err := MemberRepository.GetByID(expense.MemberID, expense.GroupID)
+		if expense.MemberID != userID {
+			_, err := app.memberships(r).GetByID(expense.MemberID, expense.GroupID)
+			if apperror.Is(err, apperror.KindNotFound) {
+				app.ResponseError(w, apperror.InvalidField("memberID", "User provided is not a member of the group"))
+				return
+			}
 			if err != nil {
-				ResponseError(w, "User provided is not a member of the group", http.StatusBadRequest)
+				app.ResponseError(w, err)
 				return
 			}
 		}
-	} else {
-		ResponseError(w, "You are not a member of this group", http.StatusBadRequest)
+	default:
+		app.ResponseError(w, apperror.Forbidden("not_group_member", "You are not a member of this group"))
 		return
 	}
 
 	// Create expense in database
-	err = ExpenseRepository.Create(&expense)
+	err = app.ExpenseRepository.WithContext(r.Context()).Create(&expense)
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Error creating expense: %s", err), http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
+	metrics.ExpensesCreated.WithLabelValues(expense.Status).Inc()
+	if expense.Status == model.StatusApproved {
+		metrics.ExpensesApproved.Inc()
+	}
 
 	// Write to response
 	savedExpenseDTO := dto.ExpenseToExpenseDTO(expense)
-	ResponseJSON(w, savedExpenseDTO)
+	app.ResponseJSON(w, savedExpenseDTO)
 }
 
-func UpdateExpense(w http.ResponseWriter, r *http.Request) {
+func (app *App) UpdateExpense(w http.ResponseWriter, r *http.Request) {
 	// Get expense id from parameters
-	params := mux.Vars(r)
-	expenseIDStr := params["expenseId"]
-	expenseID, err := strconv.ParseUint(expenseIDStr, 0, 32)
+	expenseID, err := parseID("expenseId", mux.Vars(r)["expenseId"])
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse expense id '%s': %s", expenseIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Parse expense data from request body
 	expenseDTO := &dto.ExpenseDTO{}
-	err = json.NewDecoder(r.Body).Decode(expenseDTO)
+	if err = decodeBody(r, expenseDTO); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	expense, err := expenseDTO.MapToDomain()
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse request body: %s", err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, apperror.InvalidField("purchaseTime", "Cannot parse purchase time"))
+		return
+	}
+	expense.ID = expenseID
+
+	// Members edit their own expenses, managers edit any and approve or deny them
+	expenseRepository := app.ExpenseRepository.WithContext(r.Context())
+	stored, err := expenseRepository.GetById(expenseID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	requester, err := app.requireMember(r, stored.GroupID)
+	if err == nil && requester.Role != model.RoleManager {
+		switch {
+		case stored.MemberID != requester.UserID:
+			err = apperror.Forbidden("not_group_manager", "You are not a manager, you cannot update the expense of another member")
+		case expense.Status != "" && expense.Status != stored.Status:
+			err = apperror.Forbidden("not_group_manager", "You are not a manager, you cannot approve or deny expenses")
+		}
+		// The status is left as stored, even when a manager changes it meanwhile
+		expense.Status = ""
+	}
+	if err != nil {
+		app.ResponseError(w, err)
 		return
 	}
-	expense := expenseDTO.MapToDomain()
-	expense.ID = uint(expenseID)
 
 	// Update expense in database
-	err = ExpenseRepository.Update(&expense)
+	action, err := expenseRepository.Update(&expense)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error updating expense: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
+	if action == model.ActionExpenseApproved {
+		metrics.ExpensesApproved.Inc()
+	}
 
 	// Write to response
 	w.WriteHeader(http.StatusOK)
 }
 
-func DeleteExpense(w http.ResponseWriter, r *http.Request) {
+func (app *App) DeleteExpense(w http.ResponseWriter, r *http.Request) {
 	// Get expense id from parameters
-	params := mux.Vars(r)
-	expenseIDStr := params["expenseId"]
-	expenseID, err := strconv.ParseUint(expenseIDStr, 0, 32)
+	expenseID, err := parseID("expenseId", mux.Vars(r)["expenseId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Members delete their own expenses, managers delete any
+	expenseRepository := app.ExpenseRepository.WithContext(r.Context())
+	stored, err := expenseRepository.GetById(expenseID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	requester, err := app.requireMember(r, stored.GroupID)
+	if err == nil && requester.UserID != stored.MemberID {
+		err = app.requireManager(r, stored.GroupID, "delete the expense of another member")
+	}
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse expense id '%s': %s", expenseIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Delete expense from database
-	err = ExpenseRepository.Delete(uint(expenseID))
+	err = expenseRepository.Delete(expenseID)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error deleting expense: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
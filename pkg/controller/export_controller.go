@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"mime"
+	"money_share/pkg/apperror"
+	"money_share/pkg/export"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Characters replaced in the names of exported files
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// parseExportFilter parses the date range and status of an export, expenses are exported in
+// purchase order
+func parseExportFilter(r *http.Request, groupID uint) (repository.ExpenseFilter, error) {
+	queries := r.URL.Query()
+	filter := repository.ExpenseFilter{GroupID: groupID, Status: queries.Get("status"), Sort: repository.SortPurchaseTime}
+	var errs []error
+	var err error
+	filter.PurchasedFrom, err = parseTimeQuery(queries.Get("from"), "from", false)
+	errs = append(errs, err)
+	filter.PurchasedTo, err = parseTimeQuery(queries.Get("to"), "to", true)
+	errs = append(errs, err)
+	if err = apperror.Join(errs...); err != nil {
+		return filter, err
+	}
+	return filter, filter.Validate()
+}
+
+func (app *App) ExportGroup(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	filter, err := parseExportFilter(r, groupID)
+	if err = apperror.Join(export.ValidateFormat(format), err); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Only members export a group
+	if _, err = app.requireMember(r, groupID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Get group with its members from database
+	group, err := app.GroupRepository.WithContext(r.Context()).GetById(groupID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	members := make([]*model.Member, 0, len(group.Members))
+	for i := range group.Members {
+		members = append(members, &group.Members[i])
+	}
+	ledger := export.NewLedger(members)
+
+	// Write to response, expenses are read and written a page at a time
+	fileName := strings.Trim(unsafeFileNameChars.ReplaceAllString(strings.ToLower(group.Name), "-"), "-")
+	if fileName == "" {
+		fileName = "group"
+	}
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": fmt.Sprintf("%s-expenses.%s", fileName, format),
+	}))
+	writer, err := export.NewWriter(format, w, group.Name+" expenses")
+	if err == nil {
+		err = app.writeLedger(r, writer, ledger, filter)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		// The status is sent already, aborting tells the client the file is incomplete
+		app.requestLogger(r).Error("Cannot export group", "group_id", groupID, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// writeLedger writes the expenses matching filter and the summary of the ledger
+func (app *App) writeLedger(r *http.Request, writer export.Writer, ledger *export.Ledger, filter repository.ExpenseFilter) error {
+	expenses := app.ExpenseRepository.WithContext(r.Context())
+	page := repository.PageRequest{Limit: repository.MaxPageLimit}
+	for {
+		expensePage, err := expenses.List(filter, page)
+		if err != nil {
+			return err
+		}
+		for _, expense := range expensePage.Items {
+			payer, ok := ledger.Name(expense.MemberID)
+			if !ok {
+				// Payers who left the group are looked up once
+				payer = "Former member"
+				if user, err := app.UserRepository.WithContext(r.Context()).GetById(expense.MemberID); err == nil {
+					payer = export.DisplayName(*user)
+				}
+				ledger.AddPayer(expense.MemberID, payer)
+			}
+			ledger.Add(expense)
+			err = writer.WriteExpense(export.Expense{
+				Title:        expense.Title,
+				Description:  expense.Description,
+				Amount:       expense.Amount,
+				Payer:        payer,
+				PurchaseTime: expense.PurchaseTime,
+				Status:       expense.Status,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if expensePage.NextCursor == "" {
+			break
+		}
+		page.Cursor = expensePage.NextCursor
+	}
+	return writer.WriteSummary(ledger.Summary())
+}
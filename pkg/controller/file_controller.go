@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"io"
+	"money_share/pkg/apperror"
+	"money_share/pkg/storage"
+	"net/http"
+	"strconv"
+)
+
+// GetSignedFile serves an object of the local storage through a URL produced by SignedURL.
+// Other backends hand out their own signed URLs, so this route only exists for local storage.
+func (app *App) GetSignedFile(w http.ResponseWriter, r *http.Request) {
+	localStorage, ok := app.Storage.(*storage.LocalStorage)
+	if !ok {
+		app.HandleNotFound(w, r)
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	if !localStorage.VerifySignedURL(key, r.URL.Query()) {
+		app.ResponseError(w, apperror.Forbidden("invalid_signature", "Invalid or expired signature"))
+		return
+	}
+
+	reader, info, err := localStorage.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		app.ResponseError(w, errFileNotFound)
+		return
+	}
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	defer reader.Close()
+
+	// Write to response
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, reader)
+}
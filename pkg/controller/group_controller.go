@@ -1,145 +1,137 @@
 package controller
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/gorilla/mux"
-	"log"
+	"money_share/pkg/apperror"
 	"money_share/pkg/dto"
 	"money_share/pkg/dto/request"
+	"money_share/pkg/metrics"
 	"money_share/pkg/model"
-	"money_share/pkg/repository"
 	"net/http"
-	"strconv"
 )
 
-var GroupRepository repository.GroupRepository
-
-func GetGroupById(w http.ResponseWriter, r *http.Request) {
+func (app *App) GetGroupById(w http.ResponseWriter, r *http.Request) {
 	// Get group id from parameters
-	params := mux.Vars(r)
-	groupIDStr := params["groupId"]
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse group ID '%s': %s", groupIDStr, err), http.StatusBadRequest)
+		app.ResponseError(w, err)
 		return
 	}
 
-	// Get group from database
-	group, err := GroupRepository.GetById(uint(groupID))
+	// Get group from database, only members of the group read it
+	group, err := app.GroupRepository.WithContext(r.Context()).GetById(groupID)
+	if err == nil {
+		_, err = app.requireMember(r, groupID)
+	}
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Failed to get group by ID '%d'", groupID), http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write to response
 	groupDTO := dto.GroupToGroupDTO(*group)
-	ResponseJSON(w, groupDTO)
+	app.ResponseJSON(w, groupDTO)
 }
 
-func GetGroupsByUser(w http.ResponseWriter, r *http.Request) {
+func (app *App) GetGroupsByUser(w http.ResponseWriter, r *http.Request) {
 	// Get user id from parameters
-	params := mux.Vars(r)
-	userIDStr := params["userId"]
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
+	userID, err := parseID("userId", mux.Vars(r)["userId"])
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse user id '%s': %s", userIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
-	// Get groups from database
-	groups, err := GroupRepository.GetByUser(uint(userID))
+	// Users list their own groups only
+	requesterID, err := parseID("userID", r.Header.Get("userID"))
 	if err != nil {
-		errMsg := fmt.Sprintf("Error getting groups by user: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+	if requesterID != userID {
+		app.ResponseError(w, apperror.Forbidden("forbidden", "You can only list your own groups"))
 		return
 	}
 
-	// Write to response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	var groupDTOs []dto.GroupDTO
-	for _, group := range groups {
-		groupDTOs = append(groupDTOs, dto.GroupToGroupDTO(*group))
+	page, err := parsePage(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
 	}
-	err = json.NewEncoder(w).Encode(groupDTOs)
+
+	// Get groups from database
+	groups, err := app.GroupRepository.WithContext(r.Context()).ListByUser(userID, page)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error encoding to json: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
+
+	// Write to response
+	app.ResponseJSON(w, pageResponse(groups, func(group *model.Group) dto.GroupDTO {
+		return dto.GroupToGroupDTO(*group)
+	}))
 }
 
-func CreateGroup(w http.ResponseWriter, r *http.Request) {
+func (app *App) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	// Parse group data from request body
 	groupCreationRequest := &request.GroupCreationRequest{}
-	err := json.NewDecoder(r.Body).Decode(groupCreationRequest)
-	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse request body: %s", err), http.StatusBadRequest)
+	if err := decodeBody(r, groupCreationRequest); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 	group := &model.Group{
 		Name: groupCreationRequest.Name,
 	}
 	// Get creator id from header
-	userIDStr := r.Header.Get("userID")
-	userID, _ := strconv.ParseUint(userIDStr, 0, 32)
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
 
 	// Create group in database
-	err = GroupRepository.Create(group, uint(userID))
+	err = app.GroupRepository.WithContext(r.Context()).Create(group, userID)
 	if err != nil {
-		ResponseError(w, "Error creating group", http.StatusBadRequest)
-		log.Println(err)
+		app.ResponseError(w, err)
 		return
 	}
+	metrics.GroupsCreated.Inc()
 
 	// Write to response
 	groupDTO := dto.GroupToGroupDTO(*group)
 	groupDTO.Members = nil
 	groupDTO.Expenses = nil
-	ResponseJSON(w, groupDTO)
+	app.ResponseJSON(w, groupDTO)
 }
 
-func UpdateGroup(w http.ResponseWriter, r *http.Request) {
+func (app *App) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	// Get group ID from parameters
-	params := mux.Vars(r)
-	groupIDStr := params["groupId"]
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group ID '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Parse group data from request body
 	groupDTO := &dto.GroupDTO{}
-	err = json.NewDecoder(r.Body).Decode(groupDTO)
-	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse request body: %s", err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+	if err = decodeBody(r, groupDTO); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
-	groupDTO.ID = uint(groupID)
+	groupDTO.ID = groupID
 	group, err := groupDTO.MapToDomain()
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse model: %s", err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, apperror.Wrap(err, apperror.KindValidation, "invalid_body", "Cannot parse group"))
+		return
+	}
+	if err = app.requireManager(r, groupID, "update this group"); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Update group to database
-	err = GroupRepository.Update(&group)
+	err = app.GroupRepository.WithContext(r.Context()).Update(&group)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error while updating group: %s", err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
@@ -147,24 +139,23 @@ func UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func DeleteGroup(w http.ResponseWriter, r *http.Request) {
-	// Get user id from parameters
-	params := mux.Vars(r)
-	groupIDStr := params["groupId"]
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
+func (app *App) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group ID '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
+		return
+	}
+
+	if err = app.requireManager(r, groupID, "delete this group"); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 
-	// Delete user from database and write response
-	err = GroupRepository.Delete(uint(groupID))
+	// Delete group from database and write response
+	err = app.GroupRepository.WithContext(r.Context()).Delete(groupID)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error deleting group with ID '%d': %s", groupID, err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"encoding/json"
+	"money_share/pkg/apperror"
+	"net/http"
+)
+
+// Healthz reports that the process is alive and able to serve requests
+func (app *App) Healthz(w http.ResponseWriter, r *http.Request) {
+	app.ResponseJSON(w, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether all dependencies are reachable and the server is not shutting down
+func (app *App) Readyz(w http.ResponseWriter, r *http.Request) {
+	if app.HealthChecker == nil {
+		app.ResponseError(w, apperror.New(apperror.KindUnavailable, "not_ready", "Readiness checks are not configured"))
+		return
+	}
+	report := app.HealthChecker.Check(r.Context())
+
+	// Write to response
+	code := http.StatusOK
+	if !report.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -1,134 +1,99 @@
 package controller
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/gorilla/mux"
+	"money_share/pkg/apperror"
 	"money_share/pkg/dto"
-	"money_share/pkg/repository"
+	"money_share/pkg/model"
 	"net/http"
-	"strconv"
 )
 
-var MemberRepository repository.MemberRepository
+// memberQuery parses the user and group of a member from the query, every invalid parameter is reported
+func memberQuery(r *http.Request) (userID uint, groupID uint, err error) {
+	queries := r.URL.Query()
+	userID, userErr := parseID("userId", queries.Get("userId"))
+	groupID, groupErr := parseID("groupId", queries.Get("groupId"))
+	err = apperror.Join(userErr, groupErr)
+	return
+}
 
-func GetMemberByID(w http.ResponseWriter, r *http.Request) {
+func (app *App) GetMemberByID(w http.ResponseWriter, r *http.Request) {
 	// Get user id and group id form query params
-	queries := r.URL.Query()
-	userIDStr := queries.Get("userId")
-	groupIDStr := queries.Get("groupId")
-	if len(groupIDStr) == 0 || len(groupIDStr) == 0 {
-		errMsg := fmt.Sprintf("Not enough parameters provided, required 'userId' and 'groupId'")
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
-		return
-	}
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
+	userID, groupID, err := memberQuery(r)
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse user ID '%s': %s", userIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
-	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group ID '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+
+	// Only members see the members of a group
+	if _, err = app.requireMember(r, groupID); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Get member from database
-	member, err := MemberRepository.GetByID(uint(userID), uint(groupID))
+	member, err := app.MemberRepository.WithContext(r.Context()).GetByID(userID, groupID)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error getting member by user '%d', group '%d': %s", userID, groupID, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write to response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
 	memberDTO := dto.MemberToMemberDTO(*member)
-	err = json.NewEncoder(w).Encode(memberDTO)
-	if err != nil {
-		errMsg := fmt.Sprintf("Error encoding to json: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
-		return
-	}
+	app.ResponseJSON(w, memberDTO)
 }
 
-func GetMembersOfGroup(w http.ResponseWriter, r *http.Request) {
+func (app *App) GetMembersOfGroup(w http.ResponseWriter, r *http.Request) {
 	// Get group id from parameters
-	params := mux.Vars(r)
-	groupIDStr := params["groupId"]
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group id '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
-	// Get members from database
-	members, err := MemberRepository.GetByGroup(uint(groupID))
+	page, err := parsePage(r)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error getting members by group: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
-	// Write to response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	var memberDTOs []dto.MemberDTO
-	for _, member := range members {
-		memberDTOs = append(memberDTOs, dto.MemberToMemberDTO(*member))
+	// Only members see the members of a group
+	if _, err = app.requireMember(r, groupID); err != nil {
+		app.ResponseError(w, err)
+		return
 	}
-	err = json.NewEncoder(w).Encode(memberDTOs)
+
+	// Get members from database
+	members, err := app.MemberRepository.WithContext(r.Context()).ListByGroup(groupID, page)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error encoding to json: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
+
+	// Write to response
+	app.ResponseJSON(w, pageResponse(members, func(member *model.Member) dto.MemberDTO {
+		return dto.MemberToMemberDTO(*member)
+	}))
 }
 
-func AddMemberToGroup(w http.ResponseWriter, r *http.Request) {
+func (app *App) AddMemberToGroup(w http.ResponseWriter, r *http.Request) {
 	// Get user id and group id form query params
-	queries := r.URL.Query()
-	userIDStr := queries.Get("userId")
-	groupIDStr := queries.Get("groupId")
-	if len(userIDStr) == 0 || len(groupIDStr) == 0 {
-		errMsg := fmt.Sprintf("Not enough parameters provided, required 'userId' and 'groupId'")
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
-		return
-	}
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
+	userID, groupID, err := memberQuery(r)
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse user ID '%s': %s", userIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
-	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group ID '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+
+	// Only managers add members
+	if err = app.requireManager(r, groupID, "add members to this group"); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Add member to group in database
-	err = MemberRepository.AddMemberToGroup(uint(userID), uint(groupID))
+	err = app.MemberRepository.WithContext(r.Context()).AddMemberToGroup(userID, groupID)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error adding member to group: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
@@ -136,38 +101,28 @@ func AddMemberToGroup(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func RemoveMemberFromGroup(w http.ResponseWriter, r *http.Request) {
+func (app *App) RemoveMemberFromGroup(w http.ResponseWriter, r *http.Request) {
 	// Get user id and group id form query params
-	queries := r.URL.Query()
-	userIDStr := queries.Get("userId")
-	groupIDStr := queries.Get("groupId")
-	if len(groupIDStr) == 0 || len(groupIDStr) == 0 {
-		errMsg := fmt.Sprintf("Not enough parameters provided, required 'userId' and 'groupId'")
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
-		return
-	}
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
+	userID, groupID, err := memberQuery(r)
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse user ID '%s': %s", userIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
-	groupID, err := strconv.ParseUint(groupIDStr, 0, 32)
+
+	// Managers remove members, other members can only leave
+	requester, err := app.requireMember(r, groupID)
+	if err == nil && requester.UserID != userID {
+		err = app.requireManager(r, groupID, "remove other members from this group")
+	}
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot parse group ID '%s': %s", groupIDStr, err)
-		http.Error(w, errMsg, http.StatusBadRequest)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Remove member from group in database
-	err = MemberRepository.RemoveMemberFromGroup(uint(userID), uint(groupID))
+	err = app.MemberRepository.WithContext(r.Context()).RemoveMemberFromGroup(userID, groupID)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error removing member from group: %s", err)
-		http.Error(w, errMsg, http.StatusInternalServerError)
-		fmt.Println(errMsg)
+		app.ResponseError(w, err)
 		return
 	}
 
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/apperror"
+	"money_share/pkg/dto"
+	"money_share/pkg/dto/response"
+	"money_share/pkg/model"
+	"net/http"
+	"strconv"
+)
+
+func (app *App) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	// Get requester from header
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	unreadOnly := false
+	if unreadStr := r.URL.Query().Get("unread"); unreadStr != "" {
+		if unreadOnly, err = strconv.ParseBool(unreadStr); err != nil {
+			app.ResponseError(w, apperror.InvalidField("unread", "unread must be true or false"))
+			return
+		}
+	}
+
+	// Get notifications from database
+	notificationRepository := app.NotificationRepository.WithContext(r.Context())
+	notifications, err := notificationRepository.ListByUser(userID, unreadOnly, page)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	unreadCount, err := notificationRepository.CountUnread(userID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, response.NotificationPageResponse{
+		PageResponse: pageResponse(notifications, func(notification *model.Notification) dto.NotificationDTO {
+			return dto.NotificationToNotificationDTO(*notification)
+		}),
+		UnreadCount: unreadCount,
+	})
+}
+
+func (app *App) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	// Get notification id from parameters
+	notificationID, err := parseID("notificationId", mux.Vars(r)["notificationId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Get requester from header, users mark their own notifications only
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+
+	if err = app.NotificationRepository.WithContext(r.Context()).MarkRead(userID, notificationID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (app *App) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	// Get requester from header
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+
+	if err = app.NotificationRepository.WithContext(r.Context()).MarkAllRead(userID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (app *App) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Get requester from header
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+
+	preferences, err := app.NotificationRepository.WithContext(r.Context()).GetPreferences(userID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	app.ResponseJSON(w, dto.NotificationPreferencesDTO{Preferences: preferences})
+}
+
+func (app *App) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Get requester from header
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		app.ResponseError(w, apperror.Unauthorized("invalid_token", "Missing user in token"))
+		return
+	}
+	// Types left out keep their preference
+	var body dto.NotificationPreferencesDTO
+	if err = decodeBody(r, &body); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	notificationRepository := app.NotificationRepository.WithContext(r.Context())
+	if err = notificationRepository.UpdatePreferences(userID, body.Preferences); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	preferences, err := notificationRepository.GetPreferences(userID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	app.ResponseJSON(w, dto.NotificationPreferencesDTO{Preferences: preferences})
+}
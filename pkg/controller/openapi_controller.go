@@ -0,0 +1,14 @@
+package controller
+
+import (
+	"money_share/pkg/openapi"
+	"net/http"
+)
+
+// GetOpenAPIDocument serves the OpenAPI document describing the API
+func (app *App) GetOpenAPIDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openapi.JSON)
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/apperror"
+	"money_share/pkg/dto"
+	"money_share/pkg/dto/request"
+	"money_share/pkg/model"
+	"net/http"
+)
+
+func (app *App) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Parse payment data from request body
+	paymentRequest := &request.PaymentRequest{}
+	if err = decodeBody(r, paymentRequest); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Members record the payments they made
+	payer, err := app.requireMember(r, groupID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	payment := &model.Payment{
+		GroupID: groupID,
+		PayerID: payer.UserID,
+		PayeeID: paymentRequest.PayeeID,
+		Amount:  paymentRequest.Amount,
+	}
+	if err = payment.ValidateFields(); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Validate payee is a member of group
+	_, err = app.memberships(r).GetByID(payment.PayeeID, groupID)
+	if apperror.Is(err, apperror.KindNotFound) {
+		app.ResponseError(w, apperror.InvalidField("payeeID", "User provided is not a member of the group"))
+		return
+	}
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Save payment in database
+	if err = app.PaymentRepository.WithContext(r.Context()).Create(payment); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, dto.PaymentToPaymentDTO(*payment))
+}
+
+func (app *App) GetPayments(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Only members read the payments of a group
+	if _, err = app.requireMember(r, groupID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Get payments from database
+	payments, err := app.PaymentRepository.WithContext(r.Context()).ListByGroup(groupID, page)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, pageResponse(payments, func(payment *model.Payment) dto.PaymentDTO {
+		return dto.PaymentToPaymentDTO(*payment)
+	}))
+}
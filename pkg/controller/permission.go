@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"money_share/pkg/apperror"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"net/http"
+)
+
+// memberships returns the member repository permission checks read for r
+func (app *App) memberships(r *http.Request) repository.MemberRepository {
+	if app.Memberships == nil {
+		return app.MemberRepository.WithContext(r.Context())
+	}
+	return app.Memberships.WithContext(r.Context())
+}
+
+// requireMember returns the membership of the requester of r in the group, or an error unless
+// they are a member
+func (app *App) requireMember(r *http.Request, groupID uint) (*model.Member, error) {
+	userID, err := parseID("userID", r.Header.Get("userID"))
+	if err != nil {
+		return nil, apperror.Unauthorized("invalid_token", "Missing user in token")
+	}
+	member, err := app.memberships(r).GetByID(userID, groupID)
+	if apperror.Is(err, apperror.KindNotFound) {
+		return nil, apperror.Forbidden("not_group_member", "You are not a member of this group")
+	}
+	return member, err
+}
+
+// requireManager reports an error unless the requester of r manages the group, action tells
+// what they cannot do otherwise
+func (app *App) requireManager(r *http.Request, groupID uint, action string) error {
+	member, err := app.requireMember(r, groupID)
+	if err != nil {
+		return err
+	}
+	if member.Role != model.RoleManager {
+		return apperror.Forbidden("not_group_manager", "You are not a manager, you cannot "+action)
+	}
+	return nil
+}
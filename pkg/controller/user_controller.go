@@ -1,20 +1,23 @@
 package controller
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"io"
+	"money_share/pkg/apperror"
 	"money_share/pkg/auth"
 	"money_share/pkg/dto"
 	"money_share/pkg/dto/request"
 	"money_share/pkg/dto/response"
+	"money_share/pkg/imaging"
 	"money_share/pkg/model"
-	"money_share/pkg/repository"
+	"money_share/pkg/storage"
 	"money_share/pkg/util"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -23,43 +26,58 @@ import (
 // 2 MB
 const maxUploadSize = 2 << 20
 
-var UserRepository repository.UserRepository
+// Storage key prefix of user profile images
+const profileImagePrefix = "userProfileImage"
 
-func Login(w http.ResponseWriter, r *http.Request) {
+// File names of profile images, a random ID and the extension of the encoded format
+var profileImageNamePattern = regexp.MustCompile(`^([0-9a-f]{32})\.(jpg|png)$`)
+
+// File names of profile images uploaded before resizing was introduced, the username, upload
+// time and extension of the uploaded file. They are stored as uploaded, at a single size.
+var legacyProfileImageNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._]+_[0-9]+(\.[a-zA-Z0-9]+)?$`)
+
+var errFileNotFound = apperror.NotFound("file_not_found", "File doesn't exist")
+
+func (app *App) Login(w http.ResponseWriter, r *http.Request) {
 	// Parse login request from body
 	loginRequest := &request.LoginRequest{}
-	if err := json.NewDecoder(r.Body).Decode(loginRequest); err != nil {
-		ResponseError(w, "Cannot parse request body", http.StatusBadRequest)
+	if err := decodeBody(r, loginRequest); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 	// Validate fields
 	username := loginRequest.Username
 	password := loginRequest.Password
-	if err := model.ValidateUsername(username); err != nil {
-		ResponseError(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	if err := model.ValidatePassword(password); err != nil {
-		ResponseError(w, err.Error(), http.StatusBadRequest)
+	if err := apperror.Join(model.ValidateUsername(username), model.ValidatePassword(password)); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Find database record and compare password
-	user, err := UserRepository.GetByUsername(username)
+	wrongCredentials := apperror.Unauthorized("wrong_credentials", "Wrong username or password")
+	user, err := app.UserRepository.WithContext(r.Context()).GetByUsername(username)
+	if apperror.Is(err, apperror.KindNotFound) {
+		app.ResponseError(w, wrongCredentials)
+		return
+	}
 	if err != nil {
-		ResponseError(w, "Wrong username or password", http.StatusUnauthorized)
+		app.ResponseError(w, err)
 		return
 	}
 	authorized := user.ComparePassword(password)
 	if !authorized {
-		ResponseError(w, "Wrong username or password", http.StatusUnauthorized)
+		app.ResponseError(w, wrongCredentials)
+		return
+	}
+	if user.IsDisabled() {
+		app.ResponseError(w, apperror.Forbidden("account_disabled", "Account is disabled"))
 		return
 	}
 
 	// Generate jwt token
 	accessToken, refreshToken, err := auth.GenerateTokenPair(user.ID, user.Username)
 	if err != nil {
-		ResponseError(w, "Error when generating tokens", http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
 
@@ -69,32 +87,30 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}
-	ResponseJSON(w, loginResponse)
+	app.ResponseJSON(w, loginResponse)
 }
 
-func GetUserByID(w http.ResponseWriter, r *http.Request) {
+func (app *App) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	// Get user id from parameters
-	params := mux.Vars(r)
-	userIDStr := params["userId"]
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
+	userID, err := parseID("userId", mux.Vars(r)["userId"])
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse user ID '%s': %s", userIDStr, err), http.StatusBadRequest)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Get user from database
-	user, err := UserRepository.GetById(uint(userID))
+	user, err := app.UserRepository.WithContext(r.Context()).GetById(userID)
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Failed to get user by ID '%d': %s", userID, err), http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write to response
 	userDTO := dto.UserToUserDTO(*user)
-	ResponseJSON(w, userDTO)
+	app.ResponseJSON(w, userDTO)
 }
 
-func CheckUsername(w http.ResponseWriter, r *http.Request) {
+func (app *App) CheckUsername(w http.ResponseWriter, r *http.Request) {
 	// Init responseObj object
 	responseObj := response.SimpleResponse{}
 
@@ -106,9 +122,9 @@ func CheckUsername(w http.ResponseWriter, r *http.Request) {
 	if len(username) < 8 {
 		responseObj.Result = false
 	} else {
-		available, err := UserRepository.CheckUsernameAvailability(username)
+		available, err := app.UserRepository.WithContext(r.Context()).CheckUsernameAvailability(username)
 		if err != nil {
-			ResponseError(w, fmt.Sprintf("Error checking username '%s' availability: %s", username, err), http.StatusInternalServerError)
+			app.ResponseError(w, err)
 			return
 		} else {
 			responseObj.Result = available
@@ -116,21 +132,21 @@ func CheckUsername(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Write to responseObj
-	ResponseJSON(w, responseObj)
+	app.ResponseJSON(w, responseObj)
 }
 
-func Register(w http.ResponseWriter, r *http.Request) {
+func (app *App) Register(w http.ResponseWriter, r *http.Request) {
 	// Parse user data from request body
 	registerRequest := &request.RegisterRequest{}
-	err := json.NewDecoder(r.Body).Decode(registerRequest)
+	err := decodeBody(r, registerRequest)
 	if err != nil {
-		ResponseError(w, "Cannot parse request body", http.StatusBadRequest)
+		app.ResponseError(w, err)
 		return
 	}
 	// Create user object
 	user, err := registerRequest.UserDTO.MapToDomain()
 	if err != nil {
-		ResponseError(w, "Error while parsing user object", http.StatusInternalServerError)
+		app.ResponseError(w, apperror.InvalidField("dateOfBirth", "Cannot parse date of birth"))
 		return
 	}
 	// Set password for user
@@ -141,81 +157,54 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 	// Validate fields
 	if err = user.ValidateFields(); err != nil {
-		ResponseError(w, err.Error(), http.StatusBadRequest)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Hash password
 	if err = user.HashPassword(); err != nil {
-		ResponseError(w, "Error while hashing password", http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Create user in database
-	err = UserRepository.Create(&user)
+	err = app.UserRepository.WithContext(r.Context()).Create(&user)
 	if err != nil {
-		ResponseError(w, "Error while creating user", http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write created user to response
 	responseObj := response.SimpleResponse{Result: true}
-	ResponseJSON(w, responseObj)
+	app.ResponseJSON(w, responseObj)
 }
 
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	// Get user id from parameters
-	params := mux.Vars(r)
-	userIDStr := params["userId"]
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
-	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse user ID '%s': %s", userIDStr, err), http.StatusBadRequest)
-		return
-	}
-
-	// Get username from header
-	username := r.Header.Get("username")
-	// Validate username and user id
-	validated, err := UserRepository.ValidateUsernameAndUserID(username, uint(userID))
+func (app *App) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	// Get user id from parameters and make sure it is the requester
+	userID, err := app.authorizeUser(r)
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot validate username and user id"), http.StatusInternalServerError)
-		return
-	}
-	if !validated {
-		ResponseError(w, fmt.Sprintf("You don't have permission to do this action"), http.StatusForbidden)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Parse user data from request body
 	updateUserRequest := &request.UpdateUserRequest{}
-	if err = json.NewDecoder(r.Body).Decode(updateUserRequest); err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse request body: %s", err), http.StatusBadRequest)
+	if err = decodeBody(r, updateUserRequest); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 	updateMap := make(map[string]interface{})
+	var validationErrs []error
 	// Parse fields
 	// Display name
 	if updateUserRequest.DisplayName != nil {
 		displayName := strings.TrimSpace(*updateUserRequest.DisplayName)
-		if err := model.ValidateDisplayName(displayName); err != nil {
-			ResponseError(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+		validationErrs = append(validationErrs, model.ValidateDisplayName(displayName))
 		updateMap["DisplayName"] = displayName
 	}
 	// Password
 	if updateUserRequest.Password != nil {
-		if err := model.ValidatePassword(*updateUserRequest.Password); err != nil {
-			ResponseError(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		// Hash password
-		hashedPassword, err := model.HashPassword(*updateUserRequest.Password)
-		if err != nil {
-			ResponseError(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		updateMap["Password"] = hashedPassword
+		validationErrs = append(validationErrs, model.ValidatePassword(*updateUserRequest.Password))
 	}
 	// Phone number
 	if updateUserRequest.PhoneNumber != nil {
@@ -229,138 +218,241 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	if updateUserRequest.DateOfBirth != nil {
 		dob, err := time.Parse(util.ShortDateLayout, *updateUserRequest.DateOfBirth)
 		if err != nil {
-			ResponseError(w, fmt.Sprintf("Cannot parse date of birth: %s", err), http.StatusBadRequest)
-			return
+			validationErrs = append(validationErrs, apperror.InvalidField("dateOfBirth", "Cannot parse date of birth"))
 		}
 		updateMap["DateOfBirth"] = dob
 	}
+	if err = apperror.Join(validationErrs...); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Hash password once every field is valid
+	if updateUserRequest.Password != nil {
+		hashedPassword, err := model.HashPassword(*updateUserRequest.Password)
+		if err != nil {
+			app.ResponseError(w, err)
+			return
+		}
+		updateMap["Password"] = hashedPassword
+	}
 
 	// Update user to database
-	updatedUser, err := UserRepository.Update(uint(userID), updateMap)
+	updatedUser, err := app.UserRepository.WithContext(r.Context()).Update(userID, updateMap)
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Error while updating user: %s", err), http.StatusBadRequest)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write updated data to response
 	updatedUserDTO := dto.UserToUserDTO(*updatedUser)
-	ResponseJSON(w, updatedUserDTO)
+	app.ResponseJSON(w, updatedUserDTO)
 }
 
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
-	// Get user id from parameters
-	params := mux.Vars(r)
-	userIDStr := params["userId"]
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
+func (app *App) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	// Get user id from parameters and make sure it is the requester
+	userID, err := app.authorizeUser(r)
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse user ID '%s': %s", userIDStr, err), http.StatusBadRequest)
-		return
-	}
-
-	// Get username from header
-	username := r.Header.Get("username")
-	// Validate username and user id
-	validated, err := UserRepository.ValidateUsernameAndUserID(username, uint(userID))
-	if err != nil {
-		ResponseError(w, "Cannot validate username and user id", http.StatusInternalServerError)
-		return
-	}
-	if !validated {
-		ResponseError(w, "You're not authorized to do this action", http.StatusForbidden)
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Delete user from database and write response
-	if err := UserRepository.Delete(uint(userID)); err != nil {
-		ResponseError(w, fmt.Sprintf("Error while deleting user with ID '%d'", userID), http.StatusInternalServerError)
+	if err := app.UserRepository.WithContext(r.Context()).Delete(userID); err != nil {
+		app.ResponseError(w, err)
 		return
 	}
 
 	// Write to response
 	responseObj := response.SimpleResponse{Result: true}
-	ResponseJSON(w, responseObj)
+	app.ResponseJSON(w, responseObj)
 }
 
-func UploadUserProfileImage(w http.ResponseWriter, r *http.Request) {
+func (app *App) UploadUserProfileImage(w http.ResponseWriter, r *http.Request) {
 	// Limit upload file size
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		ResponseError(w, "Uploaded file too big. Max file size is 2MB.", http.StatusBadRequest)
+		app.ResponseError(w, apperror.InvalidField("file", "Uploaded file too big. Max file size is 2MB."))
 		return
 	}
 
 	// Get file from request
-	file, fileHeader, err := r.FormFile("file")
+	file, _, err := r.FormFile("file")
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Error getting file from request: %s", err), http.StatusBadRequest)
+		app.ResponseError(w, apperror.InvalidField("file", fmt.Sprintf("Error getting file from request: %s", err)))
 		return
 	}
 	defer file.Close()
 
-	// Get user id from parameters
-	params := mux.Vars(r)
-	userIDStr := params["userId"]
-	userID, err := strconv.ParseUint(userIDStr, 0, 32)
+	// Get user id from parameters and make sure it is the requester
+	userID, err := app.authorizeUser(r)
 	if err != nil {
-		ResponseError(w, fmt.Sprintf("Cannot parse user ID '%s': %s", userIDStr, err), http.StatusBadRequest)
+		app.ResponseError(w, err)
 		return
 	}
-	// Get username from header
-	username := r.Header.Get("username")
-
-	// Create upload folder if it doesn't exist
-	err = os.MkdirAll("./fileServer/userProfileImage", os.ModePerm)
+	userRepository := app.UserRepository.WithContext(r.Context())
+	user, err := userRepository.GetById(userID)
 	if err != nil {
-		ResponseError(w, err.Error(), http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
 
-	// Create new file in upload folder
-	profileImageUrl := fmt.Sprintf("./fileServer/userProfileImage/%s_%d%s",
-		username, time.Now().Unix(), filepath.Ext(fileHeader.Filename))
-	dst, err := os.Create(profileImageUrl)
-	defer dst.Close()
-
-	// Copy uploaded file to create file
-	_, err = io.Copy(dst, file)
+	// Validate, strip metadata and resize uploaded image
+	avatar, err := imaging.ProcessAvatar(file)
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		app.ResponseError(w, apperror.Wrap(err, apperror.KindUnsupportedMediaType, "unsupported_image_format", err.Error()))
+		return
+	}
+	if errors.Is(err, imaging.ErrInvalidImage) || errors.Is(err, imaging.ErrImageTooLarge) {
+		app.ResponseError(w, apperror.InvalidField("file", err.Error()))
+		return
+	}
 	if err != nil {
-		ResponseError(w, err.Error(), http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
 
+	// Store every size, removing those already stored if one fails
+	fileName := avatar.FileName()
+	for _, size := range imaging.AvatarSizes {
+		image := avatar.Images[size]
+		err = app.Storage.Put(r.Context(), profileImageKey(fileName, size), bytes.NewReader(image), int64(len(image)),
+			imaging.ContentType(avatar.Extension))
+		if err != nil {
+			app.deleteProfileImage(r, fileName)
+			app.ResponseError(w, err)
+			return
+		}
+	}
+
 	// Update profile image url for user
 	updateMap := make(map[string]interface{})
-	updateMap["ProfileImageUrl"] = filepath.Base(profileImageUrl)
-	updatedUser, err := UserRepository.Update(uint(userID), updateMap)
+	updateMap["ProfileImageUrl"] = fileName
+	updatedUser, err := userRepository.Update(userID, updateMap)
 	if err != nil {
-		ResponseError(w, "Error while updating profile image", http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
+	// Garbage collect the replaced image
+	if user.ProfileImageUrl != "" && user.ProfileImageUrl != fileName {
+		app.deleteProfileImage(r, user.ProfileImageUrl)
+	}
 
 	// Write updated data to response
 	updatedUserDTO := dto.UserToUserDTO(*updatedUser)
-	ResponseJSON(w, updatedUserDTO)
+	app.ResponseJSON(w, updatedUserDTO)
 }
 
-func GetUserProfileImage(w http.ResponseWriter, r *http.Request) {
-	// Get file name from parameters
+func (app *App) GetUserProfileImage(w http.ResponseWriter, r *http.Request) {
+	// Get file name from parameters, only names generated on upload are accepted
 	params := mux.Vars(r)
 	fileName := params["fileName"]
-
-	fullFilePath := "./fileServer/userProfileImage/" + fileName
-	// Check if file exists
-	if _, err := os.Stat(fullFilePath); err != nil {
-		ResponseError(w, "File doesn't exist", http.StatusBadRequest)
+	matches := profileImageNamePattern.FindStringSubmatch(fileName)
+	legacy := matches == nil && legacyProfileImageNamePattern.MatchString(fileName)
+	if matches == nil && !legacy {
+		app.ResponseError(w, errFileNotFound)
 		return
 	}
+	// Get size from query, defaults to the medium size
+	size := imaging.DefaultAvatarSize
+	if sizeStr := r.URL.Query().Get("size"); sizeStr != "" {
+		parsedSize, err := strconv.Atoi(sizeStr)
+		if err != nil || !imaging.IsAvatarSize(parsedSize) {
+			app.ResponseError(w, apperror.InvalidField("size", fmt.Sprintf("Size must be one of %v", imaging.AvatarSizes)))
+			return
+		}
+		size = parsedSize
+	}
 
-	// Read file
-	fileBytes, err := os.ReadFile(fullFilePath)
+	// Read file from storage
+	reader, info, err := app.Storage.Get(r.Context(), profileImageKey(fileName, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		app.ResponseError(w, errFileNotFound)
+		return
+	}
 	if err != nil {
-		ResponseError(w, "Error reading file", http.StatusInternalServerError)
+		app.ResponseError(w, err)
 		return
 	}
+	defer reader.Close()
+	content, ok := reader.(io.ReadSeeker)
+	if !ok {
+		fileBytes, err := io.ReadAll(reader)
+		if err != nil {
+			app.ResponseError(w, err)
+			return
+		}
+		content = bytes.NewReader(fileBytes)
+	}
 
-	// Write to response
-	ResponseFile(w, fileBytes)
+	// Write to response, names are never reused so the image never changes
+	if legacy {
+		w.Header().Set("Content-Type", legacyContentType(content))
+	} else {
+		w.Header().Set("Content-Type", imaging.ContentType(matches[2]))
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if info.ETag != "" {
+		w.Header().Set("ETag", strconv.Quote(info.ETag))
+	}
+	http.ServeContent(w, r, "", info.LastModified, content)
+}
+
+// authorizeUser parses the user ID parameter and makes sure it is the user of the access token
+func (app *App) authorizeUser(r *http.Request) (uint, error) {
+	userID, err := parseID("userId", mux.Vars(r)["userId"])
+	if err != nil {
+		return 0, err
+	}
+	// Get username from header
+	username := r.Header.Get("username")
+	validated, err := app.UserRepository.WithContext(r.Context()).ValidateUsernameAndUserID(username, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !validated {
+		return 0, apperror.Forbidden("forbidden", "You don't have permission to do this action")
+	}
+	return userID, nil
+}
+
+// legacyContentType sniffs the content type of a legacy profile image, which was stored without
+// validation. Anything but an image is served as a download.
+func legacyContentType(content io.ReadSeeker) string {
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(content, head)
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "application/octet-stream"
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !strings.HasPrefix(contentType, "image/") {
+		return "application/octet-stream"
+	}
+	return contentType
+}
+
+// profileImageKey returns the storage key of a profile image at size
+func profileImageKey(fileName string, size int) string {
+	matches := profileImageNamePattern.FindStringSubmatch(fileName)
+	if matches == nil {
+		// Images uploaded before resizing was introduced are stored as is
+		return path.Join(profileImagePrefix, fileName)
+	}
+	return path.Join(profileImagePrefix, fmt.Sprintf("%s_%d.%s", matches[1], size, matches[2]))
+}
+
+// deleteProfileImage removes every size of a profile image, failures only leave garbage behind and are logged
+func (app *App) deleteProfileImage(r *http.Request, fileName string) {
+	keys := []string{profileImageKey(fileName, 0)}
+	if profileImageNamePattern.MatchString(fileName) {
+		keys = keys[:0]
+		for _, size := range imaging.AvatarSizes {
+			keys = append(keys, profileImageKey(fileName, size))
+		}
+	}
+	for _, key := range keys {
+		if err := app.Storage.Delete(r.Context(), key); err != nil {
+			app.requestLogger(r).Warn("Cannot delete profile image", "key", key, "error", err)
+		}
+	}
 }
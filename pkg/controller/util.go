@@ -1,46 +1,138 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"money_share/pkg/apperror"
 	"money_share/pkg/dto/response"
+	"money_share/pkg/repository"
 	"net/http"
-	"time"
+	"strconv"
 )
 
-func HandleNotFound(w http.ResponseWriter, r *http.Request) {
-	ResponseError(w, "Page not found", http.StatusNotFound)
+// Statuses of the error kinds, the one place errors are mapped to HTTP
+var kindStatus = map[apperror.Kind]int{
+	apperror.KindValidation:           http.StatusBadRequest,
+	apperror.KindUnauthorized:         http.StatusUnauthorized,
+	apperror.KindForbidden:            http.StatusForbidden,
+	apperror.KindNotFound:             http.StatusNotFound,
+	apperror.KindConflict:             http.StatusConflict,
+	apperror.KindPayloadTooLarge:      http.StatusRequestEntityTooLarge,
+	apperror.KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	apperror.KindTooManyRequests:      http.StatusTooManyRequests,
+	apperror.KindUnavailable:          http.StatusServiceUnavailable,
+	apperror.KindInternal:             http.StatusInternalServerError,
 }
 
-func ResponseError(w http.ResponseWriter, msg string, code int) {
+func (app *App) HandleNotFound(w http.ResponseWriter, r *http.Request) {
+	app.ResponseError(w, apperror.NotFound("route_not_found", "Page not found"))
+}
+
+// ResponseError writes err as a JSON error response with the status of its kind. Errors which
+// are not apperror errors are internal, their details are only logged.
+func (app *App) ResponseError(w http.ResponseWriter, err error) {
+	WriteError(app.Logger, w, err)
+}
+
+// WriteError is ResponseError for middlewares, which log with their own logger
+func WriteError(logger *slog.Logger, w http.ResponseWriter, err error) {
+	appErr, ok := apperror.As(err)
+	if !ok {
+		appErr = apperror.Internal(err)
+	}
+	status, ok := kindStatus[appErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(code)
+	w.WriteHeader(status)
 	responseBody := response.ErrorResponse{
-		Code:  code,
-		Message: msg,
+		Status:  status,
+		Code:    appErr.Code,
+		Message: appErr.Message,
+		Errors:  appErr.Fields,
 	}
 	_ = json.NewEncoder(w).Encode(responseBody)
-	fmt.Printf("%s : %s\n", time.Now(), msg)
+
+	// The request ID was echoed in the response headers by the RequestID middleware
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.Log(context.Background(), level, appErr.Message, "status", status, "code", appErr.Code,
+		"error", err.Error(), "request_id", w.Header().Get("X-Request-ID"))
 }
 
-func ResponseJSON(w http.ResponseWriter, object interface{}) {
+func (app *App) ResponseJSON(w http.ResponseWriter, object interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	err := json.NewEncoder(w).Encode(object)
 	if err != nil {
-		errMsg := fmt.Sprintf("Error encoding to json: %s", err)
-		ResponseError(w, errMsg, http.StatusInternalServerError)
-		return
+		// The status is already sent, the error can only be logged
+		app.Logger.Error("Error encoding to json", "error", err, "request_id", w.Header().Get("X-Request-ID"))
 	}
 }
 
-func ResponseFile(w http.ResponseWriter,file []byte) {
+func (app *App) ResponseFile(w http.ResponseWriter, file []byte) {
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write(file)
 	if err != nil {
-		ResponseError(w, "Error writing file to response", http.StatusInternalServerError)
-		return
+		app.Logger.Error("Error writing file to response", "error", err, "request_id", w.Header().Get("X-Request-ID"))
+	}
+}
+
+// parseID parses the ID parameter name, e.g. "groupId"
+func parseID(name string, value string) (uint, error) {
+	if value == "" {
+		return 0, apperror.InvalidField(name, fmt.Sprintf("Parameter '%s' is required", name))
 	}
-}
\ No newline at end of file
+	id, err := strconv.ParseUint(value, 0, 32)
+	if err != nil {
+		return 0, apperror.InvalidField(name, fmt.Sprintf("Cannot parse %s '%s'", name, value))
+	}
+	return uint(id), nil
+}
+
+// ErrBodyTooLarge is the error of request bodies over the limit set by http.MaxBytesReader
+var ErrBodyTooLarge = apperror.New(apperror.KindPayloadTooLarge, "body_too_large", "Request body is too large")
+
+// decodeBody decodes the JSON request body into v
+func decodeBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return ErrBodyTooLarge
+		}
+		return apperror.Wrap(err, apperror.KindValidation, "invalid_body", fmt.Sprintf("Cannot parse request body: %s", err))
+	}
+	return nil
+}
+
+// parsePage parses the cursor and limit query parameters of list endpoints
+func parsePage(r *http.Request) (repository.PageRequest, error) {
+	queries := r.URL.Query()
+	page := repository.PageRequest{Cursor: queries.Get("cursor")}
+	if limitStr := queries.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return page, apperror.InvalidField("limit", fmt.Sprintf("limit must be between 1 and %d", repository.MaxPageLimit))
+		}
+		page.Limit = limit
+	}
+	return page, page.Validate()
+}
+
+// pageResponse maps the items of a page to DTOs
+func pageResponse[T any, D any](page repository.Page[T], toDTO func(T) D) response.PageResponse[D] {
+	pageResponse := response.PageResponse[D]{Items: make([]D, 0, len(page.Items)), NextCursor: page.NextCursor}
+	for _, item := range page.Items {
+		pageResponse.Items = append(pageResponse.Items, toDTO(item))
+	}
+	return pageResponse
+}
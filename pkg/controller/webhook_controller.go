@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/dto"
+	"money_share/pkg/dto/request"
+	"money_share/pkg/model"
+	"money_share/pkg/webhook"
+	"net/http"
+)
+
+// Told to members who are not managers
+const manageWebhooksAction = "manage the webhooks of this group"
+
+// parseWebhookPath returns the group and webhook ids from the parameters of r once the requester
+// is checked to manage the group
+func (app *App) parseWebhookPath(r *http.Request) (uint, uint, error) {
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		return 0, 0, err
+	}
+	webhookID, err := parseID("webhookId", mux.Vars(r)["webhookId"])
+	if err != nil {
+		return 0, 0, err
+	}
+	return groupID, webhookID, app.requireManager(r, groupID, manageWebhooksAction)
+}
+
+func (app *App) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Parse webhook data from request body
+	webhookRequest := &request.WebhookRequest{}
+	if err = decodeBody(r, webhookRequest); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Only managers register webhooks
+	if err = app.requireManager(r, groupID, manageWebhooksAction); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	newWebhook := &model.Webhook{GroupID: groupID, URL: webhookRequest.URL, Secret: secret}
+	newWebhook.SetEvents(webhookRequest.Events)
+
+	// Save webhook in database
+	if err = app.WebhookRepository.WithContext(r.Context()).Create(newWebhook); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response, the secret is shown once
+	webhookDTO := dto.WebhookToWebhookDTO(*newWebhook)
+	webhookDTO.Secret = newWebhook.Secret
+	app.ResponseJSON(w, webhookDTO)
+}
+
+func (app *App) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Get group id from parameters
+	groupID, err := parseID("groupId", mux.Vars(r)["groupId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	if err = app.requireManager(r, groupID, manageWebhooksAction); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Get webhooks from database
+	webhooks, err := app.WebhookRepository.WithContext(r.Context()).ListByGroup(groupID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	webhookDTOs := make([]dto.WebhookDTO, 0, len(webhooks))
+	for _, groupWebhook := range webhooks {
+		webhookDTOs = append(webhookDTOs, dto.WebhookToWebhookDTO(*groupWebhook))
+	}
+	app.ResponseJSON(w, webhookDTOs)
+}
+
+func (app *App) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get group and webhook ids from parameters
+	groupID, webhookID, err := app.parseWebhookPath(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Get webhook from database
+	groupWebhook, err := app.WebhookRepository.WithContext(r.Context()).GetByID(groupID, webhookID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, dto.WebhookToWebhookDTO(*groupWebhook))
+}
+
+func (app *App) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get group and webhook ids from parameters
+	groupID, webhookID, err := app.parseWebhookPath(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Parse webhook data from request body
+	webhookRequest := &request.WebhookRequest{}
+	if err = decodeBody(r, webhookRequest); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	webhooks := app.WebhookRepository.WithContext(r.Context())
+	groupWebhook, err := webhooks.GetByID(groupID, webhookID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	groupWebhook.URL = webhookRequest.URL
+	groupWebhook.SetEvents(webhookRequest.Events)
+	if webhookRequest.Active != nil {
+		groupWebhook.Active = *webhookRequest.Active
+	}
+
+	// Save webhook in database, enabling a disabled webhook resets its failures
+	if err = webhooks.Update(groupWebhook); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, dto.WebhookToWebhookDTO(*groupWebhook))
+}
+
+func (app *App) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get group and webhook ids from parameters
+	groupID, webhookID, err := app.parseWebhookPath(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Delete webhook with its deliveries from database
+	if err = app.WebhookRepository.WithContext(r.Context()).Delete(groupID, webhookID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	w.WriteHeader(http.StatusOK)
+}
+
+func (app *App) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Get group and webhook ids from parameters
+	groupID, webhookID, err := app.parseWebhookPath(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Deliveries of webhooks of other groups are missing
+	webhooks := app.WebhookRepository.WithContext(r.Context())
+	if _, err = webhooks.GetByID(groupID, webhookID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	deliveries, err := webhooks.ListDeliveries(webhookID, page)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, pageResponse(deliveries, func(delivery *model.WebhookDelivery) dto.WebhookDeliveryDTO {
+		return dto.WebhookDeliveryToWebhookDeliveryDTO(*delivery)
+	}))
+}
+
+func (app *App) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	// Get group, webhook and delivery ids from parameters
+	groupID, webhookID, err := app.parseWebhookPath(r)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	deliveryID, err := parseID("deliveryId", mux.Vars(r)["deliveryId"])
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	webhooks := app.WebhookRepository.WithContext(r.Context())
+	if _, err = webhooks.GetByID(groupID, webhookID); err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+	// Queue the payload again, the delivery log keeps the original delivery
+	delivery, err := webhooks.Redeliver(webhookID, deliveryID)
+	if err != nil {
+		app.ResponseError(w, err)
+		return
+	}
+
+	// Write to response
+	app.ResponseJSON(w, dto.WebhookDeliveryToWebhookDeliveryDTO(*delivery))
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"log/slog"
+	"money_share/pkg/logging"
+	"strings"
+)
+
+// Drivers supported by Connect
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Connection used when no DSN is configured
+const (
+	defaultPostgresDSN = "host=localhost user=admin dbname=money_share password=123456 sslmode=disable"
+	defaultSQLiteDSN   = "money_share.db"
+)
+
+type SQLDB struct {
+	DB *gorm.DB
+}
+
+// Connect opens the database connection of driver and logs queries through logger. For
+// SQLite dsn is a file path or URI. The schema is managed by versioned migrations in
+// package migration, it is not migrated here.
+func Connect(driver string, dsn string, logger *slog.Logger) *SQLDB {
+	db, err := Open(driver, dsn, &gorm.Config{
+		Logger: logging.NewGormLogger(logger),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &SQLDB{DB: db}
+}
+
+// Open opens a gorm connection of driver with config
+func Open(driver string, dsn string, config *gorm.Config) (*gorm.DB, error) {
+	switch driver {
+	case DriverPostgres:
+		if dsn == "" {
+			dsn = defaultPostgresDSN
+		}
+		return gorm.Open(postgres.Open(dsn), config)
+	case DriverSQLite:
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+		db, err := gorm.Open(sqlite.Open(sqliteDSN(dsn)), config)
+		if err != nil {
+			return nil, err
+		}
+		// SQLite allows a single writer, a single connection avoids "database is locked"
+		// errors and keeps in-memory databases shared
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver '%s', use '%s' or '%s'", driver, DriverPostgres, DriverSQLite)
+	}
+}
+
+// sqliteDSN enables foreign keys, which SQLite leaves off by default, and waits on locks
+// instead of failing immediately
+func sqliteDSN(dsn string) string {
+	params := []string{"_pragma=foreign_keys(1)", "_pragma=busy_timeout(5000)"}
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	for _, param := range params {
+		// Pragmas set in dsn are kept
+		name := param[:strings.Index(param, "(")]
+		if !strings.Contains(dsn, name) {
+			dsn += separator + param
+			separator = "&"
+		}
+	}
+	return dsn
+}
+
+// Ping checks that the database is reachable
+func (p *SQLDB) Ping(ctx context.Context) error {
+	sqlDB, err := p.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the underlying connection pool
+func (p *SQLDB) Close() error {
+	if p.DB == nil {
+		return nil
+	}
+	sqlDB, err := p.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -1,13 +1,14 @@
 package database
 
-import "github.com/go-redis/redis/v9"
+import (
+	"context"
+	"github.com/go-redis/redis/v9"
+)
 
 type RedisDB struct {
 	DB *redis.Client
 }
 
-var Redis = &RedisDB{}
-
 func NewRedisClient() *RedisDB {
 	rdb := redis.NewClient(&redis.Options{
 		Addr: "localhost:6379",
@@ -15,6 +16,18 @@ func NewRedisClient() *RedisDB {
 		DB: 0,
 	})
 
-	Redis.DB = rdb
-	return Redis
-}
\ No newline at end of file
+	return &RedisDB{DB: rdb}
+}
+
+// Ping checks that the redis server is reachable
+func (r *RedisDB) Ping(ctx context.Context) error {
+	return r.DB.Ping(ctx).Err()
+}
+
+// Close closes the client and its connection pool
+func (r *RedisDB) Close() error {
+	if r.DB == nil {
+		return nil
+	}
+	return r.DB.Close()
+}
@@ -0,0 +1,16 @@
+package dto
+
+import "encoding/json"
+
+// ActivityDTO is an entry of the activity log of a group, before and after are snapshots of the
+// target and absent for creations and deletions respectively
+type ActivityDTO struct {
+	ID         uint            `json:"id"`
+	Action     string          `json:"action"`
+	ActorID    uint            `json:"actorID,omitempty"`
+	TargetType string          `json:"targetType"`
+	TargetID   uint            `json:"targetID"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  string          `json:"createdAt"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// DeviceDTO is a device of the requester receiving push notifications
+type DeviceDTO struct {
+	ID         uint   `json:"id"`
+	Platform   string `json:"platform"`
+	Token      string `json:"token"`
+	AppVersion string `json:"appVersion,omitempty"`
+	CreatedAt  string `json:"createdAt"`
+	UpdatedAt  string `json:"updatedAt"`
+}
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"money_share/pkg/model"
 	"money_share/pkg/util"
 )
@@ -67,3 +68,106 @@ func MemberToMemberDTO(domain model.Member) MemberDTO {
 		TotalExpense: domain.TotalExpense,
 	}
 }
+
+func ActivityToActivityDTO(domain model.Activity) ActivityDTO {
+	activityDTO := ActivityDTO{
+		ID:         domain.ID,
+		Action:     domain.Action,
+		TargetType: domain.TargetType,
+		TargetID:   domain.TargetID,
+		CreatedAt:  domain.CreatedAt.UTC().Format(util.DateTimeLayout),
+	}
+	if domain.ActorID != nil {
+		activityDTO.ActorID = *domain.ActorID
+	}
+	// Snapshots are JSON already
+	if domain.Before != "" {
+		activityDTO.Before = json.RawMessage(domain.Before)
+	}
+	if domain.After != "" {
+		activityDTO.After = json.RawMessage(domain.After)
+	}
+	return activityDTO
+}
+
+func NotificationToNotificationDTO(domain model.Notification) NotificationDTO {
+	notificationDTO := NotificationDTO{
+		ID:        domain.ID,
+		Type:      domain.Type,
+		GroupID:   domain.GroupID,
+		TargetID:  domain.TargetID,
+		Read:      domain.ReadAt != nil,
+		CreatedAt: domain.CreatedAt.UTC().Format(util.DateTimeLayout),
+	}
+	if domain.ActorID != nil {
+		notificationDTO.ActorID = *domain.ActorID
+	}
+	if domain.ReadAt != nil {
+		notificationDTO.ReadAt = domain.ReadAt.UTC().Format(util.DateTimeLayout)
+	}
+	// Snapshots are JSON already
+	if domain.Data != "" {
+		notificationDTO.Data = json.RawMessage(domain.Data)
+	}
+	return notificationDTO
+}
+
+func DeviceToDeviceDTO(domain model.Device) DeviceDTO {
+	return DeviceDTO{
+		ID:         domain.ID,
+		Platform:   domain.Platform,
+		Token:      domain.Token,
+		AppVersion: domain.AppVersion,
+		CreatedAt:  domain.CreatedAt.UTC().Format(util.DateTimeLayout),
+		UpdatedAt:  domain.UpdatedAt.UTC().Format(util.DateTimeLayout),
+	}
+}
+
+func PaymentToPaymentDTO(domain model.Payment) PaymentDTO {
+	return PaymentDTO{
+		ID:        domain.ID,
+		GroupID:   domain.GroupID,
+		PayerID:   domain.PayerID,
+		PayeeID:   domain.PayeeID,
+		Amount:    domain.Amount,
+		CreatedAt: domain.CreatedAt.UTC().Format(util.DateTimeLayout),
+	}
+}
+
+func WebhookToWebhookDTO(domain model.Webhook) WebhookDTO {
+	webhookDTO := WebhookDTO{
+		ID:                  domain.ID,
+		URL:                 domain.URL,
+		Events:              domain.EventList(),
+		Active:              domain.Active,
+		ConsecutiveFailures: domain.ConsecutiveFailures,
+		CreatedAt:           domain.CreatedAt.UTC().Format(util.DateTimeLayout),
+		UpdatedAt:           domain.UpdatedAt.UTC().Format(util.DateTimeLayout),
+	}
+	if domain.DisabledAt != nil {
+		webhookDTO.DisabledAt = domain.DisabledAt.UTC().Format(util.DateTimeLayout)
+	}
+	return webhookDTO
+}
+
+func WebhookDeliveryToWebhookDeliveryDTO(domain model.WebhookDelivery) WebhookDeliveryDTO {
+	deliveryDTO := WebhookDeliveryDTO{
+		ID:             domain.ID,
+		Event:          domain.Event,
+		Status:         domain.Status,
+		Attempts:       domain.Attempts,
+		Payload:        json.RawMessage(domain.Payload),
+		ResponseStatus: domain.ResponseStatus,
+		ResponseBody:   domain.ResponseBody,
+		Error:          domain.Error,
+		CreatedAt:      domain.CreatedAt.UTC().Format(util.DateTimeLayout),
+	}
+	// Only pending deliveries are attempted again
+	if domain.Status == model.DeliveryPending {
+		deliveryDTO.NextAttemptAt = domain.NextAttemptAt.UTC().Format(util.DateTimeLayout)
+	}
+	if domain.LastAttemptAt != nil {
+		deliveryDTO.LastAttemptAt = domain.LastAttemptAt.UTC().Format(util.DateTimeLayout)
+	}
+	return deliveryDTO
+}
@@ -0,0 +1,26 @@
+package dto
+
+// Payloads of the events of group streams, expense events carry an ExpenseDTO
+
+type MemberEventDTO struct {
+	UserID uint   `json:"userID"`
+	Role   string `json:"role,omitempty"`
+}
+
+type GroupEventDTO struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name,omitempty"`
+	GroupImageUrl string `json:"groupImageUrl,omitempty"`
+}
+
+// GroupTotalsDTO is the total and average of a group and the total of each member
+type GroupTotalsDTO struct {
+	TotalExpense   float32          `json:"totalExpense"`
+	AverageExpense float32          `json:"averageExpense"`
+	Members        []MemberTotalDTO `json:"members"`
+}
+
+type MemberTotalDTO struct {
+	UserID       uint    `json:"userID"`
+	TotalExpense float32 `json:"totalExpense"`
+}
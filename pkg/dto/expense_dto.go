@@ -2,7 +2,6 @@ package dto
 
 import (
 	"gorm.io/gorm"
-	"log"
 	"money_share/pkg/model"
 	"money_share/pkg/util"
 	"time"
@@ -12,18 +11,30 @@ type ExpenseDTO struct {
 	ID           uint    `json:"id,omitempty"`
 	Title        string  `json:"title,omitempty"`
 	Description  string  `json:"description,omitempty"`
-	Amount       float32 `json:"amount,omitempty"`
+	Amount       float32 `json:"amount"`
 	PurchaseTime string  `json:"purchaseTime,omitempty"`
 	Status       string  `json:"status,omitempty"` // pending, approved, denied
 	MemberID     uint    `json:"memberID,omitempty"`
 	GroupID      uint    `json:"groupID,omitempty"`
 }
 
-func (dto ExpenseDTO) MapToDomain() model.Expense {
-	// Parse purchase time
-	purchaseTime, err := time.Parse(util.DateTimeLayout, dto.PurchaseTime)
-	if err != nil {
-		log.Println(err)
+// ExpenseMatchDTO is an expense found by a search, the snippet is escaped HTML which marks
+// matched words with <mark>
+type ExpenseMatchDTO struct {
+	Expense ExpenseDTO `json:"expense"`
+	Rank    float32    `json:"rank"`
+	Snippet string     `json:"snippet"`
+}
+
+func (dto ExpenseDTO) MapToDomain() (model.Expense, error) {
+	// Parse purchase time, updates may leave it out
+	purchaseTime := time.Time{}
+	var err error
+	if dto.PurchaseTime != "" {
+		purchaseTime, err = time.Parse(util.DateTimeLayout, dto.PurchaseTime)
+		if err != nil {
+			return model.Expense{}, err
+		}
 	}
 
 	return model.Expense{
@@ -35,5 +46,5 @@ func (dto ExpenseDTO) MapToDomain() model.Expense {
 		Status:       dto.Status,
 		MemberID:     dto.MemberID,
 		GroupID:      dto.GroupID,
-	}
+	}, nil
 }
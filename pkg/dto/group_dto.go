@@ -28,7 +28,11 @@ func (dto GroupDTO) MapToDomain() (model.Group, error) {
 	// Parse expenses
 	var expenses []model.Expense
 	for _, expenseDTO := range dto.Expenses {
-		expenses = append(expenses, expenseDTO.MapToDomain())
+		expense, err := expenseDTO.MapToDomain()
+		if err != nil {
+			return model.Group{}, err
+		}
+		expenses = append(expenses, expense)
 	}
 
 	return model.Group{
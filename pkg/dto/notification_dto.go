@@ -0,0 +1,23 @@
+package dto
+
+import "encoding/json"
+
+// NotificationDTO is a notification of the requester, data is the snapshot of the target after
+// the change
+type NotificationDTO struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	GroupID   uint            `json:"groupID"`
+	ActorID   uint            `json:"actorID,omitempty"`
+	TargetID  uint            `json:"targetID"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Read      bool            `json:"read"`
+	ReadAt    string          `json:"readAt,omitempty"`
+	CreatedAt string          `json:"createdAt"`
+}
+
+// NotificationPreferencesDTO tells whether each notification type is on, updates may list some
+// types only
+type NotificationPreferencesDTO struct {
+	Preferences map[string]bool `json:"preferences"`
+}
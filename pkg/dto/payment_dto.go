@@ -0,0 +1,11 @@
+package dto
+
+// PaymentDTO is a payment between members of a group
+type PaymentDTO struct {
+	ID        uint    `json:"id"`
+	GroupID   uint    `json:"groupID"`
+	PayerID   uint    `json:"payerID"`
+	PayeeID   uint    `json:"payeeID"`
+	Amount    float32 `json:"amount"`
+	CreatedAt string  `json:"createdAt"`
+}
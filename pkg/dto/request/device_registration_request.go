@@ -0,0 +1,8 @@
+package request
+
+// DeviceRegistrationRequest registers the push token of an installation of the mobile app
+type DeviceRegistrationRequest struct {
+	Platform   string `json:"platform"`
+	Token      string `json:"token"`
+	AppVersion string `json:"appVersion"`
+}
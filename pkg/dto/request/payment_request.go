@@ -0,0 +1,7 @@
+package request
+
+// PaymentRequest records a payment of the requester to another member of the group
+type PaymentRequest struct {
+	PayeeID uint    `json:"payeeID"`
+	Amount  float32 `json:"amount"`
+}
@@ -0,0 +1,9 @@
+package request
+
+// WebhookRequest creates or updates a webhook, active is only read by updates and defaults to
+// the current state
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active,omitempty"`
+}
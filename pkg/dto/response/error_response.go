@@ -1,6 +1,16 @@
 package response
 
+import "money_share/pkg/apperror"
+
 type ErrorResponse struct {
-	Code      int    `json:"code"`
-	Message   string `json:"message,omitempty"`
+	// Status repeats the HTTP status code
+	Status int `json:"status"`
+	// Code is a stable machine-readable identifier of the error, e.g. "group_not_found"
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+	// Errors lists the fields which failed validation
+	Errors []FieldError `json:"errors,omitempty"`
 }
+
+// FieldError is a validation failure of a body field or a parameter
+type FieldError = apperror.FieldError
@@ -0,0 +1,10 @@
+package response
+
+import "money_share/pkg/dto"
+
+// NotificationPageResponse is a page of the notifications of the requester with the number of
+// their unread notifications
+type NotificationPageResponse struct {
+	PageResponse[dto.NotificationDTO]
+	UnreadCount int64 `json:"unreadCount"`
+}
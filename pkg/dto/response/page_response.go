@@ -0,0 +1,8 @@
+package response
+
+// PageResponse is a page of a list, NextCursor is passed as the cursor parameter to get the
+// next page and is empty on the last page
+type PageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+import "encoding/json"
+
+// WebhookDTO is a webhook of a group. The secret is only returned when the webhook is created.
+type WebhookDTO struct {
+	ID                  uint     `json:"id"`
+	URL                 string   `json:"url"`
+	Events              []string `json:"events"`
+	Active              bool     `json:"active"`
+	Secret              string   `json:"secret,omitempty"`
+	ConsecutiveFailures int      `json:"consecutiveFailures"`
+	DisabledAt          string   `json:"disabledAt,omitempty"`
+	CreatedAt           string   `json:"createdAt"`
+	UpdatedAt           string   `json:"updatedAt"`
+}
+
+// WebhookDeliveryDTO is a payload queued for a webhook with the outcome of its last attempt
+type WebhookDeliveryDTO struct {
+	ID             uint            `json:"id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	Payload        json.RawMessage `json:"payload"`
+	NextAttemptAt  string          `json:"nextAttemptAt,omitempty"`
+	LastAttemptAt  string          `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	ResponseBody   string          `json:"responseBody,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      string          `json:"createdAt"`
+}
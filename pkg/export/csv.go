@@ -0,0 +1,70 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"money_share/pkg/util"
+	"strconv"
+	"strings"
+)
+
+// csvWriter writes the expenses, member totals and settlement as sections of one CSV file,
+// each with a title row and a header row
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	writer := &csvWriter{w: csv.NewWriter(w)}
+	_ = writer.w.Write([]string{"Expenses"})
+	_ = writer.w.Write([]string{"Title", "Description", "Amount", "Paid by", "Purchase time", "Status"})
+	return writer
+}
+
+func (c *csvWriter) WriteExpense(expense Expense) error {
+	return c.w.Write([]string{
+		csvText(expense.Title),
+		csvText(expense.Description),
+		strconv.FormatFloat(float64(expense.Amount), 'f', 2, 32),
+		csvText(expense.Payer),
+		expense.PurchaseTime.UTC().Format(util.DateTimeLayout),
+		expense.Status,
+	})
+}
+
+func (c *csvWriter) WriteSummary(summary Summary) error {
+	rows := [][]string{
+		{},
+		{"Member totals"},
+		{"Member", "Expenses", "Approved", "Pending", "Denied", "Share", "Balance"},
+	}
+	for _, member := range summary.Members {
+		rows = append(rows, []string{
+			csvText(member.Name),
+			strconv.Itoa(member.Expenses),
+			formatCents(member.Approved),
+			formatCents(member.Pending),
+			formatCents(member.Denied),
+			formatCents(member.Share),
+			formatCents(member.Balance),
+		})
+	}
+	rows = append(rows, []string{"Total", "", formatCents(summary.Total)}, []string{}, []string{"Settlement"}, []string{"From", "To", "Amount"})
+	for _, transfer := range summary.Transfers {
+		rows = append(rows, []string{csvText(transfer.From), csvText(transfer.To), formatCents(transfer.Amount)})
+	}
+	return c.w.WriteAll(rows)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvText keeps spreadsheets from evaluating text entered by users as formulas
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
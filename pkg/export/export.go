@@ -0,0 +1,83 @@
+// Package export writes the ledger of a group as CSV, XLSX or PDF. Expenses are written as they
+// are read so that large groups are streamed, the member totals and the settlement summary
+// follow them.
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"money_share/pkg/apperror"
+	"time"
+)
+
+// Formats of exports
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatPDF:  "application/pdf",
+}
+
+func ValidateFormat(format string) (err error) {
+	if _, ok := contentTypes[format]; !ok {
+		err = apperror.InvalidField("format", "format must be 'csv', 'xlsx' or 'pdf'")
+	}
+	return
+}
+
+// ContentType returns the media type of files of the format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Expense is a row of the ledger
+type Expense struct {
+	Title        string
+	Description  string
+	Amount       float32
+	Payer        string
+	PurchaseTime time.Time
+	Status       string
+}
+
+// Writer writes a ledger in one format. Expenses are written first, then the summary, Close
+// completes the file.
+type Writer interface {
+	WriteExpense(expense Expense) error
+	WriteSummary(summary Summary) error
+	Close() error
+}
+
+// NewWriter returns a writer of the format to w, title heads the file where the format has room
+// for it
+func NewWriter(format string, w io.Writer, title string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatPDF:
+		return newPDFWriter(w, title)
+	}
+	return nil, ValidateFormat(format)
+}
+
+// toCents rounds an amount to cents
+func toCents(amount float32) int64 {
+	return int64(math.Round(float64(amount) * 100))
+}
+
+// formatCents formats an amount in cents with two decimals
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
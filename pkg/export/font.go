@@ -0,0 +1,404 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/go-fonts/dejavu/dejavusans"
+	"github.com/go-fonts/dejavu/dejavusansbold"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"unicode/utf16"
+)
+
+// DejaVu Sans covers Latin with the Vietnamese letters, Greek and Cyrillic
+var (
+	regularFace = sync.OnceValues(func() (*fontFace, error) { return parseFontFace(dejavusans.TTF) })
+	boldFace    = sync.OnceValues(func() (*fontFace, error) { return parseFontFace(dejavusansbold.TTF) })
+)
+
+// Tables of a TrueType font kept in subsets. Viewers only need the outlines and metrics as text is
+// encoded in glyph indexes, the others make the subset a complete font for other tools.
+var embeddedTables = []string{"OS/2", "cmap", "cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "prep"}
+
+// Flags of composite glyph components
+const (
+	argsAreWords   = 0x0001
+	haveScale      = 0x0008
+	moreComponents = 0x0020
+	haveXYScale    = 0x0040
+	haveTwoByTwo   = 0x0080
+)
+
+// fontFace is a parsed TrueType font, it is shared by every export
+type fontFace struct {
+	sfnt   *sfnt.Font
+	name   string
+	tables map[string][]byte
+	// glyphs holds the data of each glyph in the glyf table by glyph index
+	glyphs     [][]byte
+	unitsPerEm int
+	// Metrics of the font descriptor in thousandths of the font size
+	bbox            [4]int
+	ascent, descent int
+}
+
+func parseFontFace(ttf []byte) (*fontFace, error) {
+	parsed, err := sfnt.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	face := &fontFace{sfnt: parsed, tables: make(map[string][]byte), unitsPerEm: int(parsed.UnitsPerEm())}
+	if face.name, err = parsed.Name(nil, sfnt.NameIDPostScript); err != nil {
+		return nil, err
+	}
+
+	// The table directory follows the 12 byte header
+	if len(ttf) < 12 {
+		return nil, errors.New("font is truncated")
+	}
+	numTables := int(binary.BigEndian.Uint16(ttf[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + 16*i
+		if record+16 > len(ttf) {
+			return nil, errors.New("font is truncated")
+		}
+		tag := string(ttf[record : record+4])
+		offset, length := binary.BigEndian.Uint32(ttf[record+8:]), binary.BigEndian.Uint32(ttf[record+12:])
+		if uint64(offset)+uint64(length) > uint64(len(ttf)) {
+			return nil, fmt.Errorf("font table %q is truncated", tag)
+		}
+		face.tables[tag] = ttf[offset : offset+length]
+	}
+	head, hhea, loca, glyf := face.tables["head"], face.tables["hhea"], face.tables["loca"], face.tables["glyf"]
+	if len(head) < 54 || len(hhea) < 8 || loca == nil || glyf == nil {
+		return nil, errors.New("font has no TrueType outlines")
+	}
+
+	// Split glyf by the offsets of loca, short offsets are halved
+	numGlyphs := parsed.NumGlyphs()
+	longOffsets := binary.BigEndian.Uint16(head[50:]) == 1
+	offset := func(i int) (int, error) {
+		if longOffsets && 4*i+4 <= len(loca) {
+			return int(binary.BigEndian.Uint32(loca[4*i:])), nil
+		}
+		if !longOffsets && 2*i+2 <= len(loca) {
+			return 2 * int(binary.BigEndian.Uint16(loca[2*i:])), nil
+		}
+		return 0, errors.New("font loca table is truncated")
+	}
+	face.glyphs = make([][]byte, numGlyphs)
+	for i := range face.glyphs {
+		start, err := offset(i)
+		if err != nil {
+			return nil, err
+		}
+		end, err := offset(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		if start > end || end > len(glyf) {
+			return nil, fmt.Errorf("glyph %d is out of the glyf table", i)
+		}
+		face.glyphs[i] = glyf[start:end]
+	}
+
+	for i := range face.bbox {
+		face.bbox[i] = face.thousandths(int(int16(binary.BigEndian.Uint16(head[36+2*i:]))))
+	}
+	face.ascent = face.thousandths(int(int16(binary.BigEndian.Uint16(hhea[4:]))))
+	face.descent = face.thousandths(int(int16(binary.BigEndian.Uint16(hhea[6:]))))
+	return face, nil
+}
+
+// thousandths converts font units to thousandths of the font size
+func (f *fontFace) thousandths(units int) int {
+	return units * 1000 / f.unitsPerEm
+}
+
+// pdfFont writes text in a font face and records the glyphs used, only those are embedded
+type pdfFont struct {
+	face *fontFace
+	buf  sfnt.Buffer
+	// glyphs by character and widths by glyph in thousandths of the font size
+	glyphs map[rune]sfnt.GlyphIndex
+	widths map[sfnt.GlyphIndex]int
+}
+
+func newPDFFont(face *fontFace) *pdfFont {
+	return &pdfFont{face: face, glyphs: make(map[rune]sfnt.GlyphIndex), widths: make(map[sfnt.GlyphIndex]int)}
+}
+
+// glyph returns the glyph of r, characters the font lacks are shown as question marks
+func (f *pdfFont) glyph(r rune) sfnt.GlyphIndex {
+	if r < 32 {
+		r = ' '
+	}
+	if glyph, ok := f.glyphs[r]; ok {
+		return glyph
+	}
+	glyph, err := f.face.sfnt.GlyphIndex(&f.buf, r)
+	if (err != nil || glyph == 0) && r != '?' {
+		return f.glyph('?')
+	}
+	f.glyphs[r] = glyph
+	if _, ok := f.widths[glyph]; !ok {
+		advance, err := f.face.sfnt.GlyphAdvance(&f.buf, glyph, fixed.I(f.face.unitsPerEm), font.HintingNone)
+		if err == nil {
+			f.widths[glyph] = f.face.thousandths(advance.Round())
+		}
+	}
+	return glyph
+}
+
+// width returns the width of text in thousandths of the font size
+func (f *pdfFont) width(text string) int {
+	width := 0
+	for _, r := range text {
+		width += f.widths[f.glyph(r)]
+	}
+	return width
+}
+
+// encode returns text as a hex string of glyph indexes, the encoding of the Identity-H CMap
+func (f *pdfFont) encode(text string) string {
+	encoded := make([]byte, 0, 4*len(text)+2)
+	encoded = append(encoded, '<')
+	for _, r := range text {
+		encoded = fmt.Appendf(encoded, "%04X", uint16(f.glyph(r)))
+	}
+	return string(append(encoded, '>'))
+}
+
+// write writes the font as object number of p with the objects it refers to. The font is a
+// CID font whose CIDs are glyph indexes, ToUnicode maps them back to text for search and copy.
+func (f *pdfFont) write(p *pdfWriter, number int) {
+	used := make([]sfnt.GlyphIndex, 0, len(f.widths))
+	for glyph := range f.widths {
+		used = append(used, glyph)
+	}
+	sort.Slice(used, func(i, j int) bool { return used[i] < used[j] })
+	subset, err := f.face.subset(used)
+	if err != nil {
+		p.err = err
+		return
+	}
+
+	// Subsets are named with a tag of six capital letters derived from their glyphs
+	hash := fnv.New32a()
+	for _, glyph := range used {
+		_ = binary.Write(hash, binary.BigEndian, uint16(glyph))
+	}
+	tag, sum := make([]byte, 6), hash.Sum32()
+	for i := range tag {
+		tag[i] = byte('A' + sum%26)
+		sum /= 26
+	}
+	name := string(tag) + "+" + f.face.name
+
+	descendant, descriptor, file, toUnicode := p.next, p.next+1, p.next+2, p.next+3
+	p.next += 4
+	p.object(number, []byte(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, descendant, toUnicode)))
+
+	widths := new(bytes.Buffer)
+	for _, glyph := range used {
+		fmt.Fprintf(widths, "%d [%d] ", glyph, f.widths[glyph])
+	}
+	p.object(descendant, []byte(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [ %s] >>",
+		name, descriptor, widths)))
+	face := f.face
+	p.object(descriptor, []byte(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, face.bbox[0], face.bbox[1], face.bbox[2], face.bbox[3], face.ascent, face.descent, face.ascent, file)))
+
+	compressed := new(bytes.Buffer)
+	zw := zlib.NewWriter(compressed)
+	_, _ = zw.Write(subset)
+	_ = zw.Close()
+	p.object(file, []byte(fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), len(subset), compressed.Bytes())))
+
+	cmap := f.toUnicode()
+	p.object(toUnicode, []byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(cmap), cmap)))
+}
+
+// toUnicode returns the CMap mapping the glyphs used to their characters
+func (f *pdfFont) toUnicode() []byte {
+	characters := make(map[sfnt.GlyphIndex]rune, len(f.glyphs))
+	for r, glyph := range f.glyphs {
+		// Characters sharing a glyph, e.g. control characters and spaces, map to the lowest
+		if current, ok := characters[glyph]; !ok || r < current {
+			characters[glyph] = r
+		}
+	}
+	glyphs := make([]sfnt.GlyphIndex, 0, len(characters))
+	for glyph := range characters {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+
+	cmap := new(bytes.Buffer)
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// A block maps at most 100 characters
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(cmap, "%d beginbfchar\n", end-start)
+		for _, glyph := range glyphs[start:end] {
+			fmt.Fprintf(cmap, "<%04X> <", uint16(glyph))
+			for _, unit := range utf16.Encode([]rune{characters[glyph]}) {
+				fmt.Fprintf(cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return cmap.Bytes()
+}
+
+// subset returns a font with the outlines of the used glyphs and of the glyphs they are composed
+// of. The other glyphs are left empty, so that glyph indexes are unchanged.
+func (f *fontFace) subset(used []sfnt.GlyphIndex) ([]byte, error) {
+	// The first glyph is drawn for missing characters
+	keep := map[int]bool{0: true}
+	pending := []int{0}
+	for _, glyph := range used {
+		pending = append(pending, int(glyph))
+	}
+	for len(pending) > 0 {
+		glyph := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if glyph >= len(f.glyphs) {
+			return nil, fmt.Errorf("glyph %d is not in the font", glyph)
+		}
+		keep[glyph] = true
+		components, err := componentsOf(f.glyphs[glyph])
+		if err != nil {
+			return nil, err
+		}
+		for _, component := range components {
+			if !keep[component] {
+				pending = append(pending, component)
+			}
+		}
+	}
+
+	// Glyphs are 4 byte aligned with long loca offsets
+	glyf, loca := new(bytes.Buffer), new(bytes.Buffer)
+	for i, data := range f.glyphs {
+		_ = binary.Write(loca, binary.BigEndian, uint32(glyf.Len()))
+		if keep[i] {
+			glyf.Write(data)
+			glyf.Write(make([]byte, (4-len(data)%4)%4))
+		}
+	}
+	_ = binary.Write(loca, binary.BigEndian, uint32(glyf.Len()))
+
+	tables := make(map[string][]byte, len(embeddedTables))
+	for _, tag := range embeddedTables {
+		if data, ok := f.tables[tag]; ok {
+			tables[tag] = data
+		}
+	}
+	tables["glyf"], tables["loca"] = glyf.Bytes(), loca.Bytes()
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	tables["head"] = head
+	// Version 3 of post has no glyph names, which are most of the table
+	if post := f.tables["post"]; len(post) >= 32 {
+		post = append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(post, 0x00030000)
+		tables["post"] = post
+	}
+	return buildFont(tables), nil
+}
+
+// componentsOf returns the glyphs a composite glyph is made of, none for simple glyphs
+func componentsOf(glyph []byte) ([]int, error) {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil, nil
+	}
+	var components []int
+	for offset := 10; ; {
+		if offset+4 > len(glyph) {
+			return nil, errors.New("composite glyph is truncated")
+		}
+		flags := binary.BigEndian.Uint16(glyph[offset:])
+		components = append(components, int(binary.BigEndian.Uint16(glyph[offset+2:])))
+		offset += 4
+		if flags&argsAreWords != 0 {
+			offset += 4
+		} else {
+			offset += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			offset += 2
+		case flags&haveXYScale != 0:
+			offset += 4
+		case flags&haveTwoByTwo != 0:
+			offset += 8
+		}
+		if flags&moreComponents == 0 {
+			return components, nil
+		}
+	}
+}
+
+// buildFont writes a TrueType font of the tables, tables are 4 byte aligned and checksummed
+func buildFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	// The search fields of the header are derived from the largest power of two tables
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= len(tags) {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+	out := new(bytes.Buffer)
+	_ = binary.Write(out, binary.BigEndian, []uint32{0x00010000})
+	_ = binary.Write(out, binary.BigEndian, []uint16{uint16(len(tags)), uint16(searchRange), uint16(entrySelector), uint16(16*len(tags) - searchRange)})
+
+	offset := 12 + 16*len(tags)
+	for _, tag := range tags {
+		data := tables[tag]
+		out.WriteString(tag)
+		_ = binary.Write(out, binary.BigEndian, []uint32{checksum(data), uint32(offset), uint32(len(data))})
+		offset += (len(data) + 3) &^ 3
+	}
+	headOffset := 0
+	for _, tag := range tags {
+		if tag == "head" {
+			headOffset = out.Len()
+		}
+		out.Write(tables[tag])
+		out.Write(make([]byte, (4-len(tables[tag])%4)%4))
+	}
+	font := out.Bytes()
+	binary.BigEndian.PutUint32(font[headOffset+8:], 0xB1B0AFBA-checksum(font))
+	return font
+}
+
+// checksum is the sum of data as big endian 32-bit words
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
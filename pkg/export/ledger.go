@@ -0,0 +1,174 @@
+package export
+
+import (
+	"money_share/pkg/model"
+	"sort"
+)
+
+// MemberTotal sums the exported expenses of a member. Approved expenses are split evenly between
+// the members of the group, Share is the part of a member and Balance what they paid more than it.
+type MemberTotal struct {
+	Name     string
+	Expenses int
+	Approved int64
+	Pending  int64
+	Denied   int64
+	Share    int64
+	Balance  int64
+}
+
+// Transfer is a payment settling the balances, amounts are in cents
+type Transfer struct {
+	From   string
+	To     string
+	Amount int64
+}
+
+// Summary follows the expenses of a ledger, amounts are in cents
+type Summary struct {
+	Members   []MemberTotal
+	Total     int64
+	Transfers []Transfer
+}
+
+// Ledger sums the exported expenses by payer. Payers who left the group are owed what they paid
+// but have no share.
+type Ledger struct {
+	totals map[uint]*MemberTotal
+	// order of the members, then of the payers who left in the order they were met
+	order []uint
+	// current members by user ID
+	members map[uint]bool
+}
+
+func NewLedger(members []*model.Member) *Ledger {
+	ledger := &Ledger{totals: make(map[uint]*MemberTotal), members: make(map[uint]bool)}
+	sorted := append([]*model.Member(nil), members...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UserID < sorted[j].UserID })
+	for _, member := range sorted {
+		ledger.members[member.UserID] = true
+		ledger.add(member.UserID, DisplayName(member.User))
+	}
+	return ledger
+}
+
+// DisplayName returns the name users are shown with
+func DisplayName(user model.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Username
+}
+
+// Name returns the name of a payer known to the ledger
+func (l *Ledger) Name(userID uint) (string, bool) {
+	total, ok := l.totals[userID]
+	if !ok {
+		return "", false
+	}
+	return total.Name, true
+}
+
+// AddPayer names a payer who left the group, before their expenses are added
+func (l *Ledger) AddPayer(userID uint, name string) {
+	if _, ok := l.totals[userID]; !ok {
+		l.add(userID, name)
+	}
+}
+
+func (l *Ledger) add(userID uint, name string) *MemberTotal {
+	total := &MemberTotal{Name: name}
+	l.totals[userID] = total
+	l.order = append(l.order, userID)
+	return total
+}
+
+// Add sums an expense, payers unknown to the ledger are unnamed
+func (l *Ledger) Add(expense *model.Expense) {
+	total, ok := l.totals[expense.MemberID]
+	if !ok {
+		total = l.add(expense.MemberID, "")
+	}
+	cents := toCents(expense.Amount)
+	total.Expenses++
+	switch expense.Status {
+	case model.StatusApproved:
+		total.Approved += cents
+	case model.StatusPending:
+		total.Pending += cents
+	case model.StatusDenied:
+		total.Denied += cents
+	}
+}
+
+// Summary splits the approved expenses between the members and settles the balances
+func (l *Ledger) Summary() Summary {
+	summary := Summary{}
+	var members []uint
+	for _, userID := range l.order {
+		summary.Total += l.totals[userID].Approved
+		if l.members[userID] {
+			members = append(members, userID)
+		}
+	}
+	// Cents which cannot be split evenly go to the first members
+	if len(members) > 0 {
+		share, remainder := summary.Total/int64(len(members)), summary.Total%int64(len(members))
+		for i, userID := range members {
+			l.totals[userID].Share = share
+			if int64(i) < remainder {
+				l.totals[userID].Share++
+			}
+		}
+	}
+	for _, userID := range l.order {
+		total := l.totals[userID]
+		total.Balance = total.Approved - total.Share
+		summary.Members = append(summary.Members, *total)
+	}
+	summary.Transfers = settle(summary.Members)
+	return summary
+}
+
+// settle returns payments evening out the balances, which sum to zero. The largest debt is paid
+// to the largest credit first, which keeps the number of payments low.
+func settle(members []MemberTotal) []Transfer {
+	type balance struct {
+		name   string
+		amount int64
+	}
+	var debtors, creditors []*balance
+	for _, member := range members {
+		if member.Balance < 0 {
+			debtors = append(debtors, &balance{member.Name, -member.Balance})
+		} else if member.Balance > 0 {
+			creditors = append(creditors, &balance{member.Name, member.Balance})
+		}
+	}
+	largestFirst := func(balances []*balance) {
+		sort.SliceStable(balances, func(i, j int) bool { return balances[i].amount > balances[j].amount })
+	}
+	largestFirst(debtors)
+	largestFirst(creditors)
+
+	var transfers []Transfer
+	for len(debtors) > 0 && len(creditors) > 0 {
+		debtor, creditor := debtors[0], creditors[0]
+		amount := debtor.amount
+		if creditor.amount < amount {
+			amount = creditor.amount
+		}
+		transfers = append(transfers, Transfer{From: debtor.name, To: creditor.name, Amount: amount})
+		debtor.amount -= amount
+		creditor.amount -= amount
+		if debtor.amount == 0 {
+			debtors = debtors[1:]
+		}
+		if creditor.amount == 0 {
+			creditors = creditors[1:]
+		}
+		largestFirst(debtors)
+		largestFirst(creditors)
+	}
+	return transfers
+}
@@ -0,0 +1,273 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"money_share/pkg/util"
+	"strconv"
+)
+
+// A4 in points
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 40
+)
+
+// Objects numbered before the pages, the page tree and fonts are written last as their kids and
+// glyphs are only known then
+const (
+	pdfCatalog = 1 + iota
+	pdfPageTree
+	pdfFontRegular
+	pdfFontBold
+)
+
+// pdfColumn is a column of a table, x is the right edge of right aligned columns
+type pdfColumn struct {
+	title string
+	x     float64
+	width float64
+	right bool
+}
+
+var (
+	pdfExpenseColumns = []pdfColumn{
+		{"Purchase time", 40, 85, false},
+		{"Title", 130, 165, false},
+		{"Paid by", 300, 110, false},
+		{"Status", 415, 60, false},
+		{"Amount", 555, 70, true},
+	}
+	pdfMemberColumns = []pdfColumn{
+		{"Member", 40, 150, false},
+		{"Expenses", 245, 50, true},
+		{"Approved", 307, 58, true},
+		{"Pending", 369, 58, true},
+		{"Denied", 431, 58, true},
+		{"Share", 493, 58, true},
+		{"Balance", 555, 58, true},
+	}
+	pdfTransferColumns = []pdfColumn{
+		{"From", 40, 200, false},
+		{"To", 250, 200, false},
+		{"Amount", 555, 80, true},
+	}
+)
+
+// pdfWriter lays the ledger out as tables on A4 pages with DejaVu Sans, embedding the glyphs
+// used. Each page is written once it is full, so only one page is held in memory.
+type pdfWriter struct {
+	w io.Writer
+	// offset is the number of bytes written, offsets those of the objects by number
+	offset  int
+	offsets map[int]int
+	next    int
+	pages   []int
+	page    *bytes.Buffer
+	y       float64
+	// columns of the table being written, their header is repeated on new pages
+	columns []pdfColumn
+	// fonts by resource name
+	fonts map[string]*pdfFont
+	err   error
+}
+
+func newPDFWriter(w io.Writer, title string) (*pdfWriter, error) {
+	regular, err := regularFace()
+	if err != nil {
+		return nil, err
+	}
+	bold, err := boldFace()
+	if err != nil {
+		return nil, err
+	}
+	p := &pdfWriter{w: w, offsets: make(map[int]int), next: pdfFontBold + 1, fonts: map[string]*pdfFont{
+		"F1": newPDFFont(regular),
+		"F2": newPDFFont(bold),
+	}}
+	// The comment of binary characters tells tools the file is binary
+	p.write([]byte("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n"))
+	p.object(pdfCatalog, []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPageTree)))
+	p.startPage()
+	p.text("F2", 16, pdfMargin, p.y, title)
+	p.y -= 28
+	p.section("Expenses", pdfExpenseColumns)
+	return p, p.err
+}
+
+func (p *pdfWriter) WriteExpense(expense Expense) error {
+	height := 13.0
+	if expense.Description != "" {
+		height += 10
+	}
+	p.ensure(height)
+	p.cell(pdfExpenseColumns[0], "F1", 9, expense.PurchaseTime.UTC().Format(util.DateTimeLayout))
+	p.cell(pdfExpenseColumns[1], "F1", 9, expense.Title)
+	p.cell(pdfExpenseColumns[2], "F1", 9, expense.Payer)
+	p.cell(pdfExpenseColumns[3], "F1", 9, expense.Status)
+	p.cell(pdfExpenseColumns[4], "F1", 9, formatCents(toCents(expense.Amount)))
+	if expense.Description != "" {
+		p.y -= 10
+		p.page.WriteString("0.4 g\n")
+		p.cell(pdfColumn{x: pdfExpenseColumns[1].x, width: pdfExpenseColumns[3].x - pdfExpenseColumns[1].x}, "F1", 8, expense.Description)
+		p.page.WriteString("0 g\n")
+	}
+	p.y -= 13
+	return p.err
+}
+
+func (p *pdfWriter) WriteSummary(summary Summary) error {
+	p.y -= 12
+	p.section("Member totals", pdfMemberColumns)
+	for _, member := range summary.Members {
+		p.ensure(13)
+		values := []string{
+			member.Name,
+			strconv.Itoa(member.Expenses),
+			formatCents(member.Approved),
+			formatCents(member.Pending),
+			formatCents(member.Denied),
+			formatCents(member.Share),
+			formatCents(member.Balance),
+		}
+		for i, value := range values {
+			p.cell(pdfMemberColumns[i], "F1", 9, value)
+		}
+		p.y -= 13
+	}
+	p.ensure(13)
+	p.cell(pdfMemberColumns[0], "F2", 9, "Total")
+	p.cell(pdfMemberColumns[2], "F2", 9, formatCents(summary.Total))
+	p.y -= 25
+
+	p.section("Settlement", pdfTransferColumns)
+	if len(summary.Transfers) == 0 {
+		p.cell(pdfTransferColumns[0], "F1", 9, "Nobody owes anything")
+	}
+	for _, transfer := range summary.Transfers {
+		p.ensure(13)
+		p.cell(pdfTransferColumns[0], "F1", 9, transfer.From)
+		p.cell(pdfTransferColumns[1], "F1", 9, transfer.To)
+		p.cell(pdfTransferColumns[2], "F1", 9, formatCents(transfer.Amount))
+		p.y -= 13
+	}
+	p.columns = nil
+	return p.err
+}
+
+func (p *pdfWriter) Close() error {
+	p.finishPage()
+	kids := new(bytes.Buffer)
+	for _, page := range p.pages {
+		fmt.Fprintf(kids, "%d 0 R ", page)
+	}
+	p.object(pdfPageTree, []byte(fmt.Sprintf("<< /Type /Pages /Kids [ %s] /Count %d >>", kids, len(p.pages))))
+	p.fonts["F1"].write(p, pdfFontRegular)
+	p.fonts["F2"].write(p, pdfFontBold)
+
+	// The cross-reference table lists the offsets of the objects by number
+	xref := p.offset
+	xrefTable := new(bytes.Buffer)
+	fmt.Fprintf(xrefTable, "xref\n0 %d\n0000000000 65535 f \n", p.next)
+	for number := 1; number < p.next; number++ {
+		fmt.Fprintf(xrefTable, "%010d 00000 n \n", p.offsets[number])
+	}
+	fmt.Fprintf(xrefTable, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.next, pdfCatalog, xref)
+	p.write(xrefTable.Bytes())
+	return p.err
+}
+
+func (p *pdfWriter) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.offset += n
+	p.err = err
+}
+
+func (p *pdfWriter) object(number int, body []byte) {
+	p.offsets[number] = p.offset
+	p.write([]byte(fmt.Sprintf("%d 0 obj\n", number)))
+	p.write(body)
+	p.write([]byte("\nendobj\n"))
+}
+
+func (p *pdfWriter) startPage() {
+	p.page = new(bytes.Buffer)
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// finishPage writes the page with its content
+func (p *pdfWriter) finishPage() {
+	p.page.WriteString("0.4 g\n")
+	p.text("F1", 8, pdfMargin, pdfMargin/2, fmt.Sprintf("Page %d", len(p.pages)+1))
+	content, page := p.next, p.next+1
+	p.next += 2
+	p.object(content, []byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.page.Len(), p.page.Bytes())))
+	p.object(page, []byte(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPageTree, pdfPageWidth, pdfPageHeight, pdfFontRegular, pdfFontBold, content)))
+	p.pages = append(p.pages, page)
+}
+
+// ensure starts a new page unless height fits above the bottom margin
+func (p *pdfWriter) ensure(height float64) {
+	if p.y-height >= pdfMargin {
+		return
+	}
+	p.finishPage()
+	p.startPage()
+	if p.columns != nil {
+		p.header()
+	}
+}
+
+// section writes the title and table header of a section, on a new page unless both fit
+func (p *pdfWriter) section(title string, columns []pdfColumn) {
+	p.columns = nil
+	p.ensure(40)
+	p.text("F2", 12, pdfMargin, p.y, title)
+	p.y -= 20
+	p.columns = columns
+	p.header()
+}
+
+func (p *pdfWriter) header() {
+	for _, column := range p.columns {
+		p.cell(column, "F2", 9, column.title)
+	}
+	fmt.Fprintf(p.page, "0.5 w %d %.1f m %d %.1f l S\n", pdfMargin, p.y-4, pdfPageWidth-pdfMargin, p.y-4)
+	p.y -= 16
+}
+
+// cell writes text in a column on the current line, cut to the width of the column
+func (p *pdfWriter) cell(column pdfColumn, font string, size float64, text string) {
+	text = p.fit(font, text, column.width, size)
+	x := column.x
+	if column.right {
+		x -= p.textWidth(font, text, size)
+	}
+	p.text(font, size, x, p.y, text)
+}
+
+func (p *pdfWriter) text(font string, size float64, x float64, y float64, text string) {
+	fmt.Fprintf(p.page, "BT /%s %g Tf %.1f %.1f Td %s Tj ET\n", font, size, x, y, p.fonts[font].encode(text))
+}
+
+func (p *pdfWriter) textWidth(font string, text string, size float64) float64 {
+	return float64(p.fonts[font].width(text)) * size / 1000
+}
+
+// fit cuts text to width, ending it with an ellipsis when it is cut
+func (p *pdfWriter) fit(font string, text string, width float64, size float64) string {
+	if p.textWidth(font, text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && p.textWidth(font, string(runes)+"…", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
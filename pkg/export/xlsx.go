@@ -0,0 +1,226 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Styles of cells, indexes in the cellXfs of xlsxStyles
+const (
+	xlsxStyleHeader = 1
+	xlsxStyleAmount = 2
+	xlsxStyleDate   = 3
+)
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet2.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet3.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Expenses" sheetId="1" r:id="rId1"/><sheet name="Members" sheetId="2" r:id="rId2"/><sheet name="Settlement" sheetId="3" r:id="rId3"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>` +
+	`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet3.xml"/>` +
+	`<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// Days of spreadsheet dates count from this day
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxCell is a cell of a row, a string, a number or a time
+type xlsxCell struct {
+	value interface{}
+	style int
+}
+
+// xlsxWriter writes a workbook with a sheet of expenses, one of member totals and one of the
+// settlement. Parts are written to the zip in order, the expenses sheet is streamed.
+type xlsxWriter struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	row     int
+	summary bool
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	writer := &xlsxWriter{zip: zip.NewWriter(w)}
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		file, err := writer.zip.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+	err := writer.beginSheet(1, []float64{30, 40, 12, 20, 18, 12},
+		header("Title", "Description", "Amount", "Paid by", "Purchase time", "Status"))
+	return writer, err
+}
+
+func header(titles ...string) []xlsxCell {
+	cells := make([]xlsxCell, 0, len(titles))
+	for _, title := range titles {
+		cells = append(cells, xlsxCell{title, xlsxStyleHeader})
+	}
+	return cells
+}
+
+func (x *xlsxWriter) WriteExpense(expense Expense) error {
+	return x.writeRow([]xlsxCell{
+		{value: expense.Title},
+		{value: expense.Description},
+		{cents(toCents(expense.Amount)), xlsxStyleAmount},
+		{value: expense.Payer},
+		{expense.PurchaseTime, xlsxStyleDate},
+		{value: expense.Status},
+	})
+}
+
+func (x *xlsxWriter) WriteSummary(summary Summary) error {
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+	x.summary = true
+	err := x.beginSheet(2, []float64{24, 10, 12, 12, 12, 12, 12},
+		header("Member", "Expenses", "Approved", "Pending", "Denied", "Share", "Balance"))
+	if err != nil {
+		return err
+	}
+	for _, member := range summary.Members {
+		err = x.writeRow([]xlsxCell{
+			{value: member.Name},
+			{value: member.Expenses},
+			{cents(member.Approved), xlsxStyleAmount},
+			{cents(member.Pending), xlsxStyleAmount},
+			{cents(member.Denied), xlsxStyleAmount},
+			{cents(member.Share), xlsxStyleAmount},
+			{cents(member.Balance), xlsxStyleAmount},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = x.writeRow([]xlsxCell{{"Total", xlsxStyleHeader}, {value: ""}, {cents(summary.Total), xlsxStyleAmount}})
+	if err != nil {
+		return err
+	}
+	if err = x.endSheet(); err != nil {
+		return err
+	}
+
+	if err = x.beginSheet(3, []float64{24, 24, 12}, header("From", "To", "Amount")); err != nil {
+		return err
+	}
+	for _, transfer := range summary.Transfers {
+		err = x.writeRow([]xlsxCell{{value: transfer.From}, {value: transfer.To}, {cents(transfer.Amount), xlsxStyleAmount}})
+		if err != nil {
+			return err
+		}
+	}
+	return x.endSheet()
+}
+
+func (x *xlsxWriter) Close() error {
+	// The workbook lists three sheets
+	if !x.summary {
+		if err := x.WriteSummary(Summary{}); err != nil {
+			return err
+		}
+	}
+	return x.zip.Close()
+}
+
+func cents(amount int64) float64 {
+	return float64(amount) / 100
+}
+
+func (x *xlsxWriter) beginSheet(number int, widths []float64, header []xlsxCell) error {
+	file, err := x.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", number))
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(file)
+	x.row = 0
+	_, _ = x.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><cols>`)
+	for i, width := range widths {
+		_, _ = fmt.Fprintf(x.sheet, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+	}
+	_, _ = x.sheet.WriteString(`</cols><sheetData>`)
+	return x.writeRow(header)
+}
+
+func (x *xlsxWriter) endSheet() error {
+	_, _ = x.sheet.WriteString(`</sheetData></worksheet>`)
+	return x.sheet.Flush()
+}
+
+func (x *xlsxWriter) writeRow(cells []xlsxCell) error {
+	x.row++
+	_, _ = fmt.Fprintf(x.sheet, `<row r="%d">`, x.row)
+	for i, cell := range cells {
+		ref := string(rune('A'+i)) + strconv.Itoa(x.row)
+		style := ""
+		if cell.style != 0 {
+			style = fmt.Sprintf(` s="%d"`, cell.style)
+		}
+		switch value := cell.value.(type) {
+		case string:
+			_, _ = fmt.Fprintf(x.sheet, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+			_ = xml.EscapeText(x.sheet, []byte(value))
+			_, _ = x.sheet.WriteString(`</t></is></c>`)
+		case int:
+			_, _ = fmt.Fprintf(x.sheet, `<c r="%s"%s><v>%d</v></c>`, ref, style, value)
+		case float64:
+			_, _ = fmt.Fprintf(x.sheet, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(value, 'f', -1, 64))
+		case time.Time:
+			days := value.UTC().Sub(xlsxEpoch).Hours() / 24
+			_, _ = fmt.Fprintf(x.sheet, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(days, 'f', -1, 64))
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
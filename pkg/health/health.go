@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCheckTimeout = 2 * time.Second
+
+// CheckFunc reports whether a dependency is reachable. It must honour ctx cancellation.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// CheckResult is the outcome of a single dependency check
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Healthy  bool          `json:"healthy"`
+	Duration time.Duration `json:"-"`
+	Latency  string        `json:"latency"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report is the aggregated outcome of all registered checks
+type Report struct {
+	Ready  bool          `json:"ready"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Checker holds the readiness checks of the application's dependencies
+// and whether the application is shutting down.
+type Checker struct {
+	mu       sync.RWMutex
+	checks   []check
+	draining atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Register adds a named dependency check. A non-positive timeout falls back to the default of 2 seconds.
+func (c *Checker) Register(name string, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// SetDraining marks the application as shutting down, readiness fails from then on
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Check runs all registered checks concurrently, each bounded by its own timeout.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make([]check, len(c.checks))
+	copy(checks, c.checks)
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	report := Report{Ready: !c.Draining(), Checks: results}
+	for _, result := range results {
+		if !result.Healthy {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, ch check) CheckResult {
+	checkCtx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ch.fn(checkCtx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-checkCtx.Done():
+		err = checkCtx.Err()
+	}
+
+	duration := time.Since(start)
+	result := CheckResult{
+		Name:     ch.name,
+		Healthy:  err == nil,
+		Duration: duration,
+		Latency:  duration.String(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package imaging
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+// AvatarSizes are the square edge lengths, in pixels, every avatar is stored at
+var AvatarSizes = []int{64, 256, 512}
+
+// DefaultAvatarSize is served when the client doesn't ask for a size
+const DefaultAvatarSize = 256
+
+// Largest source image accepted, decoding is refused above this to avoid decompression bombs.
+// Decoded images take 4 bytes per pixel, 16MP is plenty for a 512px avatar.
+const maxSourcePixels = 16_000_000
+
+const jpegQuality = 85
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format, only JPEG, PNG and WebP are accepted")
+	ErrImageTooLarge     = errors.New("image dimensions are too large")
+	ErrInvalidImage      = errors.New("image is corrupt or cannot be decoded")
+)
+
+// acceptedTypes maps sniffed content types to the format of the re-encoded output.
+// There is no WebP encoder in the standard library, WebP is stored as PNG to keep transparency.
+var acceptedTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "png",
+}
+
+// Avatar is an uploaded image decoded and re-encoded at every size of AvatarSizes
+type Avatar struct {
+	// ID is random, so that uploads of the same image by different users never share objects
+	ID string
+	// Extension of the encoded images, "jpg" or "png"
+	Extension string
+	// Images holds the encoded image by edge length
+	Images map[int][]byte
+}
+
+// FileName is the name the avatar is referenced by, e.g. "3f2a...c1.png"
+func (a Avatar) FileName() string {
+	return a.ID + "." + a.Extension
+}
+
+// ContentType returns the content type of the encoded images of extension
+func ContentType(extension string) string {
+	if extension == "jpg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// ProcessAvatar validates an upload by sniffing its content, decodes it, crops it to a square
+// and re-encodes it at every avatar size. Re-encoding drops all metadata such as EXIF and GPS.
+func ProcessAvatar(reader io.Reader) (Avatar, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return Avatar{}, err
+	}
+	extension, ok := acceptedTypes[http.DetectContentType(data)]
+	if !ok {
+		return Avatar{}, ErrUnsupportedFormat
+	}
+
+	// Check dimensions from the header before allocating the full image
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Avatar{}, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return Avatar{}, ErrInvalidImage
+	}
+	if config.Width*config.Height > maxSourcePixels {
+		return Avatar{}, ErrImageTooLarge
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Avatar{}, ErrInvalidImage
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Avatar{}, err
+	}
+	avatar := Avatar{
+		ID:        hex.EncodeToString(id),
+		Extension: extension,
+		Images:    make(map[int][]byte, len(AvatarSizes)),
+	}
+	square := cropSquare(source)
+	for _, size := range AvatarSizes {
+		encoded, err := encode(resize(square, size), extension)
+		if err != nil {
+			return Avatar{}, fmt.Errorf("encoding %dpx avatar: %w", size, err)
+		}
+		avatar.Images[size] = encoded
+	}
+	return avatar, nil
+}
+
+// IsAvatarSize reports whether size is one of AvatarSizes
+func IsAvatarSize(size int) bool {
+	for _, avatarSize := range AvatarSizes {
+		if size == avatarSize {
+			return true
+		}
+	}
+	return false
+}
+
+// cropSquare returns the centered square of img with the length of its shorter edge. Decoded
+// images share their pixels with the square rather than being copied.
+func cropSquare(img image.Image) image.Image {
+	bounds := img.Bounds()
+	edge := bounds.Dx()
+	if bounds.Dy() < edge {
+		edge = bounds.Dy()
+	}
+	x := bounds.Min.X + (bounds.Dx()-edge)/2
+	y := bounds.Min.Y + (bounds.Dy()-edge)/2
+	rect := image.Rect(x, y, x+edge, y+edge)
+	if subImager, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return subImager.SubImage(rect)
+	}
+	square := image.NewNRGBA(image.Rect(0, 0, edge, edge))
+	draw.Draw(square, square.Bounds(), img, rect.Min, draw.Src)
+	return square
+}
+
+func resize(img image.Image, size int) image.Image {
+	resized := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return resized
+}
+
+func encode(img image.Image, extension string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var err error
+	if extension == "jpg" {
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(buf, img)
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+var sqlLiteralPattern = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// GormLogger forwards gorm's query log to a slog logger. Errors other than
+// record-not-found are logged at error level, slow queries at warn level and
+// every other query at debug level.
+type GormLogger struct {
+	Logger        *slog.Logger
+	SlowThreshold time.Duration
+}
+
+func NewGormLogger(logger *slog.Logger) *GormLogger {
+	return &GormLogger{Logger: logger, SlowThreshold: 200 * time.Millisecond}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.with(ctx).Info(fmt.Sprintf(msg, data...))
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.with(ctx).Warn(fmt.Sprintf(msg, data...))
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.with(ctx).Error(fmt.Sprintf(msg, data...))
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	logger := l.with(ctx)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := traceSQL(fc)
+		logger.Error("query failed", "sql", sql, "rows", rows, "latency", elapsed, "error", err)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		sql, rows := traceSQL(fc)
+		logger.Warn("slow query", "sql", sql, "rows", rows, "latency", elapsed)
+	case logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := traceSQL(fc)
+		logger.Debug("query", "sql", sql, "rows", rows, "latency", elapsed)
+	}
+}
+
+func (l *GormLogger) with(ctx context.Context) *slog.Logger {
+	return WithContext(l.Logger, ctx)
+}
+
+// traceSQL returns the traced statement, with every string literal masked when
+// the statement touches a sensitive column such as a password hash.
+func traceSQL(fc func() (string, int64)) (string, int64) {
+	sql, rows := fc()
+	if IsSensitiveKey(sql) {
+		sql = sqlLiteralPattern.ReplaceAllString(sql, "'"+redacted+"'")
+	}
+	return sql, rows
+}
@@ -2,26 +2,57 @@ package middleware
 
 import (
 	"fmt"
+	"github.com/gorilla/mux"
+	"money_share/pkg/apperror"
 	"money_share/pkg/auth"
 	"money_share/pkg/controller"
+	"money_share/pkg/metrics"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
-func Authenticate(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenStr := r.Header.Get("Authorization")
-		if tokenStr == "" {
-			controller.ResponseError(w, "Missing authorization token", http.StatusUnauthorized)
-			return
-		}
-		claims, err := auth.ValidateAccessToken(tokenStr)
-		if err != nil {
-			controller.ResponseError(w, fmt.Sprintf("Cannot validate token: %s", err), http.StatusUnauthorized)
-			return
-		}
-		r.Header.Set("userID", strconv.Itoa(int(claims.UserID)))
-		r.Header.Set("username", claims.Username)
-		h.ServeHTTP(w, r)
-	})
+// Authenticate requires a valid access token of an enabled user and records the user in the
+// request
+func Authenticate(app *controller.App) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenStr := r.Header.Get("Authorization")
+			// Browsers cannot set headers on event streams, which pass the token in the query instead
+			if tokenStr == "" && strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+				tokenStr = r.URL.Query().Get("access_token")
+			}
+			if tokenStr == "" {
+				metrics.AuthFailures.WithLabelValues("missing_token").Inc()
+				app.ResponseError(w, apperror.Unauthorized("missing_token", "Missing authorization token"))
+				return
+			}
+			claims, err := auth.ValidateAccessToken(tokenStr)
+			if err != nil {
+				metrics.AuthFailures.WithLabelValues("invalid_token").Inc()
+				app.ResponseError(w, apperror.Wrap(err, apperror.KindUnauthorized, "invalid_token", fmt.Sprintf("Cannot validate token: %s", err)))
+				return
+			}
+			// Disabled accounts lose access at once rather than when their token expires
+			user, err := app.UserRepository.WithContext(r.Context()).GetById(claims.UserID)
+			if apperror.Is(err, apperror.KindNotFound) {
+				metrics.AuthFailures.WithLabelValues("unknown_user").Inc()
+				app.ResponseError(w, apperror.Unauthorized("invalid_token", "User of the token does not exist"))
+				return
+			}
+			if err != nil {
+				app.ResponseError(w, err)
+				return
+			}
+			if user.IsDisabled() {
+				metrics.AuthFailures.WithLabelValues("account_disabled").Inc()
+				app.ResponseError(w, apperror.Forbidden("account_disabled", "Account is disabled"))
+				return
+			}
+			recordUser(r.Context(), claims.UserID)
+			r.Header.Set("userID", strconv.Itoa(int(claims.UserID)))
+			r.Header.Set("username", claims.Username)
+			h.ServeHTTP(w, r.WithContext(auth.WithUserID(r.Context(), claims.UserID)))
+		})
+	}
 }
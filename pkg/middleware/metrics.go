@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"money_share/pkg/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics records request count and latency labelled by mux route template and status
+func Metrics(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := []string{routeTemplate(r), r.Method, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
@@ -1,25 +1,84 @@
 package middleware
 
 import (
-	"context"
-	"errors"
-	"github.com/go-redis/redis/v9"
+	"fmt"
+	"log/slog"
+	"math"
+	"money_share/pkg/apperror"
+	"money_share/pkg/auth"
+	"money_share/pkg/config"
 	"money_share/pkg/controller"
-	"money_share/pkg/database"
+	"money_share/pkg/logging"
+	"money_share/pkg/metrics"
+	"money_share/pkg/ratelimit"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 )
 
-var ctx = context.Background()
+type RateLimitConfig struct {
+	Limiter ratelimit.Limiter
+	// Default applies to every route without its own policy
+	Default ratelimit.Policy
+	// Routes maps mux route templates to stricter or looser policies
+	Routes map[string]ratelimit.Policy
+	// TrustedProxies are the proxies whose X-Forwarded-For header is honoured
+	TrustedProxies []*net.IPNet
+	Logger         *slog.Logger
+}
+
+// NewRateLimitConfig builds the policies configured in cfg, stricter on login and registration
+func NewRateLimitConfig(cfg config.Config, limiter ratelimit.Limiter, logger *slog.Logger) (RateLimitConfig, error) {
+	rateLimitConfig := RateLimitConfig{
+		Limiter: limiter,
+		Logger:  logger,
+	}
+	var err error
+	if rateLimitConfig.Default, err = ratelimit.ParsePolicy("default", cfg.RateLimitDefault); err != nil {
+		return rateLimitConfig, err
+	}
+	authPolicy, err := ratelimit.ParsePolicy("auth", cfg.RateLimitAuth)
+	if err != nil {
+		return rateLimitConfig, err
+	}
+	rateLimitConfig.Routes = map[string]ratelimit.Policy{
+		"/user/login":    authPolicy,
+		"/user/register": authPolicy,
+	}
+	rateLimitConfig.TrustedProxies, err = ratelimit.ParseTrustedProxies(cfg.TrustedProxies)
+	return rateLimitConfig, err
+}
 
-func RateLimit(rateLimit int64, duration int64) func(http.Handler) http.Handler {
+// RateLimit limits requests per authenticated user, or per client IP for anonymous
+// requests, with a token bucket per route policy. Every response carries RateLimit-*
+// headers and rejected requests also carry Retry-After.
+func RateLimit(config RateLimitConfig) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-			key := "RATE_LIMIT_COUNT_" + ip
-			err := increaseRequestCount(key, rateLimit, duration)
+			policy, ok := config.Routes[routeTemplate(r)]
+			if !ok {
+				policy = config.Default
+			}
+			key := policy.Name + ":" + rateLimitSubject(r, config.TrustedProxies)
+
+			result, err := config.Limiter.Allow(r.Context(), key, policy)
 			if err != nil {
-				controller.ResponseError(w, "Too many requests, rate limit exceeded", http.StatusTooManyRequests)
+				// Fail open, an unavailable limiter must not take the API down
+				logging.WithContext(config.Logger, r.Context()).Error("Rate limiter failed", "error", err)
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+			if !result.Allowed {
+				metrics.RateLimitRejections.WithLabelValues(policy.Name).Inc()
+				header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				controller.WriteError(config.Logger, w, apperror.New(apperror.KindTooManyRequests, "rate_limited", "Too many requests, rate limit exceeded"))
 				return
 			}
 			h.ServeHTTP(w, r)
@@ -27,14 +86,17 @@ func RateLimit(rateLimit int64, duration int64) func(http.Handler) http.Handler
 	}
 }
 
-func increaseRequestCount(key string, rateLimit int64, duration int64) error {
-	err := database.Redis.DB.Watch(ctx, func(tx *redis.Tx) error {
-		tx.SetNX(ctx, key, 0, time.Duration(duration)*time.Second)
-		count, err := tx.Incr(ctx, key).Result()
-		if count > rateLimit {
-			err = errors.New("rate limit exceeded")
+// rateLimitSubject identifies the caller: the user ID from a valid access token, else the client IP.
+// The token is only inspected here, authorization is still enforced by Authenticate.
+func rateLimitSubject(r *http.Request, trustedProxies []*net.IPNet) string {
+	if tokenStr := r.Header.Get("Authorization"); tokenStr != "" {
+		if claims, err := auth.ValidateAccessToken(tokenStr); err == nil {
+			return "user:" + strconv.FormatUint(uint64(claims.UserID), 10)
 		}
-		return err
-	}, key)
-	return err
+	}
+	return "ip:" + ratelimit.ClientIP(r, trustedProxies)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
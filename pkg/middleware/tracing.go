@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"money_share/pkg/logging"
+	"money_share/pkg/tracing"
+	"net/http"
+	"strconv"
+)
+
+// Tracing starts a server span for every request, continuing the trace from incoming
+// W3C traceparent headers. The span is named after the mux route template.
+func Tracing(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeTemplate(r)
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+			))
+		defer span.End()
+		if requestID := logging.RequestIDFromContext(ctx); requestID != "" {
+			span.SetAttributes(attribute.String("http.request_id", requestID))
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		r, user := withUserSlot(r.WithContext(ctx))
+		h.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		// Recorded by Authenticate, headers can be sent by anyone
+		if user.userID != 0 {
+			span.SetAttributes(semconv.EnduserID(strconv.FormatUint(uint64(user.userID), 10)))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type userSlotKey struct{}
+
+// userSlot carries the user authenticated further down the chain back up to the outer
+// middlewares, which only see the request they were given
+type userSlot struct {
+	userID uint
+}
+
+// withUserSlot returns r with a slot for the authenticated user, reusing the slot of an outer
+// middleware
+func withUserSlot(r *http.Request) (*http.Request, *userSlot) {
+	if slot, ok := r.Context().Value(userSlotKey{}).(*userSlot); ok {
+		return r, slot
+	}
+	slot := &userSlot{}
+	return r.WithContext(context.WithValue(r.Context(), userSlotKey{}, slot)), slot
+}
+
+// recordUser fills the slot of ctx with the authenticated user
+func recordUser(ctx context.Context, userID uint) {
+	if slot, ok := ctx.Value(userSlotKey{}).(*userSlot); ok {
+		slot.userID = userID
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"mime"
+	"money_share/pkg/apperror"
+	"money_share/pkg/controller"
+	"money_share/pkg/dto/response"
+	"money_share/pkg/openapi"
+	"net/http"
+	"strings"
+)
+
+// maxJSONBodySize is the largest JSON request body read, larger bodies are rejected with 413
+const maxJSONBodySize = 1 << 20
+
+// ValidateRequest rejects requests whose parameters or JSON body do not match the operation
+// in doc with 400 and the failing fields, and JSON bodies over maxJSONBodySize with 413. Routes
+// the document does not describe pass through. Authentication is left to the Authenticate
+// middleware, which runs first. Rejections are logged with logger.
+func ValidateRequest(doc *openapi3.T, logger *slog.Logger) mux.MiddlewareFunc {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := openapi.FindRoute(doc, r)
+			if route == nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			options := &openapi3filter.Options{
+				MultiError:         true,
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			}
+			// Clients have always sent JSON without a content type, the handlers decode it anyway
+			if r.Header.Get("Content-Type") == "" && r.ContentLength != 0 {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			// Uploads are size limited and checked by their handlers, don't buffer them here
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				options.ExcludeRequestBody = true
+			} else {
+				r.Body = http.MaxBytesReader(w, r.Body, maxJSONBodySize)
+			}
+
+			err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: mux.Vars(r),
+				Route:      route,
+				Options:    options,
+			})
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				controller.WriteError(logger, w, controller.ErrBodyTooLarge)
+				return
+			}
+			if err != nil {
+				controller.WriteError(logger, w, apperror.Validation("Invalid request", fieldErrors(err)...))
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// fieldErrors flattens validation errors into one entry per failing field
+func fieldErrors(err error) []response.FieldError {
+	// Errors of the parameters and the body are collected in a MultiError
+	if errs, ok := err.(openapi3.MultiError); ok {
+		var result []response.FieldError
+		for _, e := range errs {
+			result = append(result, fieldErrors(e)...)
+		}
+		return result
+	}
+
+	var requestError *openapi3filter.RequestError
+	if !errors.As(err, &requestError) {
+		return []response.FieldError{{Message: err.Error()}}
+	}
+	if requestError.Parameter != nil {
+		return []response.FieldError{{Field: requestError.Parameter.Name, Message: errorReason(requestError)}}
+	}
+	// Body errors wrap the schema errors of each field
+	var multiError openapi3.MultiError
+	if errors.As(requestError.Err, &multiError) {
+		var result []response.FieldError
+		for _, e := range multiError {
+			result = append(result, bodyFieldError(e))
+		}
+		return result
+	}
+	return []response.FieldError{bodyFieldError(errorOrReason(requestError))}
+}
+
+func bodyFieldError(err error) response.FieldError {
+	var schemaError *openapi3.SchemaError
+	if errors.As(err, &schemaError) {
+		return response.FieldError{Field: strings.Join(schemaError.JSONPointer(), "."), Message: schemaError.Reason}
+	}
+	return response.FieldError{Message: err.Error()}
+}
+
+// errorReason describes a parameter error without repeating the parameter name
+func errorReason(requestError *openapi3filter.RequestError) string {
+	var schemaError *openapi3.SchemaError
+	if errors.As(requestError.Err, &schemaError) {
+		return schemaError.Reason
+	}
+	return errorOrReason(requestError).Error()
+}
+
+func errorOrReason(requestError *openapi3filter.RequestError) error {
+	if requestError.Err != nil {
+		return requestError.Err
+	}
+	return errors.New(requestError.Reason)
+}
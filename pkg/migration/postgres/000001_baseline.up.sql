@@ -0,0 +1,56 @@
+-- Baseline schema, equivalent to what gorm AutoMigrate created before versioned migrations.
+-- Statements are idempotent so the baseline can be recorded against an existing database.
+
+CREATE TABLE IF NOT EXISTS users (
+    id                bigserial PRIMARY KEY,
+    created_at        timestamptz,
+    updated_at        timestamptz,
+    deleted_at        timestamptz,
+    username          text NOT NULL UNIQUE,
+    password          text NOT NULL,
+    display_name      text NOT NULL,
+    profile_image_url text,
+    phone_number      text,
+    email_address     text,
+    date_of_birth     timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS groups (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    name            text NOT NULL,
+    group_image_url text,
+    total_expense   decimal DEFAULT 0,
+    average_expense decimal DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_groups_deleted_at ON groups (deleted_at);
+
+CREATE TABLE IF NOT EXISTS members (
+    role          text DEFAULT 'member',
+    total_expense decimal DEFAULT 0,
+    user_id       bigint,
+    group_id      bigint,
+    PRIMARY KEY (user_id, group_id),
+    CONSTRAINT fk_users_members FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL,
+    CONSTRAINT fk_groups_members FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS expenses (
+    id            bigserial PRIMARY KEY,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    title         text NOT NULL,
+    description   text,
+    amount        decimal NOT NULL,
+    purchase_time timestamptz NOT NULL,
+    status        text NOT NULL DEFAULT 'pending',
+    group_id      bigint,
+    member_id     bigint,
+    CONSTRAINT fk_groups_expenses FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    CONSTRAINT fk_members_expenses FOREIGN KEY (member_id, group_id) REFERENCES members (user_id, group_id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_expenses_deleted_at ON expenses (deleted_at);
@@ -0,0 +1,2 @@
+-- Accounts disabled by an operator can no longer log in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at timestamptz;
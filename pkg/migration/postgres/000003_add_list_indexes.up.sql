@@ -0,0 +1,7 @@
+-- Keyset pagination of expense lists in each sort order, and the member filter
+CREATE INDEX IF NOT EXISTS idx_expenses_group_purchase_time ON expenses (group_id, purchase_time, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_amount ON expenses (group_id, amount, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_created_at ON expenses (group_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_expenses_group_member ON expenses (group_id, member_id);
+-- The primary key of members starts with the user, lists of members of a group need their own index
+CREATE INDEX IF NOT EXISTS idx_members_group ON members (group_id, user_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_expenses_search;
+DROP TEXT SEARCH CONFIGURATION IF EXISTS expense_search;
@@ -0,0 +1,19 @@
+-- Full-text search over expenses. Vietnamese has no stemmer, so words are only lowercased and
+-- stripped of accents: "Đà Lạt" matches "da lat". SQLite searches with LIKE and needs no schema.
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'expense_search') THEN
+        CREATE TEXT SEARCH CONFIGURATION expense_search (COPY = simple);
+        ALTER TEXT SEARCH CONFIGURATION expense_search
+            ALTER MAPPING FOR hword, hword_part, word WITH unaccent, simple;
+    END IF;
+END
+$$;
+
+-- Same expression as the search query of the expense repository
+CREATE INDEX IF NOT EXISTS idx_expenses_search ON expenses USING gin (
+    (setweight(to_tsvector('expense_search', title), 'A') ||
+     setweight(to_tsvector('expense_search', coalesce(description, '')), 'B'))
+);
@@ -0,0 +1,37 @@
+-- Append-only activity log of groups, entries are written in the transaction of the change
+CREATE TABLE IF NOT EXISTS activities (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz,
+    group_id    bigint NOT NULL,
+    actor_id    bigint,
+    action      text   NOT NULL,
+    target_type text   NOT NULL,
+    target_id   bigint NOT NULL,
+    before      text,
+    after       text,
+    CONSTRAINT fk_groups_activities FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_activities FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_activities_group ON activities (group_id, id);
+
+-- Only clearing the actor when the user is deleted is allowed
+CREATE OR REPLACE FUNCTION reject_activity_update() RETURNS trigger AS $$
+BEGIN
+    IF NEW.actor_id IS NULL
+        AND NEW.id = OLD.id
+        AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at
+        AND NEW.group_id = OLD.group_id
+        AND NEW.action = OLD.action
+        AND NEW.target_type = OLD.target_type
+        AND NEW.target_id = OLD.target_id
+        AND NEW.before IS NOT DISTINCT FROM OLD.before
+        AND NEW.after IS NOT DISTINCT FROM OLD.after THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'activities are append-only';
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS activities_append_only ON activities;
+CREATE TRIGGER activities_append_only BEFORE UPDATE ON activities
+    FOR EACH ROW EXECUTE FUNCTION reject_activity_update();
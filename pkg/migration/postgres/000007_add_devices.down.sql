@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_unpushed;
+ALTER TABLE notifications DROP COLUMN IF EXISTS pushed_at;
+DROP TABLE IF EXISTS devices;
@@ -0,0 +1,34 @@
+-- Endpoints the activity of a group is posted to
+CREATE TABLE IF NOT EXISTS webhooks (
+    id                   bigserial PRIMARY KEY,
+    created_at           timestamptz,
+    updated_at           timestamptz,
+    group_id             bigint  NOT NULL,
+    url                  text    NOT NULL,
+    secret               text    NOT NULL,
+    events               text    NOT NULL,
+    active               boolean NOT NULL,
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    disabled_at          timestamptz,
+    CONSTRAINT fk_groups_webhooks FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_group ON webhooks (group_id);
+
+-- Queue and log of the payloads posted to webhooks, queued in the transaction of the change
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    webhook_id      bigint  NOT NULL,
+    event           text    NOT NULL,
+    payload         text    NOT NULL,
+    status          text    NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_attempt_at timestamptz,
+    response_status integer,
+    response_body   text,
+    error           text,
+    CONSTRAINT fk_webhooks_webhook_deliveries FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,13 @@
+-- Payments between members of groups, recorded by the payer and only appended
+CREATE TABLE IF NOT EXISTS payments (
+    id         bigserial PRIMARY KEY,
+    created_at timestamptz,
+    group_id   bigint  NOT NULL,
+    payer_id   bigint  NOT NULL,
+    payee_id   bigint  NOT NULL,
+    amount     decimal NOT NULL,
+    CONSTRAINT fk_groups_payments FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    CONSTRAINT fk_payers_payments FOREIGN KEY (payer_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_payees_payments FOREIGN KEY (payee_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_payments_group ON payments (group_id, id);
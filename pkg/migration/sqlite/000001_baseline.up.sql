@@ -0,0 +1,55 @@
+-- Baseline schema, the SQLite equivalent of postgres/000001_baseline.up.sql
+
+CREATE TABLE IF NOT EXISTS users (
+    id                integer PRIMARY KEY AUTOINCREMENT,
+    created_at        datetime,
+    updated_at        datetime,
+    deleted_at        datetime,
+    username          text NOT NULL UNIQUE,
+    password          text NOT NULL,
+    display_name      text NOT NULL,
+    profile_image_url text,
+    phone_number      text,
+    email_address     text,
+    date_of_birth     datetime
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS "groups" (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    created_at      datetime,
+    updated_at      datetime,
+    deleted_at      datetime,
+    name            text NOT NULL,
+    group_image_url text,
+    total_expense   real DEFAULT 0,
+    average_expense real DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_groups_deleted_at ON "groups" (deleted_at);
+
+CREATE TABLE IF NOT EXISTS members (
+    role          text DEFAULT 'member',
+    total_expense real DEFAULT 0,
+    user_id       integer,
+    group_id      integer,
+    PRIMARY KEY (user_id, group_id),
+    CONSTRAINT fk_users_members FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL,
+    CONSTRAINT fk_groups_members FOREIGN KEY (group_id) REFERENCES "groups" (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS expenses (
+    id            integer PRIMARY KEY AUTOINCREMENT,
+    created_at    datetime,
+    updated_at    datetime,
+    deleted_at    datetime,
+    title         text NOT NULL,
+    description   text,
+    amount        real NOT NULL,
+    purchase_time datetime NOT NULL,
+    status        text NOT NULL DEFAULT 'pending',
+    group_id      integer,
+    member_id     integer,
+    CONSTRAINT fk_groups_expenses FOREIGN KEY (group_id) REFERENCES "groups" (id) ON DELETE CASCADE,
+    CONSTRAINT fk_members_expenses FOREIGN KEY (member_id, group_id) REFERENCES members (user_id, group_id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_expenses_deleted_at ON expenses (deleted_at);
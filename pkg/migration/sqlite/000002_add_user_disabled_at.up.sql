@@ -0,0 +1,2 @@
+-- Accounts disabled by an operator can no longer log in
+ALTER TABLE users ADD COLUMN disabled_at datetime;
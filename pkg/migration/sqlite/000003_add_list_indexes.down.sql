@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_members_group;
+DROP INDEX IF EXISTS idx_expenses_group_member;
+DROP INDEX IF EXISTS idx_expenses_group_created_at;
+DROP INDEX IF EXISTS idx_expenses_group_amount;
+DROP INDEX IF EXISTS idx_expenses_group_purchase_time;
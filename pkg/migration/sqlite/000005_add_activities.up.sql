@@ -0,0 +1,30 @@
+-- Append-only activity log of groups, entries are written in the transaction of the change
+CREATE TABLE IF NOT EXISTS activities (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    created_at  datetime,
+    group_id    integer NOT NULL,
+    actor_id    integer,
+    action      text    NOT NULL,
+    target_type text    NOT NULL,
+    target_id   integer NOT NULL,
+    before      text,
+    after       text,
+    CONSTRAINT fk_groups_activities FOREIGN KEY (group_id) REFERENCES "groups" (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_activities FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_activities_group ON activities (group_id, id);
+
+-- Only clearing the actor when the user is deleted is allowed
+CREATE TRIGGER IF NOT EXISTS activities_append_only BEFORE UPDATE ON activities
+WHEN NEW.actor_id IS NOT NULL
+    OR NEW.id IS NOT OLD.id
+    OR NEW.created_at IS NOT OLD.created_at
+    OR NEW.group_id IS NOT OLD.group_id
+    OR NEW.action IS NOT OLD.action
+    OR NEW.target_type IS NOT OLD.target_type
+    OR NEW.target_id IS NOT OLD.target_id
+    OR NEW.before IS NOT OLD.before
+    OR NEW.after IS NOT OLD.after
+BEGIN
+    SELECT RAISE(ABORT, 'activities are append-only');
+END;
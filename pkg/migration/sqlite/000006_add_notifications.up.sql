@@ -0,0 +1,26 @@
+-- Notifications of users, created from the activity of groups in the transaction of the change
+CREATE TABLE IF NOT EXISTS notifications (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    user_id    integer NOT NULL,
+    group_id   integer NOT NULL,
+    type       text    NOT NULL,
+    actor_id   integer,
+    target_id  integer NOT NULL,
+    data       text,
+    read_at    datetime,
+    CONSTRAINT fk_users_notifications FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_groups_notifications FOREIGN KEY (group_id) REFERENCES "groups" (id) ON DELETE CASCADE,
+    CONSTRAINT fk_actors_notifications FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Types of notifications users turned on or off, types without a row are on
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id integer NOT NULL,
+    type    text    NOT NULL,
+    enabled boolean NOT NULL,
+    PRIMARY KEY (user_id, type),
+    CONSTRAINT fk_users_notification_preferences FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
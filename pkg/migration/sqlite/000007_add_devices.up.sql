@@ -0,0 +1,18 @@
+-- Devices of users receiving push notifications, a token belongs to one user
+CREATE TABLE IF NOT EXISTS devices (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    created_at  datetime,
+    updated_at  datetime,
+    user_id     integer NOT NULL,
+    platform    text    NOT NULL,
+    token       text    NOT NULL,
+    app_version text,
+    CONSTRAINT uni_devices_token UNIQUE (token),
+    CONSTRAINT fk_users_devices FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_devices_user ON devices (user_id);
+
+-- Notifications are pushed once, those created before push notifications are not
+ALTER TABLE notifications ADD COLUMN pushed_at datetime;
+UPDATE notifications SET pushed_at = created_at;
+CREATE INDEX IF NOT EXISTS idx_notifications_unpushed ON notifications (id) WHERE pushed_at IS NULL;
@@ -0,0 +1,11 @@
+-- Pushes to devices which failed temporarily, retried from retry_at by any instance
+CREATE TABLE IF NOT EXISTS push_retries (
+    id              integer  PRIMARY KEY AUTOINCREMENT,
+    notification_id integer  NOT NULL,
+    device_id       integer  NOT NULL,
+    attempts        integer  NOT NULL,
+    retry_at        datetime NOT NULL,
+    CONSTRAINT fk_notifications_push_retries FOREIGN KEY (notification_id) REFERENCES notifications (id) ON DELETE CASCADE,
+    CONSTRAINT fk_devices_push_retries FOREIGN KEY (device_id) REFERENCES devices (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_push_retries_due ON push_retries (retry_at);
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"encoding/json"
+	"money_share/pkg/util"
+	"time"
+)
+
+// Actions recorded in the activity log of a group
+const (
+	ActionExpenseCreated    = "expense.created"
+	ActionExpenseUpdated    = "expense.updated"
+	ActionExpenseApproved   = "expense.approved"
+	ActionExpenseDenied     = "expense.denied"
+	ActionExpenseDeleted    = "expense.deleted"
+	ActionMemberAdded       = "member.added"
+	ActionMemberRemoved     = "member.removed"
+	ActionMemberRoleChanged = "member.role_changed"
+	ActionGroupRenamed      = "group.renamed"
+	ActionGroupUpdated      = "group.updated"
+	ActionPaymentRecorded   = "payment.recorded"
+)
+
+// Types of the targets of activities, the target of a member activity is the user
+const (
+	TargetExpense = "expense"
+	TargetMember  = "member"
+	TargetGroup   = "group"
+	TargetPayment = "payment"
+)
+
+// Activity is an entry of the append-only activity log of a group. Before and After are JSON
+// snapshots of the target, Before is empty for creations and After for deletions.
+type Activity struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	GroupID   uint `gorm:"not null"`
+	// ActorID is the user who made the change, nil for changes made outside the API
+	ActorID    *uint
+	Action     string `gorm:"not null"`
+	TargetType string `gorm:"not null"`
+	TargetID   uint   `gorm:"not null"`
+	Before     string
+	After      string
+}
+
+// Snapshots of the targets, the fields of the changes worth recording
+
+type ExpenseSnapshot struct {
+	Title        string  `json:"title"`
+	Description  string  `json:"description,omitempty"`
+	Amount       float32 `json:"amount"`
+	PurchaseTime string  `json:"purchaseTime"`
+	Status       string  `json:"status"`
+	MemberID     uint    `json:"memberID"`
+}
+
+type MemberSnapshot struct {
+	UserID uint   `json:"userID"`
+	Role   string `json:"role"`
+}
+
+type GroupSnapshot struct {
+	Name          string `json:"name"`
+	GroupImageUrl string `json:"groupImageUrl,omitempty"`
+}
+
+type PaymentSnapshot struct {
+	PayerID uint    `json:"payerID"`
+	PayeeID uint    `json:"payeeID"`
+	Amount  float32 `json:"amount"`
+}
+
+// snapshot encodes the snapshot of a target, nil gives an empty snapshot
+func snapshot[T any](target *T, toSnapshot func(*T) interface{}) string {
+	if target == nil {
+		return ""
+	}
+	encoded, _ := json.Marshal(toSnapshot(target))
+	return string(encoded)
+}
+
+// NewExpenseActivity records a change of an expense from before to after, either may be nil
+func NewExpenseActivity(action string, before *Expense, after *Expense) Activity {
+	target := after
+	if target == nil {
+		target = before
+	}
+	toSnapshot := func(e *Expense) interface{} {
+		return ExpenseSnapshot{
+			Title:        e.Title,
+			Description:  e.Description,
+			Amount:       e.Amount,
+			PurchaseTime: e.PurchaseTime.UTC().Format(util.DateTimeLayout),
+			Status:       e.Status,
+			MemberID:     e.MemberID,
+		}
+	}
+	return Activity{
+		GroupID:    target.GroupID,
+		Action:     action,
+		TargetType: TargetExpense,
+		TargetID:   target.ID,
+		Before:     snapshot(before, toSnapshot),
+		After:      snapshot(after, toSnapshot),
+	}
+}
+
+// ExpenseUpdateAction is the action of an update of an expense, approving and denying are
+// recorded as such
+func ExpenseUpdateAction(before *Expense, after *Expense) string {
+	if before.Status != after.Status {
+		switch after.Status {
+		case StatusApproved:
+			return ActionExpenseApproved
+		case StatusDenied:
+			return ActionExpenseDenied
+		}
+	}
+	return ActionExpenseUpdated
+}
+
+// NewMemberActivity records a change of a member from before to after, either may be nil
+func NewMemberActivity(action string, before *Member, after *Member) Activity {
+	target := after
+	if target == nil {
+		target = before
+	}
+	toSnapshot := func(m *Member) interface{} {
+		return MemberSnapshot{UserID: m.UserID, Role: m.Role}
+	}
+	return Activity{
+		GroupID:    target.GroupID,
+		Action:     action,
+		TargetType: TargetMember,
+		TargetID:   target.UserID,
+		Before:     snapshot(before, toSnapshot),
+		After:      snapshot(after, toSnapshot),
+	}
+}
+
+// NewGroupActivity records an update of a group, renaming is recorded as such
+func NewGroupActivity(before *Group, after *Group) Activity {
+	action := ActionGroupUpdated
+	if before.Name != after.Name {
+		action = ActionGroupRenamed
+	}
+	toSnapshot := func(g *Group) interface{} {
+		return GroupSnapshot{Name: g.Name, GroupImageUrl: g.GroupImageUrl}
+	}
+	return Activity{
+		GroupID:    after.ID,
+		Action:     action,
+		TargetType: TargetGroup,
+		TargetID:   after.ID,
+		Before:     snapshot(before, toSnapshot),
+		After:      snapshot(after, toSnapshot),
+	}
+}
+
+// NewPaymentActivity records a payment between members
+func NewPaymentActivity(payment *Payment) Activity {
+	toSnapshot := func(p *Payment) interface{} {
+		return PaymentSnapshot{PayerID: p.PayerID, PayeeID: p.PayeeID, Amount: p.Amount}
+	}
+	return Activity{
+		GroupID:    payment.GroupID,
+		Action:     ActionPaymentRecorded,
+		TargetType: TargetPayment,
+		TargetID:   payment.ID,
+		After:      snapshot(payment, toSnapshot),
+	}
+}
+
+// Changed reports whether the snapshots differ, updates which change nothing are not recorded
+func (a Activity) Changed() bool {
+	return a.Before != a.After
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"money_share/pkg/apperror"
+	"time"
+)
+
+// Platforms of devices, each is pushed to through its provider
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+// Device is an installation of the mobile app which receives push notifications for a user. A
+// token belongs to one device, registering it again moves it to the user who registered it.
+type Device struct {
+	ID         uint `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uint   `gorm:"not null"`
+	Platform   string `gorm:"not null"`
+	Token      string `gorm:"not null;unique"`
+	AppVersion string
+}
+
+// ValidateFields reports every invalid field of a device
+func (d *Device) ValidateFields() error {
+	var errs []error
+	errs = append(errs, ValidatePlatform(d.Platform))
+	if len(d.Token) == 0 {
+		errs = append(errs, apperror.InvalidField("token", "token cannot be empty"))
+	}
+	return apperror.Join(errs...)
+}
+
+func ValidatePlatform(platform string) (err error) {
+	if platform != PlatformAndroid && platform != PlatformIOS {
+		err = apperror.InvalidField("platform", "platform must be 'android' or 'ios'")
+	}
+	return
+}
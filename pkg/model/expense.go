@@ -2,6 +2,7 @@ package model
 
 import (
 	"gorm.io/gorm"
+	"money_share/pkg/apperror"
 	"time"
 )
 
@@ -16,47 +17,103 @@ type Expense struct {
 	MemberID     uint      ``                                // Many-to-one relationship with Member entity
 }
 
-// Hooks to update member and group expenses
+// Statuses of an expense
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+)
 
-func (e *Expense) AfterCreate(tx *gorm.DB) (err error) {
-	return e.updateMemberAndGroupExpenses(tx)
+// ValidateFields reports every invalid field of a new expense
+func (e *Expense) ValidateFields() error {
+	var errs []error
+	if len(e.Title) == 0 {
+		errs = append(errs, apperror.InvalidField("title", "title cannot be empty"))
+	}
+	errs = append(errs, ValidateAmount(e.Amount))
+	if e.PurchaseTime.IsZero() {
+		errs = append(errs, apperror.InvalidField("purchaseTime", "purchase time is not set"))
+	}
+	if e.MemberID <= 0 {
+		errs = append(errs, apperror.InvalidField("memberID", "memberId must be greater than 0"))
+	}
+	return apperror.Join(errs...)
 }
 
-func (e *Expense) AfterUpdate(tx *gorm.DB) (err error) {
-	if tx.Statement.Changed("Status") && e.Status == "approved" {
-		return e.updateMemberAndGroupExpenses(tx)
-	}
-	if tx.Statement.Changed("Amount") {
-		return e.updateMemberAndGroupExpenses(tx)
+func ValidateAmount(amount float32) (err error) {
+	if amount < 0 {
+		err = apperror.InvalidField("amount", "amount must be equal or greater than 0")
 	}
 	return
 }
 
-func (e *Expense) AfterDelete(tx *gorm.DB) (err error) {
-	if e.Status != "approved" {
-		return e.updateMemberAndGroupExpenses(tx)
+func ValidateStatus(status string) (err error) {
+	if status != StatusPending && status != StatusApproved && status != StatusDenied {
+		err = apperror.InvalidField("status", "invalid status, must be 'pending', 'approved' or 'denied'")
 	}
 	return
 }
 
-func (e *Expense) updateMemberAndGroupExpenses(tx *gorm.DB) (err error) {
-	// Update member total expense
-	err = tx.Model(&Member{}).Where("user_id = ? AND group_id = ?",
-		e.MemberID, e.GroupID).Update("total_expense",
-		tx.Model(&Expense{}).Select("SUM(amount)").Where("member_id = ? AND group_id = ? AND status='approved'",
-			e.MemberID, e.GroupID)).Error
+// Hooks to update member and group expenses
+
+func (e *Expense) AfterCreate(tx *gorm.DB) (err error) {
+	return UpdateGroupTotals(tx, e.GroupID)
+}
+
+func (e *Expense) AfterUpdate(tx *gorm.DB) (err error) {
+	return UpdateGroupTotals(tx, e.GroupID)
+}
+
+func (e *Expense) AfterDelete(tx *gorm.DB) (err error) {
+	return UpdateGroupTotals(tx, e.GroupID)
+}
+
+// UpdateGroupTotals recomputes the total of every member of the group and the group total
+// and average from approved expenses. The average is the total split between all members.
+// Totals are summed here rather than in correlated subqueries so every driver runs the same SQL.
+func UpdateGroupTotals(tx *gorm.DB, groupID uint) (err error) {
+	if groupID == 0 {
+		return
+	}
+	// Hooks receive the statement of the caller, start from a clean one
+	tx = tx.Session(&gorm.Session{NewDB: true})
+
+	var memberIDs []uint
+	if err = tx.Model(&Member{}).Where("group_id = ?", groupID).Pluck("user_id", &memberIDs).Error; err != nil {
+		return
+	}
+	var rows []struct {
+		MemberID uint
+		Total    float32
+	}
+	err = tx.Model(&Expense{}).Select("member_id, SUM(amount) AS total").
+		Where("group_id = ? AND status = ?", groupID, "approved").Group("member_id").Scan(&rows).Error
 	if err != nil {
 		return
 	}
+	memberTotals := make(map[uint]float32, len(memberIDs))
+	var total float32
+	for _, row := range rows {
+		memberTotals[row.MemberID] = row.Total
+		total += row.Total
+	}
+
+	// Update member total expenses
+	for _, memberID := range memberIDs {
+		err = tx.Model(&Member{}).Where("group_id = ? AND user_id = ?", groupID, memberID).
+			Update("total_expense", memberTotals[memberID]).Error
+		if err != nil {
+			return
+		}
+	}
 
 	// Update group total expense and average expense
-	groupSpecificClause := "group_id = ? AND status='approved'"
-	err = tx.Model(&Group{}).Where("id = ?", e.GroupID).Updates(map[string]interface{}{
-		"total_expense": tx.Model(&Expense{}).Select("SUM(amount)").Where(groupSpecificClause, e.GroupID),
-		"average_expense": tx.Model(&Expense{}).Select("SUM(amount)/(?)",
-			tx.Model(&Member{}).Select("COUNT(1)").Where("user_id = ? AND group_id = ?",
-				e.MemberID, e.GroupID)).Where(groupSpecificClause, e.GroupID),
+	var average float32
+	if len(memberIDs) > 0 {
+		average = total / float32(len(memberIDs))
+	}
+	return tx.Model(&Group{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+		"total_expense":   total,
+		"average_expense": average,
 	}).Error
-
-	return
 }
@@ -11,3 +11,33 @@ type Group struct {
 	Members         []Member  `gorm:"constraint:OnDelete:CASCADE"` // One-to-many relationship with Member entity
 	Expenses        []Expense `gorm:"constraint:OnDelete:CASCADE"` // One-to-many relationship with Expense entity
 }
+
+// GroupTotals are the expense totals of a group and its members
+type GroupTotals struct {
+	Total   float32
+	Average float32
+	// Members holds the total of each member by user ID
+	Members map[uint]float32
+}
+
+// ComputeTotals sums the approved expenses of the group, members and expenses must be loaded.
+// The average is the total split evenly between all members.
+func (g *Group) ComputeTotals() GroupTotals {
+	totals := GroupTotals{Members: make(map[uint]float32, len(g.Members))}
+	for _, member := range g.Members {
+		totals.Members[member.UserID] = 0
+	}
+	for _, expense := range g.Expenses {
+		if expense.Status != "approved" {
+			continue
+		}
+		totals.Total += expense.Amount
+		if _, ok := totals.Members[expense.MemberID]; ok {
+			totals.Members[expense.MemberID] += expense.Amount
+		}
+	}
+	if len(g.Members) > 0 {
+		totals.Average = totals.Total / float32(len(g.Members))
+	}
+	return totals
+}
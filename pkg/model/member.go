@@ -1,5 +1,13 @@
 package model
 
+import "money_share/pkg/apperror"
+
+// Roles of a member in a group
+const (
+	RoleMember  = "member"
+	RoleManager = "manager"
+)
+
 type Member struct {
 	User         User    ``                      // Owned relationship with User entity
 	Role         string  `gorm:"default:member"` // member/manager
@@ -10,4 +18,11 @@ type Member struct {
 	Expenses []Expense `gorm:"foreignKey:MemberID,GroupID;references:UserID,GroupID;constraint:OnDelete:SET NULL;"`
 }
 
-// TODO: update average expense of group when new member is created
\ No newline at end of file
+// TODO: update average expense of group when new member is created
+
+func ValidateRole(role string) (err error) {
+	if role != RoleMember && role != RoleManager {
+		err = apperror.InvalidField("role", "role must be 'member' or 'manager'")
+	}
+	return
+}
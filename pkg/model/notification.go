@@ -0,0 +1,161 @@
+package model
+
+import (
+	"encoding/json"
+	"money_share/pkg/apperror"
+	"time"
+)
+
+// Types of notifications, users turn each off in their preferences
+const (
+	NotificationMemberAdded     = "member.added"
+	NotificationExpensePending  = "expense.pending"
+	NotificationExpenseApproved = "expense.approved"
+	NotificationExpenseDenied   = "expense.denied"
+	NotificationPaymentReceived = "payment.received"
+)
+
+// NotificationTypes lists every notification type in the order of the preferences
+var NotificationTypes = []string{
+	NotificationMemberAdded,
+	NotificationExpensePending,
+	NotificationExpenseApproved,
+	NotificationExpenseDenied,
+	NotificationPaymentReceived,
+}
+
+// Notification tells a user about a change of one of their groups. Notifications are created
+// from the activity of the change in its transaction, Data is the snapshot of the target after
+// the change.
+type Notification struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"not null"`
+	GroupID   uint   `gorm:"not null"`
+	Type      string `gorm:"not null"`
+	// ActorID is the user who made the change, nil for changes made outside the API
+	ActorID  *uint
+	TargetID uint `gorm:"not null"`
+	Data     string
+	// ReadAt is nil until the user reads the notification
+	ReadAt *time.Time
+	// PushedAt is nil until the notification is pushed to the devices of the user
+	PushedAt *time.Time
+}
+
+// PushRetry is a push of a notification to a device which failed temporarily, it is attempted
+// again from RetryAt
+type PushRetry struct {
+	ID             uint          `gorm:"primaryKey"`
+	NotificationID uint          `gorm:"not null"`
+	Notification   *Notification `gorm:"foreignKey:NotificationID"`
+	DeviceID       uint          `gorm:"not null"`
+	Device         *Device       `gorm:"foreignKey:DeviceID"`
+	// Attempts is the number of attempts made so far
+	Attempts int       `gorm:"not null"`
+	RetryAt  time.Time `gorm:"not null"`
+}
+
+// NotificationPreference turns a type of notifications on or off for a user, types without a
+// preference are on
+type NotificationPreference struct {
+	UserID  uint   `gorm:"primaryKey"`
+	Type    string `gorm:"primaryKey"`
+	Enabled bool   `gorm:"not null"`
+}
+
+func ValidateNotificationType(notificationType string) (err error) {
+	for _, t := range NotificationTypes {
+		if t == notificationType {
+			return nil
+		}
+	}
+	return apperror.InvalidField("type", "type must be a notification type")
+}
+
+// NewNotifications returns the notifications of the users an activity concerns, regardless of
+// their preferences. managers returns the user IDs of the managers of the group, it is only
+// called for expenses waiting for approval. Nobody is notified of their own changes.
+func NewNotifications(activity Activity, managers func() ([]uint, error)) ([]Notification, error) {
+	var notificationType string
+	var recipients []uint
+	switch activity.Action {
+	case ActionMemberAdded:
+		notificationType = NotificationMemberAdded
+		recipients = []uint{activity.TargetID}
+	case ActionExpenseCreated, ActionExpenseApproved, ActionExpenseDenied:
+		var expense ExpenseSnapshot
+		if err := json.Unmarshal([]byte(activity.After), &expense); err != nil {
+			return nil, err
+		}
+		switch {
+		case activity.Action == ActionExpenseApproved:
+			notificationType = NotificationExpenseApproved
+			recipients = []uint{expense.MemberID}
+		case activity.Action == ActionExpenseDenied:
+			notificationType = NotificationExpenseDenied
+			recipients = []uint{expense.MemberID}
+		case expense.Status == StatusPending:
+			notificationType = NotificationExpensePending
+			ids, err := managers()
+			if err != nil {
+				return nil, err
+			}
+			recipients = ids
+		}
+	case ActionPaymentRecorded:
+		var payment PaymentSnapshot
+		if err := json.Unmarshal([]byte(activity.After), &payment); err != nil {
+			return nil, err
+		}
+		notificationType = NotificationPaymentReceived
+		recipients = []uint{payment.PayeeID}
+	}
+
+	var notifications []Notification
+	for _, userID := range recipients {
+		if activity.ActorID != nil && *activity.ActorID == userID {
+			continue
+		}
+		notifications = append(notifications, Notification{
+			UserID:   userID,
+			GroupID:  activity.GroupID,
+			Type:     notificationType,
+			ActorID:  activity.ActorID,
+			TargetID: activity.TargetID,
+			Data:     activity.After,
+		})
+	}
+	return notifications, nil
+}
+
+// MergeNotificationPreferences returns whether each notification type is on given the stored
+// preferences of a user
+func MergeNotificationPreferences(stored []NotificationPreference) map[string]bool {
+	preferences := make(map[string]bool, len(NotificationTypes))
+	for _, notificationType := range NotificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		// Preferences of types which were removed are ignored
+		if _, ok := preferences[preference.Type]; ok {
+			preferences[preference.Type] = preference.Enabled
+		}
+	}
+	return preferences
+}
+
+// WithoutUsers drops the notifications of the given users
+func WithoutUsers(notifications []Notification, userIDs []uint) []Notification {
+	kept := notifications[:0]
+	for _, notification := range notifications {
+		skip := false
+		for _, userID := range userIDs {
+			skip = skip || notification.UserID == userID
+		}
+		if !skip {
+			kept = append(kept, notification)
+		}
+	}
+	return kept
+}
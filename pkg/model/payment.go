@@ -0,0 +1,32 @@
+package model
+
+import (
+	"money_share/pkg/apperror"
+	"time"
+)
+
+// Payment is money a member of a group paid another member to settle what they owe. Payments
+// are recorded by the payer and only appended.
+type Payment struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	GroupID   uint `gorm:"not null"`
+	// PayerID is the user who paid, PayeeID the user who received the money
+	PayerID uint    `gorm:"not null"`
+	PayeeID uint    `gorm:"not null"`
+	Amount  float32 `gorm:"not null"`
+}
+
+// ValidateFields reports every invalid field of a payment
+func (p *Payment) ValidateFields() error {
+	var errs []error
+	if p.Amount <= 0 {
+		errs = append(errs, apperror.InvalidField("amount", "amount must be greater than 0"))
+	}
+	if p.PayeeID <= 0 {
+		errs = append(errs, apperror.InvalidField("payeeID", "payeeId must be greater than 0"))
+	} else if p.PayeeID == p.PayerID {
+		errs = append(errs, apperror.InvalidField("payeeID", "payee must be another member"))
+	}
+	return apperror.Join(errs...)
+}
@@ -1,9 +1,9 @@
 package model
 
 import (
-	"errors"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"money_share/pkg/apperror"
 	"regexp"
 	"strings"
 	"time"
@@ -11,14 +11,15 @@ import (
 
 type User struct {
 	gorm.Model
-	Username        string    `gorm:"unique;not null"`
-	Password        string    `gorm:"not null"`
-	DisplayName     string    `gorm:"not null"`
-	ProfileImageUrl string    ``
-	PhoneNumber     string    ``
-	EmailAddress    string    ``
-	DateOfBirth     time.Time ``
-	Members         []Member  `gorm:"constraint:OnDelete:SET NULL;"` // One to many with Member entity
+	Username        string     `gorm:"unique;not null"`
+	Password        string     `gorm:"not null"`
+	DisplayName     string     `gorm:"not null"`
+	ProfileImageUrl string     ``
+	PhoneNumber     string     ``
+	EmailAddress    string     ``
+	DateOfBirth     time.Time  ``
+	DisabledAt      *time.Time ``                                     // Set when an operator disabled the account
+	Members         []Member   `gorm:"constraint:OnDelete:SET NULL;"` // One to many with Member entity
 }
 
 func (u *User) ValidateUsername() (err error) {
@@ -28,17 +29,17 @@ func (u *User) ValidateUsername() (err error) {
 func ValidateUsername(username string) (err error) {
 	// Validate length
 	if len(username) < 8 {
-		err = errors.New("username must be at least 8 characters")
+		err = apperror.InvalidField("username", "username must be at least 8 characters")
 		return
 	}
 	if len(username) > 20 {
-		err = errors.New("username must be at most 20 characters")
+		err = apperror.InvalidField("username", "username must be at most 20 characters")
 		return
 	}
 	// Validate characters used
 	match, _ := regexp.MatchString("^[a-zA-Z0-9._]+$", username)
 	if !match {
-		err = errors.New("username must only contains alphabet characters, number and/or dot(.) and/or underscore(_)")
+		err = apperror.InvalidField("username", "username must only contains alphabet characters, number and/or dot(.) and/or underscore(_)")
 		return
 	}
 
@@ -52,11 +53,11 @@ func (u *User) ValidatePassword() (err error) {
 func ValidatePassword(password string) (err error) {
 	// Validate length
 	if len(password) < 8 {
-		err = errors.New("password must be at least 8 characters")
+		err = apperror.InvalidField("password", "password must be at least 8 characters")
 		return
 	}
 	if len(password) > 20 {
-		err = errors.New("password must be at most 20 characters")
+		err = apperror.InvalidField("password", "password must be at most 20 characters")
 		return
 	}
 
@@ -74,11 +75,11 @@ func (u *User) ValidateDisplayName() (err error) {
 func ValidateDisplayName(displayName string) (err error) {
 	// Validate length
 	if len(displayName) < 4 {
-		err = errors.New("display name must be at least 4 characters")
+		err = apperror.InvalidField("displayName", "display name must be at least 4 characters")
 		return
 	}
 	if len(displayName) > 32 {
-		err = errors.New("password must be at most 32 characters")
+		err = apperror.InvalidField("displayName", "display name must be at most 32 characters")
 		return
 	}
 
@@ -101,46 +102,36 @@ func HashPassword(password string) (hashedPassword string, err error) {
 	return
 }
 
+// IsDisabled reports whether the account was disabled and must neither log in nor use the API
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
 func (u *User) ComparePassword(providedPwd string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(providedPwd))
 	return err == nil
 }
 
+// ValidateFields reports every invalid field
 func (u *User) ValidateFields() (err error) {
-	if err = u.ValidateUsername(); err != nil {
-		return
-	}
-	if err = u.ValidatePassword(); err != nil {
-		return
-	}
-	if err = u.ValidateDisplayName(); err != nil {
-		return
-	}
-	return
+	return apperror.Join(u.ValidateUsername(), u.ValidatePassword(), u.ValidateDisplayName())
 }
 
+// ValidateNonNullFields reports every invalid field which is set
 func (u *User) ValidateNonNullFields() (err error) {
+	var errs []error
 	// Validate username
 	if u.Username != "" {
-		err = u.ValidateUsername()
-		if err != nil {
-			return
-		}
+		errs = append(errs, u.ValidateUsername())
 	}
 	// Validate password
 	if u.Password != "" {
-		err = u.ValidatePassword()
-		if err != nil {
-			return
-		}
+		errs = append(errs, u.ValidatePassword())
 	}
 	// Validate display name
 	if u.DisplayName != "" {
-		err = u.ValidateDisplayName()
-		if err != nil {
-			return
-		}
+		errs = append(errs, u.ValidateDisplayName())
 	}
 
-	return
+	return apperror.Join(errs...)
 }
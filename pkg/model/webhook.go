@@ -0,0 +1,174 @@
+package model
+
+import (
+	"encoding/json"
+	"money_share/pkg/apperror"
+	"money_share/pkg/util"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Statuses of webhook deliveries
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookEvents lists the event types webhooks subscribe to, the actions of the activity log
+var WebhookEvents = []string{
+	ActionExpenseCreated,
+	ActionExpenseUpdated,
+	ActionExpenseApproved,
+	ActionExpenseDenied,
+	ActionExpenseDeleted,
+	ActionMemberAdded,
+	ActionMemberRemoved,
+	ActionMemberRoleChanged,
+	ActionGroupRenamed,
+	ActionGroupUpdated,
+	ActionPaymentRecorded,
+}
+
+// Webhook posts the activity of a group to an endpoint of the managers of the group
+type Webhook struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	GroupID   uint   `gorm:"not null"`
+	URL       string `gorm:"not null"`
+	// Secret signs the payloads, receivers verify the signature with it
+	Secret string `gorm:"not null"`
+	// Events is the comma separated list of the event types delivered
+	Events string `gorm:"not null"`
+	Active bool   `gorm:"not null"`
+	// ConsecutiveFailures counts the deliveries failed since the last one which succeeded, the
+	// webhook is disabled when it reaches the limit
+	ConsecutiveFailures int `gorm:"not null;default:0"`
+	DisabledAt          *time.Time
+}
+
+// WebhookDelivery is a payload queued for a webhook together with the outcome of its last
+// attempt. Pending deliveries are attempted from NextAttemptAt.
+type WebhookDelivery struct {
+	ID            uint `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	WebhookID     uint     `gorm:"not null"`
+	Webhook       *Webhook `gorm:"foreignKey:WebhookID"`
+	Event         string   `gorm:"not null"`
+	Payload       string   `gorm:"not null"`
+	Status        string   `gorm:"not null"`
+	Attempts      int      `gorm:"not null;default:0"`
+	NextAttemptAt time.Time
+	LastAttemptAt *time.Time
+	// ResponseStatus and ResponseBody are those of the last attempt, Error is set when it failed
+	ResponseStatus int
+	ResponseBody   string
+	Error          string
+}
+
+// EventList returns the event types the webhook subscribes to
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// SetEvents sets the event types the webhook subscribes to
+func (w *Webhook) SetEvents(events []string) {
+	w.Events = strings.Join(events, ",")
+}
+
+// Subscribes reports whether the webhook delivers events of the type
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFields reports every invalid field of a webhook
+func (w *Webhook) ValidateFields() error {
+	var errs []error
+	errs = append(errs, ValidateWebhookURL(w.URL))
+	events := w.EventList()
+	if len(events) == 0 {
+		errs = append(errs, apperror.InvalidField("events", "events cannot be empty"))
+	}
+	for _, event := range events {
+		if !isWebhookEvent(event) {
+			errs = append(errs, apperror.InvalidField("events", "events must be event types, "+event+" is not"))
+		}
+	}
+	return apperror.Join(errs...)
+}
+
+func ValidateWebhookURL(rawURL string) (err error) {
+	parsed, parseErr := url.Parse(rawURL)
+	if parseErr != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		err = apperror.InvalidField("url", "url must be an absolute http or https URL")
+	}
+	return
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookPayload is the body posted to webhooks, an entry of the activity log
+type webhookPayload struct {
+	Event      string          `json:"event"`
+	GroupID    uint            `json:"groupID"`
+	ActivityID uint            `json:"activityID"`
+	ActorID    *uint           `json:"actorID,omitempty"`
+	TargetType string          `json:"targetType"`
+	TargetID   uint            `json:"targetID"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  string          `json:"createdAt"`
+}
+
+// NewWebhookDeliveries queues the activity for the webhooks of its group which subscribe to its
+// action, the first attempt is at now
+func NewWebhookDeliveries(activity Activity, webhooks []*Webhook, now time.Time) []WebhookDelivery {
+	var deliveries []WebhookDelivery
+	payload := webhookPayload{
+		Event:      activity.Action,
+		GroupID:    activity.GroupID,
+		ActivityID: activity.ID,
+		ActorID:    activity.ActorID,
+		TargetType: activity.TargetType,
+		TargetID:   activity.TargetID,
+		CreatedAt:  activity.CreatedAt.UTC().Format(util.DateTimeLayout),
+	}
+	// Snapshots are JSON already
+	if activity.Before != "" {
+		payload.Before = json.RawMessage(activity.Before)
+	}
+	if activity.After != "" {
+		payload.After = json.RawMessage(activity.After)
+	}
+	encoded, _ := json.Marshal(payload)
+	for _, webhook := range webhooks {
+		if !webhook.Active || !webhook.Subscribes(activity.Action) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         activity.Action,
+			Payload:       string(encoded),
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	return deliveries
+}
@@ -0,0 +1,75 @@
+// Package openapi holds the OpenAPI document describing every route of the API
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+	"net/http"
+	"regexp"
+	"sync"
+)
+
+// JSON is the OpenAPI document served at /openapi.json
+//
+//go:embed openapi.json
+var JSON []byte
+
+var (
+	loadOnce sync.Once
+	document *openapi3.T
+	loadErr  error
+)
+
+// Load parses and validates the embedded document, it is parsed once and shared
+func Load() (*openapi3.T, error) {
+	loadOnce.Do(func() {
+		document, loadErr = openapi3.NewLoader().LoadFromData(JSON)
+		if loadErr == nil {
+			loadErr = document.Validate(context.Background())
+		}
+	})
+	return document, loadErr
+}
+
+// MustLoad is Load for callers which cannot run without the document
+func MustLoad() *openapi3.T {
+	doc, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// Matches the regular expression of a mux path variable, e.g. ":[0-9]+" in "{userId:[0-9]+}"
+var variablePattern = regexp.MustCompile(`\{([^:}]+):[^}]*\}`)
+
+// PathFromTemplate converts a mux path template to an OpenAPI path
+func PathFromTemplate(template string) string {
+	return variablePattern.ReplaceAllString(template, "{$1}")
+}
+
+// FindRoute returns the operation of the mux route matched for r, or nil when the
+// document does not describe it
+func FindRoute(doc *openapi3.T, r *http.Request) *routers.Route {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return nil
+	}
+	template, err := current.GetPathTemplate()
+	if err != nil {
+		return nil
+	}
+	path := PathFromTemplate(template)
+	pathItem := doc.Paths.Find(path)
+	if pathItem == nil {
+		return nil
+	}
+	operation := pathItem.GetOperation(r.Method)
+	if operation == nil {
+		return nil
+	}
+	return &routers.Route{Spec: doc, Path: path, PathItem: pathItem, Method: r.Method, Operation: operation}
+}
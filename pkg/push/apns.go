@@ -0,0 +1,150 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Base URLs of APNs, apps built for development receive notifications from the sandbox
+const (
+	APNsProduction = "https://api.push.apple.com"
+	APNsSandbox    = "https://api.sandbox.push.apple.com"
+)
+
+// APNs rejects provider tokens older than an hour and refreshed more often than every 20 minutes
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNsConfig holds the token based credentials of an app
+type APNsConfig struct {
+	// Key is the content of the .p8 signing key file
+	Key    []byte
+	KeyID  string
+	TeamID string
+	// Topic is the bundle ID of the app
+	Topic    string
+	Endpoint string
+}
+
+// APNsSender sends messages through the HTTP/2 API of APNs
+type APNsSender struct {
+	Client *http.Client
+	Config APNsConfig
+
+	key      *ecdsa.PrivateKey
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+func NewAPNsSender(config APNsConfig) (*APNsSender, error) {
+	key, err := jwt.ParseECPrivateKeyFromPEM(config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse APNs key: %w", err)
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = APNsProduction
+	}
+	return &APNsSender{
+		// TLS connections negotiate HTTP/2, which APNs requires
+		Client: &http.Client{Timeout: 10 * time.Second},
+		Config: config,
+		key:    key,
+	}, nil
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type apnsAPS struct {
+	Alert apnsAlert `json:"alert"`
+	Sound string    `json:"sound"`
+}
+
+func (s *APNsSender) Send(ctx context.Context, token string, message Message) error {
+	// Data keys sit next to the aps dictionary
+	payload := map[string]interface{}{
+		"aps": apnsAPS{Alert: apnsAlert{Title: message.Title, Body: message.Body}, Sound: "default"},
+	}
+	for key, value := range message.Data {
+		payload[key] = value
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	providerToken, err := s.providerToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Config.Endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", s.Config.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return &TemporaryError{Err: err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	var errorResponse struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&errorResponse)
+	err = fmt.Errorf("APNs responded %d %s", res.StatusCode, errorResponse.Reason)
+	switch {
+	case res.StatusCode == http.StatusGone,
+		errorResponse.Reason == "BadDeviceToken",
+		errorResponse.Reason == "DeviceTokenNotForTopic",
+		errorResponse.Reason == "Unregistered":
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	case errorResponse.Reason == "ExpiredProviderToken":
+		// Sign a new provider token for the retry
+		s.mu.Lock()
+		s.token = ""
+		s.mu.Unlock()
+		return &TemporaryError{Err: err}
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return &TemporaryError{Err: err, RetryAfter: retryAfter(res)}
+	}
+	return err
+}
+
+// providerToken returns the JWT authenticating the requests, it is reused for its lifetime
+func (s *APNsSender) providerToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Since(s.issuedAt) < apnsTokenLifetime {
+		return s.token, nil
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": s.Config.TeamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = s.Config.KeyID
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.issuedAt = signed, now
+	return signed, nil
+}
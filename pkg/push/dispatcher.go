@@ -0,0 +1,97 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sync"
+	"time"
+)
+
+// Dispatcher sends messages to every device of a user. Temporary failures are returned to be
+// retried with exponential backoff, tokens the provider reports as invalid are removed.
+type Dispatcher struct {
+	Devices repository.DeviceRepository
+	// Senders by platform, devices of platforms without a sender are skipped
+	Senders map[string]Sender
+	// MaxAttempts is the number of times a message is sent to a device before giving up
+	MaxAttempts int
+	// Backoff is the delay before the first retry, it doubles with every retry
+	Backoff time.Duration
+	// MaxRetryDelay caps the delay before a retry, including the delay the provider asked for
+	MaxRetryDelay time.Duration
+	Logger        *slog.Logger
+}
+
+// Retry is a push to a device which failed temporarily, it is worth attempting again after
+// Delay. Attempts is the number of attempts made.
+type Retry struct {
+	Device   *model.Device
+	Attempts int
+	Delay    time.Duration
+}
+
+// Send attempts message once on every device of the user and returns the pushes to retry
+func (d *Dispatcher) Send(ctx context.Context, userID uint, message Message) ([]Retry, error) {
+	devices, err := d.Devices.WithContext(ctx).ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	var retries []Retry
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		wg.Add(1)
+		go func(device *model.Device) {
+			defer wg.Done()
+			if retry, ok := d.SendToDevice(ctx, device, message, 1); ok {
+				mu.Lock()
+				retries = append(retries, retry)
+				mu.Unlock()
+			}
+		}(device)
+	}
+	wg.Wait()
+	return retries, nil
+}
+
+// SendToDevice makes the attempt numbered attempt to send message to device, it reports whether
+// the push failed temporarily and is worth retrying
+func (d *Dispatcher) SendToDevice(ctx context.Context, device *model.Device, message Message, attempt int) (Retry, bool) {
+	sender, ok := d.Senders[device.Platform]
+	if !ok {
+		return Retry{}, false
+	}
+	err := sender.Send(ctx, device.Token, message)
+	if err == nil {
+		return Retry{}, false
+	}
+	if errors.Is(err, ErrInvalidToken) {
+		d.Logger.Info("Removing invalid push token", "user_id", device.UserID, "device_id", device.ID)
+		if err := d.Devices.WithContext(ctx).DeleteByToken(device.Token); err != nil {
+			d.Logger.Warn("Cannot remove invalid push token", "device_id", device.ID, "error", err)
+		}
+		return Retry{}, false
+	}
+	var temporary *TemporaryError
+	if !errors.As(err, &temporary) || attempt >= d.MaxAttempts {
+		d.Logger.Warn("Cannot send push notification", "device_id", device.ID, "attempts", attempt, "error", err)
+		return Retry{}, false
+	}
+	return Retry{Device: device, Attempts: attempt, Delay: d.retryDelay(attempt, temporary.RetryAfter)}, true
+}
+
+// retryDelay returns the delay before the retry following attempt. The delay the provider asked
+// for is honoured up to MaxRetryDelay, a longer one would hold the push past its use.
+func (d *Dispatcher) retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := d.Backoff << (attempt - 1)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	if d.MaxRetryDelay > 0 && (delay > d.MaxRetryDelay || delay < 0) {
+		delay = d.MaxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,66 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SentMessage is a message received by the fake sender
+type SentMessage struct {
+	Token   string
+	Message Message
+}
+
+// Fake records the messages it is asked to send instead of sending them, for tests and local
+// development
+type Fake struct {
+	mu   sync.Mutex
+	sent []SentMessage
+	// invalid tokens are rejected with ErrInvalidToken
+	invalid map[string]bool
+	// failures is the number of temporary failures left per token
+	failures map[string]int
+	// RetryAfter is the delay temporary failures ask for
+	RetryAfter time.Duration
+}
+
+func NewFake() *Fake {
+	return &Fake{invalid: make(map[string]bool), failures: make(map[string]int)}
+}
+
+func (f *Fake) Send(ctx context.Context, token string, message Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.invalid[token] {
+		return ErrInvalidToken
+	}
+	if f.failures[token] > 0 {
+		f.failures[token]--
+		return &TemporaryError{Err: errors.New("provider unavailable"), RetryAfter: f.RetryAfter}
+	}
+	f.sent = append(f.sent, SentMessage{Token: token, Message: message})
+	return nil
+}
+
+// Invalidate rejects the token from now on, like the token of an uninstalled app
+func (f *Fake) Invalidate(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.invalid[token] = true
+}
+
+// FailTimes fails the next n messages to the token with a temporary error
+func (f *Fake) FailTimes(token string, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[token] = n
+}
+
+// Sent returns the messages sent so far
+func (f *Fake) Sent() []SentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SentMessage(nil), f.sent...)
+}
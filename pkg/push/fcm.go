@@ -0,0 +1,205 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope of the access tokens of the FCM HTTP v1 API
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMCredentials is the service account key file of a Firebase project
+type FCMCredentials struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMSender sends messages through the FCM HTTP v1 API, authenticated as a service account
+type FCMSender struct {
+	Client *http.Client
+	// Endpoint is the base URL of the API
+	Endpoint    string
+	Credentials FCMCredentials
+
+	key         *rsa.PrivateKey
+	mu          sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+// NewFCMSender returns a sender for the project of the service account key file
+func NewFCMSender(credentialsJSON []byte) (*FCMSender, error) {
+	var credentials FCMCredentials
+	if err := json.Unmarshal(credentialsJSON, &credentials); err != nil {
+		return nil, fmt.Errorf("cannot parse FCM credentials: %w", err)
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse FCM private key: %w", err)
+	}
+	return &FCMSender{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		Endpoint:    "https://fcm.googleapis.com",
+		Credentials: credentials,
+		key:         key,
+	}, nil
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      fcmAndroid        `json:"android"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	Priority string `json:"priority"`
+}
+
+type fcmErrorResponse struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (s *FCMSender) Send(ctx context.Context, token string, message Message) error {
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return &TemporaryError{Err: err}
+	}
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: message.Title, Body: message.Body},
+		Data:         message.Data,
+		Android:      fcmAndroid{Priority: "high"},
+	}})
+	if err != nil {
+		return err
+	}
+	sendURL := fmt.Sprintf("%s/v1/projects/%s/messages:send", s.Endpoint, url.PathEscape(s.Credentials.ProjectID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return &TemporaryError{Err: err}
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	var errorResponse fcmErrorResponse
+	_ = json.NewDecoder(res.Body).Decode(&errorResponse)
+	errorCode := errorResponse.Error.Status
+	for _, detail := range errorResponse.Error.Details {
+		if detail.ErrorCode != "" {
+			errorCode = detail.ErrorCode
+		}
+	}
+	err = fmt.Errorf("FCM responded %d %s: %s", res.StatusCode, errorCode, errorResponse.Error.Message)
+	switch {
+	case res.StatusCode == http.StatusNotFound || errorCode == "UNREGISTERED":
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	case res.StatusCode == http.StatusUnauthorized:
+		// The access token may have been revoked, get a new one for the retry
+		s.mu.Lock()
+		s.accessToken = ""
+		s.mu.Unlock()
+		return &TemporaryError{Err: err}
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return &TemporaryError{Err: err, RetryAfter: retryAfter(res)}
+	}
+	return err
+}
+
+// token returns an access token of the service account, it is renewed shortly before it expires
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Before(s.expiry.Add(-time.Minute)) {
+		return s.accessToken, nil
+	}
+
+	// Exchange a JWT signed with the key of the service account for an access token
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.Credentials.ClientEmail,
+		"scope": fcmScope,
+		"aud":   s.Credentials.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Credentials.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint responded %d", res.StatusCode)
+	}
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+	s.accessToken = tokenResponse.AccessToken
+	s.expiry = now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
+
+// retryAfter returns the delay of the Retry-After header in seconds, zero without one
+func retryAfter(res *http.Response) time.Duration {
+	var seconds int
+	if _, err := fmt.Sscan(res.Header.Get("Retry-After"), &seconds); err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,77 @@
+// Package push sends notifications to the devices of users through the push provider of their
+// platform, FCM for Android and APNs for iOS.
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"money_share/pkg/model"
+	"time"
+)
+
+// ErrInvalidToken is returned for tokens the provider no longer accepts, e.g. of uninstalled apps
+var ErrInvalidToken = errors.New("push token is no longer valid")
+
+// TemporaryError is a failure worth retrying. RetryAfter is the delay the provider asked for,
+// zero when it did not ask for one.
+type TemporaryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *TemporaryError) Error() string {
+	return fmt.Sprintf("temporary push failure: %s", e.Err)
+}
+
+func (e *TemporaryError) Unwrap() error {
+	return e.Err
+}
+
+// Message is a notification as shown by the device, Data is passed to the app
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Sender sends messages through the push provider of a platform
+type Sender interface {
+	Send(ctx context.Context, token string, message Message) error
+}
+
+// NewMessage returns the message of a notification, groupName and actorName are the names of
+// its group and of the user who made the change
+func NewMessage(notification model.Notification, groupName string, actorName string) Message {
+	if actorName == "" {
+		actorName = "Someone"
+	}
+	message := Message{
+		Title: groupName,
+		Data: map[string]string{
+			"notificationID": fmt.Sprint(notification.ID),
+			"type":           notification.Type,
+			"groupID":        fmt.Sprint(notification.GroupID),
+			"targetID":       fmt.Sprint(notification.TargetID),
+		},
+	}
+	// Expense and payment notifications carry a snapshot of their target
+	var expense model.ExpenseSnapshot
+	var payment model.PaymentSnapshot
+	_ = json.Unmarshal([]byte(notification.Data), &expense)
+	_ = json.Unmarshal([]byte(notification.Data), &payment)
+	switch notification.Type {
+	case model.NotificationMemberAdded:
+		message.Body = fmt.Sprintf("%s added you to the group", actorName)
+	case model.NotificationExpensePending:
+		message.Body = fmt.Sprintf("%s added %q, it is waiting for your approval", actorName, expense.Title)
+	case model.NotificationExpenseApproved:
+		message.Body = fmt.Sprintf("%s approved your expense %q", actorName, expense.Title)
+	case model.NotificationExpenseDenied:
+		message.Body = fmt.Sprintf("%s denied your expense %q", actorName, expense.Title)
+	case model.NotificationPaymentReceived:
+		message.Body = fmt.Sprintf("%s paid you %.2f", actorName, payment.Amount)
+	}
+	return message
+}
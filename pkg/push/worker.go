@@ -0,0 +1,157 @@
+package push
+
+import (
+	"context"
+	"log/slog"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sync"
+	"time"
+)
+
+// Claimed retries are reserved this long for their attempt, longer than the timeout of the
+// senders. A retry whose instance stopped during the attempt is due again after it.
+const retryLease = time.Minute
+
+// Worker pushes the notifications created by the repositories. Notifications are claimed
+// before they are sent, so that every instance may run a worker and each notification is
+// pushed once. Pushes which failed temporarily are stored and retried by any instance.
+type Worker struct {
+	Notifications repository.NotificationRepository
+	Groups        repository.GroupRepository
+	Users         repository.UserRepository
+	Dispatcher    *Dispatcher
+	// Interval is the delay between polls for new notifications
+	Interval time.Duration
+	// BatchSize is the number of notifications pushed at once
+	BatchSize int
+	// MaxAge is the age after which notifications are no longer worth pushing
+	MaxAge time.Duration
+	Logger *slog.Logger
+}
+
+// Run pushes new notifications until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// A full batch means more are waiting
+		for {
+			pushed, err := w.Flush(ctx)
+			if err != nil && ctx.Err() == nil {
+				w.Logger.Warn("Cannot push notifications", "error", err)
+			}
+			if err != nil || pushed < w.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// Flush pushes a batch of the notifications not pushed yet and retries a batch of the due
+// pushes, it returns the larger number claimed. Every device is attempted once, so that a slow
+// provider holds the batch for one request at most.
+func (w *Worker) Flush(ctx context.Context) (int, error) {
+	notifications, err := w.Notifications.WithContext(ctx).ListUnpushed(w.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, notification := range notifications {
+		claimed, err := w.Notifications.WithContext(ctx).ClaimPush(notification.ID)
+		if err != nil {
+			return 0, err
+		}
+		if !claimed || time.Since(notification.CreatedAt) > w.MaxAge {
+			continue
+		}
+		wg.Add(1)
+		go func(notification *model.Notification) {
+			defer wg.Done()
+			w.push(ctx, notification)
+		}(notification)
+	}
+
+	retries, err := w.Notifications.WithContext(ctx).ClaimPushRetries(w.BatchSize, retryLease)
+	if err != nil {
+		return len(notifications), err
+	}
+	for _, retry := range retries {
+		wg.Add(1)
+		go func(retry *model.PushRetry) {
+			defer wg.Done()
+			w.retry(ctx, retry)
+		}(retry)
+	}
+	return max(len(notifications), len(retries)), nil
+}
+
+func (w *Worker) push(ctx context.Context, notification *model.Notification) {
+	message, err := w.message(ctx, notification)
+	if err != nil {
+		w.Logger.Warn("Cannot read group of notification", "notification_id", notification.ID, "error", err)
+		return
+	}
+	retries, err := w.Dispatcher.Send(ctx, notification.UserID, message)
+	if err != nil {
+		w.Logger.Warn("Cannot push notification", "notification_id", notification.ID, "error", err)
+		return
+	}
+	for _, retry := range retries {
+		w.saveRetry(ctx, &model.PushRetry{NotificationID: notification.ID, DeviceID: retry.Device.ID}, retry)
+	}
+}
+
+// retry attempts a stored push again, it is rescheduled or removed depending on the outcome
+func (w *Worker) retry(ctx context.Context, stored *model.PushRetry) {
+	notifications := w.Notifications.WithContext(ctx)
+	// The device may have been unregistered since
+	if stored.Device != nil && time.Since(stored.Notification.CreatedAt) <= w.MaxAge {
+		message, err := w.message(ctx, stored.Notification)
+		if err != nil {
+			// The lease expires and the push is retried then
+			w.Logger.Warn("Cannot read group of notification", "notification_id", stored.NotificationID, "error", err)
+			return
+		}
+		retry, ok := w.Dispatcher.SendToDevice(ctx, stored.Device, message, stored.Attempts+1)
+		if ok {
+			w.saveRetry(ctx, stored, retry)
+			return
+		}
+	}
+	if err := notifications.DeletePushRetry(stored.ID); err != nil {
+		w.Logger.Warn("Cannot remove push retry", "retry_id", stored.ID, "error", err)
+	}
+}
+
+func (w *Worker) saveRetry(ctx context.Context, stored *model.PushRetry, retry Retry) {
+	stored.Attempts = retry.Attempts
+	stored.RetryAt = time.Now().Add(retry.Delay)
+	if err := w.Notifications.WithContext(ctx).SavePushRetry(stored); err != nil {
+		w.Logger.Warn("Cannot schedule push retry", "notification_id", stored.NotificationID, "device_id", stored.DeviceID, "error", err)
+	}
+}
+
+// message returns the message of a notification with the names of its group and actor
+func (w *Worker) message(ctx context.Context, notification *model.Notification) (Message, error) {
+	group, err := w.Groups.WithContext(ctx).GetById(notification.GroupID)
+	if err != nil {
+		return Message{}, err
+	}
+	actorName := ""
+	if notification.ActorID != nil {
+		if actor, err := w.Users.WithContext(ctx).GetById(*notification.ActorID); err == nil {
+			actorName = actor.DisplayName
+			if actorName == "" {
+				actorName = actor.Username
+			}
+		}
+	}
+	return NewMessage(*notification, group.Name, actorName), nil
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// FallbackLimiter uses Primary and switches to Fallback for requests where Primary
+// fails, so an unavailable redis doesn't take the API down with it.
+type FallbackLimiter struct {
+	Primary  Limiter
+	Fallback Limiter
+	Logger   *slog.Logger
+	degraded atomic.Bool
+}
+
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	result, err := l.Primary.Allow(ctx, key, policy)
+	if err == nil {
+		if l.degraded.Swap(false) {
+			l.Logger.Info("Rate limiter recovered, using primary limiter again")
+		}
+		return result, nil
+	}
+	// Only log the transition to avoid one log line per request during an outage
+	if !l.degraded.Swap(true) {
+		l.Logger.Warn("Rate limiter unavailable, falling back to in-process limits", "error", err)
+	}
+	return l.Fallback.Allow(ctx, key, policy)
+}
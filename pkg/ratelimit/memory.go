@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	expiry time.Time
+}
+
+// MemoryLimiter keeps token buckets in process memory. It is used as fallback
+// when redis is unavailable, limits are then enforced per instance only.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Limit), last: now}
+		l.buckets[key] = b
+	}
+	tokens, result := takeToken(b.tokens, b.last, now, policy)
+	b.tokens = tokens
+	b.last = now
+	b.expiry = now.Add(result.Reset)
+	return result, nil
+}
+
+// sweep drops full buckets at most once a minute so memory stays bounded
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.After(b.expiry) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is a token bucket: up to Limit requests in a burst, refilled evenly over Window
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// ParsePolicy parses "<limit>/<window>", e.g. "50/10s" or "5/1m"
+func ParsePolicy(name string, value string) (Policy, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Policy{}, fmt.Errorf("invalid rate limit policy '%s', expected <limit>/<window>", value)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit <= 0 {
+		return Policy{}, fmt.Errorf("invalid rate limit '%s', must be a positive integer", parts[0])
+	}
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	// Buckets refill per millisecond, a shorter window would divide by zero
+	if err != nil || window < time.Millisecond {
+		return Policy{}, fmt.Errorf("invalid rate limit window '%s', must be at least 1ms", parts[1])
+	}
+	return Policy{Name: name, Limit: limit, Window: window}, nil
+}
+
+// refillPerMillisecond is the number of tokens added to the bucket every millisecond
+func (p Policy) refillPerMillisecond() float64 {
+	return float64(p.Limit) / float64(p.Window.Milliseconds())
+}
+
+// Result is the state of a bucket after a request was counted against it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request would be allowed, zero if allowed
+	RetryAfter time.Duration
+}
+
+// Limiter counts a request against the bucket identified by key
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// takeToken applies the token bucket algorithm, shared by the in-process limiter.
+// The redis limiter implements the same algorithm in Lua.
+func takeToken(tokens float64, last time.Time, now time.Time, policy Policy) (float64, Result) {
+	rate := policy.refillPerMillisecond()
+	capacity := float64(policy.Limit)
+	elapsed := float64(now.Sub(last).Milliseconds())
+	if elapsed > 0 {
+		tokens = minFloat(capacity, tokens+elapsed*rate)
+	}
+
+	result := Result{Limit: policy.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1-tokens)/rate) * time.Millisecond
+	}
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((capacity-tokens)/rate) * time.Millisecond
+	return tokens, result
+}
+
+func minFloat(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
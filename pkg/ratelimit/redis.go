@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/go-redis/redis/v9"
+	"time"
+)
+
+// tokenBucketScript atomically refills and takes a token from the bucket in KEYS[1].
+// ARGV: capacity, refill rate per millisecond. Redis server time is used so that all
+// instances share the same clock.
+// Returns: allowed (0/1), remaining tokens, retry after ms, reset ms.
+var tokenBucketScript = redis.NewScript(`
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+local elapsed = now - ts
+if elapsed > 0 then
+	tokens = math.min(capacity, tokens + elapsed * rate)
+end
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry_after = math.ceil((1 - tokens) / rate)
+end
+
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), retry_after, reset}
+`)
+
+// RedisLimiter shares token buckets between all server instances
+type RedisLimiter struct {
+	Client *redis.Client
+	Prefix string
+}
+
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{Client: client, Prefix: "ratelimit:"}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	values, err := tokenBucketScript.Run(ctx, l.Client, []string{l.Prefix + key},
+		policy.Limit, policy.refillPerMillisecond()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      policy.Limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"time"
+)
+
+// acquireScript registers a connection of a user unless the user has limit live connections.
+// Connections are scored by the expiry of their lease, connections of crashed instances
+// expire with their lease.
+var acquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return 1
+`)
+
+// Connection is an open stream counted against the connection limit of its user
+type Connection struct {
+	hub   *Hub
+	key   string
+	id    string
+	lease time.Duration
+}
+
+func (h *Hub) connectionsKey(userID uint) string {
+	return fmt.Sprintf("%suser:%d:connections", h.Prefix, userID)
+}
+
+// Connect counts a new stream of the user across instances. ok is false when the user already
+// has limit streams open. The connection holds for lease unless it is refreshed.
+func (h *Hub) Connect(ctx context.Context, userID uint, limit int, lease time.Duration) (connection *Connection, ok bool, err error) {
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return nil, false, err
+	}
+	connection = &Connection{hub: h, key: h.connectionsKey(userID), id: hex.EncodeToString(id), lease: lease}
+	now := time.Now()
+	acquired, err := acquireScript.Run(ctx, h.Client, []string{connection.key},
+		now.UnixMilli(), limit, now.Add(lease).UnixMilli(), connection.id, lease.Milliseconds()).Int()
+	if err != nil || acquired == 0 {
+		return nil, false, err
+	}
+	return connection, true, nil
+}
+
+// Refresh extends the lease of the connection
+func (c *Connection) Refresh(ctx context.Context) error {
+	score := float64(time.Now().Add(c.lease).UnixMilli())
+	pipe := c.hub.Client.TxPipeline()
+	pipe.ZAddXX(ctx, c.key, redis.Z{Score: score, Member: c.id})
+	pipe.PExpire(ctx, c.key, c.lease)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Close stops counting the connection, it uses its own context as the stream's is done by then
+func (c *Connection) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.hub.Client.ZRem(ctx, c.key, c.id).Err(); err != nil {
+		c.hub.Logger.Warn("Cannot release event stream connection", "error", err)
+	}
+}
@@ -0,0 +1,311 @@
+// Package realtime streams the changes of groups to their members. Events are appended to a
+// capped Redis stream per group, which clients resume from, and published over Redis pub/sub
+// to every instance, which fans them out to its open streams.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of events a subscriber may lag behind before it is dropped, it resumes from the
+// history when it reconnects
+const subscriberBuffer = 64
+
+// Delay before subscribing again after Redis failed
+const resubscribeDelay = time.Second
+
+// ErrClosed is returned when subscribing to a closed hub
+var ErrClosed = errors.New("event hub closed")
+
+// Event is a change of a group. IDs are Redis stream IDs, which increase with every event of
+// a group.
+type Event struct {
+	ID      string
+	GroupID uint
+	Type    string
+	// Data is the JSON payload of the event
+	Data json.RawMessage
+}
+
+// publishScript appends an event to the history of its group and publishes it in one step, so
+// subscribers receive events in the order of the history
+var publishScript = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], 'MAXLEN', ARGV[1], '*', 'type', ARGV[2], 'data', ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+redis.call('PUBLISH', KEYS[2], id .. '\n' .. ARGV[2] .. '\n' .. ARGV[3])
+return id
+`)
+
+type Hub struct {
+	Client *redis.Client
+	Prefix string
+	// History is the number of events of a group kept for resuming
+	History int64
+	// Retention is how long the history of a group without new events is kept
+	Retention time.Duration
+	Logger    *slog.Logger
+
+	mu          sync.Mutex
+	subscribers map[uint]map[*Subscription]struct{}
+	// ready is closed once events are received from Redis, done when the hub closes
+	ready  chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+func NewHub(client *redis.Client, logger *slog.Logger) *Hub {
+	return &Hub{
+		Client:      client,
+		Prefix:      "events:",
+		History:     1000,
+		Retention:   24 * time.Hour,
+		Logger:      logger,
+		subscribers: make(map[uint]map[*Subscription]struct{}),
+		ready:       make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (h *Hub) historyKey(groupID uint) string {
+	return fmt.Sprintf("%sgroup:%d:history", h.Prefix, groupID)
+}
+
+func (h *Hub) channel(groupID uint) string {
+	return fmt.Sprintf("%sgroup:%d", h.Prefix, groupID)
+}
+
+// Publish appends an event to the history of the group and sends it to every instance
+func (h *Hub) Publish(ctx context.Context, groupID uint, eventType string, data interface{}) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	keys := []string{h.historyKey(groupID), h.channel(groupID)}
+	id, err := publishScript.Run(ctx, h.Client, keys, h.History, eventType, encoded, h.Retention.Milliseconds()).Text()
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{ID: id, GroupID: groupID, Type: eventType, Data: encoded}, nil
+}
+
+// Since returns the events of the group after the event with ID lastID. complete is false when
+// lastID is no longer in the history, the events in between are lost and the client has to
+// reload the group. latest is the ID of the newest event of the history.
+func (h *Hub) Since(ctx context.Context, groupID uint, lastID string) (events []Event, complete bool, latest string, err error) {
+	messages, err := h.Client.XRange(ctx, h.historyKey(groupID), lastID, "+").Result()
+	if err != nil {
+		return nil, false, "", err
+	}
+	// The range includes lastID itself when it is still in the history
+	if len(messages) == 0 || messages[0].ID != lastID {
+		newest, err := h.Client.XRevRangeN(ctx, h.historyKey(groupID), "+", "-", 1).Result()
+		if err != nil {
+			return nil, false, "", err
+		}
+		if len(newest) > 0 {
+			latest = newest[0].ID
+		}
+		return nil, false, latest, nil
+	}
+	for _, message := range messages[1:] {
+		eventType, _ := message.Values["type"].(string)
+		data, _ := message.Values["data"].(string)
+		events = append(events, Event{ID: message.ID, GroupID: groupID, Type: eventType, Data: json.RawMessage(data)})
+	}
+	return events, true, messages[len(messages)-1].ID, nil
+}
+
+// Subscription receives the events of a group published after it was created
+type Subscription struct {
+	// Events is closed when the subscription is dropped for lagging behind or the hub closes
+	Events <-chan Event
+
+	events  chan Event
+	hub     *Hub
+	groupID uint
+	once    sync.Once
+}
+
+// Subscribe subscribes to the events of the group, it waits until the hub receives events
+func (h *Hub) Subscribe(ctx context.Context, groupID uint) (*Subscription, error) {
+	select {
+	case <-h.ready:
+	case <-h.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	events := make(chan Event, subscriberBuffer)
+	subscription := &Subscription{Events: events, events: events, hub: h, groupID: groupID}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if h.subscribers[groupID] == nil {
+		h.subscribers[groupID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[groupID][subscription] = struct{}{}
+	return subscription, nil
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.drop()
+}
+
+// drop removes the subscription from the hub and closes its events, the caller holds the lock
+func (s *Subscription) drop() {
+	s.once.Do(func() {
+		delete(s.hub.subscribers[s.groupID], s)
+		if len(s.hub.subscribers[s.groupID]) == 0 {
+			delete(s.hub.subscribers, s.groupID)
+		}
+		close(s.events)
+	})
+}
+
+// Run receives the events of every group from Redis until ctx is cancelled
+func (h *Hub) Run(ctx context.Context) {
+	pubsub := h.Client.PSubscribe(ctx, h.Prefix+"group:*")
+	defer pubsub.Close()
+	// Receive blocks on the connection regardless of ctx, closing it ends the wait
+	stop := context.AfterFunc(ctx, func() { _ = pubsub.Close() })
+	defer stop()
+
+	subscribed := false
+	for {
+		message, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			h.Logger.Warn("Cannot receive events", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(resubscribeDelay):
+			}
+			continue
+		}
+
+		switch message := message.(type) {
+		case *redis.Subscription:
+			if !subscribed {
+				subscribed = true
+				close(h.ready)
+			} else {
+				// Events published while the connection was down are lost, drop every
+				// subscriber so that clients reconnect and resume from the history
+				h.dropAll()
+			}
+		case *redis.Message:
+			event, err := h.parse(message)
+			if err != nil {
+				h.Logger.Warn("Cannot parse event", "channel", message.Channel, "error", err)
+				continue
+			}
+			h.dispatch(event)
+		}
+	}
+}
+
+// parse decodes a message published by Publish
+func (h *Hub) parse(message *redis.Message) (Event, error) {
+	groupID, err := strconv.ParseUint(strings.TrimPrefix(message.Channel, h.Prefix+"group:"), 10, 32)
+	if err != nil {
+		return Event{}, err
+	}
+	parts := strings.SplitN(message.Payload, "\n", 3)
+	if len(parts) != 3 {
+		return Event{}, errors.New("malformed event")
+	}
+	return Event{ID: parts[0], GroupID: uint(groupID), Type: parts[1], Data: json.RawMessage(parts[2])}, nil
+}
+
+func (h *Hub) dispatch(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for subscription := range h.subscribers[event.GroupID] {
+		select {
+		case subscription.events <- event:
+		default:
+			h.Logger.Info("Dropping lagging event subscriber", "group_id", event.GroupID)
+			subscription.drop()
+		}
+	}
+}
+
+func (h *Hub) dropAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subscriptions := range h.subscribers {
+		for subscription := range subscriptions {
+			subscription.drop()
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones, streams end so the server can shut down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.done)
+	}
+	h.mu.Unlock()
+	h.dropAll()
+}
+
+// CompareIDs compares two event IDs like strings.Compare, by time and then sequence number
+func CompareIDs(a string, b string) int {
+	aTime, aSeq := splitID(a)
+	bTime, bSeq := splitID(b)
+	if aTime != bTime {
+		return compareUint(aTime, bTime)
+	}
+	return compareUint(aSeq, bSeq)
+}
+
+// ValidID reports whether id is an event ID
+func ValidID(id string) bool {
+	parts := strings.Split(id, "-")
+	if len(parts) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		if _, err := strconv.ParseUint(part, 10, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func splitID(id string) (uint64, uint64) {
+	timePart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(timePart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
+
+func compareUint(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
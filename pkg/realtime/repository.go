@@ -0,0 +1,180 @@
+package realtime
+
+import (
+	"context"
+	"log/slog"
+	"money_share/pkg/dto"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+)
+
+// Publisher publishes the changes written through the repositories of this package. Failures
+// are logged, the change has been written and clients reload the group when they reconnect.
+type Publisher struct {
+	Hub *Hub
+	// Groups reads the totals published after expense and member changes
+	Groups repository.GroupRepository
+	Logger *slog.Logger
+}
+
+func (p *Publisher) publish(ctx context.Context, groupID uint, eventType string, data interface{}) {
+	if _, err := p.Hub.Publish(ctx, groupID, eventType, data); err != nil {
+		p.Logger.Warn("Cannot publish group event", "group_id", groupID, "type", eventType, "error", err)
+	}
+}
+
+func (p *Publisher) publishTotals(ctx context.Context, groupID uint) {
+	group, err := p.Groups.WithContext(ctx).GetById(groupID)
+	if err != nil {
+		p.Logger.Warn("Cannot read group totals", "group_id", groupID, "error", err)
+		return
+	}
+	totals := dto.GroupTotalsDTO{
+		TotalExpense:   group.TotalExpense,
+		AverageExpense: group.AverageExpense,
+		Members:        []dto.MemberTotalDTO{},
+	}
+	for _, member := range group.Members {
+		totals.Members = append(totals.Members, dto.MemberTotalDTO{UserID: member.UserID, TotalExpense: member.TotalExpense})
+	}
+	p.publish(ctx, groupID, EventTotalsUpdated, totals)
+}
+
+// GroupRepository publishes updates and deletions of groups
+type GroupRepository struct {
+	repository.GroupRepository
+	Publisher *Publisher
+	ctx       context.Context
+}
+
+func NewGroupRepository(groupRepository repository.GroupRepository, publisher *Publisher) repository.GroupRepository {
+	return GroupRepository{groupRepository, publisher, context.Background()}
+}
+
+func (repository GroupRepository) WithContext(ctx context.Context) repository.GroupRepository {
+	return GroupRepository{repository.GroupRepository.WithContext(ctx), repository.Publisher, ctx}
+}
+
+func (repository GroupRepository) Update(group *model.Group) error {
+	err := repository.GroupRepository.Update(group)
+	if err != nil {
+		return err
+	}
+	// Updates carry the changed fields only, publish the whole group
+	updated, err := repository.GroupRepository.GetById(group.ID)
+	if err != nil {
+		repository.Publisher.Logger.Warn("Cannot read updated group", "group_id", group.ID, "error", err)
+		return nil
+	}
+	repository.Publisher.publish(repository.ctx, group.ID, EventGroupUpdated, dto.GroupEventDTO{
+		ID:            updated.ID,
+		Name:          updated.Name,
+		GroupImageUrl: updated.GroupImageUrl,
+	})
+	return nil
+}
+
+func (repository GroupRepository) Delete(groupId uint) error {
+	err := repository.GroupRepository.Delete(groupId)
+	if err == nil {
+		repository.Publisher.publish(repository.ctx, groupId, EventGroupDeleted, dto.GroupEventDTO{ID: groupId})
+	}
+	return err
+}
+
+// MemberRepository publishes member changes, adding and removing members changes the average
+type MemberRepository struct {
+	repository.MemberRepository
+	Publisher *Publisher
+	ctx       context.Context
+}
+
+func NewMemberRepository(memberRepository repository.MemberRepository, publisher *Publisher) repository.MemberRepository {
+	return MemberRepository{memberRepository, publisher, context.Background()}
+}
+
+func (repository MemberRepository) WithContext(ctx context.Context) repository.MemberRepository {
+	return MemberRepository{repository.MemberRepository.WithContext(ctx), repository.Publisher, ctx}
+}
+
+func (repository MemberRepository) AddMemberToGroup(userID uint, groupID uint) error {
+	err := repository.MemberRepository.AddMemberToGroup(userID, groupID)
+	if err == nil {
+		repository.Publisher.publish(repository.ctx, groupID, EventMemberAdded, dto.MemberEventDTO{UserID: userID, Role: model.RoleMember})
+		repository.Publisher.publishTotals(repository.ctx, groupID)
+	}
+	return err
+}
+
+func (repository MemberRepository) RemoveMemberFromGroup(userID uint, groupID uint) error {
+	err := repository.MemberRepository.RemoveMemberFromGroup(userID, groupID)
+	if err == nil {
+		repository.Publisher.publish(repository.ctx, groupID, EventMemberRemoved, dto.MemberEventDTO{UserID: userID})
+		repository.Publisher.publishTotals(repository.ctx, groupID)
+	}
+	return err
+}
+
+func (repository MemberRepository) UpdateRole(userID uint, groupID uint, role string) error {
+	err := repository.MemberRepository.UpdateRole(userID, groupID, role)
+	if err == nil {
+		repository.Publisher.publish(repository.ctx, groupID, EventMemberUpdated, dto.MemberEventDTO{UserID: userID, Role: role})
+	}
+	return err
+}
+
+// ExpenseRepository publishes every expense written and the totals of its group
+type ExpenseRepository struct {
+	repository.ExpenseRepository
+	Publisher *Publisher
+	ctx       context.Context
+}
+
+func NewExpenseRepository(expenseRepository repository.ExpenseRepository, publisher *Publisher) repository.ExpenseRepository {
+	return ExpenseRepository{expenseRepository, publisher, context.Background()}
+}
+
+func (repository ExpenseRepository) WithContext(ctx context.Context) repository.ExpenseRepository {
+	return ExpenseRepository{repository.ExpenseRepository.WithContext(ctx), repository.Publisher, ctx}
+}
+
+func (repository ExpenseRepository) Create(expense *model.Expense) error {
+	err := repository.ExpenseRepository.Create(expense)
+	if err == nil {
+		repository.publish(EventExpenseCreated, expense)
+	}
+	return err
+}
+
+func (repository ExpenseRepository) Update(expense *model.Expense) (string, error) {
+	action, err := repository.ExpenseRepository.Update(expense)
+	if err != nil {
+		return "", err
+	}
+	// Updates carry the changed fields only, publish the whole expense
+	updated, err := repository.ExpenseRepository.GetById(expense.ID)
+	if err != nil {
+		repository.Publisher.Logger.Warn("Cannot read updated expense", "expense_id", expense.ID, "error", err)
+		return action, nil
+	}
+	repository.publish(EventExpenseUpdated, updated)
+	return action, nil
+}
+
+func (repository ExpenseRepository) Delete(expenseId uint) error {
+	expense, err := repository.ExpenseRepository.GetById(expenseId)
+	if err != nil {
+		// The delete reports the invalid ID or missing expense
+		return repository.ExpenseRepository.Delete(expenseId)
+	}
+	err = repository.ExpenseRepository.Delete(expenseId)
+	if err == nil {
+		repository.publish(EventExpenseDeleted, expense)
+	}
+	return err
+}
+
+func (repository ExpenseRepository) publish(eventType string, expense *model.Expense) {
+	repository.Publisher.publish(repository.ctx, expense.GroupID, eventType, dto.ExpenseToExpenseDTO(*expense))
+	repository.Publisher.publishTotals(repository.ctx, expense.GroupID)
+}
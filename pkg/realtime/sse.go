@@ -0,0 +1,39 @@
+package realtime
+
+import (
+	"fmt"
+	"io"
+)
+
+// Types of the events of group streams
+const (
+	EventExpenseCreated = "expense.created"
+	EventExpenseUpdated = "expense.updated"
+	EventExpenseDeleted = "expense.deleted"
+	EventMemberAdded    = "member.added"
+	EventMemberRemoved  = "member.removed"
+	EventMemberUpdated  = "member.updated"
+	EventGroupUpdated   = "group.updated"
+	EventGroupDeleted   = "group.deleted"
+	EventTotalsUpdated  = "totals.updated"
+	// EventReset tells a resuming client that events were lost, it has to reload the group
+	EventReset = "reset"
+)
+
+// WriteEvent writes event in the Server-Sent Events format, the data is a single line of JSON
+func WriteEvent(w io.Writer, event Event) error {
+	var err error
+	if event.ID != "" {
+		_, err = fmt.Fprintf(w, "id: %s\n", event.ID)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+	}
+	return err
+}
+
+// WriteHeartbeat writes a comment, which keeps proxies from closing an idle stream
+func WriteHeartbeat(w io.Writer) error {
+	_, err := io.WriteString(w, ": heartbeat\n\n")
+	return err
+}
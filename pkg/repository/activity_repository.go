@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"money_share/pkg/model"
+)
+
+// ActivityRepository reads the activity log of groups. Entries are only appended, by the
+// repositories making the changes in the same transaction.
+type ActivityRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) ActivityRepository
+	// ListByGroup returns a page of the activity of a group, newest first
+	ListByGroup(groupID uint, page PageRequest) (Page[*model.Activity], error)
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"money_share/pkg/auth"
+	"money_share/pkg/model"
+)
+
+// Sort key of activity cursors
+const activityCursorKey = "id:desc"
+
+type ActivityRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewActivityRepository(db *gorm.DB) ActivityRepository {
+	return ActivityRepositoryImpl{db}
+}
+
+func (repository ActivityRepositoryImpl) WithContext(ctx context.Context) ActivityRepository {
+	return ActivityRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
+func (repository ActivityRepositoryImpl) ListByGroup(groupID uint, page PageRequest) (Page[*model.Activity], error) {
+	db := repository.DB
+	result := Page[*model.Activity]{}
+	if groupID <= 0 {
+		return result, InvalidID("groupId")
+	}
+	if err := page.Validate(); err != nil {
+		return result, err
+	}
+	cursor, err := DecodeCursor(page.Cursor, activityCursorKey)
+	if err != nil {
+		return result, err
+	}
+
+	query := db.Where("group_id = ?", groupID)
+	if cursor != nil {
+		query = query.Where("id < ?", cursor.ID)
+	}
+	var activities []*model.Activity
+	if err = query.Order("id DESC").Limit(page.Limit + 1).Find(&activities).Error; err != nil {
+		return result, err
+	}
+	return PageOf(activities, page.Limit, ActivityCursor), nil
+}
+
+// ActivityCursor returns the cursor after activity in the activity feed
+func ActivityCursor(activity *model.Activity) Cursor {
+	return Cursor{Sort: activityCursorKey, ID: activity.ID}
+}
+
+// appendActivity records activity in the transaction of the change, the actor is the user of
+// the context of tx. Activities which change nothing are skipped, the others notify the users
+// they concern and are queued for the webhooks of the group.
+func appendActivity(tx *gorm.DB, activity model.Activity) error {
+	if !activity.Changed() {
+		return nil
+	}
+	if userID, ok := auth.UserIDFrom(tx.Statement.Context); ok {
+		activity.ActorID = &userID
+	}
+	if err := tx.Create(&activity).Error; err != nil {
+		return err
+	}
+	if err := notify(tx, activity); err != nil {
+		return err
+	}
+	return enqueueWebhooks(tx, activity)
+}
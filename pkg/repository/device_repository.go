@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"money_share/pkg/model"
+)
+
+// DeviceRepository stores the devices users receive push notifications on
+type DeviceRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) DeviceRepository
+	// Register saves a device, a registered token is updated and moved to the user of device
+	Register(device *model.Device) error
+	// ListByUser returns the devices of a user ordered by ID
+	ListByUser(userID uint) ([]*model.Device, error)
+	// Delete unregisters a device of the user
+	Delete(userID uint, deviceID uint) error
+	// DeleteByToken removes the device of a token the push provider no longer accepts
+	DeleteByToken(token string) error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"money_share/pkg/model"
+)
+
+type DeviceRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return DeviceRepositoryImpl{db}
+}
+
+func (repository DeviceRepositoryImpl) WithContext(ctx context.Context) DeviceRepository {
+	return DeviceRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
+func (repository DeviceRepositoryImpl) Register(device *model.Device) error {
+	db := repository.DB
+	if device.UserID <= 0 {
+		return InvalidID("userId")
+	}
+	if err := device.ValidateFields(); err != nil {
+		return err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "app_version", "updated_at"}),
+		}).Create(device).Error
+		if err != nil {
+			return err
+		}
+		// The ID of an updated row is not returned by every driver
+		return tx.Where("token = ?", device.Token).First(device).Error
+	})
+	return translateError(err, "device")
+}
+
+func (repository DeviceRepositoryImpl) ListByUser(userID uint) ([]*model.Device, error) {
+	if userID <= 0 {
+		return nil, InvalidID("userId")
+	}
+	var devices []*model.Device
+	err := repository.DB.Where("user_id = ?", userID).Order("id").Find(&devices).Error
+	return devices, err
+}
+
+func (repository DeviceRepositoryImpl) Delete(userID uint, deviceID uint) error {
+	if userID <= 0 {
+		return InvalidID("userId")
+	}
+	if deviceID <= 0 {
+		return InvalidID("deviceId")
+	}
+	// Devices of other users are missing to the user
+	result := repository.DB.Where("id = ? AND user_id = ?", deviceID, userID).Delete(&model.Device{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return NotFound("device")
+	}
+	return nil
+}
+
+func (repository DeviceRepositoryImpl) DeleteByToken(token string) error {
+	return repository.DB.Where("token = ?", token).Delete(&model.Device{}).Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgconn"
+	"gorm.io/gorm"
+	sqlite3 "modernc.org/sqlite/lib"
+	"money_share/pkg/apperror"
+	"strings"
+)
+
+// Postgres error codes of constraint violations
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// NotFound returns the error of a missing entity, e.g. "user" gives the code user_not_found.
+// It wraps gorm.ErrRecordNotFound so every implementation fails the same way.
+func NotFound(entity string) *apperror.Error {
+	return apperror.Wrap(gorm.ErrRecordNotFound, apperror.KindNotFound, entity+"_not_found", capitalize(entity)+" not found")
+}
+
+// Conflict returns the error of an entity which already exists, e.g. "member" gives the code member_exists
+func Conflict(entity string, err error) *apperror.Error {
+	return apperror.Wrap(err, apperror.KindConflict, entity+"_exists", capitalize(entity)+" already exists")
+}
+
+// UsernameTaken returns the error of a user whose username is used by another user
+func UsernameTaken(err error) *apperror.Error {
+	return apperror.Wrap(err, apperror.KindConflict, "username_taken", "Username is already taken")
+}
+
+// MissingReference returns the error of a row referencing a missing user, group or member
+func MissingReference(err error) *apperror.Error {
+	return apperror.Wrap(err, apperror.KindNotFound, "reference_not_found", "Referenced record not found")
+}
+
+// InvalidID returns the validation error of an unset ID
+func InvalidID(field string) *apperror.Error {
+	return apperror.InvalidField(field, field+" must be greater than 0")
+}
+
+// translateError converts errors of gorm and the database drivers of the entity to apperror
+// errors. Errors which are already translated and unexpected errors are returned as is.
+func translateError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := apperror.As(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NotFound(entity)
+	case isUniqueViolation(err):
+		return Conflict(entity, err)
+	case isForeignKeyViolation(err):
+		return MissingReference(err)
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY ||
+			strings.Contains(sqliteErr.Error(), "UNIQUE constraint failed")
+	}
+	return false
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgForeignKeyViolation
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// Violations found when a statement completes only carry a generic code
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY ||
+			strings.Contains(sqliteErr.Error(), "FOREIGN KEY constraint failed")
+	}
+	return false
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
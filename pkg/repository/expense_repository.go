@@ -1,12 +1,23 @@
 package repository
 
-import "money_share/pkg/model"
+import (
+	"context"
+	"money_share/pkg/model"
+)
 
 type ExpenseRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) ExpenseRepository
 	GetById(expenseId uint) (*model.Expense, error)
 	GetByGroup(groupId uint) ([]*model.Expense, error)
 	GetByMember(memberId uint, groupId uint) ([]*model.Expense, error)
+	// List returns a page of the expenses of a group matching filter in its order
+	List(filter ExpenseFilter, page PageRequest) (Page[*model.Expense], error)
+	// Search returns a page of the expenses of the groups of a user matching a query, most relevant first
+	Search(search ExpenseSearch, page PageRequest) (Page[*ExpenseMatch], error)
 	Create(expense *model.Expense) error
-	Update(expense *model.Expense) error
+	// Update changes the non-zero fields of an expense and returns the action recorded, e.g.
+	// model.ActionExpenseApproved when it approves the expense
+	Update(expense *model.Expense) (string, error)
 	Delete(expenseId uint) error
 }
@@ -1,10 +1,13 @@
 package repository
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"money_share/pkg/apperror"
 	"money_share/pkg/model"
+	"strings"
 )
 
 type ExpenseRepositoryImpl struct {
@@ -15,20 +18,24 @@ func NewExpenseRepository(db *gorm.DB) ExpenseRepository {
 	return ExpenseRepositoryImpl{db}
 }
 
+func (repository ExpenseRepositoryImpl) WithContext(ctx context.Context) ExpenseRepository {
+	return ExpenseRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
 func (repository ExpenseRepositoryImpl) GetById(expenseId uint) (*model.Expense, error) {
 	db := repository.DB
 	if expenseId <= 0 {
-		return nil, errors.New("expenseId must be greater than 0")
+		return nil, InvalidID("expenseId")
 	}
-	var expense *model.Expense
+	expense := &model.Expense{}
 	err := db.First(expense, expenseId).Error
-	return expense, err
+	return expense, translateError(err, "expense")
 }
 
 func (repository ExpenseRepositoryImpl) GetByGroup(groupId uint) ([]*model.Expense, error) {
 	db := repository.DB
 	if groupId <= 0 {
-		return nil, errors.New("groupId must be greater than 0")
+		return nil, InvalidID("groupId")
 	}
 	var expenses []*model.Expense
 	err := db.Where("group_id = ?", groupId).Find(&expenses).Error
@@ -38,67 +45,240 @@ func (repository ExpenseRepositoryImpl) GetByGroup(groupId uint) ([]*model.Expen
 func (repository ExpenseRepositoryImpl) GetByMember(memberId uint, groupId uint) ([]*model.Expense, error) {
 	db := repository.DB
 	if memberId <= 0 || groupId <= 0 {
-		return nil, errors.New("memberId and groupId must be greater than 0")
+		return nil, InvalidID("memberId")
 	}
 	var expenses []*model.Expense
 	err := db.Where("group_id = ? AND member_id = ?", groupId, memberId).Find(&expenses).Error
 	return expenses, err
 }
 
-func (repository ExpenseRepositoryImpl) Create(expense *model.Expense) error {
+// Columns of the expense sort orders
+var expenseSortColumns = map[ExpenseSort]string{
+	SortPurchaseTime: "purchase_time",
+	SortAmount:       "amount",
+	SortCreatedAt:    "created_at",
+}
+
+func (repository ExpenseRepositoryImpl) List(filter ExpenseFilter, page PageRequest) (Page[*model.Expense], error) {
 	db := repository.DB
-	// Validate fields
-	if len(expense.Title) == 0 {
-		return errors.New("title cannot be empty")
+	result := Page[*model.Expense]{}
+	if err := apperror.Join(filter.Validate(), page.Validate()); err != nil {
+		return result, err
+	}
+	cursor, err := DecodeCursor(page.Cursor, filter.CursorKey())
+	if err != nil {
+		return result, err
+	}
+
+	// Filter
+	query := db.Where("group_id = ?", filter.GroupID)
+	if filter.MemberID != 0 {
+		query = query.Where("member_id = ?", filter.MemberID)
 	}
-	if expense.Amount < 0 {
-		return errors.New("amount must be equal or greater than 0")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
 	}
-	if expense.PurchaseTime.IsZero() {
-		return errors.New("purchase time is not set")
+	if filter.PurchasedFrom != nil {
+		query = query.Where("purchase_time >= ?", filter.PurchasedFrom.UTC())
 	}
-	if expense.MemberID <= 0 {
-		return errors.New("memberId must be greater than 0")
+	if filter.PurchasedTo != nil {
+		query = query.Where("purchase_time <= ?", filter.PurchasedTo.UTC())
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("amount <= ?", *filter.MaxAmount)
+	}
+
+	// Continue after the cursor, rows with the same sort value are ordered by ID
+	column := expenseSortColumns[filter.Sort]
+	operator, direction := ">", "ASC"
+	if filter.Descending {
+		operator, direction = "<", "DESC"
+	}
+	if cursor != nil {
+		value, err := filter.SortValue(cursor)
+		if err != nil {
+			return result, err
+		}
+		query = query.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, operator), value, value, cursor.ID)
+	}
+
+	var expenses []*model.Expense
+	err = query.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).Limit(page.Limit + 1).Find(&expenses).Error
+	if err != nil {
+		return result, err
 	}
+	return PageOf(expenses, page.Limit, func(expense *model.Expense) Cursor {
+		return ExpenseCursor(filter, expense)
+	}), nil
+}
+
+// Weighted text of the expenses searched on Postgres, the same expression is indexed by the
+// add_expense_search migration
+const expenseSearchVector = "setweight(to_tsvector('expense_search', expenses.title), 'A') || " +
+	"setweight(to_tsvector('expense_search', coalesce(expenses.description, '')), 'B')"
 
-	err := db.Create(expense).Error
-	return err
+// Options of the Postgres snippets, which are escaped by EscapeSnippet
+var expenseHeadlineOptions = fmt.Sprintf("StartSel=\"%s\", StopSel=\"%s\", MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=\" … \"",
+	rawHighlightStart, rawHighlightStop)
+
+// expenseMatchRow is an expense with the rank and snippet selected by a search
+type expenseMatchRow struct {
+	model.Expense
+	Rank    float32
+	Snippet string
 }
 
-func (repository ExpenseRepositoryImpl) Update(expense *model.Expense) error {
+func (repository ExpenseRepositoryImpl) Search(search ExpenseSearch, page PageRequest) (Page[*ExpenseMatch], error) {
+	db := repository.DB
+	result := Page[*ExpenseMatch]{}
+	if err := apperror.Join(search.Validate(), page.Validate()); err != nil {
+		return result, err
+	}
+	offset, err := SearchOffset(page)
+	if err != nil {
+		return result, err
+	}
+
+	// Only the groups of the user are searched
+	query := db.Model(&model.Expense{}).
+		Joins("JOIN members ON members.group_id = expenses.group_id AND members.user_id = ?", search.UserID)
+	if search.GroupID != 0 {
+		query = query.Where("expenses.group_id = ?", search.GroupID)
+	}
+	postgres := db.Dialector.Name() == "postgres"
+	if postgres {
+		// Full-text search, the expense_search configuration ignores accents
+		query = query.Joins("CROSS JOIN websearch_to_tsquery('expense_search', ?) AS search_query", search.Query).
+			Where(expenseSearchVector+" @@ search_query").
+			Select("expenses.*, ts_rank("+expenseSearchVector+", search_query) AS rank, "+
+				"ts_headline('expense_search', translate(concat_ws(' ', expenses.title, expenses.description), ?, ''), search_query, ?) AS snippet",
+				rawHighlightStart+rawHighlightStop, expenseHeadlineOptions)
+	} else {
+		query = likeSearch(query, SearchTerms(search.Query))
+	}
+
+	var rows []*expenseMatchRow
+	err = query.Order("rank DESC, expenses.purchase_time DESC, expenses.id DESC").
+		Offset(offset).Limit(page.Limit + 1).Scan(&rows).Error
+	if err != nil {
+		return result, err
+	}
+	rowPage := PageOf(rows, page.Limit, func(*expenseMatchRow) Cursor {
+		return SearchCursor(offset + page.Limit)
+	})
+	result.NextCursor = rowPage.NextCursor
+	result.Items = make([]*ExpenseMatch, 0, len(rowPage.Items))
+	for _, row := range rowPage.Items {
+		match := &ExpenseMatch{Expense: &row.Expense, Rank: row.Rank, Snippet: EscapeSnippet(row.Snippet)}
+		if !postgres {
+			match.Snippet = FallbackSnippet(match.Expense, SearchTerms(search.Query))
+		}
+		result.Items = append(result.Items, match)
+	}
+	return result, nil
+}
+
+// likeSearch matches every term in the title or description and ranks like FallbackRank, for
+// databases without full-text search. Only ASCII letters are compared case-insensitively.
+func likeSearch(query *gorm.DB, terms []string) *gorm.DB {
+	var rankParts []string
+	var rankArgs []interface{}
+	for _, term := range terms {
+		pattern := "%" + likeEscaper.Replace(term) + "%"
+		query = query.Where(`(LOWER(expenses.title) LIKE ? ESCAPE '\' OR LOWER(expenses.description) LIKE ? ESCAPE '\')`, pattern, pattern)
+		rankParts = append(rankParts, `CASE WHEN LOWER(expenses.title) LIKE ? ESCAPE '\' THEN 1 ELSE 0 END + `+
+			`CASE WHEN LOWER(expenses.description) LIKE ? ESCAPE '\' THEN 0.5 ELSE 0 END`)
+		rankArgs = append(rankArgs, pattern, pattern)
+	}
+	return query.Select("expenses.*, "+strings.Join(rankParts, " + ")+" AS rank", rankArgs...)
+}
+
+// Escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (repository ExpenseRepositoryImpl) Create(expense *model.Expense) error {
+	db := repository.DB
+	// Validate fields
+	if err := expense.ValidateFields(); err != nil {
+		return err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(expense).Error; err != nil {
+			return err
+		}
+		return appendActivity(tx, model.NewExpenseActivity(model.ActionExpenseCreated, nil, expense))
+	})
+	return translateError(err, "expense")
+}
+
+func (repository ExpenseRepositoryImpl) Update(expense *model.Expense) (string, error) {
 	db := repository.DB
 	// Validate fields
 	if expense.ID <= 0 {
-		return errors.New("expenseId must be greater than 0")
+		return "", InvalidID("expenseId")
 	}
-	if expense.Amount < 0 {
-		return errors.New("amount must be equal or greater than 0")
+	if err := model.ValidateAmount(expense.Amount); err != nil {
+		return "", err
 	}
-	if len(expense.Status) > 0 && (expense.Status != "pending" && expense.Status != "accepted" && expense.Status != "denied") {
-		return errors.New("invalid status, must be 'pending', 'accepted' or 'denied'")
+	if len(expense.Status) > 0 {
+		if err := model.ValidateStatus(expense.Status); err != nil {
+			return "", err
+		}
 	}
 
 	updateExpense := &model.Expense{}
 	updateExpense.ID = expense.ID
 
+	// The action is worked out against the expense read in the transaction
+	var action string
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// Make sure record exists
-		queryRs := db.First(updateExpense)
+		queryRs := tx.First(updateExpense)
 		if err := queryRs.Error; err != nil {
 			return err
 		}
 
+		before := *updateExpense
+
 		// Update fields
 		// Omit forbidden fields
 		err := queryRs.Omit("ID", "MemberID", "GroupID", clause.Associations).Updates(expense).Error
+		if err != nil {
+			return err
+		}
 
-		return err
+		after := &model.Expense{}
+		if err = tx.First(after, expense.ID).Error; err != nil {
+			return err
+		}
+		action = model.ExpenseUpdateAction(&before, after)
+		return appendActivity(tx, model.NewExpenseActivity(action, &before, after))
 	})
-
-	return err
+	if err != nil {
+		return "", translateError(err, "expense")
+	}
+	return action, nil
 }
 
 func (repository ExpenseRepositoryImpl) Delete(expenseId uint) error {
 	db := repository.DB
-	return db.Delete(&model.Expense{}, expenseId).Error
+	if expenseId <= 0 {
+		return InvalidID("expenseId")
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Load the expense so the delete hook knows which group totals to update
+		expense := &model.Expense{}
+		if err := tx.First(expense, expenseId).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(expense).Error; err != nil {
+			return err
+		}
+		return appendActivity(tx, model.NewExpenseActivity(model.ActionExpenseDeleted, expense, nil))
+	})
+	return translateError(err, "expense")
 }
@@ -1,12 +1,24 @@
 package repository
 
-import "money_share/pkg/model"
+import (
+	"context"
+	"money_share/pkg/model"
+)
 
 type GroupRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) GroupRepository
+	// GetById returns the group with its members, expenses are listed by ExpenseRepository.List
 	GetById(groupId uint) (*model.Group, error)
+	// GetWithExpenses returns the group with its members and every expense
+	GetWithExpenses(groupId uint) (*model.Group, error)
 	GetByUser(memberId uint) ([]*model.Group, error)
+	// ListByUser returns a page of the groups of a user ordered by ID
+	ListByUser(userID uint, page PageRequest) (Page[*model.Group], error)
 	Create(group *model.Group, creatorID uint) error
 	Update(group *model.Group) error
 	Delete(groupId uint) error
 	GetMemberRole(memberID uint, groupID uint) (string, error)
+	// UpdateTotals overwrites the group total and average and the total of each member in memberTotals
+	UpdateTotals(groupID uint, total float32, average float32, memberTotals map[uint]float32) error
 }
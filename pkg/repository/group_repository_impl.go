@@ -1,7 +1,7 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"money_share/pkg/model"
@@ -15,22 +15,36 @@ func NewGroupRepository(db *gorm.DB) GroupRepository {
 	return GroupRepositoryImpl{db}
 }
 
+func (repository GroupRepositoryImpl) WithContext(ctx context.Context) GroupRepository {
+	return GroupRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
 func (repository GroupRepositoryImpl) GetById(groupId uint) (*model.Group, error) {
 	db := repository.DB
 	if groupId <= 0 {
-		return &model.Group{}, errors.New("groupId must be greater than 0")
+		return &model.Group{}, InvalidID("groupId")
+	}
+
+	group := &model.Group{}
+	err := db.Preload("Members").Preload("Members.User").First(group, groupId).Error
+	return group, translateError(err, "group")
+}
+
+func (repository GroupRepositoryImpl) GetWithExpenses(groupId uint) (*model.Group, error) {
+	db := repository.DB
+	if groupId <= 0 {
+		return &model.Group{}, InvalidID("groupId")
 	}
 
 	group := &model.Group{}
-	//group.ID = groupId
 	err := db.Preload("Members").Preload("Members.User").Preload("Expenses").First(group, groupId).Error
-	return group, err
+	return group, translateError(err, "group")
 }
 
 func (repository GroupRepositoryImpl) GetByUser(userId uint) ([]*model.Group, error) {
 	db := repository.DB
 	if userId <= 0 {
-		return nil, errors.New("userId must be greater than 0")
+		return nil, InvalidID("userId")
 	}
 	var groups []*model.Group
 	err := db.Where("id in (?)",
@@ -38,16 +52,43 @@ func (repository GroupRepositoryImpl) GetByUser(userId uint) ([]*model.Group, er
 	return groups, err
 }
 
+func (repository GroupRepositoryImpl) ListByUser(userID uint, page PageRequest) (Page[*model.Group], error) {
+	db := repository.DB
+	result := Page[*model.Group]{}
+	if userID <= 0 {
+		return result, InvalidID("userId")
+	}
+	if err := page.Validate(); err != nil {
+		return result, err
+	}
+	cursor, err := DecodeCursor(page.Cursor, "id")
+	if err != nil {
+		return result, err
+	}
+
+	query := db.Where("id in (?)", db.Table("members").Select("group_id").Where("user_id = ?", userID))
+	if cursor != nil {
+		query = query.Where("id > ?", cursor.ID)
+	}
+	var groups []*model.Group
+	if err = query.Order("id").Limit(page.Limit + 1).Find(&groups).Error; err != nil {
+		return result, err
+	}
+	return PageOf(groups, page.Limit, func(group *model.Group) Cursor {
+		return Cursor{Sort: "id", ID: group.ID}
+	}), nil
+}
+
 func (repository GroupRepositoryImpl) Create(group *model.Group, creatorID uint) error {
 	db := repository.DB
-	if creatorID < 0 {
-		return errors.New("creatorID must be greater than 0")
+	if creatorID <= 0 {
+		return InvalidID("creatorId")
 	}
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// Validate creator existence
 		creator := &model.User{}
 		if err := tx.First(creator, creatorID).Error; err != nil {
-			return err
+			return translateError(err, "user")
 		}
 		// Create group
 		if err := tx.Omit(clause.Associations).Create(group).Error; err != nil {
@@ -67,45 +108,83 @@ func (repository GroupRepositoryImpl) Create(group *model.Group, creatorID uint)
 		return nil
 	})
 
-	return err
+	return translateError(err, "group")
 }
 
 func (repository GroupRepositoryImpl) Update(group *model.Group) error {
 	db := repository.DB
 	if group.ID == 0 {
-		return errors.New("group ID not provided")
+		return InvalidID("groupId")
 	}
 	updateGroup := &model.Group{}
 	updateGroup.ID = group.ID
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// Make sure record exists
-		queryRs := db.First(&updateGroup)
+		queryRs := tx.First(&updateGroup)
 		if err := queryRs.Error; err != nil {
 			return err
 		}
 
+		before := *updateGroup
+
 		// Update fields
 		err := queryRs.Omit(clause.Associations).Updates(group).Error
-		return err
+		if err != nil {
+			return err
+		}
+
+		after := &model.Group{}
+		if err = tx.First(after, group.ID).Error; err != nil {
+			return err
+		}
+		return appendActivity(tx, model.NewGroupActivity(&before, after))
 	})
 
-	return err
+	return translateError(err, "group")
 }
 
 func (repository GroupRepositoryImpl) Delete(groupId uint) error {
 	db := repository.DB
 	if groupId == 0 {
-		return errors.New("group ID not provided")
+		return InvalidID("groupId")
 	}
 
 	return db.Delete(&model.Group{}, groupId).Error
 }
 
+func (repository GroupRepositoryImpl) UpdateTotals(groupID uint, total float32, average float32, memberTotals map[uint]float32) error {
+	db := repository.DB
+	if groupID == 0 {
+		return InvalidID("groupId")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Group{}).Where("id = ?", groupID).Updates(map[string]interface{}{
+			"total_expense":   total,
+			"average_expense": average,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return NotFound("group")
+		}
+		for userID, memberTotal := range memberTotals {
+			err := tx.Model(&model.Member{}).Where("user_id = ? AND group_id = ?", userID, groupID).
+				Update("total_expense", memberTotal).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (repository GroupRepositoryImpl) GetMemberRole(memberID uint, groupID uint) (role string, err error) {
 	db := repository.DB
 	if memberID <= 0 || groupID <= 0 {
-		err = errors.New("member ID or group ID must be greater than 0")
+		err = InvalidID("memberId")
 		return
 	}
 
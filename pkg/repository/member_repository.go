@@ -1,11 +1,19 @@
 package repository
 
-import "money_share/pkg/model"
+import (
+	"context"
+	"money_share/pkg/model"
+)
 
 type MemberRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) MemberRepository
 	GetByID(userID uint, groupID uint) (*model.Member, error)
 	GetByGroup(groupID uint) ([]*model.Member, error)
+	// ListByGroup returns a page of the members of a group ordered by user ID
+	ListByGroup(groupID uint, page PageRequest) (Page[*model.Member], error)
 	AddMemberToGroup(userID uint, groupID uint) error
 	RemoveMemberFromGroup(userID uint, groupID uint) error
+	UpdateRole(userID uint, groupID uint, role string) error
 	IncreaseTotalExpense(userID uint, groupID uint, updateValue float32) error
 }
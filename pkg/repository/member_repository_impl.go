@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"gorm.io/gorm"
 	"money_share/pkg/model"
 )
@@ -13,11 +14,15 @@ func NewMemberRepository(db *gorm.DB) MemberRepository {
 	return MemberRepositoryImpl{db}
 }
 
+func (repository MemberRepositoryImpl) WithContext(ctx context.Context) MemberRepository {
+	return MemberRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
 func (repository MemberRepositoryImpl) GetByID(userID uint, groupID uint) (*model.Member, error) {
 	db := repository.DB
 	member := &model.Member{}
 	err := db.Where("user_id = ? AND group_id = ?", userID, groupID).Preload("User").First(member).Error
-	return member, err
+	return member, translateError(err, "member")
 }
 
 func (repository MemberRepositoryImpl) GetByGroup(groupID uint) ([]*model.Member, error) {
@@ -27,20 +32,92 @@ func (repository MemberRepositoryImpl) GetByGroup(groupID uint) ([]*model.Member
 	return members, err
 }
 
+func (repository MemberRepositoryImpl) ListByGroup(groupID uint, page PageRequest) (Page[*model.Member], error) {
+	db := repository.DB
+	result := Page[*model.Member]{}
+	if err := page.Validate(); err != nil {
+		return result, err
+	}
+	cursor, err := DecodeCursor(page.Cursor, "userId")
+	if err != nil {
+		return result, err
+	}
+
+	query := db.Where("group_id = ?", groupID)
+	if cursor != nil {
+		query = query.Where("user_id > ?", cursor.ID)
+	}
+	var members []*model.Member
+	if err = query.Preload("User").Order("user_id").Limit(page.Limit + 1).Find(&members).Error; err != nil {
+		return result, err
+	}
+	return PageOf(members, page.Limit, func(member *model.Member) Cursor {
+		return Cursor{Sort: "userId", ID: member.UserID}
+	}), nil
+}
+
 func (repository MemberRepositoryImpl) AddMemberToGroup(userID uint, groupID uint) error {
 	db := repository.DB
 	member := &model.Member{
 		UserID:  userID,
 		GroupID: groupID,
 	}
-	err := db.Create(member).Error
-	return err
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		// The group average depends on the number of members
+		if err := model.UpdateGroupTotals(tx, groupID); err != nil {
+			return err
+		}
+		added := &model.Member{}
+		if err := tx.Where("user_id = ? AND group_id = ?", userID, groupID).First(added).Error; err != nil {
+			return err
+		}
+		return appendActivity(tx, model.NewMemberActivity(model.ActionMemberAdded, nil, added))
+	})
+	return translateError(err, "member")
 }
 
 func (repository MemberRepositoryImpl) RemoveMemberFromGroup(userID uint, groupID uint) error {
 	db := repository.DB
-	err := db.Where("user_id = ? AND group_id = ?", userID, groupID).Delete(&model.Member{}).Error
-	return err
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Removing a member who is not in the group changes nothing
+		removed := &model.Member{}
+		err := tx.Where("user_id = ? AND group_id = ?", userID, groupID).Limit(1).Find(removed).Error
+		if err != nil || removed.UserID == 0 {
+			return err
+		}
+		// Expenses of the member are detached from the group by the foreign key
+		err = tx.Where("user_id = ? AND group_id = ?", userID, groupID).Delete(&model.Member{}).Error
+		if err != nil {
+			return err
+		}
+		if err = model.UpdateGroupTotals(tx, groupID); err != nil {
+			return err
+		}
+		return appendActivity(tx, model.NewMemberActivity(model.ActionMemberRemoved, removed, nil))
+	})
+}
+
+func (repository MemberRepositoryImpl) UpdateRole(userID uint, groupID uint, role string) error {
+	db := repository.DB
+	if err := model.ValidateRole(role); err != nil {
+		return err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		member := &model.Member{}
+		if err := tx.Where("user_id = ? AND group_id = ?", userID, groupID).First(member).Error; err != nil {
+			return err
+		}
+		before := *member
+		if err := tx.Model(member).Where("user_id = ? AND group_id = ?", userID, groupID).Update("role", role).Error; err != nil {
+			return err
+		}
+		member.Role = role
+		return appendActivity(tx, model.NewMemberActivity(model.ActionMemberRoleChanged, &before, member))
+	})
+	return translateError(err, "member")
 }
 
 func (repository MemberRepositoryImpl) IncreaseTotalExpense(userID uint, groupID uint, updateValue float32) error {
@@ -55,5 +132,5 @@ func (repository MemberRepositoryImpl) IncreaseTotalExpense(userID uint, groupID
 		err = tx.Model(member).Update("total_expense", newExpense).Error
 		return err
 	})
-	return err
+	return translateError(err, "member")
 }
@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+)
+
+type ActivityRepository struct {
+	Store *Store
+}
+
+func NewActivityRepository(store *Store) repository.ActivityRepository {
+	return ActivityRepository{Store: store}
+}
+
+func (repository ActivityRepository) WithContext(ctx context.Context) repository.ActivityRepository {
+	return repository
+}
+
+func (repository ActivityRepository) ListByGroup(groupID uint, page repository.PageRequest) (repository.Page[*model.Activity], error) {
+	return repository.Store.pageOfActivities(groupID, page)
+}
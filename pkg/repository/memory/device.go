@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sort"
+)
+
+type DeviceRepository struct {
+	Store *Store
+}
+
+func NewDeviceRepository(store *Store) repository.DeviceRepository {
+	return DeviceRepository{Store: store}
+}
+
+func (repository DeviceRepository) WithContext(ctx context.Context) repository.DeviceRepository {
+	return repository
+}
+
+func (repository DeviceRepository) Register(device *model.Device) error {
+	if device.UserID <= 0 {
+		return invalidID("userId")
+	}
+	if err := device.ValidateFields(); err != nil {
+		return err
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Like the foreign key, which soft deleted users still satisfy
+	if _, ok := s.users[device.UserID]; !ok {
+		return missingReference()
+	}
+	now := s.Clock()
+	for id, registered := range s.devices {
+		if registered.Token == device.Token {
+			device.ID = id
+			device.CreatedAt = registered.CreatedAt
+			device.UpdatedAt = now
+			s.devices[id] = *device
+			return nil
+		}
+	}
+	device.ID = s.nextID()
+	device.CreatedAt = now
+	device.UpdatedAt = now
+	s.devices[device.ID] = *device
+	return nil
+}
+
+func (repository DeviceRepository) ListByUser(userID uint) ([]*model.Device, error) {
+	if userID <= 0 {
+		return nil, invalidID("userId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var devices []*model.Device
+	for _, device := range s.devices {
+		if device.UserID == userID {
+			device := device
+			devices = append(devices, &device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+	return devices, nil
+}
+
+func (repository DeviceRepository) Delete(userID uint, deviceID uint) error {
+	if userID <= 0 {
+		return invalidID("userId")
+	}
+	if deviceID <= 0 {
+		return invalidID("deviceId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	device, ok := s.devices[deviceID]
+	if !ok || device.UserID != userID {
+		return notFound("device")
+	}
+	delete(s.devices, deviceID)
+	return nil
+}
+
+func (repository DeviceRepository) DeleteByToken(token string) error {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, device := range s.devices {
+		if device.Token == token {
+			delete(s.devices, id)
+		}
+	}
+	return nil
+}
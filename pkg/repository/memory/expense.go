@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+)
+
+type ExpenseRepository struct {
+	Store *Store
+	// ctx carries the actor of the changes recorded in the activity log
+	ctx context.Context
+}
+
+func NewExpenseRepository(store *Store) repository.ExpenseRepository {
+	return ExpenseRepository{Store: store}
+}
+
+func (repository ExpenseRepository) WithContext(ctx context.Context) repository.ExpenseRepository {
+	repository.ctx = ctx
+	return repository
+}
+
+func (repository ExpenseRepository) GetById(expenseId uint) (*model.Expense, error) {
+	s := repository.Store
+	if expenseId <= 0 {
+		return nil, invalidID("expenseId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expense, ok := s.expenses[expenseId]
+	if !ok || expense.DeletedAt.Valid {
+		return &model.Expense{}, notFound("expense")
+	}
+	return &expense, nil
+}
+
+func (repository ExpenseRepository) GetByGroup(groupId uint) ([]*model.Expense, error) {
+	s := repository.Store
+	if groupId <= 0 {
+		return nil, invalidID("groupId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expensesWhere(func(expense model.Expense) bool { return expense.GroupID == groupId }), nil
+}
+
+func (repository ExpenseRepository) GetByMember(memberId uint, groupId uint) ([]*model.Expense, error) {
+	s := repository.Store
+	if memberId <= 0 || groupId <= 0 {
+		return nil, invalidID("memberId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expensesWhere(func(expense model.Expense) bool {
+		return expense.GroupID == groupId && expense.MemberID == memberId
+	}), nil
+}
+
+func (repository ExpenseRepository) List(filter repository.ExpenseFilter, page repository.PageRequest) (repository.Page[*model.Expense], error) {
+	return repository.Store.listExpenses(filter, page)
+}
+
+func (repository ExpenseRepository) Search(search repository.ExpenseSearch, page repository.PageRequest) (repository.Page[*repository.ExpenseMatch], error) {
+	return repository.Store.searchExpenses(search, page)
+}
+
+func (repository ExpenseRepository) Create(expense *model.Expense) error {
+	s := repository.Store
+	// Validate fields
+	if err := expense.ValidateFields(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.members[memberKey{expense.MemberID, expense.GroupID}]; !ok {
+		return missingReference()
+	}
+	if expense.ID == 0 {
+		expense.ID = s.nextID()
+	} else if _, ok := s.expenses[expense.ID]; ok {
+		return conflict("expense")
+	}
+	if expense.Status == "" {
+		expense.Status = "pending"
+	}
+	now := s.Clock()
+	if expense.CreatedAt.IsZero() {
+		expense.CreatedAt = now
+	}
+	if expense.UpdatedAt.IsZero() {
+		expense.UpdatedAt = now
+	}
+	s.expenses[expense.ID] = *expense
+	s.updateGroupTotals(expense.GroupID)
+	s.appendActivity(repository.ctx, model.NewExpenseActivity(model.ActionExpenseCreated, nil, expense))
+	return nil
+}
+
+func (repository ExpenseRepository) Update(expense *model.Expense) (string, error) {
+	s := repository.Store
+	// Validate fields
+	if expense.ID <= 0 {
+		return "", invalidID("expenseId")
+	}
+	if err := model.ValidateAmount(expense.Amount); err != nil {
+		return "", err
+	}
+	if len(expense.Status) > 0 {
+		if err := model.ValidateStatus(expense.Status); err != nil {
+			return "", err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Make sure record exists
+	updateExpense, ok := s.expenses[expense.ID]
+	if !ok || updateExpense.DeletedAt.Valid {
+		return "", notFound("expense")
+	}
+	before := updateExpense
+	// Update non-zero fields, member and group cannot change
+	if expense.Title != "" {
+		updateExpense.Title = expense.Title
+	}
+	if expense.Description != "" {
+		updateExpense.Description = expense.Description
+	}
+	if expense.Amount != 0 {
+		updateExpense.Amount = expense.Amount
+	}
+	if !expense.PurchaseTime.IsZero() {
+		updateExpense.PurchaseTime = expense.PurchaseTime
+	}
+	if expense.Status != "" {
+		updateExpense.Status = expense.Status
+	}
+	updateExpense.UpdatedAt = s.Clock()
+	s.expenses[expense.ID] = updateExpense
+	s.updateGroupTotals(updateExpense.GroupID)
+	action := model.ExpenseUpdateAction(&before, &updateExpense)
+	s.appendActivity(repository.ctx, model.NewExpenseActivity(action, &before, &updateExpense))
+	return action, nil
+}
+
+func (repository ExpenseRepository) Delete(expenseId uint) error {
+	s := repository.Store
+	if expenseId <= 0 {
+		return invalidID("expenseId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expense, ok := s.expenses[expenseId]
+	if !ok || expense.DeletedAt.Valid {
+		return notFound("expense")
+	}
+	expense.DeletedAt = softDelete(s.Clock)
+	s.expenses[expenseId] = expense
+	s.updateGroupTotals(expense.GroupID)
+	s.appendActivity(repository.ctx, model.NewExpenseActivity(model.ActionExpenseDeleted, &expense, nil))
+	return nil
+}
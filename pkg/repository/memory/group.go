@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sort"
+)
+
+type GroupRepository struct {
+	Store *Store
+	// ctx carries the actor of the changes recorded in the activity log
+	ctx context.Context
+}
+
+func NewGroupRepository(store *Store) repository.GroupRepository {
+	return GroupRepository{Store: store}
+}
+
+func (repository GroupRepository) WithContext(ctx context.Context) repository.GroupRepository {
+	repository.ctx = ctx
+	return repository
+}
+
+func (repository GroupRepository) GetById(groupId uint) (*model.Group, error) {
+	s := repository.Store
+	if groupId <= 0 {
+		return &model.Group{}, invalidID("groupId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.activeGroup(groupId)
+	if !ok {
+		return &model.Group{}, notFound("group")
+	}
+	// Preload members with their user
+	group.Members = nil
+	for _, member := range s.membersOfGroup(groupId) {
+		group.Members = append(group.Members, *s.memberWithUser(member))
+	}
+	group.Expenses = nil
+	return &group, nil
+}
+
+func (repository GroupRepository) GetWithExpenses(groupId uint) (*model.Group, error) {
+	group, err := repository.GetById(groupId)
+	if err != nil {
+		return group, err
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, expense := range s.expensesWhere(func(expense model.Expense) bool { return expense.GroupID == groupId }) {
+		group.Expenses = append(group.Expenses, *expense)
+	}
+	return group, nil
+}
+
+func (repository GroupRepository) GetByUser(userId uint) ([]*model.Group, error) {
+	s := repository.Store
+	if userId <= 0 {
+		return nil, invalidID("userId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var groups []*model.Group
+	for key := range s.members {
+		if key.UserID != userId {
+			continue
+		}
+		if group, ok := s.activeGroup(key.GroupID); ok {
+			groups = append(groups, &group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups, nil
+}
+
+func (repository GroupRepository) ListByUser(userID uint, page repository.PageRequest) (result repository.Page[*model.Group], err error) {
+	groups, err := repository.GetByUser(userID)
+	if err != nil {
+		return
+	}
+	return repository.Store.pageOfGroups(groups, page)
+}
+
+func (repository GroupRepository) Create(group *model.Group, creatorID uint) error {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Validate creator existence
+	creator, ok := s.activeUser(creatorID)
+	if !ok {
+		return notFound("user")
+	}
+	// Create group
+	if group.ID == 0 {
+		group.ID = s.nextID()
+	} else if _, ok := s.groups[group.ID]; ok {
+		return conflict("group")
+	}
+	now := s.Clock()
+	if group.CreatedAt.IsZero() {
+		group.CreatedAt = now
+	}
+	if group.UpdatedAt.IsZero() {
+		group.UpdatedAt = now
+	}
+	saved := *group
+	saved.Members, saved.Expenses = nil, nil
+	s.groups[group.ID] = saved
+
+	// Create member object from creator
+	member := model.Member{
+		TotalExpense: 0,
+		UserID:       creator.ID,
+		GroupID:      group.ID,
+		Role:         model.RoleManager,
+	}
+	s.members[memberKey{member.UserID, member.GroupID}] = member
+	group.Members = append(group.Members, member)
+	return nil
+}
+
+func (repository GroupRepository) Update(group *model.Group) error {
+	s := repository.Store
+	if group.ID == 0 {
+		return invalidID("groupId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Make sure record exists
+	updateGroup, ok := s.activeGroup(group.ID)
+	if !ok {
+		return notFound("group")
+	}
+	before := updateGroup
+	// Update non-zero fields, like gorm does when updating from a struct
+	if group.Name != "" {
+		updateGroup.Name = group.Name
+	}
+	if group.GroupImageUrl != "" {
+		updateGroup.GroupImageUrl = group.GroupImageUrl
+	}
+	if group.TotalExpense != 0 {
+		updateGroup.TotalExpense = group.TotalExpense
+	}
+	if group.AverageExpense != 0 {
+		updateGroup.AverageExpense = group.AverageExpense
+	}
+	updateGroup.UpdatedAt = s.Clock()
+	s.groups[group.ID] = updateGroup
+	s.appendActivity(repository.ctx, model.NewGroupActivity(&before, &updateGroup))
+	return nil
+}
+
+func (repository GroupRepository) Delete(groupId uint) error {
+	s := repository.Store
+	if groupId == 0 {
+		return invalidID("groupId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if group, ok := s.activeGroup(groupId); ok {
+		group.DeletedAt = softDelete(s.Clock)
+		s.groups[groupId] = group
+	}
+	return nil
+}
+
+func (repository GroupRepository) GetMemberRole(memberID uint, groupID uint) (role string, err error) {
+	s := repository.Store
+	if memberID <= 0 || groupID <= 0 {
+		err = invalidID("memberId")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role = s.members[memberKey{memberID, groupID}].Role
+	return
+}
+
+func (repository GroupRepository) UpdateTotals(groupID uint, total float32, average float32, memberTotals map[uint]float32) error {
+	s := repository.Store
+	if groupID == 0 {
+		return invalidID("groupId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.activeGroup(groupID)
+	if !ok {
+		return notFound("group")
+	}
+	group.TotalExpense, group.AverageExpense = total, average
+	group.UpdatedAt = s.Clock()
+	s.groups[groupID] = group
+	for userID, memberTotal := range memberTotals {
+		key := memberKey{userID, groupID}
+		if member, ok := s.members[key]; ok {
+			member.TotalExpense = memberTotal
+			s.members[key] = member
+		}
+	}
+	return nil
+}
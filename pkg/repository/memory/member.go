@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+)
+
+type MemberRepository struct {
+	Store *Store
+	// ctx carries the actor of the changes recorded in the activity log
+	ctx context.Context
+}
+
+func NewMemberRepository(store *Store) repository.MemberRepository {
+	return MemberRepository{Store: store}
+}
+
+func (repository MemberRepository) WithContext(ctx context.Context) repository.MemberRepository {
+	repository.ctx = ctx
+	return repository
+}
+
+func (repository MemberRepository) GetByID(userID uint, groupID uint) (*model.Member, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	member, ok := s.members[memberKey{userID, groupID}]
+	if !ok {
+		return &model.Member{}, notFound("member")
+	}
+	return s.memberWithUser(member), nil
+}
+
+func (repository MemberRepository) GetByGroup(groupID uint) ([]*model.Member, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var members []*model.Member
+	for _, member := range s.membersOfGroup(groupID) {
+		members = append(members, s.memberWithUser(member))
+	}
+	return members, nil
+}
+
+func (repository MemberRepository) ListByGroup(groupID uint, page repository.PageRequest) (repository.Page[*model.Member], error) {
+	members, _ := repository.GetByGroup(groupID)
+	return repository.Store.pageOfMembers(members, page)
+}
+
+func (repository MemberRepository) AddMemberToGroup(userID uint, groupID uint) error {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Foreign keys reference rows, which soft deleted users and groups still are
+	if _, ok := s.users[userID]; !ok {
+		return missingReference()
+	}
+	if _, ok := s.groups[groupID]; !ok {
+		return missingReference()
+	}
+	key := memberKey{userID, groupID}
+	if _, ok := s.members[key]; ok {
+		return conflict("member")
+	}
+	added := model.Member{UserID: userID, GroupID: groupID, Role: model.RoleMember}
+	s.members[key] = added
+	// The group average depends on the number of members
+	s.updateGroupTotals(groupID)
+	s.appendActivity(repository.ctx, model.NewMemberActivity(model.ActionMemberAdded, nil, &added))
+	return nil
+}
+
+func (repository MemberRepository) RemoveMemberFromGroup(userID uint, groupID uint) error {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := memberKey{userID, groupID}
+	removed, ok := s.members[key]
+	if !ok {
+		return nil
+	}
+	delete(s.members, key)
+	// Expenses of the member are detached from the group by the foreign key
+	for id, expense := range s.expenses {
+		if expense.MemberID == userID && expense.GroupID == groupID {
+			expense.MemberID, expense.GroupID = 0, 0
+			s.expenses[id] = expense
+		}
+	}
+	s.updateGroupTotals(groupID)
+	s.appendActivity(repository.ctx, model.NewMemberActivity(model.ActionMemberRemoved, &removed, nil))
+	return nil
+}
+
+func (repository MemberRepository) UpdateRole(userID uint, groupID uint, role string) error {
+	s := repository.Store
+	if err := model.ValidateRole(role); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := memberKey{userID, groupID}
+	member, ok := s.members[key]
+	if !ok {
+		return notFound("member")
+	}
+	before := member
+	member.Role = role
+	s.members[key] = member
+	s.appendActivity(repository.ctx, model.NewMemberActivity(model.ActionMemberRoleChanged, &before, &member))
+	return nil
+}
+
+func (repository MemberRepository) IncreaseTotalExpense(userID uint, groupID uint, updateValue float32) error {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := memberKey{userID, groupID}
+	member, ok := s.members[key]
+	if !ok {
+		return notFound("member")
+	}
+	member.TotalExpense += updateValue
+	s.members[key] = member
+	return nil
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sort"
+	"time"
+)
+
+type NotificationRepository struct {
+	Store *Store
+}
+
+func NewNotificationRepository(store *Store) repository.NotificationRepository {
+	return NotificationRepository{Store: store}
+}
+
+func (repository NotificationRepository) WithContext(ctx context.Context) repository.NotificationRepository {
+	return repository
+}
+
+func (repository NotificationRepository) ListByUser(userID uint, unreadOnly bool, page repository.PageRequest) (repository.Page[*model.Notification], error) {
+	return repository.Store.pageOfNotifications(userID, unreadOnly, page)
+}
+
+func (repository NotificationRepository) CountUnread(userID uint) (int64, error) {
+	if userID <= 0 {
+		return 0, invalidID("userId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var count int64
+	for _, notification := range s.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (repository NotificationRepository) MarkRead(userID uint, notificationID uint) error {
+	if userID <= 0 {
+		return invalidID("userId")
+	}
+	if notificationID <= 0 {
+		return invalidID("notificationId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, notification := range s.notifications {
+		if notification.ID == notificationID && notification.UserID == userID {
+			if notification.ReadAt == nil {
+				now := s.Clock()
+				s.notifications[i].ReadAt = &now
+			}
+			return nil
+		}
+	}
+	return notFound("notification")
+}
+
+func (repository NotificationRepository) MarkAllRead(userID uint) error {
+	if userID <= 0 {
+		return invalidID("userId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Clock()
+	for i, notification := range s.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			s.notifications[i].ReadAt = &now
+		}
+	}
+	return nil
+}
+
+func (repository NotificationRepository) ListUnpushed(limit int) ([]*model.Notification, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var notifications []*model.Notification
+	for _, notification := range s.notifications {
+		if notification.PushedAt == nil && len(notifications) < limit {
+			notification := notification
+			notifications = append(notifications, &notification)
+		}
+	}
+	return notifications, nil
+}
+
+func (repository NotificationRepository) ClaimPush(notificationID uint) (bool, error) {
+	if notificationID <= 0 {
+		return false, invalidID("notificationId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, notification := range s.notifications {
+		if notification.ID == notificationID && notification.PushedAt == nil {
+			now := s.Clock()
+			s.notifications[i].PushedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repository NotificationRepository) SavePushRetry(retry *model.PushRetry) error {
+	if retry.NotificationID <= 0 {
+		return invalidID("notificationId")
+	}
+	if retry.DeviceID <= 0 {
+		return invalidID("deviceId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if retry.ID != 0 {
+		if saved, ok := s.pushRetries[retry.ID]; ok {
+			saved.Attempts, saved.RetryAt = retry.Attempts, retry.RetryAt
+			s.pushRetries[retry.ID] = saved
+		}
+		return nil
+	}
+	if _, ok := s.devices[retry.DeviceID]; !ok || s.notification(retry.NotificationID) == nil {
+		return missingReference()
+	}
+	retry.ID = s.nextID()
+	saved := *retry
+	saved.Notification, saved.Device = nil, nil
+	s.pushRetries[retry.ID] = saved
+	return nil
+}
+
+func (repository NotificationRepository) ClaimPushRetries(limit int, lease time.Duration) ([]*model.PushRetry, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Clock()
+	var due []model.PushRetry
+	for id, retry := range s.pushRetries {
+		// Like the foreign keys, retries of removed devices are removed with them
+		if _, ok := s.devices[retry.DeviceID]; !ok {
+			delete(s.pushRetries, id)
+			continue
+		}
+		if !retry.RetryAt.After(now) {
+			due = append(due, retry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].RetryAt.Equal(due[j].RetryAt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].RetryAt.Before(due[j].RetryAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]*model.PushRetry, 0, len(due))
+	for _, retry := range due {
+		saved := retry
+		saved.RetryAt = now.Add(lease)
+		s.pushRetries[retry.ID] = saved
+		notification := *s.notification(retry.NotificationID)
+		device := s.devices[retry.DeviceID]
+		retry.Notification, retry.Device = &notification, &device
+		claimed = append(claimed, &retry)
+	}
+	return claimed, nil
+}
+
+func (repository NotificationRepository) DeletePushRetry(retryID uint) error {
+	if retryID <= 0 {
+		return invalidID("retryId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pushRetries, retryID)
+	return nil
+}
+
+func (repository NotificationRepository) GetPreferences(userID uint) (map[string]bool, error) {
+	if userID <= 0 {
+		return nil, invalidID("userId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stored []model.NotificationPreference
+	for key, enabled := range s.preferences {
+		if key.UserID == userID {
+			stored = append(stored, model.NotificationPreference{UserID: userID, Type: key.Type, Enabled: enabled})
+		}
+	}
+	return model.MergeNotificationPreferences(stored), nil
+}
+
+func (repository NotificationRepository) UpdatePreferences(userID uint, preferences map[string]bool) error {
+	if userID <= 0 {
+		return invalidID("userId")
+	}
+	for notificationType := range preferences {
+		if err := model.ValidateNotificationType(notificationType); err != nil {
+			return err
+		}
+	}
+	if len(preferences) == 0 {
+		return nil
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Like the foreign key, which soft deleted users still satisfy
+	if _, ok := s.users[userID]; !ok {
+		return missingReference()
+	}
+	for notificationType, enabled := range preferences {
+		s.preferences[preferenceKey{userID, notificationType}] = enabled
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package memory
+
+import (
+	"money_share/pkg/apperror"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sort"
+	"strings"
+	"time"
+)
+
+// paginate returns the page of items, which are in list order, following the cursor.
+// after reports whether an item follows the cursor, cursorOf returns the cursor of an item.
+func paginate[T any](items []T, page repository.PageRequest, after func(item T) bool, cursorOf func(item T) repository.Cursor) repository.Page[T] {
+	var rows []T
+	for _, item := range items {
+		if !after(item) {
+			continue
+		}
+		rows = append(rows, item)
+		if len(rows) > page.Limit {
+			break
+		}
+	}
+	return repository.PageOf(rows, page.Limit, cursorOf)
+}
+
+// pageOfGroups pages groups ordered by ID like the gorm implementation
+func (s *Store) pageOfGroups(groups []*model.Group, page repository.PageRequest) (repository.Page[*model.Group], error) {
+	if err := page.Validate(); err != nil {
+		return repository.Page[*model.Group]{}, err
+	}
+	cursor, err := repository.DecodeCursor(page.Cursor, "id")
+	if err != nil {
+		return repository.Page[*model.Group]{}, err
+	}
+	return paginate(groups, page, func(group *model.Group) bool {
+		return cursor == nil || group.ID > cursor.ID
+	}, func(group *model.Group) repository.Cursor {
+		return repository.Cursor{Sort: "id", ID: group.ID}
+	}), nil
+}
+
+// pageOfMembers pages members ordered by user ID like the gorm implementation
+func (s *Store) pageOfMembers(members []*model.Member, page repository.PageRequest) (repository.Page[*model.Member], error) {
+	if err := page.Validate(); err != nil {
+		return repository.Page[*model.Member]{}, err
+	}
+	cursor, err := repository.DecodeCursor(page.Cursor, "userId")
+	if err != nil {
+		return repository.Page[*model.Member]{}, err
+	}
+	return paginate(members, page, func(member *model.Member) bool {
+		return cursor == nil || member.UserID > cursor.ID
+	}, func(member *model.Member) repository.Cursor {
+		return repository.Cursor{Sort: "userId", ID: member.UserID}
+	}), nil
+}
+
+// pageOfActivities pages the activities of a group newest first like the gorm implementation
+func (s *Store) pageOfActivities(groupID uint, page repository.PageRequest) (repository.Page[*model.Activity], error) {
+	if groupID <= 0 {
+		return repository.Page[*model.Activity]{}, invalidID("groupId")
+	}
+	if err := page.Validate(); err != nil {
+		return repository.Page[*model.Activity]{}, err
+	}
+	cursor, err := repository.DecodeCursor(page.Cursor, "id:desc")
+	if err != nil {
+		return repository.Page[*model.Activity]{}, err
+	}
+
+	s.mu.Lock()
+	var activities []*model.Activity
+	for i := len(s.activities) - 1; i >= 0; i-- {
+		if s.activities[i].GroupID == groupID {
+			activity := s.activities[i]
+			activities = append(activities, &activity)
+		}
+	}
+	s.mu.Unlock()
+	return paginate(activities, page, func(activity *model.Activity) bool {
+		return cursor == nil || activity.ID < cursor.ID
+	}, repository.ActivityCursor), nil
+}
+
+// pageOfPayments pages the payments of a group newest first like the gorm implementation
+func (s *Store) pageOfPayments(groupID uint, page repository.PageRequest) (repository.Page[*model.Payment], error) {
+	if groupID <= 0 {
+		return repository.Page[*model.Payment]{}, invalidID("groupId")
+	}
+	if err := page.Validate(); err != nil {
+		return repository.Page[*model.Payment]{}, err
+	}
+	cursor, err := repository.DecodeCursor(page.Cursor, "id:desc")
+	if err != nil {
+		return repository.Page[*model.Payment]{}, err
+	}
+
+	s.mu.Lock()
+	var payments []*model.Payment
+	for i := len(s.payments) - 1; i >= 0; i-- {
+		if s.payments[i].GroupID == groupID {
+			payment := s.payments[i]
+			payments = append(payments, &payment)
+		}
+	}
+	s.mu.Unlock()
+	return paginate(payments, page, func(payment *model.Payment) bool {
+		return cursor == nil || payment.ID < cursor.ID
+	}, repository.PaymentCursor), nil
+}
+
+// pageOfNotifications pages the notifications of a user newest first like the gorm implementation
+func (s *Store) pageOfNotifications(userID uint, unreadOnly bool, page repository.PageRequest) (repository.Page[*model.Notification], error) {
+	if userID <= 0 {
+		return repository.Page[*model.Notification]{}, invalidID("userId")
+	}
+	if err := page.Validate(); err != nil {
+		return repository.Page[*model.Notification]{}, err
+	}
+	cursor, err := repository.DecodeCursor(page.Cursor, "id:desc")
+	if err != nil {
+		return repository.Page[*model.Notification]{}, err
+	}
+
+	s.mu.Lock()
+	var notifications []*model.Notification
+	for i := len(s.notifications) - 1; i >= 0; i-- {
+		if s.notifications[i].UserID == userID && (!unreadOnly || s.notifications[i].ReadAt == nil) {
+			notification := s.notifications[i]
+			notifications = append(notifications, &notification)
+		}
+	}
+	s.mu.Unlock()
+	return paginate(notifications, page, func(notification *model.Notification) bool {
+		return cursor == nil || notification.ID < cursor.ID
+	}, repository.NotificationCursor), nil
+}
+
+// pageOfDeliveries pages the deliveries of a webhook newest first like the gorm implementation
+func (s *Store) pageOfDeliveries(webhookID uint, page repository.PageRequest) (repository.Page[*model.WebhookDelivery], error) {
+	if webhookID <= 0 {
+		return repository.Page[*model.WebhookDelivery]{}, invalidID("webhookId")
+	}
+	if err := page.Validate(); err != nil {
+		return repository.Page[*model.WebhookDelivery]{}, err
+	}
+	cursor, err := repository.DecodeCursor(page.Cursor, "id:desc")
+	if err != nil {
+		return repository.Page[*model.WebhookDelivery]{}, err
+	}
+
+	s.mu.Lock()
+	var deliveries []*model.WebhookDelivery
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if s.deliveries[i].WebhookID == webhookID {
+			delivery := s.deliveries[i]
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	s.mu.Unlock()
+	return paginate(deliveries, page, func(delivery *model.WebhookDelivery) bool {
+		return cursor == nil || delivery.ID < cursor.ID
+	}, repository.DeliveryCursor), nil
+}
+
+// listExpenses filters, orders and pages expenses like the gorm implementation
+func (s *Store) listExpenses(filter repository.ExpenseFilter, page repository.PageRequest) (repository.Page[*model.Expense], error) {
+	if err := apperror.Join(filter.Validate(), page.Validate()); err != nil {
+		return repository.Page[*model.Expense]{}, err
+	}
+	cursor, err := repository.DecodeCursor(page.Cursor, filter.CursorKey())
+	if err != nil {
+		return repository.Page[*model.Expense]{}, err
+	}
+	var cursorValue interface{}
+	if cursor != nil {
+		if cursorValue, err = filter.SortValue(cursor); err != nil {
+			return repository.Page[*model.Expense]{}, err
+		}
+	}
+
+	s.mu.Lock()
+	expenses := s.expensesWhere(func(expense model.Expense) bool {
+		return expense.GroupID == filter.GroupID &&
+			(filter.MemberID == 0 || expense.MemberID == filter.MemberID) &&
+			(filter.Status == "" || expense.Status == filter.Status) &&
+			(filter.PurchasedFrom == nil || !expense.PurchaseTime.Before(*filter.PurchasedFrom)) &&
+			(filter.PurchasedTo == nil || !expense.PurchaseTime.After(*filter.PurchasedTo)) &&
+			(filter.MinAmount == nil || expense.Amount >= *filter.MinAmount) &&
+			(filter.MaxAmount == nil || expense.Amount <= *filter.MaxAmount)
+	})
+	s.mu.Unlock()
+
+	// Order by the sort value, then by ID
+	compare := func(expense *model.Expense, value interface{}, id uint) int {
+		result := compareSortValues(expenseSortValue(filter, expense), value)
+		if result == 0 {
+			result = compareSortValues(expense.ID, id)
+		}
+		if filter.Descending {
+			result = -result
+		}
+		return result
+	}
+	sort.SliceStable(expenses, func(i, j int) bool {
+		return compare(expenses[i], expenseSortValue(filter, expenses[j]), expenses[j].ID) < 0
+	})
+	return paginate(expenses, page, func(expense *model.Expense) bool {
+		return cursor == nil || compare(expense, cursorValue, cursor.ID) > 0
+	}, func(expense *model.Expense) repository.Cursor {
+		return repository.ExpenseCursor(filter, expense)
+	}), nil
+}
+
+func expenseSortValue(filter repository.ExpenseFilter, expense *model.Expense) interface{} {
+	switch filter.Sort {
+	case repository.SortAmount:
+		return expense.Amount
+	case repository.SortCreatedAt:
+		return expense.CreatedAt
+	}
+	return expense.PurchaseTime
+}
+
+// compareSortValues compares two times, amounts or IDs
+func compareSortValues(a interface{}, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case float32:
+		b := b.(float32)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	case uint:
+		b := b.(uint)
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+	}
+	return 0
+}
+
+// searchExpenses matches and ranks expenses like the LIKE fallback of the gorm implementation
+func (s *Store) searchExpenses(search repository.ExpenseSearch, page repository.PageRequest) (repository.Page[*repository.ExpenseMatch], error) {
+	result := repository.Page[*repository.ExpenseMatch]{}
+	if err := apperror.Join(search.Validate(), page.Validate()); err != nil {
+		return result, err
+	}
+	offset, err := repository.SearchOffset(page)
+	if err != nil {
+		return result, err
+	}
+	terms := repository.SearchTerms(search.Query)
+
+	s.mu.Lock()
+	expenses := s.expensesWhere(func(expense model.Expense) bool {
+		if _, ok := s.members[memberKey{search.UserID, expense.GroupID}]; !ok {
+			return false
+		}
+		if search.GroupID != 0 && expense.GroupID != search.GroupID {
+			return false
+		}
+		title, description := strings.ToLower(expense.Title), strings.ToLower(expense.Description)
+		for _, term := range terms {
+			if !strings.Contains(title, term) && !strings.Contains(description, term) {
+				return false
+			}
+		}
+		return true
+	})
+	s.mu.Unlock()
+
+	// Most relevant first, then newest
+	matches := make([]*repository.ExpenseMatch, 0, len(expenses))
+	for _, expense := range expenses {
+		matches = append(matches, &repository.ExpenseMatch{
+			Expense: expense,
+			Rank:    repository.FallbackRank(expense, terms),
+			Snippet: repository.FallbackSnippet(expense, terms),
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if !a.Expense.PurchaseTime.Equal(b.Expense.PurchaseTime) {
+			return a.Expense.PurchaseTime.After(b.Expense.PurchaseTime)
+		}
+		return a.Expense.ID > b.Expense.ID
+	})
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	return repository.PageOf(matches[offset:], page.Limit, func(*repository.ExpenseMatch) repository.Cursor {
+		return repository.SearchCursor(offset + page.Limit)
+	}), nil
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+)
+
+type PaymentRepository struct {
+	Store *Store
+	// ctx carries the actor of the changes recorded in the activity log
+	ctx context.Context
+}
+
+func NewPaymentRepository(store *Store) repository.PaymentRepository {
+	return PaymentRepository{Store: store}
+}
+
+func (repository PaymentRepository) WithContext(ctx context.Context) repository.PaymentRepository {
+	repository.ctx = ctx
+	return repository
+}
+
+func (repository PaymentRepository) Create(payment *model.Payment) error {
+	if payment.GroupID <= 0 {
+		return invalidID("groupId")
+	}
+	if payment.PayerID <= 0 {
+		return invalidID("payerId")
+	}
+	if err := payment.ValidateFields(); err != nil {
+		return err
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Foreign keys reference rows, which soft deleted users and groups still are
+	_, payerOK := s.users[payment.PayerID]
+	_, payeeOK := s.users[payment.PayeeID]
+	_, groupOK := s.groups[payment.GroupID]
+	if !payerOK || !payeeOK || !groupOK {
+		return missingReference()
+	}
+	payment.ID = s.nextID()
+	payment.CreatedAt = s.Clock()
+	s.payments = append(s.payments, *payment)
+	s.appendActivity(repository.ctx, model.NewPaymentActivity(payment))
+	return nil
+}
+
+func (repository PaymentRepository) ListByGroup(groupID uint, page repository.PageRequest) (repository.Page[*model.Payment], error) {
+	return repository.Store.pageOfPayments(groupID, page)
+}
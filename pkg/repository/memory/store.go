@@ -0,0 +1,297 @@
+// Package memory implements the repositories in memory, for tests which cannot reach a database.
+// Behaviour mirrors the gorm implementations, including soft deletes, foreign keys and totals.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"money_share/pkg/auth"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateKey is returned where the database would violate a unique constraint
+	ErrDuplicateKey = errors.New("duplicate key value violates unique constraint")
+	// ErrForeignKey is returned where the database would violate a foreign key constraint
+	ErrForeignKey = errors.New("insert or update violates foreign key constraint")
+)
+
+// Errors are translated like those of the gorm implementations, receivers shadow the package name
+
+func notFound(entity string) error {
+	return repository.NotFound(entity)
+}
+
+func conflict(entity string) error {
+	return repository.Conflict(entity, ErrDuplicateKey)
+}
+
+func usernameTaken() error {
+	return repository.UsernameTaken(ErrDuplicateKey)
+}
+
+func missingReference() error {
+	return repository.MissingReference(ErrForeignKey)
+}
+
+func invalidID(field string) error {
+	return repository.InvalidID(field)
+}
+
+type preferenceKey struct {
+	UserID uint
+	Type   string
+}
+
+type memberKey struct {
+	UserID  uint
+	GroupID uint
+}
+
+// Store holds the tables shared by the repositories of one test
+type Store struct {
+	mu       sync.Mutex
+	users    map[uint]model.User
+	groups   map[uint]model.Group
+	members  map[memberKey]model.Member
+	expenses map[uint]model.Expense
+	// activities are in the order they were appended
+	activities []model.Activity
+	// notifications are in the order they were created
+	notifications []model.Notification
+	preferences   map[preferenceKey]bool
+	devices       map[uint]model.Device
+	pushRetries   map[uint]model.PushRetry
+	webhooks      map[uint]model.Webhook
+	// deliveries are in the order they were queued
+	deliveries []model.WebhookDelivery
+	// payments are in the order they were recorded
+	payments []model.Payment
+	lastID   uint
+	// Clock stamps created, updated and deleted times
+	Clock func() time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:       make(map[uint]model.User),
+		groups:      make(map[uint]model.Group),
+		members:     make(map[memberKey]model.Member),
+		expenses:    make(map[uint]model.Expense),
+		preferences: make(map[preferenceKey]bool),
+		devices:     make(map[uint]model.Device),
+		pushRetries: make(map[uint]model.PushRetry),
+		webhooks:    make(map[uint]model.Webhook),
+		Clock:       time.Now,
+	}
+}
+
+// appendActivity records activity like the gorm implementations, the caller holds the lock
+func (s *Store) appendActivity(ctx context.Context, activity model.Activity) {
+	if !activity.Changed() {
+		return
+	}
+	if userID, ok := auth.UserIDFrom(ctx); ok {
+		activity.ActorID = &userID
+	}
+	activity.ID = s.nextID()
+	activity.CreatedAt = s.Clock()
+	s.activities = append(s.activities, activity)
+	s.notify(activity)
+	s.enqueueWebhooks(activity)
+}
+
+// notify creates the notifications of activity like the gorm implementations, the caller holds
+// the lock
+func (s *Store) notify(activity model.Activity) {
+	notifications, err := model.NewNotifications(activity, func() ([]uint, error) {
+		var managers []uint
+		for _, member := range s.membersOfGroup(activity.GroupID) {
+			if member.Role == model.RoleManager {
+				managers = append(managers, member.UserID)
+			}
+		}
+		return managers, nil
+	})
+	if err != nil {
+		return
+	}
+	for _, notification := range notifications {
+		if enabled, ok := s.preferences[preferenceKey{notification.UserID, notification.Type}]; ok && !enabled {
+			continue
+		}
+		notification.ID = s.nextID()
+		notification.CreatedAt = activity.CreatedAt
+		s.notifications = append(s.notifications, notification)
+	}
+}
+
+// enqueueWebhooks queues activity for the webhooks of its group like the gorm implementations,
+// the caller holds the lock
+func (s *Store) enqueueWebhooks(activity model.Activity) {
+	var webhooks []*model.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.GroupID == activity.GroupID {
+			webhook := webhook
+			webhooks = append(webhooks, &webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	for _, delivery := range model.NewWebhookDeliveries(activity, webhooks, s.Clock()) {
+		delivery.ID = s.nextID()
+		delivery.CreatedAt = activity.CreatedAt
+		s.deliveries = append(s.deliveries, delivery)
+	}
+}
+
+func (s *Store) nextID() uint {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *Store) activeUser(userID uint) (model.User, bool) {
+	user, ok := s.users[userID]
+	return user, ok && !user.DeletedAt.Valid
+}
+
+func (s *Store) activeGroup(groupID uint) (model.Group, bool) {
+	group, ok := s.groups[groupID]
+	return group, ok && !group.DeletedAt.Valid
+}
+
+// notification returns the stored notification, nil when there is none
+func (s *Store) notification(notificationID uint) *model.Notification {
+	for i := range s.notifications {
+		if s.notifications[i].ID == notificationID {
+			return &s.notifications[i]
+		}
+	}
+	return nil
+}
+
+// memberWithUser returns a copy of the member with its user loaded, like Preload("User")
+func (s *Store) memberWithUser(member model.Member) *model.Member {
+	if user, ok := s.activeUser(member.UserID); ok {
+		member.User = user
+	}
+	member.Expenses = nil
+	return &member
+}
+
+// membersOfGroup returns the members of the group ordered by user ID
+func (s *Store) membersOfGroup(groupID uint) []model.Member {
+	var members []model.Member
+	for key, member := range s.members {
+		if key.GroupID == groupID {
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	return members
+}
+
+// expensesWhere returns the expenses which are not deleted and match, ordered by ID
+func (s *Store) expensesWhere(match func(expense model.Expense) bool) []*model.Expense {
+	var expenses []*model.Expense
+	for _, expense := range s.expenses {
+		if !expense.DeletedAt.Valid && match(expense) {
+			expense := expense
+			expenses = append(expenses, &expense)
+		}
+	}
+	sort.Slice(expenses, func(i, j int) bool { return expenses[i].ID < expenses[j].ID })
+	return expenses
+}
+
+// updateGroupTotals mirrors model.UpdateGroupTotals
+func (s *Store) updateGroupTotals(groupID uint) {
+	group, ok := s.activeGroup(groupID)
+	if !ok {
+		return
+	}
+	members := s.membersOfGroup(groupID)
+	memberTotals := make(map[uint]float32, len(members))
+	for _, member := range members {
+		memberTotals[member.UserID] = 0
+	}
+	var total float32
+	for _, expense := range s.expensesWhere(func(expense model.Expense) bool {
+		return expense.GroupID == groupID && expense.Status == "approved"
+	}) {
+		total += expense.Amount
+		if _, ok := memberTotals[expense.MemberID]; ok {
+			memberTotals[expense.MemberID] += expense.Amount
+		}
+	}
+	for _, member := range members {
+		member.TotalExpense = memberTotals[member.UserID]
+		s.members[memberKey{member.UserID, groupID}] = member
+	}
+	group.TotalExpense = total
+	group.AverageExpense = 0
+	if len(members) > 0 {
+		group.AverageExpense = total / float32(len(members))
+	}
+	group.UpdatedAt = s.Clock()
+	s.groups[groupID] = group
+}
+
+func softDelete(clock func() time.Time) gorm.DeletedAt {
+	return gorm.DeletedAt{Time: clock(), Valid: true}
+}
+
+var namingStrategy = schema.NamingStrategy{}
+
+// applyUpdateMap sets the fields of dst named by the keys of updateMap, which may be
+// field names or column names like gorm accepts. A nil value sets the zero value.
+func applyUpdateMap(dst interface{}, updateMap map[string]interface{}) error {
+	value := reflect.ValueOf(dst).Elem()
+	for key, update := range updateMap {
+		field, ok := findField(value, key)
+		if !ok {
+			return fmt.Errorf("unknown field '%s'", key)
+		}
+		if update == nil {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		updateValue := reflect.ValueOf(update)
+		switch {
+		case updateValue.Type().AssignableTo(field.Type()):
+			field.Set(updateValue)
+		case field.Kind() == reflect.Ptr && updateValue.Type().AssignableTo(field.Type().Elem()):
+			pointer := reflect.New(field.Type().Elem())
+			pointer.Elem().Set(updateValue)
+			field.Set(pointer)
+		case updateValue.Type().ConvertibleTo(field.Type()):
+			field.Set(updateValue.Convert(field.Type()))
+		default:
+			return fmt.Errorf("cannot assign %T to field '%s'", update, key)
+		}
+	}
+	return nil
+}
+
+func findField(value reflect.Value, key string) (reflect.Value, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		if structField.Anonymous {
+			if field, ok := findField(value.Field(i), key); ok {
+				return field, true
+			}
+			continue
+		}
+		if structField.Name == key || namingStrategy.ColumnName("", structField.Name) == key {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/apperror"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sort"
+	"strings"
+)
+
+type UserRepository struct {
+	Store *Store
+}
+
+func NewUserRepository(store *Store) repository.UserRepository {
+	return UserRepository{store}
+}
+
+func (repository UserRepository) WithContext(ctx context.Context) repository.UserRepository {
+	return repository
+}
+
+func (repository UserRepository) GetById(userId uint) (*model.User, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.activeUser(userId)
+	if !ok {
+		return &model.User{}, notFound("user")
+	}
+	return &user, nil
+}
+
+func (repository UserRepository) GetByUsername(username string) (*model.User, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user.Username == username && !user.DeletedAt.Valid {
+			return &user, nil
+		}
+	}
+	return &model.User{}, notFound("user")
+}
+
+func (repository UserRepository) Find(query string, limit int) ([]*model.User, error) {
+	s := repository.Store
+	if limit <= 0 {
+		return nil, apperror.InvalidField("limit", "limit must be greater than 0")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query = strings.ToLower(query)
+	var users []*model.User
+	for _, user := range s.users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		if strings.Contains(strings.ToLower(user.Username), query) ||
+			strings.Contains(strings.ToLower(user.DisplayName), query) ||
+			strings.Contains(strings.ToLower(user.EmailAddress), query) {
+			user := user
+			users = append(users, &user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (repository UserRepository) CheckUsernameAvailability(username string) (bool, error) {
+	_, err := repository.GetByUsername(username)
+	return apperror.Is(err, apperror.KindNotFound), nil
+}
+
+func (repository UserRepository) ValidateUsernameAndUserID(username string, userID uint) (bool, error) {
+	user, err := repository.GetById(userID)
+	if err != nil {
+		return false, nil
+	}
+	return user.Username == username, nil
+}
+
+func (repository UserRepository) Create(user *model.User) error {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The unique index also covers soft deleted users
+	for _, existing := range s.users {
+		if existing.Username == user.Username {
+			return usernameTaken()
+		}
+	}
+	if user.ID == 0 {
+		user.ID = s.nextID()
+	} else if _, ok := s.users[user.ID]; ok {
+		return conflict("user")
+	}
+	now := s.Clock()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	saved := *user
+	saved.Members = nil
+	s.users[user.ID] = saved
+	return nil
+}
+
+func (repository UserRepository) Update(userID uint, updateMap map[string]interface{}) (*model.User, error) {
+	s := repository.Store
+	if userID == 0 {
+		return &model.User{}, invalidID("userId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.activeUser(userID)
+	if !ok {
+		return &model.User{}, notFound("user")
+	}
+	if err := applyUpdateMap(&user, updateMap); err != nil {
+		return &model.User{}, err
+	}
+	for _, existing := range s.users {
+		if existing.ID != userID && existing.Username == user.Username {
+			return &model.User{}, usernameTaken()
+		}
+	}
+	user.UpdatedAt = s.Clock()
+	s.users[userID] = user
+	return &user, nil
+}
+
+func (repository UserRepository) Delete(userId uint) error {
+	s := repository.Store
+	if userId == 0 {
+		return invalidID("userId")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.activeUser(userId)
+	if !ok {
+		return notFound("user")
+	}
+	user.DeletedAt = softDelete(s.Clock)
+	s.users[userId] = user
+	return nil
+}
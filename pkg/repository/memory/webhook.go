@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"sort"
+	"time"
+)
+
+type WebhookRepository struct {
+	Store *Store
+}
+
+func NewWebhookRepository(store *Store) repository.WebhookRepository {
+	return WebhookRepository{Store: store}
+}
+
+func (repository WebhookRepository) WithContext(ctx context.Context) repository.WebhookRepository {
+	return repository
+}
+
+func (repository WebhookRepository) Create(webhook *model.Webhook) error {
+	if webhook.GroupID <= 0 {
+		return invalidID("groupId")
+	}
+	if err := webhook.ValidateFields(); err != nil {
+		return err
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Like the foreign key, which soft deleted groups still satisfy
+	if _, ok := s.groups[webhook.GroupID]; !ok {
+		return missingReference()
+	}
+	now := s.Clock()
+	webhook.ID = s.nextID()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	webhook.Active = true
+	s.webhooks[webhook.ID] = *webhook
+	return nil
+}
+
+func (repository WebhookRepository) GetByID(groupID uint, webhookID uint) (*model.Webhook, error) {
+	if webhookID <= 0 {
+		return &model.Webhook{}, invalidID("webhookId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhook, ok := s.webhooks[webhookID]
+	if !ok || webhook.GroupID != groupID {
+		return &model.Webhook{}, notFound("webhook")
+	}
+	return &webhook, nil
+}
+
+func (repository WebhookRepository) ListByGroup(groupID uint) ([]*model.Webhook, error) {
+	if groupID <= 0 {
+		return nil, invalidID("groupId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var webhooks []*model.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.GroupID == groupID {
+			webhook := webhook
+			webhooks = append(webhooks, &webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (repository WebhookRepository) Update(webhook *model.Webhook) error {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.webhooks[webhook.ID]
+	if !ok || current.GroupID != webhook.GroupID {
+		return notFound("webhook")
+	}
+	if err := webhook.ValidateFields(); err != nil {
+		return err
+	}
+	now := s.Clock()
+	if webhook.Active && !current.Active {
+		current.ConsecutiveFailures = 0
+		current.DisabledAt = nil
+	}
+	if !webhook.Active && current.Active {
+		current.DisabledAt = &now
+	}
+	current.URL = webhook.URL
+	current.Events = webhook.Events
+	current.Active = webhook.Active
+	current.UpdatedAt = now
+	s.webhooks[current.ID] = current
+	*webhook = current
+	return nil
+}
+
+func (repository WebhookRepository) Delete(groupID uint, webhookID uint) error {
+	if webhookID <= 0 {
+		return invalidID("webhookId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhook, ok := s.webhooks[webhookID]
+	if !ok || webhook.GroupID != groupID {
+		return notFound("webhook")
+	}
+	delete(s.webhooks, webhookID)
+	deliveries := s.deliveries[:0]
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID != webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	s.deliveries = deliveries
+	return nil
+}
+
+func (repository WebhookRepository) ListDeliveries(webhookID uint, page repository.PageRequest) (repository.Page[*model.WebhookDelivery], error) {
+	return repository.Store.pageOfDeliveries(webhookID, page)
+}
+
+func (repository WebhookRepository) Redeliver(webhookID uint, deliveryID uint) (*model.WebhookDelivery, error) {
+	if deliveryID <= 0 {
+		return &model.WebhookDelivery{}, invalidID("deliveryId")
+	}
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, original := range s.deliveries {
+		if original.ID == deliveryID && original.WebhookID == webhookID {
+			now := s.Clock()
+			delivery := model.WebhookDelivery{
+				ID:            s.nextID(),
+				CreatedAt:     now,
+				WebhookID:     webhookID,
+				Event:         original.Event,
+				Payload:       original.Payload,
+				Status:        model.DeliveryPending,
+				NextAttemptAt: now,
+			}
+			s.deliveries = append(s.deliveries, delivery)
+			return &delivery, nil
+		}
+	}
+	return &model.WebhookDelivery{}, notFound("delivery")
+}
+
+func (repository WebhookRepository) ClaimDue(limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Clock()
+	var due []int
+	for i, delivery := range s.deliveries {
+		if delivery.Status == model.DeliveryPending && !delivery.NextAttemptAt.After(now) && s.webhooks[delivery.WebhookID].Active {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return s.deliveries[due[i]].NextAttemptAt.Before(s.deliveries[due[j]].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]*model.WebhookDelivery, 0, len(due))
+	for _, i := range due {
+		s.deliveries[i].NextAttemptAt = now.Add(lease)
+		delivery := s.deliveries[i]
+		webhook := s.webhooks[delivery.WebhookID]
+		delivery.Webhook = &webhook
+		claimed = append(claimed, &delivery)
+	}
+	return claimed, nil
+}
+
+func (repository WebhookRepository) SaveAttempt(delivery *model.WebhookDelivery, disableAfter int) (bool, error) {
+	s := repository.Store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, saved := range s.deliveries {
+		if saved.ID != delivery.ID {
+			continue
+		}
+		saved.Status = delivery.Status
+		saved.Attempts = delivery.Attempts
+		saved.NextAttemptAt = delivery.NextAttemptAt
+		saved.LastAttemptAt = delivery.LastAttemptAt
+		saved.ResponseStatus = delivery.ResponseStatus
+		saved.ResponseBody = delivery.ResponseBody
+		saved.Error = delivery.Error
+		s.deliveries[i] = saved
+		break
+	}
+
+	webhook, ok := s.webhooks[delivery.WebhookID]
+	if !ok {
+		return false, nil
+	}
+	disabled := false
+	switch delivery.Status {
+	case model.DeliverySucceeded:
+		webhook.ConsecutiveFailures = 0
+	case model.DeliveryFailed:
+		webhook.ConsecutiveFailures++
+		if webhook.Active && webhook.ConsecutiveFailures >= disableAfter {
+			now := s.Clock()
+			webhook.Active = false
+			webhook.DisabledAt = &now
+			disabled = true
+		}
+	}
+	s.webhooks[webhook.ID] = webhook
+	return disabled, nil
+}
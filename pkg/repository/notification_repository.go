@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"time"
+)
+
+// NotificationRepository reads and marks the notifications of users and stores their
+// preferences. Notifications are created by the repositories making the changes, with the
+// activity of the change.
+type NotificationRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) NotificationRepository
+	// ListByUser returns a page of the notifications of a user, newest first
+	ListByUser(userID uint, unreadOnly bool, page PageRequest) (Page[*model.Notification], error)
+	CountUnread(userID uint) (int64, error)
+	// MarkRead marks a notification of the user as read, marking it again changes nothing
+	MarkRead(userID uint, notificationID uint) error
+	MarkAllRead(userID uint) error
+	// ListUnpushed returns at most limit notifications which are not pushed yet, oldest first
+	ListUnpushed(limit int) ([]*model.Notification, error)
+	// ClaimPush marks a notification as pushed, it reports false when it was claimed already so
+	// that every notification is pushed by one instance only
+	ClaimPush(notificationID uint) (bool, error)
+	// SavePushRetry schedules a push to retry, or reschedules it when it has an ID
+	SavePushRetry(retry *model.PushRetry) error
+	// ClaimPushRetries returns at most limit push retries which are due, with their notification
+	// and device. They are not due again for lease, so that one instance attempts each.
+	ClaimPushRetries(limit int, lease time.Duration) ([]*model.PushRetry, error)
+	// DeletePushRetry removes a retry once it was attempted for the last time
+	DeletePushRetry(retryID uint) error
+	// GetPreferences returns whether each notification type is on for the user
+	GetPreferences(userID uint) (map[string]bool, error)
+	// UpdatePreferences turns the given types on or off, other types are unchanged
+	UpdatePreferences(userID uint, preferences map[string]bool) error
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"money_share/pkg/model"
+	"time"
+)
+
+// Sort key of notification cursors
+const notificationCursorKey = "id:desc"
+
+type NotificationRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return NotificationRepositoryImpl{db}
+}
+
+func (repository NotificationRepositoryImpl) WithContext(ctx context.Context) NotificationRepository {
+	return NotificationRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
+func (repository NotificationRepositoryImpl) ListByUser(userID uint, unreadOnly bool, page PageRequest) (Page[*model.Notification], error) {
+	db := repository.DB
+	result := Page[*model.Notification]{}
+	if userID <= 0 {
+		return result, InvalidID("userId")
+	}
+	if err := page.Validate(); err != nil {
+		return result, err
+	}
+	cursor, err := DecodeCursor(page.Cursor, notificationCursorKey)
+	if err != nil {
+		return result, err
+	}
+
+	query := db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if cursor != nil {
+		query = query.Where("id < ?", cursor.ID)
+	}
+	var notifications []*model.Notification
+	if err = query.Order("id DESC").Limit(page.Limit + 1).Find(&notifications).Error; err != nil {
+		return result, err
+	}
+	return PageOf(notifications, page.Limit, NotificationCursor), nil
+}
+
+func (repository NotificationRepositoryImpl) CountUnread(userID uint) (int64, error) {
+	if userID <= 0 {
+		return 0, InvalidID("userId")
+	}
+	var count int64
+	err := repository.DB.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (repository NotificationRepositoryImpl) MarkRead(userID uint, notificationID uint) error {
+	db := repository.DB
+	if userID <= 0 {
+		return InvalidID("userId")
+	}
+	if notificationID <= 0 {
+		return InvalidID("notificationId")
+	}
+	// Notifications of other users are missing to the user
+	notification := &model.Notification{}
+	if err := db.Where("id = ? AND user_id = ?", notificationID, userID).First(notification).Error; err != nil {
+		return translateError(err, "notification")
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return db.Model(notification).Update("read_at", db.NowFunc()).Error
+}
+
+func (repository NotificationRepositoryImpl) MarkAllRead(userID uint) error {
+	db := repository.DB
+	if userID <= 0 {
+		return InvalidID("userId")
+	}
+	return db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", db.NowFunc()).Error
+}
+
+func (repository NotificationRepositoryImpl) ListUnpushed(limit int) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+	err := repository.DB.Where("pushed_at IS NULL").Order("id").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+func (repository NotificationRepositoryImpl) ClaimPush(notificationID uint) (bool, error) {
+	db := repository.DB
+	if notificationID <= 0 {
+		return false, InvalidID("notificationId")
+	}
+	result := db.Model(&model.Notification{}).
+		Where("id = ? AND pushed_at IS NULL", notificationID).
+		Update("pushed_at", db.NowFunc())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (repository NotificationRepositoryImpl) SavePushRetry(retry *model.PushRetry) error {
+	if retry.NotificationID <= 0 {
+		return InvalidID("notificationId")
+	}
+	if retry.DeviceID <= 0 {
+		return InvalidID("deviceId")
+	}
+	if retry.ID == 0 {
+		return translateError(repository.DB.Omit("Notification", "Device").Create(retry).Error, "push retry")
+	}
+	return repository.DB.Model(&model.PushRetry{}).Where("id = ?", retry.ID).Updates(map[string]interface{}{
+		"attempts": retry.Attempts,
+		"retry_at": retry.RetryAt,
+	}).Error
+}
+
+func (repository NotificationRepositoryImpl) ClaimPushRetries(limit int, lease time.Duration) ([]*model.PushRetry, error) {
+	db := repository.DB
+	now := db.NowFunc()
+	var due []*model.PushRetry
+	err := db.Where("retry_at <= ?", now).
+		Order("retry_at").
+		Limit(limit).
+		Preload("Notification").
+		Preload("Device").
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Another instance may have claimed a retry since, it is no longer due then
+	var claimed []*model.PushRetry
+	for _, retry := range due {
+		result := db.Model(&model.PushRetry{}).
+			Where("id = ? AND retry_at <= ?", retry.ID, now).
+			Update("retry_at", now.Add(lease))
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, retry)
+		}
+	}
+	return claimed, nil
+}
+
+func (repository NotificationRepositoryImpl) DeletePushRetry(retryID uint) error {
+	if retryID <= 0 {
+		return InvalidID("retryId")
+	}
+	return repository.DB.Delete(&model.PushRetry{}, retryID).Error
+}
+
+func (repository NotificationRepositoryImpl) GetPreferences(userID uint) (map[string]bool, error) {
+	if userID <= 0 {
+		return nil, InvalidID("userId")
+	}
+	var stored []model.NotificationPreference
+	if err := repository.DB.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	return model.MergeNotificationPreferences(stored), nil
+}
+
+func (repository NotificationRepositoryImpl) UpdatePreferences(userID uint, preferences map[string]bool) error {
+	if userID <= 0 {
+		return InvalidID("userId")
+	}
+	for notificationType := range preferences {
+		if err := model.ValidateNotificationType(notificationType); err != nil {
+			return err
+		}
+	}
+	if len(preferences) == 0 {
+		return nil
+	}
+	var rows []model.NotificationPreference
+	for notificationType, enabled := range preferences {
+		rows = append(rows, model.NotificationPreference{UserID: userID, Type: notificationType, Enabled: enabled})
+	}
+	err := repository.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(&rows).Error
+	return translateError(err, "user")
+}
+
+// NotificationCursor returns the cursor after notification in the notifications of its user
+func NotificationCursor(notification *model.Notification) Cursor {
+	return Cursor{Sort: notificationCursorKey, ID: notification.ID}
+}
+
+// notify creates the notifications of activity in the transaction of the change, for the users
+// who have not turned their type off
+func notify(tx *gorm.DB, activity model.Activity) error {
+	notifications, err := model.NewNotifications(activity, func() ([]uint, error) {
+		var managers []uint
+		err := tx.Model(&model.Member{}).
+			Where("group_id = ? AND role = ?", activity.GroupID, model.RoleManager).
+			Order("user_id").
+			Pluck("user_id", &managers).Error
+		return managers, err
+	})
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+
+	var recipients []uint
+	for _, notification := range notifications {
+		recipients = append(recipients, notification.UserID)
+	}
+	var disabled []uint
+	err = tx.Model(&model.NotificationPreference{}).
+		Where("type = ? AND enabled = ? AND user_id IN ?", notifications[0].Type, false, recipients).
+		Pluck("user_id", &disabled).Error
+	if err != nil {
+		return err
+	}
+	notifications = model.WithoutUsers(notifications, disabled)
+	if len(notifications) == 0 {
+		return nil
+	}
+	return tx.Create(&notifications).Error
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"money_share/pkg/apperror"
+	"money_share/pkg/model"
+	"strconv"
+	"time"
+)
+
+// Number of rows of a page when the limit is not set, and the largest limit accepted
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// PageRequest selects a page of a list, Cursor is the NextCursor of the previous page and
+// empty for the first page
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// Validate checks the limit and sets the default when it is not set
+func (p *PageRequest) Validate() error {
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit < 0 || p.Limit > MaxPageLimit {
+		return apperror.InvalidField("limit", fmt.Sprintf("limit must be between 1 and %d", MaxPageLimit))
+	}
+	return nil
+}
+
+// Page is a page of a list, NextCursor is empty on the last page
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// PageOf makes the page of rows read with one more row than the limit, the extra row tells
+// that there is a next page, which starts after the cursor of the last row of the page
+func PageOf[T any](rows []T, limit int, cursorOf func(row T) Cursor) Page[T] {
+	page := Page[T]{Items: rows}
+	if len(rows) > limit {
+		page.Items = rows[:limit]
+		page.NextCursor = cursorOf(page.Items[limit-1]).Encode()
+	}
+	return page
+}
+
+// Cursor is the position after the last row of a page: the sort value and ID of the row.
+// Clients only see it encoded, Sort makes sure it is used with the order it was created for.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (c Cursor) Encode() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor decodes a cursor created for the order sort, an empty token gives a nil cursor
+func DecodeCursor(token string, sort string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	invalid := apperror.InvalidField("cursor", "Invalid cursor")
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	cursor := &Cursor{}
+	if err = json.Unmarshal(decoded, cursor); err != nil || cursor.Sort != sort {
+		return nil, invalid
+	}
+	return cursor, nil
+}
+
+// ExpenseSort is the order of an expense list
+type ExpenseSort string
+
+const (
+	SortPurchaseTime ExpenseSort = "purchaseTime"
+	SortAmount       ExpenseSort = "amount"
+	SortCreatedAt    ExpenseSort = "createdAt"
+)
+
+// ExpenseFilter selects and orders the expenses of a group, zero fields do not filter
+type ExpenseFilter struct {
+	GroupID  uint
+	MemberID uint
+	Status   string
+	// PurchasedFrom and PurchasedTo bound the purchase time, both inclusive
+	PurchasedFrom *time.Time
+	PurchasedTo   *time.Time
+	// MinAmount and MaxAmount bound the amount, both inclusive
+	MinAmount *float32
+	MaxAmount *float32
+	// Sort defaults to the purchase time, ties are ordered by ID
+	Sort       ExpenseSort
+	Descending bool
+}
+
+// Validate checks the filter and sets the default sort when it is not set
+func (f *ExpenseFilter) Validate() error {
+	var errs []error
+	if f.GroupID == 0 {
+		errs = append(errs, InvalidID("groupId"))
+	}
+	if f.Status != "" {
+		errs = append(errs, model.ValidateStatus(f.Status))
+	}
+	if f.Sort == "" {
+		f.Sort = SortPurchaseTime
+	}
+	if f.Sort != SortPurchaseTime && f.Sort != SortAmount && f.Sort != SortCreatedAt {
+		errs = append(errs, apperror.InvalidField("sort", "sort must be 'purchaseTime', 'amount' or 'createdAt'"))
+	}
+	if f.PurchasedFrom != nil && f.PurchasedTo != nil && f.PurchasedTo.Before(*f.PurchasedFrom) {
+		errs = append(errs, apperror.InvalidField("to", "to must not be before from"))
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MaxAmount < *f.MinAmount {
+		errs = append(errs, apperror.InvalidField("maxAmount", "maxAmount must not be less than minAmount"))
+	}
+	return apperror.Join(errs...)
+}
+
+// CursorKey is the sort key of cursors of the filter, including the direction
+func (f ExpenseFilter) CursorKey() string {
+	if f.Descending {
+		return string(f.Sort) + ":desc"
+	}
+	return string(f.Sort)
+}
+
+// encodeSortValue encodes a time or amount sort value of a cursor
+func encodeSortValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	}
+	return fmt.Sprint(value)
+}
+
+// ExpenseCursor returns the cursor after expense in the order of the filter
+func ExpenseCursor(filter ExpenseFilter, expense *model.Expense) Cursor {
+	var value interface{}
+	switch filter.Sort {
+	case SortAmount:
+		value = expense.Amount
+	case SortCreatedAt:
+		value = expense.CreatedAt
+	default:
+		value = expense.PurchaseTime
+	}
+	return Cursor{Sort: filter.CursorKey(), Value: encodeSortValue(value), ID: expense.ID}
+}
+
+// SortValue decodes the sort value of a cursor of the filter
+func (f ExpenseFilter) SortValue(cursor *Cursor) (interface{}, error) {
+	invalid := apperror.InvalidField("cursor", "Invalid cursor")
+	if f.Sort == SortAmount {
+		amount, err := strconv.ParseFloat(cursor.Value, 32)
+		if err != nil {
+			return nil, invalid
+		}
+		return float32(amount), nil
+	}
+	value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, invalid
+	}
+	return value, nil
+}
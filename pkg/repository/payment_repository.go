@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"money_share/pkg/model"
+)
+
+// PaymentRepository stores the payments between members of groups. Payments are only appended,
+// recording one notifies the payee.
+type PaymentRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) PaymentRepository
+	// Create records a payment, the payer is the actor of its activity
+	Create(payment *model.Payment) error
+	// ListByGroup returns a page of the payments of a group, newest first
+	ListByGroup(groupID uint, page PageRequest) (Page[*model.Payment], error)
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"money_share/pkg/model"
+)
+
+// Sort key of payment cursors
+const paymentCursorKey = "id:desc"
+
+type PaymentRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepository {
+	return PaymentRepositoryImpl{db}
+}
+
+func (repository PaymentRepositoryImpl) WithContext(ctx context.Context) PaymentRepository {
+	return PaymentRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
+func (repository PaymentRepositoryImpl) Create(payment *model.Payment) error {
+	db := repository.DB
+	if payment.GroupID <= 0 {
+		return InvalidID("groupId")
+	}
+	if payment.PayerID <= 0 {
+		return InvalidID("payerId")
+	}
+	if err := payment.ValidateFields(); err != nil {
+		return err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		return appendActivity(tx, model.NewPaymentActivity(payment))
+	})
+	return translateError(err, "payment")
+}
+
+func (repository PaymentRepositoryImpl) ListByGroup(groupID uint, page PageRequest) (Page[*model.Payment], error) {
+	db := repository.DB
+	result := Page[*model.Payment]{}
+	if groupID <= 0 {
+		return result, InvalidID("groupId")
+	}
+	if err := page.Validate(); err != nil {
+		return result, err
+	}
+	cursor, err := DecodeCursor(page.Cursor, paymentCursorKey)
+	if err != nil {
+		return result, err
+	}
+
+	query := db.Where("group_id = ?", groupID)
+	if cursor != nil {
+		query = query.Where("id < ?", cursor.ID)
+	}
+	var payments []*model.Payment
+	if err = query.Order("id DESC").Limit(page.Limit + 1).Find(&payments).Error; err != nil {
+		return result, err
+	}
+	return PageOf(payments, page.Limit, PaymentCursor), nil
+}
+
+// PaymentCursor returns the cursor after payment in the payments of a group
+func PaymentCursor(payment *model.Payment) Cursor {
+	return Cursor{Sort: paymentCursorKey, ID: payment.ID}
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"html"
+	"money_share/pkg/apperror"
+	"money_share/pkg/model"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Markers around the matched words of search snippets
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// Markers around the matched words of snippets before they are escaped, control characters
+// which HTML escaping leaves alone
+const (
+	rawHighlightStart = "\x02"
+	rawHighlightStop  = "\x03"
+)
+
+// Longest search query accepted, in characters
+const MaxSearchQueryLength = 200
+
+// Number of characters of fallback snippets around the first match
+const snippetLength = 120
+
+// ExpenseSearch is a full-text search over the titles and descriptions of the expenses of the
+// groups a user belongs to
+type ExpenseSearch struct {
+	UserID uint
+	Query  string
+	// GroupID narrows the search to one group of the user, zero searches all of them
+	GroupID uint
+}
+
+// Validate checks the search and trims the query
+func (s *ExpenseSearch) Validate() error {
+	var errs []error
+	if s.UserID == 0 {
+		errs = append(errs, InvalidID("userId"))
+	}
+	s.Query = strings.TrimSpace(s.Query)
+	if len(SearchTerms(s.Query)) == 0 {
+		errs = append(errs, apperror.InvalidField("q", "q must contain a word to search"))
+	} else if utf8.RuneCountInString(s.Query) > MaxSearchQueryLength {
+		errs = append(errs, apperror.InvalidField("q", "q must not be longer than "+strconv.Itoa(MaxSearchQueryLength)+" characters"))
+	}
+	return apperror.Join(errs...)
+}
+
+// ExpenseMatch is an expense found by a search
+type ExpenseMatch struct {
+	Expense *model.Expense
+	// Rank is the relevance of the expense, higher is more relevant
+	Rank float32
+	// Snippet is the matching text of the title and description, HTML-escaped, matched words
+	// are between HighlightStart and HighlightStop
+	Snippet string
+}
+
+// Search results are ordered by relevance, which changes as expenses are written, so their
+// cursor is the offset of the next page rather than a position in the order
+const searchCursorKey = "rank"
+
+// SearchOffset decodes the offset of a search page
+func SearchOffset(page PageRequest) (int, error) {
+	cursor, err := DecodeCursor(page.Cursor, searchCursorKey)
+	if err != nil || cursor == nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(cursor.Value)
+	if err != nil || offset < 0 {
+		return 0, apperror.InvalidField("cursor", "Invalid cursor")
+	}
+	return offset, nil
+}
+
+// SearchCursor returns the cursor of the search page starting at offset
+func SearchCursor(offset int) Cursor {
+	return Cursor{Sort: searchCursorKey, Value: strconv.Itoa(offset)}
+}
+
+// SearchTerms splits a query into the lowercase words searched by the LIKE fallback
+func SearchTerms(query string) []string {
+	var terms []string
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		term = strings.Trim(term, `"'`)
+		if term != "" && term != "-" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// FallbackRank ranks an expense for the LIKE fallback: every term found in the title counts
+// one, every term found in the description half
+func FallbackRank(expense *model.Expense, terms []string) float32 {
+	var rank float32
+	title, description := strings.ToLower(expense.Title), strings.ToLower(expense.Description)
+	for _, term := range terms {
+		if strings.Contains(title, term) {
+			rank += 1
+		}
+		if strings.Contains(description, term) {
+			rank += 0.5
+		}
+	}
+	return rank
+}
+
+// EscapeSnippet HTML-escapes a snippet whose matched words are between the raw markers and
+// then marks them with HighlightStart and HighlightStop, so that the text of expenses cannot
+// inject markup
+func EscapeSnippet(raw string) string {
+	escaped := html.EscapeString(raw)
+	return strings.NewReplacer(rawHighlightStart, HighlightStart, rawHighlightStop, HighlightStop).Replace(escaped)
+}
+
+// FallbackSnippet highlights the terms in the title and description of an expense, long
+// texts are cut around the first match
+func FallbackSnippet(expense *model.Expense, terms []string) string {
+	// Markers typed by users would be turned into highlights
+	text := strings.TrimSpace(strings.NewReplacer(rawHighlightStart, "", rawHighlightStop, "").
+		Replace(expense.Title + " " + expense.Description))
+	lower := strings.ToLower(text)
+	// Lowercasing can change the byte length of some characters, matches are then not highlighted
+	if len(lower) != len(text) {
+		lower = text
+	}
+
+	// Mark the bytes matched by any term, overlapping and adjacent matches form one highlight
+	matched := make([]bool, len(text))
+	first := len(text)
+	for _, term := range terms {
+		for start := 0; start < len(lower); {
+			index := strings.Index(lower[start:], term)
+			if index < 0 {
+				break
+			}
+			index += start
+			for i := index; i < index+len(term); i++ {
+				matched[i] = true
+			}
+			if index < first {
+				first = index
+			}
+			start = index + len(term)
+		}
+	}
+
+	// Cut the text around the first match
+	from, to := 0, len(text)
+	if utf8.RuneCountInString(text) > snippetLength {
+		if first == len(text) {
+			first = 0
+		}
+		from = first
+		for back := 0; from > 0 && back < snippetLength/4; back++ {
+			_, size := utf8.DecodeLastRuneInString(text[:from])
+			from -= size
+		}
+		to = from
+		for count := 0; to < len(text) && count < snippetLength; count++ {
+			_, size := utf8.DecodeRuneInString(text[to:])
+			to += size
+		}
+	}
+
+	var snippet strings.Builder
+	if from > 0 {
+		snippet.WriteString("…")
+	}
+	for i := from; i < to; i++ {
+		if matched[i] && (i == from || !matched[i-1]) {
+			snippet.WriteString(rawHighlightStart)
+		}
+		snippet.WriteByte(text[i])
+		if matched[i] && (i == to-1 || !matched[i+1]) {
+			snippet.WriteString(rawHighlightStop)
+		}
+	}
+	if to < len(text) {
+		snippet.WriteString("…")
+	}
+	return EscapeSnippet(snippet.String())
+}
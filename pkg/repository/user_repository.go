@@ -1,12 +1,17 @@
 package repository
 
 import (
+	"context"
 	"money_share/pkg/model"
 )
 
 type UserRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) UserRepository
 	GetById(userId uint) (*model.User, error)
 	GetByUsername(username string) (*model.User, error)
+	// Find returns at most limit users whose username, display name or email address contains query
+	Find(query string, limit int) ([]*model.User, error)
 	CheckUsernameAvailability(username string) (bool, error)
 	ValidateUsernameAndUserID(username string, userID uint) (bool, error)
 	Create(user *model.User) error
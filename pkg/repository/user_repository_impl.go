@@ -1,10 +1,12 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"money_share/pkg/apperror"
 	"money_share/pkg/model"
+	"strings"
 )
 
 type UserRepositoryImpl struct {
@@ -15,18 +17,34 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return UserRepositoryImpl{db}
 }
 
+func (repository UserRepositoryImpl) WithContext(ctx context.Context) UserRepository {
+	return UserRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
 func (repository UserRepositoryImpl) GetById(userId uint) (*model.User, error) {
 	db := repository.DB
 	var user = &model.User{}
 	err := db.First(user, userId).Error
-	return user, err
+	return user, translateError(err, "user")
 }
 
 func (repository UserRepositoryImpl) GetByUsername(username string) (*model.User, error) {
 	db := repository.DB
 	var user = &model.User{}
 	err := db.Where("username = ?", username).First(user).Error
-	return user, err
+	return user, translateError(err, "user")
+}
+
+func (repository UserRepositoryImpl) Find(query string, limit int) ([]*model.User, error) {
+	db := repository.DB
+	if limit <= 0 {
+		return nil, apperror.InvalidField("limit", "limit must be greater than 0")
+	}
+	pattern := "%" + strings.ToLower(query) + "%"
+	var users []*model.User
+	err := db.Where("LOWER(username) LIKE ? OR LOWER(display_name) LIKE ? OR LOWER(email_address) LIKE ?",
+		pattern, pattern, pattern).Order("id").Limit(limit).Find(&users).Error
+	return users, err
 }
 
 func (repository UserRepositoryImpl) CheckUsernameAvailability(username string) (bool, error) {
@@ -52,31 +70,48 @@ func (repository UserRepositoryImpl) ValidateUsernameAndUserID(username string,
 func (repository UserRepositoryImpl) Create(user *model.User) (err error) {
 	// Skip all associations before inserting
 	err = repository.DB.Omit(clause.Associations).Create(user).Error
-	return
+	return translateUserError(err)
 }
 
 func (repository UserRepositoryImpl) Update(userID uint, updateMap map[string]interface{}) (*model.User, error) {
 	db := repository.DB
 	if userID == 0 {
-		return &model.User{}, errors.New("user ID not provided")
+		return &model.User{}, InvalidID("userId")
 	}
 
+	// Read the row back in the same transaction, RETURNING is not supported by every driver
 	user := &model.User{}
-	user.ID = userID
-	updateRs := db.Model(user).Clauses(clause.Returning{}).Updates(updateMap)
-	return user, updateRs.Error
+	err := db.Transaction(func(tx *gorm.DB) error {
+		updateRs := tx.Model(&model.User{}).Where("id = ?", userID).Updates(updateMap)
+		if updateRs.Error != nil {
+			return updateRs.Error
+		}
+		if updateRs.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(user, userID).Error
+	})
+	return user, translateUserError(err)
 }
 
 func (repository UserRepositoryImpl) Delete(userId uint) error {
 	if userId == 0 {
-		return errors.New("user ID not provided")
+		return InvalidID("userId")
 	}
 	result := repository.DB.Delete(&model.User{}, userId)
 	if result.Error != nil {
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("no row changed")
+		return NotFound("user")
 	}
 	return nil
 }
+
+// translateUserError reports a duplicate username as taken rather than as a duplicate user
+func translateUserError(err error) error {
+	if isUniqueViolation(err) {
+		return UsernameTaken(err)
+	}
+	return translateError(err, "user")
+}
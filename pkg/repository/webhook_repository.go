@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"money_share/pkg/model"
+	"time"
+)
+
+// WebhookRepository stores the webhooks of groups and queues their deliveries. Deliveries are
+// queued by the repositories making the changes, with the activity of the change.
+type WebhookRepository interface {
+	// WithContext returns a repository whose queries run with ctx, for cancellation and tracing
+	WithContext(ctx context.Context) WebhookRepository
+	Create(webhook *model.Webhook) error
+	GetByID(groupID uint, webhookID uint) (*model.Webhook, error)
+	// ListByGroup returns the webhooks of a group ordered by ID
+	ListByGroup(groupID uint) ([]*model.Webhook, error)
+	// Update changes the URL, events and active state of a webhook, enabling a disabled webhook
+	// resets its failures
+	Update(webhook *model.Webhook) error
+	// Delete removes a webhook with its deliveries
+	Delete(groupID uint, webhookID uint) error
+	// ListDeliveries returns a page of the deliveries of a webhook, newest first
+	ListDeliveries(webhookID uint, page PageRequest) (Page[*model.WebhookDelivery], error)
+	// Redeliver queues the payload of a delivery again and returns the new delivery
+	Redeliver(webhookID uint, deliveryID uint) (*model.WebhookDelivery, error)
+	// ClaimDue returns at most limit pending deliveries of active webhooks which are due, with
+	// their webhook. They are not due again for lease, so that one instance attempts each.
+	ClaimDue(limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+	// SaveAttempt saves the outcome of an attempt. A delivery which succeeded resets the failures
+	// of its webhook, one which failed counts as a failure and the webhook is disabled once
+	// disableAfter deliveries failed in a row. It reports whether the webhook was disabled.
+	SaveAttempt(delivery *model.WebhookDelivery, disableAfter int) (bool, error)
+}
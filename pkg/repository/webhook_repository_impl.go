@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"money_share/pkg/model"
+	"time"
+)
+
+// Sort key of delivery cursors
+const deliveryCursorKey = "id:desc"
+
+type WebhookRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return WebhookRepositoryImpl{db}
+}
+
+func (repository WebhookRepositoryImpl) WithContext(ctx context.Context) WebhookRepository {
+	return WebhookRepositoryImpl{repository.DB.WithContext(ctx)}
+}
+
+func (repository WebhookRepositoryImpl) Create(webhook *model.Webhook) error {
+	if webhook.GroupID <= 0 {
+		return InvalidID("groupId")
+	}
+	if err := webhook.ValidateFields(); err != nil {
+		return err
+	}
+	webhook.Active = true
+	err := repository.DB.Create(webhook).Error
+	return translateError(err, "webhook")
+}
+
+func (repository WebhookRepositoryImpl) GetByID(groupID uint, webhookID uint) (*model.Webhook, error) {
+	if webhookID <= 0 {
+		return &model.Webhook{}, InvalidID("webhookId")
+	}
+	// Webhooks of other groups are missing to the group
+	webhook := &model.Webhook{}
+	err := repository.DB.Where("id = ? AND group_id = ?", webhookID, groupID).First(webhook).Error
+	return webhook, translateError(err, "webhook")
+}
+
+func (repository WebhookRepositoryImpl) ListByGroup(groupID uint) ([]*model.Webhook, error) {
+	if groupID <= 0 {
+		return nil, InvalidID("groupId")
+	}
+	var webhooks []*model.Webhook
+	err := repository.DB.Where("group_id = ?", groupID).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (repository WebhookRepositoryImpl) Update(webhook *model.Webhook) error {
+	db := repository.DB
+	err := db.Transaction(func(tx *gorm.DB) error {
+		current := &model.Webhook{}
+		if err := tx.Where("id = ? AND group_id = ?", webhook.ID, webhook.GroupID).First(current).Error; err != nil {
+			return err
+		}
+		if err := webhook.ValidateFields(); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"url": webhook.URL, "events": webhook.Events, "active": webhook.Active}
+		if webhook.Active && !current.Active {
+			updates["consecutive_failures"] = 0
+			updates["disabled_at"] = nil
+		}
+		if !webhook.Active && current.Active {
+			updates["disabled_at"] = tx.NowFunc()
+		}
+		if err := tx.Model(current).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(webhook, current.ID).Error
+	})
+	return translateError(err, "webhook")
+}
+
+func (repository WebhookRepositoryImpl) Delete(groupID uint, webhookID uint) error {
+	db := repository.DB
+	if webhookID <= 0 {
+		return InvalidID("webhookId")
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND group_id = ?", webhookID, groupID).Delete(&model.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("webhook_id = ?", webhookID).Delete(&model.WebhookDelivery{}).Error
+	})
+	return translateError(err, "webhook")
+}
+
+func (repository WebhookRepositoryImpl) ListDeliveries(webhookID uint, page PageRequest) (Page[*model.WebhookDelivery], error) {
+	result := Page[*model.WebhookDelivery]{}
+	if webhookID <= 0 {
+		return result, InvalidID("webhookId")
+	}
+	if err := page.Validate(); err != nil {
+		return result, err
+	}
+	cursor, err := DecodeCursor(page.Cursor, deliveryCursorKey)
+	if err != nil {
+		return result, err
+	}
+
+	query := repository.DB.Where("webhook_id = ?", webhookID)
+	if cursor != nil {
+		query = query.Where("id < ?", cursor.ID)
+	}
+	var deliveries []*model.WebhookDelivery
+	if err = query.Order("id DESC").Limit(page.Limit + 1).Find(&deliveries).Error; err != nil {
+		return result, err
+	}
+	return PageOf(deliveries, page.Limit, DeliveryCursor), nil
+}
+
+func (repository WebhookRepositoryImpl) Redeliver(webhookID uint, deliveryID uint) (*model.WebhookDelivery, error) {
+	db := repository.DB
+	if deliveryID <= 0 {
+		return &model.WebhookDelivery{}, InvalidID("deliveryId")
+	}
+	original := &model.WebhookDelivery{}
+	if err := db.Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(original).Error; err != nil {
+		return original, translateError(err, "delivery")
+	}
+	delivery := &model.WebhookDelivery{
+		WebhookID:     webhookID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: db.NowFunc(),
+	}
+	err := db.Omit(clause.Associations).Create(delivery).Error
+	return delivery, err
+}
+
+func (repository WebhookRepositoryImpl) ClaimDue(limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	db := repository.DB
+	now := db.NowFunc()
+	var due []*model.WebhookDelivery
+	err := db.Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id").
+		Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ? AND webhooks.active = ?", model.DeliveryPending, now, true).
+		Order("webhook_deliveries.next_attempt_at").
+		Limit(limit).
+		Preload("Webhook").
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Another instance may have claimed a delivery since, it is no longer due then
+	var claimed []*model.WebhookDelivery
+	for _, delivery := range due {
+		result := db.Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, model.DeliveryPending, now).
+			Update("next_attempt_at", now.Add(lease))
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (repository WebhookRepositoryImpl) SaveAttempt(delivery *model.WebhookDelivery, disableAfter int) (bool, error) {
+	disabled := false
+	err := repository.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"last_attempt_at": delivery.LastAttemptAt,
+			"response_status": delivery.ResponseStatus,
+			"response_body":   delivery.ResponseBody,
+			"error":           delivery.Error,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		webhooks := tx.Model(&model.Webhook{}).Where("id = ?", delivery.WebhookID)
+		switch delivery.Status {
+		case model.DeliverySucceeded:
+			return webhooks.Update("consecutive_failures", 0).Error
+		case model.DeliveryFailed:
+			if err := webhooks.Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+				return err
+			}
+			result := tx.Model(&model.Webhook{}).
+				Where("id = ? AND active = ? AND consecutive_failures >= ?", delivery.WebhookID, true, disableAfter).
+				Updates(map[string]interface{}{"active": false, "disabled_at": tx.NowFunc()})
+			disabled = result.RowsAffected == 1
+			return result.Error
+		}
+		return nil
+	})
+	return disabled, err
+}
+
+// DeliveryCursor returns the cursor after delivery in the deliveries of its webhook
+func DeliveryCursor(delivery *model.WebhookDelivery) Cursor {
+	return Cursor{Sort: deliveryCursorKey, ID: delivery.ID}
+}
+
+// enqueueWebhooks queues activity for the webhooks of its group in the transaction of the change
+func enqueueWebhooks(tx *gorm.DB, activity model.Activity) error {
+	var webhooks []*model.Webhook
+	if err := tx.Where("group_id = ? AND active = ?", activity.GroupID, true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+	deliveries := model.NewWebhookDeliveries(activity, webhooks, tx.NowFunc())
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Create(&deliveries).Error
+}
@@ -0,0 +1,15 @@
+package route
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/controller"
+	"money_share/pkg/middleware"
+)
+
+var RegisterDeviceRoutes = func(router *mux.Router, app *controller.App) {
+	deviceRouter := router.PathPrefix("/device").Subrouter()
+	deviceRouter.HandleFunc("", app.GetDevices).Methods("GET")
+	deviceRouter.HandleFunc("", app.RegisterDevice).Methods("POST")
+	deviceRouter.HandleFunc("/{deviceId:[0-9]+}", app.UnregisterDevice).Methods("DELETE")
+	deviceRouter.Use(middleware.Authenticate(app))
+}
@@ -6,14 +6,15 @@ import (
 	"money_share/pkg/middleware"
 )
 
-var RegisterExpenseRoutes = func(router *mux.Router) {
+var RegisterExpenseRoutes = func(router *mux.Router, app *controller.App) {
 	expenseRouter := router.PathPrefix("/expense").Subrouter()
-	expenseRouter.HandleFunc("/{expenseId:[0-9]+}", controller.GetExpenseByID).Methods("GET")
-	expenseRouter.HandleFunc("/group/{groupId:[0-9]+}", controller.GetExpensesByGroup).Methods("GET")
-	expenseRouter.HandleFunc("", controller.GetExpensesByMember).Methods("GET")
-	expenseRouter.HandleFunc("", controller.CreateExpense).Methods("POST")
-	expenseRouter.HandleFunc("/{expenseId:[0-9]+}", controller.UpdateExpense).Methods("PUT")
-	expenseRouter.HandleFunc("/{expenseId:[0-9]+}", controller.DeleteExpense).Methods("DELETE")
-	expenseRouter.Use(middleware.Authenticate)
+	expenseRouter.HandleFunc("/{expenseId:[0-9]+}", app.GetExpenseByID).Methods("GET")
+	expenseRouter.HandleFunc("/group/{groupId:[0-9]+}", app.GetExpensesByGroup).Methods("GET")
+	expenseRouter.HandleFunc("", app.GetExpensesByMember).Methods("GET")
+	expenseRouter.HandleFunc("/search", app.SearchExpenses).Methods("GET")
+	expenseRouter.HandleFunc("", app.CreateExpense).Methods("POST")
+	expenseRouter.HandleFunc("/{expenseId:[0-9]+}", app.UpdateExpense).Methods("PUT")
+	expenseRouter.HandleFunc("/{expenseId:[0-9]+}", app.DeleteExpense).Methods("DELETE")
+	expenseRouter.Use(middleware.Authenticate(app))
 
 }
@@ -0,0 +1,10 @@
+package route
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/controller"
+)
+
+var RegisterFileRoutes = func(router *mux.Router, app *controller.App) {
+	router.HandleFunc("/file/{key:.+}", app.GetSignedFile).Methods("GET")
+}
@@ -6,12 +6,24 @@ import (
 	"money_share/pkg/middleware"
 )
 
-var RegisterGroupRoutes = func(router *mux.Router) {
+var RegisterGroupRoutes = func(router *mux.Router, app *controller.App) {
 	groupRouter := router.PathPrefix("/group").Subrouter()
-	groupRouter.HandleFunc("/{groupId:[0-9]+}", controller.GetGroupById).Methods("GET")
-	groupRouter.HandleFunc("/user/{userId:[0-9]+}", controller.GetGroupsByUser).Methods("GET")
-	groupRouter.HandleFunc("", controller.CreateGroup).Methods("POST")
-	groupRouter.HandleFunc("/{groupId:[0-9]+}", controller.UpdateGroup).Methods("PUT")
-	groupRouter.HandleFunc("/{groupId:[0-9]+}", controller.DeleteGroup).Methods("DELETE")
-	groupRouter.Use(middleware.Authenticate)
+	groupRouter.HandleFunc("/{groupId:[0-9]+}", app.GetGroupById).Methods("GET")
+	groupRouter.HandleFunc("/user/{userId:[0-9]+}", app.GetGroupsByUser).Methods("GET")
+	groupRouter.HandleFunc("", app.CreateGroup).Methods("POST")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}", app.UpdateGroup).Methods("PUT")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}", app.DeleteGroup).Methods("DELETE")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/activity", app.GetGroupActivity).Methods("GET")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/events", app.StreamGroupEvents).Methods("GET")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/export", app.ExportGroup).Methods("GET")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/payment", app.GetPayments).Methods("GET")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/payment", app.CreatePayment).Methods("POST")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/webhook", app.GetWebhooks).Methods("GET")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/webhook", app.CreateWebhook).Methods("POST")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/webhook/{webhookId:[0-9]+}", app.GetWebhook).Methods("GET")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/webhook/{webhookId:[0-9]+}", app.UpdateWebhook).Methods("PUT")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/webhook/{webhookId:[0-9]+}", app.DeleteWebhook).Methods("DELETE")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/webhook/{webhookId:[0-9]+}/delivery", app.GetWebhookDeliveries).Methods("GET")
+	groupRouter.HandleFunc("/{groupId:[0-9]+}/webhook/{webhookId:[0-9]+}/delivery/{deliveryId:[0-9]+}/redeliver", app.RedeliverWebhook).Methods("POST")
+	groupRouter.Use(middleware.Authenticate(app))
 }
\ No newline at end of file
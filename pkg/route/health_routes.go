@@ -0,0 +1,11 @@
+package route
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/controller"
+)
+
+var RegisterHealthRoutes = func(router *mux.Router, app *controller.App) {
+	router.HandleFunc("/healthz", app.Healthz).Methods("GET")
+	router.HandleFunc("/readyz", app.Readyz).Methods("GET")
+}
@@ -3,11 +3,14 @@ package route
 import (
 	"github.com/gorilla/mux"
 	"money_share/pkg/controller"
+	"money_share/pkg/middleware"
 )
 
-var RegisterMemberRoutes = func(router *mux.Router) {
-	router.HandleFunc("/member", controller.GetMemberByID).Methods("GET")
-	router.HandleFunc("/member/group/{groupId:[0-9]+}", controller.GetMembersOfGroup).Methods("GET")
-	router.HandleFunc("/member", controller.AddMemberToGroup).Methods("POST")
-	router.HandleFunc("/member", controller.RemoveMemberFromGroup).Methods("DELETE")
+var RegisterMemberRoutes = func(router *mux.Router, app *controller.App) {
+	memberRouter := router.PathPrefix("/member").Subrouter()
+	memberRouter.HandleFunc("", app.GetMemberByID).Methods("GET")
+	memberRouter.HandleFunc("/group/{groupId:[0-9]+}", app.GetMembersOfGroup).Methods("GET")
+	memberRouter.HandleFunc("", app.AddMemberToGroup).Methods("POST")
+	memberRouter.HandleFunc("", app.RemoveMemberFromGroup).Methods("DELETE")
+	memberRouter.Use(middleware.Authenticate(app))
 }
@@ -0,0 +1,11 @@
+package route
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/controller"
+	"money_share/pkg/metrics"
+)
+
+var RegisterMetricsRoutes = func(router *mux.Router, app *controller.App) {
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+}
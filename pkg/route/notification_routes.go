@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/controller"
+	"money_share/pkg/middleware"
+)
+
+var RegisterNotificationRoutes = func(router *mux.Router, app *controller.App) {
+	notificationRouter := router.PathPrefix("/notification").Subrouter()
+	notificationRouter.HandleFunc("", app.GetNotifications).Methods("GET")
+	notificationRouter.HandleFunc("/read", app.MarkAllNotificationsRead).Methods("PUT")
+	notificationRouter.HandleFunc("/{notificationId:[0-9]+}/read", app.MarkNotificationRead).Methods("PUT")
+	notificationRouter.HandleFunc("/preferences", app.GetNotificationPreferences).Methods("GET")
+	notificationRouter.HandleFunc("/preferences", app.UpdateNotificationPreferences).Methods("PUT")
+	notificationRouter.Use(middleware.Authenticate(app))
+}
@@ -0,0 +1,10 @@
+package route
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/controller"
+)
+
+var RegisterOpenAPIRoutes = func(router *mux.Router, app *controller.App) {
+	router.HandleFunc("/openapi.json", app.GetOpenAPIDocument).Methods("GET")
+}
@@ -0,0 +1,43 @@
+package route
+
+import (
+	"github.com/gorilla/mux"
+	"money_share/pkg/controller"
+	"money_share/pkg/middleware"
+	"money_share/pkg/openapi"
+)
+
+// NewRouter registers every route of app together with the middleware chain
+func NewRouter(app *controller.App, rateLimit middleware.RateLimitConfig) *mux.Router {
+	r := mux.NewRouter()
+	// Assign request IDs, trace, record metrics and log every request
+	r.Use(middleware.RequestID, middleware.Tracing, middleware.Metrics, middleware.AccessLog(app.Logger))
+	// Health and metrics routes are registered outside the rate limiter so probes and scrapes never get throttled
+	RegisterHealthRoutes(r, app)
+	RegisterMetricsRoutes(r, app)
+	RegisterOpenAPIRoutes(r, app)
+
+	api := r.NewRoute().Subrouter()
+	// Set up rate limiter middleware
+	api.Use(middleware.RateLimit(rateLimit))
+	// Set up routes
+	RegisterUserRoutes(api, app)
+	RegisterGroupRoutes(api, app)
+	RegisterMemberRoutes(api, app)
+	RegisterExpenseRoutes(api, app)
+	RegisterNotificationRoutes(api, app)
+	RegisterDeviceRoutes(api, app)
+	RegisterFileRoutes(api, app)
+	// Handle not found with custom message
+	api.HandleFunc("/", app.HandleNotFound)
+	// Reject requests which do not match the OpenAPI document. The handlers are wrapped rather
+	// than the router so authentication runs first and anonymous callers get 401, not 400.
+	validate := middleware.ValidateRequest(openapi.MustLoad(), app.Logger)
+	_ = api.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if handler := route.GetHandler(); handler != nil {
+			route.Handler(validate(handler))
+		}
+		return nil
+	})
+	return r
+}
@@ -6,18 +6,18 @@ import (
 	"money_share/pkg/middleware"
 )
 
-var RegisterUserRoutes = func(router *mux.Router) {
+var RegisterUserRoutes = func(router *mux.Router, app *controller.App) {
 	userRouter := router.PathPrefix("/user").Subrouter()
-	userRouter.HandleFunc("/login", controller.Login).Methods("POST")
-	userRouter.HandleFunc("/{userId:[0-9]+}", controller.GetUserByID).Methods("GET")
-	userRouter.HandleFunc("/checkUsername/{username}", controller.CheckUsername).Methods("GET")
-	userRouter.HandleFunc("/register", controller.Register).Methods("POST")
-	userRouter.HandleFunc("/profileImage/{fileName}", controller.GetUserProfileImage).Methods("GET")
+	userRouter.HandleFunc("/login", app.Login).Methods("POST")
+	userRouter.HandleFunc("/{userId:[0-9]+}", app.GetUserByID).Methods("GET")
+	userRouter.HandleFunc("/checkUsername/{username}", app.CheckUsername).Methods("GET")
+	userRouter.HandleFunc("/register", app.Register).Methods("POST")
+	userRouter.HandleFunc("/profileImage/{fileName}", app.GetUserProfileImage).Methods("GET")
 
 	// Authentication required routes
 	authSub := userRouter.PathPrefix("/auth").Subrouter()
-	authSub.HandleFunc("/{userId:[0-9]+}", controller.UpdateUser).Methods("PUT")
-	authSub.HandleFunc("/{userId:[0-9]+}/profileImage", controller.UploadUserProfileImage).Methods("POST")
-	authSub.HandleFunc("/{userId:[0-9]+}", controller.DeleteUser).Methods("DELETE")
-	authSub.Use(middleware.Authenticate)
+	authSub.HandleFunc("/{userId:[0-9]+}", app.UpdateUser).Methods("PUT")
+	authSub.HandleFunc("/{userId:[0-9]+}/profileImage", app.UploadUserProfileImage).Methods("POST")
+	authSub.HandleFunc("/{userId:[0-9]+}", app.DeleteUser).Methods("DELETE")
+	authSub.Use(middleware.Authenticate(app))
 }
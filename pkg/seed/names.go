@@ -0,0 +1,44 @@
+package seed
+
+var firstNames = []string{
+	"Anh", "Binh", "Chi", "Dung", "Giang", "Hoa", "Huy", "Khanh", "Lan", "Linh", "Minh", "Nam",
+	"Ngoc", "Phuong", "Quang", "Thao", "Trang", "Tuan", "Vy", "Alice", "Bob", "Carol", "David",
+	"Emma", "Frank", "Grace", "Henry", "Isabel", "James", "Kate", "Liam", "Mia", "Noah", "Olivia",
+}
+
+var lastNames = []string{
+	"Nguyen", "Tran", "Le", "Pham", "Hoang", "Huynh", "Phan", "Vu", "Vo", "Dang", "Bui", "Do",
+	"Smith", "Johnson", "Williams", "Brown", "Jones", "Miller", "Davis", "Wilson", "Taylor",
+}
+
+var groupNames = []string{
+	"Flatmates", "Office lunch", "Trip to Da Lat", "Weekend in Hoi An", "Football team",
+	"Family groceries", "Book club", "Wedding gift", "Birthday party", "Road trip", "Camping",
+	"Study group", "Apartment 12B", "Ski trip", "Beach house",
+}
+
+// expenseTemplate describes a kind of expense, amounts are in thousands
+type expenseTemplate struct {
+	title       string
+	description string
+	min         float64
+	max         float64
+}
+
+var expenseTemplates = []expenseTemplate{
+	{"Groceries", "Weekly groceries at the supermarket", 150, 1200},
+	{"Dinner", "Dinner at a restaurant", 300, 2500},
+	{"Lunch", "", 100, 600},
+	{"Coffee", "", 30, 200},
+	{"Electricity bill", "Monthly electricity bill", 400, 1500},
+	{"Water bill", "", 80, 300},
+	{"Internet", "Monthly internet subscription", 200, 400},
+	{"Rent", "Monthly rent", 4000, 12000},
+	{"Taxi", "", 50, 400},
+	{"Fuel", "", 100, 600},
+	{"Hotel", "Accommodation", 800, 5000},
+	{"Train tickets", "", 300, 2000},
+	{"Cinema", "Movie tickets and snacks", 150, 600},
+	{"Cleaning supplies", "", 50, 300},
+	{"Gift", "Shared gift", 200, 3000},
+}
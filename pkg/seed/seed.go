@@ -0,0 +1,249 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"math/rand"
+	"money_share/pkg/model"
+	"strings"
+	"time"
+)
+
+// Rows per insert statement
+const batchSize = 500
+
+// Config controls the size and shape of the generated data. Generating twice with the same
+// config gives the same data.
+type Config struct {
+	Seed       int64
+	Users      int
+	Groups     int
+	MinMembers int
+	MaxMembers int
+	// ExpensesPerMonth is the average number of expenses of a group per month
+	ExpensesPerMonth int
+	// Months is how far back from End expenses are spread
+	Months int
+	End    time.Time
+	// Password every seeded user can log in with
+	Password string
+}
+
+func (c Config) Validate() error {
+	if c.Users <= 0 {
+		return errors.New("number of users must be greater than 0")
+	}
+	if c.Groups < 0 || c.ExpensesPerMonth < 0 {
+		return errors.New("number of groups and expenses cannot be negative")
+	}
+	if c.MinMembers < 1 || c.MaxMembers < c.MinMembers {
+		return errors.New("members per group must be at least 1 and max members cannot be smaller than min members")
+	}
+	if c.MaxMembers > c.Users {
+		return fmt.Errorf("max members per group (%d) cannot exceed the number of users (%d)", c.MaxMembers, c.Users)
+	}
+	if c.Months <= 0 {
+		return errors.New("number of months must be greater than 0")
+	}
+	return model.ValidatePassword(c.Password)
+}
+
+// Dataset is generated data ready to be inserted. Members and expenses reference users by
+// their index in Users, since IDs are only known after inserting.
+type Dataset struct {
+	Users  []model.User
+	Groups []GroupSeed
+}
+
+type GroupSeed struct {
+	Group    model.Group
+	Members  []MemberSeed
+	Expenses []ExpenseSeed
+}
+
+type MemberSeed struct {
+	UserIndex int
+	Role      string
+}
+
+type ExpenseSeed struct {
+	UserIndex int
+	Expense   model.Expense
+}
+
+// Generate builds a dataset from config. Users keep their plain text password in Password,
+// Insert hashes it.
+func Generate(config Config) (Dataset, error) {
+	if err := config.Validate(); err != nil {
+		return Dataset{}, err
+	}
+	random := rand.New(rand.NewSource(config.Seed))
+	start := config.End.AddDate(0, -config.Months, 0)
+	data := Dataset{}
+
+	for i := 1; i <= config.Users; i++ {
+		firstName := pick(random, firstNames)
+		lastName := pick(random, lastNames)
+		username := fmt.Sprintf("%s.%05d", strings.ToLower(firstName), i)
+		user := model.User{
+			Username:     username,
+			Password:     config.Password,
+			DisplayName:  firstName + " " + lastName,
+			PhoneNumber:  fmt.Sprintf("09%08d", random.Intn(100000000)),
+			EmailAddress: username + "@example.com",
+			DateOfBirth:  time.Date(1960+random.Intn(45), time.Month(1+random.Intn(12)), 1+random.Intn(28), 0, 0, 0, 0, time.UTC),
+		}
+		user.CreatedAt = randomTime(random, start.AddDate(0, -6, 0), start)
+		user.UpdatedAt = user.CreatedAt
+		data.Users = append(data.Users, user)
+	}
+
+	for i := 1; i <= config.Groups; i++ {
+		groupSeed := GroupSeed{
+			Group: model.Group{Name: fmt.Sprintf("%s #%d", pick(random, groupNames), i)},
+		}
+		groupSeed.Group.CreatedAt = randomTime(random, start.AddDate(0, -1, 0), start)
+		groupSeed.Group.UpdatedAt = groupSeed.Group.CreatedAt
+
+		// The first member created the group and manages it
+		memberCount := config.MinMembers + random.Intn(config.MaxMembers-config.MinMembers+1)
+		for j, userIndex := range random.Perm(config.Users)[:memberCount] {
+			role := model.RoleMember
+			if j == 0 {
+				role = model.RoleManager
+			}
+			groupSeed.Members = append(groupSeed.Members, MemberSeed{UserIndex: userIndex, Role: role})
+		}
+
+		// Vary activity between groups around the configured average
+		expenseCount := int(math.Round(float64(config.ExpensesPerMonth*config.Months) * (0.5 + random.Float64())))
+		for j := 0; j < expenseCount; j++ {
+			template := pick(random, expenseTemplates)
+			purchaseTime := randomTime(random, start, config.End)
+			expense := model.Expense{
+				Title:        template.title,
+				Description:  template.description,
+				Amount:       float32(math.Round(template.min+random.Float64()*(template.max-template.min))) * 1000,
+				PurchaseTime: purchaseTime,
+				Status:       randomStatus(random),
+			}
+			expense.CreatedAt = purchaseTime.Add(time.Duration(random.Intn(48)) * time.Hour)
+			if expense.CreatedAt.After(config.End) {
+				expense.CreatedAt = config.End
+			}
+			expense.UpdatedAt = expense.CreatedAt
+			member := groupSeed.Members[random.Intn(len(groupSeed.Members))]
+			groupSeed.Expenses = append(groupSeed.Expenses, ExpenseSeed{UserIndex: member.UserIndex, Expense: expense})
+		}
+		data.Groups = append(data.Groups, groupSeed)
+	}
+	return data, nil
+}
+
+// Insert writes the dataset in a single transaction. Expense hooks are skipped, member and
+// group totals are computed once per group instead.
+func Insert(db *gorm.DB, data Dataset) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Session(&gorm.Session{SkipHooks: true})
+
+		// Every user gets the same password, hash it once
+		users := append([]model.User{}, data.Users...)
+		hashedPasswords := make(map[string]string)
+		for i := range users {
+			hashed, ok := hashedPasswords[users[i].Password]
+			if !ok {
+				var err error
+				if hashed, err = model.HashPassword(users[i].Password); err != nil {
+					return err
+				}
+				hashedPasswords[users[i].Password] = hashed
+			}
+			users[i].Password = hashed
+		}
+		if len(users) > 0 {
+			if err := tx.Omit(clause.Associations).CreateInBatches(&users, batchSize).Error; err != nil {
+				return fmt.Errorf("inserting users: %w", err)
+			}
+		}
+
+		for _, groupSeed := range data.Groups {
+			group := groupSeed.Group
+			members := make([]model.Member, 0, len(groupSeed.Members))
+			for _, memberSeed := range groupSeed.Members {
+				members = append(members, model.Member{UserID: users[memberSeed.UserIndex].ID, Role: memberSeed.Role})
+			}
+			expenses := make([]model.Expense, 0, len(groupSeed.Expenses))
+			for _, expenseSeed := range groupSeed.Expenses {
+				expense := expenseSeed.Expense
+				expense.MemberID = users[expenseSeed.UserIndex].ID
+				expenses = append(expenses, expense)
+			}
+
+			// Totals only depend on user IDs, which are known now
+			group.Members, group.Expenses = members, expenses
+			totals := group.ComputeTotals()
+			group.TotalExpense, group.AverageExpense = totals.Total, totals.Average
+			group.Members, group.Expenses = nil, nil
+
+			if err := tx.Omit(clause.Associations).Create(&group).Error; err != nil {
+				return fmt.Errorf("inserting group '%s': %w", group.Name, err)
+			}
+			for i := range members {
+				members[i].GroupID = group.ID
+				members[i].TotalExpense = totals.Members[members[i].UserID]
+			}
+			if err := tx.Omit(clause.Associations).CreateInBatches(&members, batchSize).Error; err != nil {
+				return fmt.Errorf("inserting members of group '%s': %w", group.Name, err)
+			}
+			for i := range expenses {
+				expenses[i].GroupID = group.ID
+			}
+			if len(expenses) > 0 {
+				if err := tx.Omit(clause.Associations).CreateInBatches(&expenses, batchSize).Error; err != nil {
+					return fmt.Errorf("inserting expenses of group '%s': %w", group.Name, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Clear deletes all users, groups, members and expenses, including soft deleted ones
+func Clear(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Session(&gorm.Session{AllowGlobalUpdate: true, SkipHooks: true}).Unscoped()
+		for _, table := range []interface{}{&model.Expense{}, &model.Member{}, &model.Group{}, &model.User{}} {
+			if err := tx.Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func pick[T any](random *rand.Rand, values []T) T {
+	return values[random.Intn(len(values))]
+}
+
+func randomTime(random *rand.Rand, from time.Time, to time.Time) time.Time {
+	delta := to.Sub(from)
+	if delta <= 0 {
+		return from
+	}
+	return from.Add(time.Duration(random.Int63n(int64(delta)))).Truncate(time.Second)
+}
+
+// randomStatus approves most expenses and leaves some pending or denied
+func randomStatus(random *rand.Rand) string {
+	switch n := random.Intn(100); {
+	case n < 80:
+		return "approved"
+	case n < 95:
+		return "pending"
+	default:
+		return "denied"
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"money_share/pkg/config"
+	"money_share/pkg/model"
+	"money_share/pkg/push"
+	"os"
+)
+
+// NewPushSenders returns the senders of the push providers whose credentials are set, by
+// platform
+func NewPushSenders(cfg config.Config) (map[string]push.Sender, error) {
+	senders := make(map[string]push.Sender)
+	if cfg.FCMCredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read FCM credentials: %w", err)
+		}
+		sender, err := push.NewFCMSender(credentials)
+		if err != nil {
+			return nil, err
+		}
+		senders[model.PlatformAndroid] = sender
+	}
+	if cfg.APNsKeyFile != "" {
+		key, err := os.ReadFile(cfg.APNsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read APNs key: %w", err)
+		}
+		endpoint := push.APNsProduction
+		if cfg.APNsSandbox {
+			endpoint = push.APNsSandbox
+		}
+		sender, err := push.NewAPNsSender(push.APNsConfig{
+			Key:      key,
+			KeyID:    cfg.APNsKeyID,
+			TeamID:   cfg.APNsTeamID,
+			Topic:    cfg.APNsTopic,
+			Endpoint: endpoint,
+		})
+		if err != nil {
+			return nil, err
+		}
+		senders[model.PlatformIOS] = sender
+	}
+	return senders, nil
+}
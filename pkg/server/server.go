@@ -0,0 +1,158 @@
+// Package server wires the API: the repositories with their cache and realtime decorators, the
+// background workers and the router. The API binary and the end-to-end tests share it, so that
+// the tests run the production wiring.
+package server
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v9"
+	"gorm.io/gorm"
+	"log/slog"
+	"money_share/pkg/background"
+	"money_share/pkg/cache"
+	"money_share/pkg/config"
+	"money_share/pkg/controller"
+	"money_share/pkg/database"
+	"money_share/pkg/health"
+	"money_share/pkg/middleware"
+	"money_share/pkg/push"
+	"money_share/pkg/ratelimit"
+	"money_share/pkg/realtime"
+	"money_share/pkg/repository"
+	"money_share/pkg/route"
+	"money_share/pkg/storage"
+	"money_share/pkg/webhook"
+	"net/http"
+	"time"
+)
+
+// Notifications older than this when they are polled are not pushed, e.g. after an outage
+const pushMaxAge = time.Hour
+
+// Options are the dependencies of a server which are not built from the config
+type Options struct {
+	Storage storage.Storage
+	// PushSenders send push notifications by platform, nothing is pushed without senders
+	PushSenders map[string]push.Sender
+	Logger      *slog.Logger
+}
+
+// Server is the API with the background workers it needs
+type Server struct {
+	App     *controller.App
+	Handler http.Handler
+	// PushWorker is nil when there are no push senders
+	PushWorker *push.Worker
+	Webhooks   *webhook.Deliverer
+}
+
+// New builds the API on db and rdb. The workers are started with Start.
+func New(cfg config.Config, db *gorm.DB, rdb *redis.Client, opts Options) (*Server, error) {
+	logger := opts.Logger
+
+	// Set up readiness checks for dependencies
+	healthChecker := health.NewChecker()
+	healthChecker.Register(cfg.DatabaseDriver, cfg.ReadinessCheckTimeout, (&database.SQLDB{DB: db}).Ping)
+	healthChecker.Register("redis", cfg.ReadinessCheckTimeout, (&database.RedisDB{DB: rdb}).Ping)
+
+	app := &controller.App{
+		Config:                 cfg,
+		Clock:                  time.Now,
+		Logger:                 logger,
+		Redis:                  rdb,
+		Storage:                opts.Storage,
+		UserRepository:         repository.NewUserRepository(db),
+		GroupRepository:        repository.NewGroupRepository(db),
+		MemberRepository:       repository.NewMemberRepository(db),
+		ExpenseRepository:      repository.NewExpenseRepository(db),
+		ActivityRepository:     repository.NewActivityRepository(db),
+		NotificationRepository: repository.NewNotificationRepository(db),
+		DeviceRepository:       repository.NewDeviceRepository(db),
+		WebhookRepository:      repository.NewWebhookRepository(db),
+		PaymentRepository:      repository.NewPaymentRepository(db),
+		HealthChecker:          healthChecker,
+	}
+	if cfg.CacheEnabled {
+		// Group screens poll group details and balances, serve them from redis
+		groupCache := cache.New(rdb, cfg.CacheTTL, logger)
+		// Permission checks read members uncached, a missed invalidation must not keep access
+		app.Memberships = app.MemberRepository
+		app.UserRepository = cache.NewUserRepository(app.UserRepository, app.GroupRepository, groupCache)
+		app.GroupRepository = cache.NewGroupRepository(app.GroupRepository, groupCache)
+		app.MemberRepository = cache.NewMemberRepository(app.MemberRepository, groupCache)
+		app.ExpenseRepository = cache.NewExpenseRepository(app.ExpenseRepository, groupCache)
+	}
+	// Stream the changes of groups to their members, fanned out to every instance through redis
+	app.Events = realtime.NewHub(rdb, logger)
+	publisher := &realtime.Publisher{Hub: app.Events, Groups: app.GroupRepository, Logger: logger}
+	app.GroupRepository = realtime.NewGroupRepository(app.GroupRepository, publisher)
+	app.MemberRepository = realtime.NewMemberRepository(app.MemberRepository, publisher)
+	app.ExpenseRepository = realtime.NewExpenseRepository(app.ExpenseRepository, publisher)
+
+	// Requests are limited in memory while redis is unreachable
+	rateLimiter := &ratelimit.FallbackLimiter{
+		Primary:  ratelimit.NewRedisLimiter(rdb),
+		Fallback: ratelimit.NewMemoryLimiter(),
+		Logger:   logger,
+	}
+	rateLimitConfig, err := middleware.NewRateLimitConfig(cfg, rateLimiter, logger)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	return &Server{
+		App:        app,
+		Handler:    route.NewRouter(app, rateLimitConfig),
+		PushWorker: newPushWorker(cfg, app, opts.PushSenders, logger),
+		Webhooks:   newWebhookDeliverer(cfg, app, logger),
+	}, nil
+}
+
+// Start runs the event hub and the workers pushing notifications and delivering webhooks
+func (s *Server) Start(workers *background.Group) {
+	workers.Go("events", s.App.Events.Run)
+	if s.PushWorker != nil {
+		workers.Go("push", s.PushWorker.Run)
+	}
+	workers.Go("webhooks", s.Webhooks.Run)
+}
+
+// newPushWorker returns the worker pushing notifications through senders, nil when there are none
+func newPushWorker(cfg config.Config, app *controller.App, senders map[string]push.Sender, logger *slog.Logger) *push.Worker {
+	if len(senders) == 0 {
+		return nil
+	}
+	return &push.Worker{
+		Notifications: app.NotificationRepository,
+		Groups:        app.GroupRepository,
+		Users:         app.UserRepository,
+		Dispatcher: &push.Dispatcher{
+			Devices:       app.DeviceRepository,
+			Senders:       senders,
+			MaxAttempts:   cfg.PushMaxAttempts,
+			Backoff:       cfg.PushBackoff,
+			MaxRetryDelay: cfg.PushMaxRetryDelay,
+			Logger:        logger,
+		},
+		Interval:  cfg.PushPollInterval,
+		BatchSize: 100,
+		MaxAge:    pushMaxAge,
+		Logger:    logger,
+	}
+}
+
+// newWebhookDeliverer returns the deliverer posting the activity of groups to their webhooks
+func newWebhookDeliverer(cfg config.Config, app *controller.App, logger *slog.Logger) *webhook.Deliverer {
+	return &webhook.Deliverer{
+		Webhooks:     app.WebhookRepository,
+		Client:       webhook.NewClient(cfg.WebhookTimeout, cfg.WebhookAllowPrivateNetworks),
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		Backoff:      cfg.WebhookBackoff,
+		DisableAfter: cfg.WebhookDisableAfter,
+		Interval:     cfg.WebhookPollInterval,
+		BatchSize:    50,
+		// Attempts end with the timeout of the client, the margin covers saving them
+		Lease:  cfg.WebhookTimeout + 30*time.Second,
+		Logger: logger,
+	}
+}
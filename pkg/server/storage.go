@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"money_share/pkg/config"
+	"money_share/pkg/storage"
+)
+
+// storageSigningLabel separates the derived URL signing key from other uses of the JWT key
+const storageSigningLabel = "money_share storage URL signing"
+
+// NewStorage builds the storage backend selected by STORAGE_BACKEND
+func NewStorage(ctx context.Context, cfg config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case "", "local":
+		signingKey, err := storageSigningKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewLocalStorage(cfg.StorageLocalDir, cfg.StoragePublicURL, signingKey), nil
+	case "s3":
+		s3Storage, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			UseSSL:    cfg.S3UseSSL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s3Storage.EnsureBucket(ctx); err != nil {
+			return nil, fmt.Errorf("cannot ensure bucket '%s': %w", cfg.S3Bucket, err)
+		}
+		return s3Storage, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend '%s'", cfg.StorageBackend)
+	}
+}
+
+// storageSigningKey is STORAGE_SIGNING_KEY, or else a key derived from the JWT key so that
+// the token signing secret is not used to sign URLs as well
+func storageSigningKey(cfg config.Config) ([]byte, error) {
+	if cfg.StorageSigningKey != "" {
+		return []byte(cfg.StorageSigningKey), nil
+	}
+	if cfg.JWTKey == "" {
+		return nil, errors.New("STORAGE_SIGNING_KEY or JWT_KEY must be set to sign file URLs")
+	}
+	key := make([]byte, sha256.Size)
+	_, err := io.ReadFull(hkdf.New(sha256.New, []byte(cfg.JWTKey), nil, []byte(storageSigningLabel)), key)
+	return key, err
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// MigrateDirectory copies every regular file below dir into dst under prefix, keeping
+// relative paths, and calls progress after each file. Existing objects are overwritten
+// so an interrupted migration can simply be run again.
+func MigrateDirectory(ctx context.Context, dir string, prefix string, dst Storage, progress func(key string)) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		key := path.Join(prefix, filepath.ToSlash(relative))
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if err := dst.Put(ctx, key, file, info.Size(), mime.TypeByExtension(filepath.Ext(filePath))); err != nil {
+			return err
+		}
+		count++
+		if progress != nil {
+			progress(key)
+		}
+		return nil
+	})
+	return count, err
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Length of the response bodies kept in the delivery log
+const maxResponseBody = 1024
+
+// Deliverer posts the queued deliveries to their webhooks. Deliveries are claimed before they
+// are attempted, so that every instance may run a deliverer and each attempt is made once.
+type Deliverer struct {
+	Webhooks repository.WebhookRepository
+	Client   *http.Client
+	// MaxAttempts is the number of times a delivery is attempted before it fails
+	MaxAttempts int
+	// Backoff is the delay before the first retry, it doubles with every retry
+	Backoff time.Duration
+	// DisableAfter is the number of deliveries failing in a row which disables a webhook
+	DisableAfter int
+	// Interval is the delay between polls for due deliveries
+	Interval time.Duration
+	// BatchSize is the number of deliveries attempted at once
+	BatchSize int
+	// Lease is the time a claimed delivery is reserved for its attempt, a delivery whose
+	// instance stopped is attempted again after it
+	Lease  time.Duration
+	Logger *slog.Logger
+}
+
+// Run attempts due deliveries until ctx is cancelled
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// A full batch means more are waiting
+		for {
+			attempted, err := d.Flush(ctx)
+			if err != nil && ctx.Err() == nil {
+				d.Logger.Warn("Cannot deliver webhooks", "error", err)
+			}
+			if err != nil || attempted < d.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// Flush attempts a batch of the due deliveries and returns the number attempted
+func (d *Deliverer) Flush(ctx context.Context) (int, error) {
+	deliveries, err := d.Webhooks.WithContext(ctx).ClaimDue(d.BatchSize, d.Lease)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (d *Deliverer) attempt(ctx context.Context, delivery *model.WebhookDelivery) {
+	status, body, err := d.post(ctx, delivery)
+	if ctx.Err() != nil {
+		// Stopping, the delivery is attempted again once its lease expires
+		return
+	}
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.Error = ""
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliverySucceeded
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = now.Add(d.Backoff << (delivery.Attempts - 1))
+	}
+
+	disabled, err := d.Webhooks.WithContext(ctx).SaveAttempt(delivery, d.DisableAfter)
+	if err != nil {
+		d.Logger.Warn("Cannot save webhook delivery", "delivery_id", delivery.ID, "error", err)
+		return
+	}
+	if disabled {
+		d.Logger.Info("Disabled failing webhook", "webhook_id", delivery.WebhookID, "group_id", delivery.Webhook.GroupID)
+	}
+}
+
+// post sends the payload and returns the status and beginning of the body of the response, with
+// an error unless the status is 2xx
+func (d *Deliverer) post(ctx context.Context, delivery *model.WebhookDelivery) (int, string, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Webhook.Secret, payload))
+	res, err := d.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+	// The rest is drained so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*maxResponseBody))
+	// Bodies are stored as text, which cannot hold invalid UTF-8 or NUL
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(body), ""), "\x00", "")
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, text, fmt.Errorf("endpoint responded %d", res.StatusCode)
+	}
+	return res.StatusCode, text, nil
+}
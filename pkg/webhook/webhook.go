@@ -0,0 +1,41 @@
+// Package webhook delivers the activity of groups to the endpoints their managers register.
+// Deliveries are queued by the repositories with the change and attempted by a Deliverer, which
+// retries failed deliveries with exponential backoff and disables webhooks which keep failing.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Headers of delivery requests
+const (
+	HeaderEvent     = "X-MoneyShare-Event"
+	HeaderDelivery  = "X-MoneyShare-Delivery"
+	HeaderSignature = "X-MoneyShare-Signature-256"
+	userAgent       = "MoneyShare-Webhook/1.0"
+)
+
+// Sign returns the signature of a payload, the hex encoded HMAC-SHA256 of the body keyed with
+// the secret of the webhook and prefixed with sha256=
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body, receivers do the same check
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// NewSecret returns a random secret for a new webhook
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"bytes"
+	testifyRequire "github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"money_share/pkg/admin"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubUserRepository serves a single user and records updates
+type stubUserRepository struct {
+	repository.UserRepository
+	user    *model.User
+	updates []map[string]interface{}
+}
+
+func (repo *stubUserRepository) GetByUsername(username string) (*model.User, error) {
+	if username != repo.user.Username {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return repo.user, nil
+}
+
+func (repo *stubUserRepository) Update(userID uint, updateMap map[string]interface{}) (*model.User, error) {
+	repo.updates = append(repo.updates, updateMap)
+	return repo.user, nil
+}
+
+// stubGroupRepository serves a single group and records recomputed totals
+type stubGroupRepository struct {
+	repository.GroupRepository
+	group  *model.Group
+	totals *model.GroupTotals
+}
+
+func (repo *stubGroupRepository) GetWithExpenses(groupId uint) (*model.Group, error) {
+	return repo.group, nil
+}
+
+func (repo *stubGroupRepository) UpdateTotals(groupID uint, total float32, average float32, memberTotals map[uint]float32) error {
+	repo.totals = &model.GroupTotals{Total: total, Average: average, Members: memberTotals}
+	return nil
+}
+
+func newAdmin(users *stubUserRepository, groups *stubGroupRepository, input string) (*admin.Admin, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &admin.Admin{
+		UserRepository:  users,
+		GroupRepository: groups,
+		In:              strings.NewReader(input),
+		Out:             out,
+		Clock:           func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}, out
+}
+
+func TestChangesAreDryRunByDefault(t *testing.T) {
+	require := testifyRequire.New(t)
+	users := &stubUserRepository{user: &model.User{Model: gorm.Model{ID: 3}, Username: "alice.smith"}}
+	a, out := newAdmin(users, nil, "")
+
+	require.NoError(a.Run([]string{"reset-password", "alice.smith"}))
+	require.Contains(out.String(), "Reset password of user 3 (alice.smith)")
+	require.Contains(out.String(), "Dry run")
+	require.Empty(users.updates)
+}
+
+func TestChangesAreAppliedOnlyWhenConfirmed(t *testing.T) {
+	require := testifyRequire.New(t)
+	users := &stubUserRepository{user: &model.User{Model: gorm.Model{ID: 3}, Username: "alice.smith"}}
+
+	a, _ := newAdmin(users, nil, "n\n")
+	a.Apply = true
+	require.ErrorIs(a.Run([]string{"disable-user", "alice.smith"}), admin.ErrNotConfirmed)
+	require.Empty(users.updates)
+
+	a, out := newAdmin(users, nil, "y\n")
+	a.Apply = true
+	require.NoError(a.Run([]string{"disable-user", "alice.smith"}))
+	require.Contains(out.String(), "Disable user 3 (alice.smith), requests with tokens already issued are rejected")
+	require.Contains(out.String(), "Applied.")
+	require.Len(users.updates, 1)
+	require.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), users.updates[0]["DisabledAt"])
+}
+
+func TestResetPasswordPrintsWorkingPassword(t *testing.T) {
+	require := testifyRequire.New(t)
+	users := &stubUserRepository{user: &model.User{Model: gorm.Model{ID: 3}, Username: "alice.smith"}}
+	a, out := newAdmin(users, nil, "")
+	a.Apply, a.Yes = true, true
+
+	require.NoError(a.Run([]string{"reset-password", "alice.smith"}))
+	require.Len(users.updates, 1)
+	var password string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "New password of user 3 (alice.smith): ") {
+			password = strings.TrimPrefix(line, "New password of user 3 (alice.smith): ")
+		}
+	}
+	require.NoError(model.ValidatePassword(password))
+	hashed := &model.User{Password: users.updates[0]["Password"].(string)}
+	require.True(hashed.ComparePassword(password))
+}
+
+func TestRecomputeTotals(t *testing.T) {
+	require := testifyRequire.New(t)
+	group := &model.Group{
+		Model:        gorm.Model{ID: 7},
+		TotalExpense: 999,
+		Members: []model.Member{
+			{UserID: 1, GroupID: 7, TotalExpense: 10},
+			{UserID: 2, GroupID: 7, TotalExpense: 50},
+		},
+		Expenses: []model.Expense{
+			{Amount: 10, Status: "approved", MemberID: 1, GroupID: 7},
+			{Amount: 30, Status: "approved", MemberID: 2, GroupID: 7},
+			{Amount: 500, Status: "pending", MemberID: 2, GroupID: 7},
+		},
+	}
+	groups := &stubGroupRepository{group: group}
+	a, out := newAdmin(nil, groups, "")
+	a.Apply, a.Yes = true, true
+	var invalidated []uint
+	a.InvalidateGroup = func(groupID uint) error {
+		invalidated = append(invalidated, groupID)
+		return nil
+	}
+
+	require.NoError(a.Run([]string{"recompute-totals", "7"}))
+	require.Equal([]uint{7}, invalidated)
+	require.Contains(out.String(), "Group 7 total: 999.00 -> 40.00")
+	require.Contains(out.String(), "Member 2 () total: 50.00 -> 30.00")
+	require.NotContains(out.String(), "Member 1")
+	require.Equal(&model.GroupTotals{Total: 40, Average: 20, Members: map[uint]float32{1: 10, 2: 30}}, groups.totals)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	a, _ := newAdmin(nil, nil, "")
+	testifyRequire.ErrorIs(t, a.Run([]string{"drop-database"}), admin.ErrUsage)
+	testifyRequire.ErrorIs(t, a.Run([]string{"set-role", "1"}), admin.ErrUsage)
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/cache"
+	"money_share/pkg/metrics"
+	"money_share/pkg/model"
+	"money_share/pkg/repository/memory"
+	testmodel "money_share/test_tool/model"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCache(t *testing.T, server *miniredis.Miniredis) *cache.Cache {
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return cache.New(client, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestReadThroughAndInvalidation(t *testing.T) {
+	require := testifyRequire.New(t)
+	c := newCache(t, miniredis.RunT(t))
+	ctx := context.Background()
+	hits := metrics.CacheRequests.WithLabelValues("test", "hit")
+	misses := metrics.CacheRequests.WithLabelValues("test", "miss")
+	hitsBefore, missesBefore := testutil.ToFloat64(hits), testutil.ToFloat64(misses)
+
+	loads := 0
+	load := func() (*model.Group, error) {
+		loads++
+		return &model.Group{Name: "Flatmates", TotalExpense: float32(loads)}, nil
+	}
+	for i := 0; i < 3; i++ {
+		group, err := cache.Load(ctx, c, "test", 1, "detail", load)
+		require.NoError(err)
+		require.Equal(float32(1), group.TotalExpense)
+	}
+	require.Equal(1, loads)
+	require.Equal(float64(2), testutil.ToFloat64(hits)-hitsBefore)
+	require.Equal(float64(1), testutil.ToFloat64(misses)-missesBefore)
+
+	// Invalidating a group reloads its entries only
+	require.NoError(c.InvalidateGroup(ctx, 1))
+	group, err := cache.Load(ctx, c, "test", 1, "detail", load)
+	require.NoError(err)
+	require.Equal(float32(2), group.TotalExpense)
+	_, err = cache.Load(ctx, c, "test", 2, "detail", load)
+	require.NoError(err)
+	require.NoError(c.InvalidateGroup(ctx, 2))
+	group, err = cache.Load(ctx, c, "test", 1, "detail", load)
+	require.NoError(err)
+	require.Equal(float32(2), group.TotalExpense)
+
+	// Errors are not cached
+	notFound := errors.New("not found")
+	_, err = cache.Load(ctx, c, "test", 3, "detail", func() (*model.Group, error) { return nil, notFound })
+	require.ErrorIs(err, notFound)
+	group, err = cache.Load(ctx, c, "test", 3, "detail", load)
+	require.NoError(err)
+	require.NotNil(group)
+}
+
+func TestConcurrentMissesLoadOnce(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := miniredis.RunT(t)
+	// Two instances share redis but not their in-process guard
+	instances := []*cache.Cache{newCache(t, server), newCache(t, server)}
+
+	var loads atomic.Int32
+	load := func() (string, error) {
+		loads.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return "balances", nil
+	}
+	values := make([]string, 20)
+	errs := make([]error, 20)
+	var wg sync.WaitGroup
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i] = cache.Load(context.Background(), instances[i%2], "test", 1, "member:1", load)
+		}(i)
+	}
+	wg.Wait()
+	for i := range values {
+		require.NoError(errs[i])
+		require.Equal("balances", values[i])
+	}
+	require.Equal(int32(1), loads.Load())
+}
+
+func TestRedisUnavailableReadsThrough(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := miniredis.RunT(t)
+	c := newCache(t, server)
+	server.Close()
+	errorsCounter := metrics.CacheRequests.WithLabelValues("test", "error")
+	before := testutil.ToFloat64(errorsCounter)
+
+	value, err := cache.Load(context.Background(), c, "test", 1, "detail", func() (string, error) { return "fresh", nil })
+	require.NoError(err)
+	require.Equal("fresh", value)
+	require.Equal(float64(1), testutil.ToFloat64(errorsCounter)-before)
+	require.Error(c.InvalidateGroup(context.Background(), 1))
+}
+
+func TestRepositoriesInvalidateOnWrites(t *testing.T) {
+	require := testifyRequire.New(t)
+	c := newCache(t, miniredis.RunT(t))
+	store := memory.NewStore()
+	users := cache.NewUserRepository(memory.NewUserRepository(store), memory.NewGroupRepository(store), c)
+	groups := cache.NewGroupRepository(memory.NewGroupRepository(store), c)
+	members := cache.NewMemberRepository(memory.NewMemberRepository(store), c)
+	expenses := cache.NewExpenseRepository(memory.NewExpenseRepository(store), c)
+
+	alice, bob := testmodel.GenerateRandomUser(), testmodel.GenerateRandomUser()
+	require.NoError(users.Create(&alice))
+	require.NoError(users.Create(&bob))
+	group := testmodel.GenerateRandomGroup()
+	require.NoError(groups.Create(&group, alice.ID))
+	cached, err := groups.GetById(group.ID)
+	require.NoError(err)
+	require.Len(cached.Members, 1)
+	require.Empty(cached.Members[0].User.Password, "password hashes are not cached")
+
+	// Member changes, expense hooks and group updates all show up on the next read
+	require.NoError(members.AddMemberToGroup(bob.ID, group.ID))
+	cached, err = groups.GetById(group.ID)
+	require.NoError(err)
+	require.Len(cached.Members, 2)
+
+	expense := model.Expense{Title: "Rent", Amount: 300, PurchaseTime: time.Now(), Status: "approved", GroupID: group.ID, MemberID: bob.ID}
+	require.NoError(expenses.Create(&expense))
+	cached, err = groups.GetById(group.ID)
+	require.NoError(err)
+	require.InDelta(300, cached.TotalExpense, 0.001)
+	member, err := members.GetByID(bob.ID, group.ID)
+	require.NoError(err)
+	require.InDelta(300, member.TotalExpense, 0.001)
+
+	_, err = expenses.Update(&model.Expense{Model: expense.Model, Amount: 100})
+	require.NoError(err)
+	member, err = members.GetByID(bob.ID, group.ID)
+	require.NoError(err)
+	require.InDelta(100, member.TotalExpense, 0.001)
+	require.NoError(expenses.Delete(expense.ID))
+	cached, err = groups.GetById(group.ID)
+	require.NoError(err)
+	require.Zero(cached.TotalExpense)
+
+	require.NoError(members.UpdateRole(bob.ID, group.ID, model.RoleManager))
+	member, err = members.GetByID(bob.ID, group.ID)
+	require.NoError(err)
+	require.Equal(model.RoleManager, member.Role)
+
+	require.NoError(groups.UpdateTotals(group.ID, 50, 25, map[uint]float32{alice.ID: 50, bob.ID: 0}))
+	cached, err = groups.GetById(group.ID)
+	require.NoError(err)
+	require.InDelta(50, cached.TotalExpense, 0.001)
+
+	// Cached members show the new display name of their user
+	_, err = users.Update(bob.ID, map[string]interface{}{"DisplayName": "Bobby"})
+	require.NoError(err)
+	member, err = members.GetByID(bob.ID, group.ID)
+	require.NoError(err)
+	require.Equal("Bobby", member.User.DisplayName)
+
+	require.NoError(groups.Update(&model.Group{Model: group.Model, Name: "Renamed"}))
+	cached, err = groups.GetById(group.ID)
+	require.NoError(err)
+	require.Equal("Renamed", cached.Name)
+
+	require.NoError(groups.Delete(group.ID))
+	_, err = groups.GetById(group.ID)
+	require.Error(err)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	testifyRequire "github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"io"
+	"log/slog"
+	"money_share/pkg/controller"
+	"money_share/pkg/dto"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubUserRepository serves a fixed user, other methods are not used by these tests
+type stubUserRepository struct {
+	repository.UserRepository
+	user model.User
+}
+
+func (repo stubUserRepository) WithContext(ctx context.Context) repository.UserRepository {
+	return repo
+}
+
+func (repo stubUserRepository) GetById(userId uint) (*model.User, error) {
+	if userId != repo.user.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	user := repo.user
+	return &user, nil
+}
+
+func newApp(user model.User) *controller.App {
+	return &controller.App{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		UserRepository: stubUserRepository{user: user},
+	}
+}
+
+func TestHandlersUseInjectedRepositories(t *testing.T) {
+	require := testifyRequire.New(t)
+	alice := model.User{Model: gorm.Model{ID: 1}, Username: "alice.smith"}
+	bob := model.User{Model: gorm.Model{ID: 1}, Username: "bob.jones"}
+
+	// Two independent apps in the same process
+	for _, user := range []model.User{alice, bob} {
+		app := newApp(user)
+		r := mux.NewRouter()
+		r.HandleFunc("/user/{userId:[0-9]+}", app.GetUserByID)
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/user/1", nil))
+		require.Equal(http.StatusOK, rec.Code)
+		userDTO := dto.UserDTO{}
+		require.NoError(json.Unmarshal(rec.Body.Bytes(), &userDTO))
+		require.Equal(user.Username, userDTO.Username)
+	}
+}
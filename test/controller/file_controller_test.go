@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/controller"
+	"money_share/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetSignedFile(t *testing.T) {
+	require := testifyRequire.New(t)
+	localStorage := storage.NewLocalStorage(t.TempDir(), "http://localhost/file", []byte("key"))
+	require.Nil(localStorage.Put(context.Background(), "userProfileImage/a.png", strings.NewReader("png"), 3, "image/png"))
+	app := &controller.App{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), Storage: localStorage}
+	r := mux.NewRouter()
+	r.HandleFunc("/file/{key:.+}", app.GetSignedFile)
+
+	signedURL, err := localStorage.SignedURL(context.Background(), "userProfileImage/a.png", time.Minute)
+	require.Nil(err)
+	parsed, err := url.Parse(signedURL)
+	require.Nil(err)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", parsed.RequestURI(), nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal("image/png", rec.Header().Get("Content-Type"))
+	require.Equal("png", rec.Body.String())
+
+	// Unsigned and tampered requests are refused
+	for _, target := range []string{"/file/userProfileImage/a.png", "/file/userProfileImage/b.png?" + parsed.RawQuery} {
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		require.Equal(http.StatusForbidden, rec.Code, target)
+	}
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	testifyRequire "github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"money_share/pkg/controller"
+	"money_share/pkg/dto"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"money_share/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// memoryUserRepository keeps a single user and applies profile image updates
+type memoryUserRepository struct {
+	repository.UserRepository
+	user *model.User
+}
+
+func (repo memoryUserRepository) WithContext(ctx context.Context) repository.UserRepository {
+	return repo
+}
+
+func (repo memoryUserRepository) GetById(userId uint) (*model.User, error) {
+	if userId != repo.user.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	user := *repo.user
+	return &user, nil
+}
+
+func (repo memoryUserRepository) ValidateUsernameAndUserID(username string, userId uint) (bool, error) {
+	return username == repo.user.Username && userId == repo.user.ID, nil
+}
+
+func (repo memoryUserRepository) Update(userId uint, updateMap map[string]interface{}) (*model.User, error) {
+	repo.user.ProfileImageUrl = updateMap["ProfileImageUrl"].(string)
+	return repo.GetById(userId)
+}
+
+func newProfileImageRouter(t *testing.T) (*mux.Router, storage.Storage) {
+	fileStorage := storage.NewLocalStorage(t.TempDir(), "", nil)
+	app := &controller.App{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Storage:        fileStorage,
+		UserRepository: memoryUserRepository{user: &model.User{Model: gorm.Model{ID: 1}, Username: "alice.smith"}},
+	}
+	r := mux.NewRouter()
+	r.HandleFunc("/user/profileImage/{fileName}", app.GetUserProfileImage).Methods("GET")
+	r.HandleFunc("/user/auth/{userId:[0-9]+}/profileImage", app.UploadUserProfileImage).Methods("POST")
+	return r, fileStorage
+}
+
+func uploadProfileImage(t *testing.T, r *mux.Router, content []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "avatar.jpg")
+	testifyRequire.NoError(t, err)
+	_, _ = part.Write(content)
+	testifyRequire.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/user/auth/1/profileImage", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("username", "alice.smith")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func pngBytes(t *testing.T, width int) []byte {
+	buf := &bytes.Buffer{}
+	testifyRequire.NoError(t, png.Encode(buf, image.NewGray(image.Rect(0, 0, width, width))))
+	return buf.Bytes()
+}
+
+func TestProfileImageUploadAndDownload(t *testing.T) {
+	require := testifyRequire.New(t)
+	r, fileStorage := newProfileImageRouter(t)
+
+	// Content is sniffed, the client supplied extension is ignored
+	rec := uploadProfileImage(t, r, []byte("#!/bin/sh\nrm -rf /\n"))
+	require.Equal(http.StatusUnsupportedMediaType, rec.Code)
+
+	rec = uploadProfileImage(t, r, pngBytes(t, 10))
+	require.Equal(http.StatusOK, rec.Code)
+	first := dto.UserDTO{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &first))
+	require.Regexp(`^[0-9a-f]{32}\.png$`, first.ProfileImageUrl)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/user/profileImage/"+first.ProfileImageUrl+"?size=64", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal("image/png", rec.Header().Get("Content-Type"))
+	require.Contains(rec.Header().Get("Cache-Control"), "immutable")
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(etag)
+	config, err := png.DecodeConfig(rec.Body)
+	require.NoError(err)
+	require.Equal(64, config.Width)
+
+	// Conditional requests are answered without a body
+	req := httptest.NewRequest("GET", "/user/profileImage/"+first.ProfileImageUrl+"?size=64", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(http.StatusNotModified, rec.Code)
+
+	// Unknown sizes and names that weren't generated on upload are refused
+	for target, code := range map[string]int{
+		"/user/profileImage/" + first.ProfileImageUrl + "?size=100": http.StatusBadRequest,
+		"/user/profileImage/..%5C..%5Cwin.ini":                      http.StatusNotFound,
+		"/user/profileImage/avatar.html":                            http.StatusNotFound,
+	} {
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		require.Equal(code, rec.Code, target)
+	}
+
+	// Replacing the image removes every size of the old one
+	rec = uploadProfileImage(t, r, pngBytes(t, 20))
+	require.Equal(http.StatusOK, rec.Code)
+	second := dto.UserDTO{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &second))
+	require.NotEqual(first.ProfileImageUrl, second.ProfileImageUrl)
+	id := first.ProfileImageUrl[:32]
+	for _, key := range []string{id + "_64.png", id + "_256.png", id + "_512.png"} {
+		_, _, err := fileStorage.Get(context.Background(), "userProfileImage/"+key)
+		require.ErrorIs(err, storage.ErrNotFound, key)
+	}
+}
+
+func TestLegacyProfileImage(t *testing.T) {
+	require := testifyRequire.New(t)
+	r, fileStorage := newProfileImageRouter(t)
+	// Stored as uploaded before resizing, and copied as is by migrate-files
+	image := pngBytes(t, 10)
+	require.NoError(fileStorage.Put(context.Background(), "userProfileImage/alice.smith_1700000000.png",
+		bytes.NewReader(image), int64(len(image)), "image/png"))
+	page := []byte("<html><script>alert(1)</script></html>")
+	require.NoError(fileStorage.Put(context.Background(), "userProfileImage/alice.smith_1700000001.html",
+		bytes.NewReader(page), int64(len(page)), "text/html"))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/user/profileImage/alice.smith_1700000000.png", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal("image/png", rec.Header().Get("Content-Type"))
+	require.Equal(image, rec.Body.Bytes())
+
+	// Legacy uploads weren't validated, anything but an image is a download
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/user/profileImage/alice.smith_1700000001.html", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal("application/octet-stream", rec.Header().Get("Content-Type"))
+	require.Equal("nosniff", rec.Header().Get("X-Content-Type-Options"))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/user/profileImage/alice.smith_1700000002.png", nil))
+	require.Equal(http.StatusNotFound, rec.Code)
+}
+
+func TestProfileImageUploadRequiresOwner(t *testing.T) {
+	require := testifyRequire.New(t)
+	r, _ := newProfileImageRouter(t)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "avatar.png")
+	require.NoError(err)
+	_, _ = part.Write(pngBytes(t, 10))
+	require.NoError(writer.Close())
+
+	req := httptest.NewRequest("POST", "/user/auth/1/profileImage", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("username", "bob.jones")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(http.StatusForbidden, rec.Code)
+}
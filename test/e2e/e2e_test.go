@@ -0,0 +1,906 @@
+package e2e
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"money_share/pkg/dto"
+	"money_share/pkg/dto/request"
+	"money_share/pkg/dto/response"
+	"money_share/pkg/model"
+	"money_share/pkg/webhook"
+	"money_share/test_tool/e2e"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpenseJourney(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+
+	// The creator of a group manages it
+	group := alice.CreateGroup("Flatmates")
+	require.NotZero(group.ID)
+	require.Equal("Flatmates", group.Name)
+	alice.AddMember(group.ID, bob.User.ID)
+	group = alice.Group(group.ID)
+	require.Len(group.Members, 2)
+	require.Equal("manager", alice.Member(group.ID, alice.User.ID).Role)
+	require.Equal("member", alice.Member(group.ID, bob.User.ID).Role)
+
+	// Expenses of members wait for approval
+	rent := bob.CreateExpense(dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	require.Equal("pending", rent.Status)
+	require.Zero(alice.Group(group.ID).TotalExpense)
+
+	// Members cannot add expenses for others and outsiders cannot add expenses at all
+	res := bob.Post("/expense", dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: alice.User.ID,
+	})
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_manager", res.Error().Code)
+	res = carol.Post("/expense", dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: carol.User.ID,
+	})
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_member", res.Error().Code)
+
+	// Approving counts the expense in the totals
+	alice.SetExpenseStatus(rent.ID, "approved")
+	group = alice.Group(group.ID)
+	require.InDelta(300, group.TotalExpense, 0.001)
+	require.InDelta(150, group.AverageExpense, 0.001)
+	require.InDelta(300, alice.Member(group.ID, bob.User.ID).TotalExpense, 0.001)
+
+	// Expenses of managers are approved immediately, also when added for another member
+	groceries := alice.CreateExpense(dto.ExpenseDTO{
+		Title: "Groceries", Amount: 50.5, PurchaseTime: "2024-01-02 18:30:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	require.Equal("approved", groceries.Status)
+	internet := alice.CreateExpense(dto.ExpenseDTO{
+		Title: "Internet", Amount: 20, PurchaseTime: "2024-01-03 09:00:00", GroupID: group.ID, MemberID: alice.User.ID,
+	})
+	group = alice.Group(group.ID)
+	require.InDelta(370.5, group.TotalExpense, 0.001)
+	require.InDelta(185.25, group.AverageExpense, 0.001)
+	require.InDelta(20, alice.Member(group.ID, alice.User.ID).TotalExpense, 0.001)
+	require.InDelta(350.5, alice.Member(group.ID, bob.User.ID).TotalExpense, 0.001)
+	require.Len(alice.Expenses(group.ID, "").Items, 3)
+
+	// Deleting and denying take expenses out of the totals
+	alice.Delete(fmt.Sprintf("/expense/%d", internet.ID)).RequireStatus(http.StatusOK)
+	alice.SetExpenseStatus(groceries.ID, "denied")
+	group = alice.Group(group.ID)
+	require.InDelta(300, group.TotalExpense, 0.001)
+	require.Len(alice.Expenses(group.ID, "").Items, 2)
+
+	// A new member lowers the average
+	alice.AddMember(group.ID, carol.User.ID)
+	require.InDelta(100, alice.Group(group.ID).AverageExpense, 0.001)
+}
+
+func TestListPagination(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	for day := 1; day <= 5; day++ {
+		alice.CreateExpense(dto.ExpenseDTO{
+			Title: fmt.Sprintf("Day %d", day), Amount: float32(day * 10), PurchaseTime: fmt.Sprintf("2024-01-0%d 10:00:00", day),
+			GroupID: group.ID, MemberID: []uint{alice.User.ID, bob.User.ID}[day%2],
+		})
+	}
+
+	// Pages follow each other through the cursor, newest first by default
+	var titles []string
+	page := alice.Expenses(group.ID, "limit=2")
+	for {
+		require.LessOrEqual(len(page.Items), 2)
+		for _, expense := range page.Items {
+			titles = append(titles, expense.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		page = alice.Expenses(group.ID, "limit=2&cursor="+page.NextCursor)
+	}
+	require.Equal([]string{"Day 5", "Day 4", "Day 3", "Day 2", "Day 1"}, titles)
+
+	// Filters are combined, a date bound covers the whole day
+	page = alice.Expenses(group.ID, fmt.Sprintf("memberId=%d&from=2024-01-02&to=2024-01-04&sort=amount&order=asc", bob.User.ID))
+	require.Len(page.Items, 1)
+	require.Equal("Day 3", page.Items[0].Title)
+	page = alice.Expenses(group.ID, "minAmount=20&maxAmount=40&to=2024-01-03")
+	require.Len(page.Items, 2)
+	require.Empty(page.NextCursor)
+
+	// A cursor only continues the order it was created for
+	page = alice.Expenses(group.ID, "limit=1")
+	res := alice.Get(fmt.Sprintf("/expense/group/%d?sort=amount&cursor=%s", group.ID, page.NextCursor))
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	require.Equal("cursor", res.Error().Errors[0].Field)
+	res = alice.Get(fmt.Sprintf("/expense/group/%d?limit=1000&status=unknown", group.ID))
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+
+	// Groups and members are paged too
+	groups := response.PageResponse[dto.GroupDTO]{}
+	alice.Get(fmt.Sprintf("/group/user/%d?limit=1", alice.User.ID)).RequireStatus(http.StatusOK).Decode(&groups)
+	require.Len(groups.Items, 1)
+	require.Empty(groups.NextCursor)
+	members := response.PageResponse[dto.MemberDTO]{}
+	alice.Get(fmt.Sprintf("/member/group/%d?limit=1", group.ID)).RequireStatus(http.StatusOK).Decode(&members)
+	require.Len(members.Items, 1)
+	require.NotEmpty(members.NextCursor)
+	last := response.PageResponse[dto.MemberDTO]{}
+	alice.Get(fmt.Sprintf("/member/group/%d?limit=1&cursor=%s", group.ID, members.NextCursor)).RequireStatus(http.StatusOK).Decode(&last)
+	require.Len(last.Items, 1)
+	require.NotEqual(members.Items[0].User.ID, last.Items[0].User.ID)
+	require.Empty(last.NextCursor)
+}
+
+func TestExpenseSearch(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	group := alice.CreateGroup("Da Lat trip")
+	alice.CreateExpense(dto.ExpenseDTO{
+		Title: "Taxi", Description: "Night market in Da Lat", Amount: 8, PurchaseTime: "2024-04-02 21:00:00",
+		GroupID: group.ID, MemberID: alice.User.ID,
+	})
+
+	page := response.PageResponse[dto.ExpenseMatchDTO]{}
+	alice.Get("/expense/search?q=taxi+lat").RequireStatus(http.StatusOK).Decode(&page)
+	require.Len(page.Items, 1)
+	require.Equal("Taxi", page.Items[0].Expense.Title)
+	require.Contains(page.Items[0].Snippet, "<mark>Taxi</mark>")
+
+	// Other users do not see the expenses of groups they are not in
+	page = response.PageResponse[dto.ExpenseMatchDTO]{}
+	bob.Get("/expense/search?q=taxi").RequireStatus(http.StatusOK).Decode(&page)
+	require.Empty(page.Items)
+
+	res := alice.Get("/expense/search")
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+}
+
+func TestActivityFeed(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	rent := bob.CreateExpense(dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	alice.SetExpenseStatus(rent.ID, "approved")
+	alice.Put(fmt.Sprintf("/group/%d", group.ID), dto.GroupDTO{Name: "Flat"}).RequireStatus(http.StatusOK)
+
+	// Every member reads the feed newest first, the actor is the user who made the change
+	page := bob.Activity(group.ID, "limit=2")
+	require.Len(page.Items, 2)
+	require.Equal("group.renamed", page.Items[0].Action)
+	require.Equal(alice.User.ID, page.Items[0].ActorID)
+	require.JSONEq(`{"name":"Flat"}`, string(page.Items[0].After))
+	require.Equal("expense.approved", page.Items[1].Action)
+	require.Equal(rent.ID, page.Items[1].TargetID)
+	page = bob.Activity(group.ID, "limit=2&cursor="+page.NextCursor)
+	require.Len(page.Items, 2)
+	require.Equal("expense.created", page.Items[0].Action)
+	require.Equal(bob.User.ID, page.Items[0].ActorID)
+	require.Empty(page.Items[0].Before)
+	require.Equal("member.added", page.Items[1].Action)
+	require.Equal(bob.User.ID, page.Items[1].TargetID)
+	require.Equal(alice.User.ID, page.Items[1].ActorID)
+	require.Empty(page.NextCursor)
+
+	// Outsiders cannot read it
+	res := carol.Get(fmt.Sprintf("/group/%d/activity", group.ID))
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_member", res.Error().Code)
+}
+
+func TestNotificationInbox(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+
+	// Bob is told he was added, alice that his expense waits for her approval
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	rent := bob.CreateExpense(dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	page := alice.Notifications("")
+	require.Len(page.Items, 1)
+	require.Equal(int64(1), page.UnreadCount)
+	require.Equal("expense.pending", page.Items[0].Type)
+	require.Equal(rent.ID, page.Items[0].TargetID)
+	require.Equal(bob.User.ID, page.Items[0].ActorID)
+	require.False(page.Items[0].Read)
+
+	// Bob turns approvals off and is told about the denial only
+	var preferences dto.NotificationPreferencesDTO
+	bob.Put("/notification/preferences", map[string]interface{}{
+		"preferences": map[string]bool{"expense.approved": false},
+	}).RequireStatus(http.StatusOK).Decode(&preferences)
+	require.False(preferences.Preferences["expense.approved"])
+	require.True(preferences.Preferences["expense.denied"])
+	require.True(preferences.Preferences["payment.received"])
+	require.Len(preferences.Preferences, 5)
+	alice.SetExpenseStatus(rent.ID, "approved")
+	alice.SetExpenseStatus(rent.ID, "denied")
+	page = bob.Notifications("limit=1")
+	require.Equal(int64(2), page.UnreadCount)
+	require.Equal("expense.denied", page.Items[0].Type)
+	require.NotEmpty(page.NextCursor)
+
+	// Reading one and then all
+	bob.Put(fmt.Sprintf("/notification/%d/read", page.Items[0].ID), nil).RequireStatus(http.StatusOK)
+	page = bob.Notifications("unread=true")
+	require.Equal(int64(1), page.UnreadCount)
+	require.Len(page.Items, 1)
+	require.Equal("member.added", page.Items[0].Type)
+	res := alice.Put(fmt.Sprintf("/notification/%d/read", page.Items[0].ID), nil)
+	require.Equal(http.StatusNotFound, res.StatusCode)
+	require.Equal("notification_not_found", res.Error().Code)
+	bob.Put("/notification/read", nil).RequireStatus(http.StatusOK)
+	page = bob.Notifications("")
+	require.Zero(page.UnreadCount)
+	require.Len(page.Items, 2)
+	require.True(page.Items[1].Read)
+	require.NotEmpty(page.Items[1].ReadAt)
+
+	// Unknown types are rejected
+	res = bob.Put("/notification/preferences", map[string]interface{}{
+		"preferences": map[string]bool{"expense.paid": false},
+	})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+}
+
+func TestPayments(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	alice.Post("/device", request.DeviceRegistrationRequest{Platform: "android", Token: "alice-phone"}).RequireStatus(http.StatusOK)
+	paymentsPath := fmt.Sprintf("/group/%d/payment", group.ID)
+
+	// Bob settles his share of the rent, alice is notified and the payment is in the activity
+	var payment dto.PaymentDTO
+	bob.Post(paymentsPath, request.PaymentRequest{PayeeID: alice.User.ID, Amount: 45}).
+		RequireStatus(http.StatusOK).Decode(&payment)
+	require.Equal(bob.User.ID, payment.PayerID)
+	require.Equal(alice.User.ID, payment.PayeeID)
+	require.InDelta(45, payment.Amount, 0.001)
+	page := alice.Notifications("")
+	require.Equal("payment.received", page.Items[0].Type)
+	require.Equal(payment.ID, page.Items[0].TargetID)
+	require.Equal(bob.User.ID, page.Items[0].ActorID)
+	require.Eventually(func() bool {
+		for _, sent := range server.Push.Sent() {
+			if sent.Message.Data["type"] == "payment.received" {
+				return strings.HasSuffix(sent.Message.Body, "paid you 45.00")
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+	activity := alice.Activity(group.ID, "limit=1").Items[0]
+	require.Equal("payment.recorded", activity.Action)
+	require.Equal("payment", activity.TargetType)
+	require.Equal(payment.ID, activity.TargetID)
+
+	// Members read the payments of the group, newest first
+	bob.Post(paymentsPath, request.PaymentRequest{PayeeID: alice.User.ID, Amount: 5}).RequireStatus(http.StatusOK)
+	var payments response.PageResponse[dto.PaymentDTO]
+	alice.Get(paymentsPath + "?limit=1").RequireStatus(http.StatusOK).Decode(&payments)
+	require.Len(payments.Items, 1)
+	require.InDelta(5, payments.Items[0].Amount, 0.001)
+	require.NotEmpty(payments.NextCursor)
+
+	// Payments are to other members, of a positive amount, and by members only
+	res := bob.Post(paymentsPath, request.PaymentRequest{PayeeID: carol.User.ID, Amount: 10})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	require.Equal("payeeID", res.Error().Errors[0].Field)
+	res = bob.Post(paymentsPath, request.PaymentRequest{PayeeID: bob.User.ID, Amount: 10})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	res = bob.Post(paymentsPath, request.PaymentRequest{PayeeID: alice.User.ID, Amount: 0})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	res = carol.Post(paymentsPath, request.PaymentRequest{PayeeID: alice.User.ID, Amount: 10})
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_member", res.Error().Code)
+	res = carol.Get(paymentsPath)
+	require.Equal(http.StatusForbidden, res.StatusCode)
+}
+
+func TestPushNotifications(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+
+	var phone dto.DeviceDTO
+	bob.Post("/device", request.DeviceRegistrationRequest{
+		Platform: "android", Token: "bob-phone", AppVersion: "2.3.0",
+	}).RequireStatus(http.StatusOK).Decode(&phone)
+	require.Equal("bob-phone", phone.Token)
+	var tablet dto.DeviceDTO
+	bob.Post("/device", request.DeviceRegistrationRequest{Platform: "ios", Token: "bob-tablet"}).RequireStatus(http.StatusOK).Decode(&tablet)
+	var devices []dto.DeviceDTO
+	bob.Get("/device").RequireStatus(http.StatusOK).Decode(&devices)
+	require.Len(devices, 2)
+
+	// Notifications are pushed to every device, tokens the provider rejects are removed
+	server.Push.Invalidate("bob-tablet")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	require.Eventually(func() bool { return len(server.Push.Sent()) == 1 }, time.Second, 10*time.Millisecond)
+	sent := server.Push.Sent()[0]
+	require.Equal("bob-phone", sent.Token)
+	require.Equal("Flatmates", sent.Message.Title)
+	require.Equal("member.added", sent.Message.Data["type"])
+	require.Eventually(func() bool {
+		bob.Get("/device").RequireStatus(http.StatusOK).Decode(&devices)
+		return len(devices) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Temporary failures are retried
+	server.Push.FailTimes("bob-phone", 2)
+	rent := bob.CreateExpense(dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	alice.SetExpenseStatus(rent.ID, "approved")
+	require.Eventually(func() bool { return len(server.Push.Sent()) == 2 }, time.Second, 10*time.Millisecond)
+	require.Contains(server.Push.Sent()[1].Message.Body, `approved your expense "Rent"`)
+
+	// Devices are unregistered by their user only
+	res := alice.Delete(fmt.Sprintf("/device/%d", phone.ID))
+	require.Equal(http.StatusNotFound, res.StatusCode)
+	bob.Delete(fmt.Sprintf("/device/%d", phone.ID)).RequireStatus(http.StatusOK)
+	bob.Get("/device").RequireStatus(http.StatusOK).Decode(&devices)
+	require.Empty(devices)
+
+	res = bob.Post("/device", map[string]string{"platform": "windows", "token": "pc"})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+}
+
+func TestWebhooks(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+
+	received := make(chan *http.Request, 10)
+	bodies := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- string(body)
+	}))
+	defer receiver.Close()
+
+	// Only managers register webhooks
+	hookRequest := request.WebhookRequest{URL: receiver.URL, Events: []string{"expense.created"}}
+	res := bob.Post(fmt.Sprintf("/group/%d/webhook", group.ID), hookRequest)
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_manager", res.Error().Code)
+	res = alice.Post(fmt.Sprintf("/group/%d/webhook", group.ID), request.WebhookRequest{URL: receiver.URL, Events: []string{"expense.paid"}})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	var hook dto.WebhookDTO
+	alice.Post(fmt.Sprintf("/group/%d/webhook", group.ID), hookRequest).RequireStatus(http.StatusOK).Decode(&hook)
+	require.True(hook.Active)
+	require.NotEmpty(hook.Secret)
+
+	// Changes are posted signed with the secret of the webhook
+	rent := bob.CreateExpense(dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	var delivered *http.Request
+	select {
+	case delivered = <-received:
+	case <-time.After(time.Second):
+		require.FailNow("webhook was not delivered")
+	}
+	body := <-bodies
+	require.Equal("expense.created", delivered.Header.Get(webhook.HeaderEvent))
+	require.True(webhook.Verify(hook.Secret, []byte(body), delivered.Header.Get(webhook.HeaderSignature)))
+	var payload map[string]interface{}
+	require.NoError(json.Unmarshal([]byte(body), &payload))
+	require.Equal(float64(rent.ID), payload["targetID"])
+
+	path := fmt.Sprintf("/group/%d/webhook/%d", group.ID, hook.ID)
+	var deliveries response.PageResponse[dto.WebhookDeliveryDTO]
+	require.Eventually(func() bool {
+		alice.Get(path + "/delivery").RequireStatus(http.StatusOK).Decode(&deliveries)
+		return len(deliveries.Items) == 1 && deliveries.Items[0].Status == "succeeded"
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(1, deliveries.Items[0].Attempts)
+	require.Equal(http.StatusOK, deliveries.Items[0].ResponseStatus)
+	require.Equal(float64(rent.ID), func() interface{} {
+		var logged map[string]interface{}
+		require.NoError(json.Unmarshal(deliveries.Items[0].Payload, &logged))
+		return logged["targetID"]
+	}())
+
+	// Deliveries can be sent again
+	var redelivery dto.WebhookDeliveryDTO
+	alice.Post(fmt.Sprintf("%s/delivery/%d/redeliver", path, deliveries.Items[0].ID), nil).RequireStatus(http.StatusOK).Decode(&redelivery)
+	require.Equal("pending", redelivery.Status)
+	select {
+	case <-received:
+		require.Equal(body, <-bodies)
+	case <-time.After(time.Second):
+		require.FailNow("webhook was not redelivered")
+	}
+
+	// Inactive webhooks receive nothing, the secret is not shown again
+	active := false
+	hookRequest.Active = &active
+	var updated dto.WebhookDTO
+	alice.Put(path, hookRequest).RequireStatus(http.StatusOK).Decode(&updated)
+	require.False(updated.Active)
+	require.NotEmpty(updated.DisabledAt)
+	require.Empty(updated.Secret)
+	alice.SetExpenseStatus(rent.ID, "approved")
+	var hooks []dto.WebhookDTO
+	alice.Get(fmt.Sprintf("/group/%d/webhook", group.ID)).RequireStatus(http.StatusOK).Decode(&hooks)
+	require.Len(hooks, 1)
+
+	alice.Delete(path).RequireStatus(http.StatusOK)
+	res = alice.Get(path)
+	require.Equal(http.StatusNotFound, res.StatusCode)
+	require.Empty(received)
+}
+
+func TestExport(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+	group := alice.CreateGroup("Trip to Lisbon")
+	alice.AddMember(group.ID, bob.User.ID)
+
+	// More expenses than fit in a page of the repository
+	for i := 0; i < 120; i++ {
+		payer := alice.User.ID
+		if i%3 == 0 {
+			payer = bob.User.ID
+		}
+		require.NoError(server.App.ExpenseRepository.Create(&model.Expense{
+			Title:        fmt.Sprintf("Expense %d", i),
+			Amount:       10,
+			PurchaseTime: time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC),
+			Status:       model.StatusApproved,
+			GroupID:      group.ID,
+			MemberID:     payer,
+		}))
+	}
+	bob.CreateExpense(dto.ExpenseDTO{
+		Title: "Taxi", Amount: 25, PurchaseTime: "2024-01-02 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+
+	res := bob.Get(fmt.Sprintf("/group/%d/export?format=csv&status=approved&to=2024-01-01", group.ID)).RequireStatus(http.StatusOK)
+	require.Equal("text/csv; charset=utf-8", res.Header.Get("Content-Type"))
+	require.Equal(`attachment; filename=trip-to-lisbon-expenses.csv`, res.Header.Get("Content-Disposition"))
+	reader := csv.NewReader(bytes.NewReader(res.Body))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	require.NoError(err)
+	require.Equal([]string{"Expense 0", "", "10.00", "bob.jones", "2024-01-01 00:00:00", "approved"}, rows[2])
+	require.Equal("Expense 119", rows[121][0])
+	// Alice paid 800 and bob 400 of 1200, bob owes alice 200
+	require.Equal([]string{"Total", "", "1200.00"}, rows[126])
+	require.Equal([]string{"bob.jones", "alice.smith", "200.00"}, rows[129])
+
+	res = alice.Get(fmt.Sprintf("/group/%d/export?format=xlsx", group.ID)).RequireStatus(http.StatusOK)
+	require.Equal("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", res.Header.Get("Content-Type"))
+	archive, err := zip.NewReader(bytes.NewReader(res.Body), int64(len(res.Body)))
+	require.NoError(err)
+	require.Len(archive.File, 8)
+	res = alice.Get(fmt.Sprintf("/group/%d/export?format=pdf&from=2024-01-02", group.ID)).RequireStatus(http.StatusOK)
+	require.Equal("application/pdf", res.Header.Get("Content-Type"))
+	require.True(bytes.HasPrefix(res.Body, []byte("%PDF-")))
+
+	// Only members export the group
+	res = carol.Get(fmt.Sprintf("/group/%d/export", group.ID))
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	res = alice.Get(fmt.Sprintf("/group/%d/export?format=doc", group.ID))
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	res = alice.Get(fmt.Sprintf("/group/%d/export?from=2024-02-01&to=2024-01-01", group.ID))
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+}
+
+func TestEventStream(t *testing.T) {
+	require := testifyRequire.New(t)
+	cfg := e2e.Config()
+	cfg.StreamHeartbeatInterval = 50 * time.Millisecond
+	cfg.StreamMaxConnections = 3
+	server := e2e.NewServerWithConfig(t, cfg)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+
+	// Members receive changes as they happen
+	stream := bob.Stream(group.ID, "")
+	alice.CreateExpense(dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	event := stream.NextEvent()
+	require.Equal("expense.created", event.Type)
+	require.NotEmpty(event.ID)
+	expense := dto.ExpenseDTO{}
+	require.NoError(json.Unmarshal([]byte(event.Data), &expense))
+	require.Equal("Rent", expense.Title)
+	event = stream.NextEvent()
+	require.Equal("totals.updated", event.Type)
+	totals := dto.GroupTotalsDTO{}
+	require.NoError(json.Unmarshal([]byte(event.Data), &totals))
+	require.InDelta(300, totals.TotalExpense, 0.001)
+	require.Len(totals.Members, 2)
+	lastEventID := event.ID
+
+	// Idle streams receive heartbeats
+	require.Equal("heartbeat", stream.Next().Comment)
+	stream.Close()
+
+	// Reconnecting resumes after the last event received
+	alice.Put(fmt.Sprintf("/group/%d", group.ID), dto.GroupDTO{Name: "Flat"}).RequireStatus(http.StatusOK)
+	stream = bob.Stream(group.ID, lastEventID)
+	event = stream.NextEvent()
+	require.Equal("group.updated", event.Type)
+	require.JSONEq(fmt.Sprintf(`{"id":%d,"name":"Flat"}`, group.ID), event.Data)
+	stream.Close()
+
+	// Events which are no longer kept cannot be resumed from
+	stream = bob.Stream(group.ID, "1-0")
+	event = stream.NextEvent()
+	require.Equal("reset", event.Type)
+	require.NotEmpty(event.ID)
+	stream.Close()
+
+	// Only members can stream a group, with a valid token
+	res := carol.Get(fmt.Sprintf("/group/%d/events", group.ID))
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	res = server.Anonymous().Get(fmt.Sprintf("/group/%d/events?access_token=invalid", group.ID))
+	require.Equal(http.StatusUnauthorized, res.StatusCode)
+
+	// A user may have a limited number of streams open
+	for i := 0; i < cfg.StreamMaxConnections; i++ {
+		alice.Stream(group.ID, "")
+	}
+	res = alice.Get(fmt.Sprintf("/group/%d/events", group.ID))
+	require.Equal(http.StatusTooManyRequests, res.StatusCode)
+	require.Equal("too_many_streams", res.Error().Code)
+
+	// Streams of disabled accounts are closed
+	dave := server.NewUser("dave.brown")
+	alice.AddMember(group.ID, dave.User.ID)
+	stream = dave.Stream(group.ID, "")
+	disabledAt := time.Now()
+	_, err := server.App.UserRepository.Update(dave.User.ID, map[string]interface{}{"DisabledAt": &disabledAt})
+	require.NoError(err)
+	stream.RequireEnd()
+}
+
+func TestAuthentication(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	require.NotEmpty(alice.Token)
+	require.Equal("alice.smith", alice.User.Username)
+
+	// Protected routes need a valid access token
+	res := server.Anonymous().Post("/group", request.GroupCreationRequest{Name: "Flatmates"})
+	require.Equal(http.StatusUnauthorized, res.StatusCode)
+	require.Equal(http.StatusUnauthorized, res.Error().Status)
+	require.Equal("missing_token", res.Error().Code)
+	forged := *alice
+	forged.Token += "x"
+	require.Equal(http.StatusUnauthorized, forged.Post("/group", request.GroupCreationRequest{Name: "Flatmates"}).StatusCode)
+
+	// Wrong credentials and duplicate usernames are rejected
+	res = server.Anonymous().Post("/user/login", request.LoginRequest{Username: "alice.smith", Password: "wrong_password"})
+	require.Equal(http.StatusUnauthorized, res.StatusCode)
+	require.Equal("Wrong username or password", res.Error().Message)
+	require.Equal("wrong_credentials", res.Error().Code)
+	res = server.Anonymous().Post("/user/register", request.RegisterRequest{
+		UserDTO:  dto.UserDTO{Username: "alice.smith", DisplayName: "Alice"},
+		Password: e2e.Password,
+	})
+	require.Equal(http.StatusConflict, res.StatusCode)
+	require.Equal("username_taken", res.Error().Code)
+
+	// Users can only change their own profile
+	bob := server.NewUser("bob.jones")
+	displayName := "Mallory"
+	res = bob.Put(fmt.Sprintf("/user/auth/%d", alice.User.ID), request.UpdateUserRequest{DisplayName: &displayName})
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("forbidden", res.Error().Code)
+	user := dto.UserDTO{}
+	server.Anonymous().Get(fmt.Sprintf("/user/%d", alice.User.ID)).RequireStatus(http.StatusOK).Decode(&user)
+	require.Equal("alice.smith", user.DisplayName)
+
+	// Disabled accounts are locked out before their token expires
+	disabledAt := time.Now()
+	_, err := server.App.UserRepository.Update(bob.User.ID, map[string]interface{}{"DisabledAt": &disabledAt})
+	require.NoError(err)
+	res = bob.Post("/group", request.GroupCreationRequest{Name: "Flatmates"})
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("account_disabled", res.Error().Code)
+}
+
+func TestMemberPermissions(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	memberPath := func(userID uint) string {
+		return fmt.Sprintf("/member?userId=%d&groupId=%d", userID, group.ID)
+	}
+
+	// Member routes need a valid access token
+	res := server.Anonymous().Post(memberPath(carol.User.ID), nil)
+	require.Equal(http.StatusUnauthorized, res.StatusCode)
+	require.Equal("missing_token", res.Error().Code)
+	res = server.Anonymous().Get(fmt.Sprintf("/member/group/%d", group.ID))
+	require.Equal(http.StatusUnauthorized, res.StatusCode)
+
+	// Only managers add and remove other members
+	res = bob.Post(memberPath(carol.User.ID), nil)
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_manager", res.Error().Code)
+	res = carol.Post(memberPath(carol.User.ID), nil)
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_member", res.Error().Code)
+	alice.AddMember(group.ID, carol.User.ID)
+	res = bob.Delete(memberPath(carol.User.ID))
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_manager", res.Error().Code)
+
+	// Outsiders cannot see the members
+	dave := server.NewUser("dave.brown")
+	res = dave.Get(memberPath(alice.User.ID))
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_member", res.Error().Code)
+
+	// Members can leave by themselves
+	bob.Delete(memberPath(bob.User.ID)).RequireStatus(http.StatusOK)
+	res = alice.Get(memberPath(bob.User.ID))
+	require.Equal(http.StatusNotFound, res.StatusCode)
+}
+
+func TestExpenseAndGroupPermissions(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+	dave := server.NewUser("dave.brown")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	alice.AddMember(group.ID, carol.User.ID)
+	rent := bob.CreateExpense(dto.ExpenseDTO{
+		Title: "Rent", Amount: 300, PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: bob.User.ID,
+	})
+	expensePath := fmt.Sprintf("/expense/%d", rent.ID)
+	requireForbidden := func(res *e2e.Response, code string) {
+		t.Helper()
+		require.Equal(http.StatusForbidden, res.StatusCode)
+		require.Equal(code, res.Error().Code)
+	}
+
+	// Outsiders can neither read nor change the group and its expenses
+	requireForbidden(dave.Get(expensePath), "not_group_member")
+	requireForbidden(dave.Get(fmt.Sprintf("/expense/group/%d", group.ID)), "not_group_member")
+	requireForbidden(dave.Get(fmt.Sprintf("/expense?groupId=%d&memberId=%d", group.ID, bob.User.ID)), "not_group_member")
+	requireForbidden(dave.Get(fmt.Sprintf("/group/%d", group.ID)), "not_group_member")
+	requireForbidden(dave.Get(fmt.Sprintf("/group/user/%d", alice.User.ID)), "forbidden")
+	requireForbidden(dave.Put(expensePath, map[string]string{"status": "approved"}), "not_group_member")
+	requireForbidden(dave.Delete(expensePath), "not_group_member")
+
+	// Members cannot approve, not even their own expenses, nor change those of others
+	requireForbidden(bob.Put(expensePath, map[string]string{"status": "approved"}), "not_group_manager")
+	requireForbidden(carol.Put(expensePath, map[string]string{"status": "approved"}), "not_group_manager")
+	requireForbidden(carol.Put(expensePath, map[string]interface{}{"amount": 1}), "not_group_manager")
+	requireForbidden(carol.Delete(expensePath), "not_group_manager")
+	requireForbidden(bob.Put(fmt.Sprintf("/group/%d", group.ID), map[string]string{"name": "Mine"}), "not_group_manager")
+	requireForbidden(bob.Delete(fmt.Sprintf("/group/%d", group.ID)), "not_group_manager")
+	stored := dto.ExpenseDTO{}
+	carol.Get(expensePath).RequireStatus(http.StatusOK).Decode(&stored)
+	require.Equal("pending", stored.Status)
+	require.InDelta(300, stored.Amount, 0.001)
+	require.Equal("Flatmates", carol.Group(group.ID).Name)
+	for _, activity := range alice.Activity(group.ID, "").Items {
+		require.NotEqual("expense.approved", activity.Action)
+	}
+
+	// Members edit their own expenses, resending the status they have
+	bob.Put(expensePath, map[string]interface{}{"amount": 320, "status": "pending"}).RequireStatus(http.StatusOK)
+	require.InDelta(320, bob.Expenses(group.ID, "").Items[0].Amount, 0.001)
+
+	// Managers approve and rename, members delete their own expenses
+	alice.SetExpenseStatus(rent.ID, "approved")
+	alice.Put(fmt.Sprintf("/group/%d", group.ID), map[string]string{"name": "Flat 4B"}).RequireStatus(http.StatusOK)
+	require.Equal("Flat 4B", bob.Group(group.ID).Name)
+	bob.Delete(expensePath).RequireStatus(http.StatusOK)
+}
+
+func TestPermissionsReadUncachedMembers(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	require.True(server.App.Config.CacheEnabled)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	carol := server.NewUser("carol.white")
+	group := alice.CreateGroup("Flatmates")
+	alice.AddMember(group.ID, bob.User.ID)
+	require.NoError(server.App.MemberRepository.UpdateRole(bob.User.ID, group.ID, model.RoleManager))
+	require.Equal(model.RoleManager, bob.Member(group.ID, bob.User.ID).Role)
+
+	// A demotion whose invalidation was missed still shows the cached role but takes away access
+	require.NoError(server.App.Memberships.UpdateRole(bob.User.ID, group.ID, model.RoleMember))
+	require.Equal(model.RoleManager, bob.Member(group.ID, bob.User.ID).Role)
+	res := bob.Post(fmt.Sprintf("/member?userId=%d&groupId=%d", carol.User.ID, group.ID), nil)
+	require.Equal(http.StatusForbidden, res.StatusCode)
+	require.Equal("not_group_manager", res.Error().Code)
+}
+
+func TestMalformedRequests(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+
+	res := alice.Post("/group", "not an object")
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	require.Equal("application/json; charset=utf-8", res.Header.Get("Content-Type"))
+	require.Equal(http.StatusBadRequest, res.Error().Status)
+	require.Equal("validation_failed", res.Error().Code)
+
+	res = alice.Get("/no/such/route")
+	require.Equal(http.StatusNotFound, res.StatusCode)
+}
+
+func TestErrorMapping(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	bob := server.NewUser("bob.jones")
+	group := alice.CreateGroup("Flatmates")
+
+	// Missing records are 404 with a code naming the record
+	for path, code := range map[string]string{
+		"/user/9999":    "user_not_found",
+		"/group/9999":   "group_not_found",
+		"/expense/9999": "expense_not_found",
+		fmt.Sprintf("/member?userId=%d&groupId=%d", bob.User.ID, group.ID): "member_not_found",
+	} {
+		res := alice.Get(path)
+		require.Equal(http.StatusNotFound, res.StatusCode, path)
+		require.Equal(http.StatusNotFound, res.Error().Status, path)
+		require.Equal(code, res.Error().Code, path)
+	}
+	res := alice.Put("/expense/9999", map[string]string{"status": "approved"})
+	require.Equal(http.StatusNotFound, res.StatusCode)
+	require.Equal("expense_not_found", res.Error().Code)
+
+	// Adding a member twice conflicts, adding a missing user references nothing
+	alice.AddMember(group.ID, bob.User.ID)
+	res = alice.Post(fmt.Sprintf("/member?userId=%d&groupId=%d", bob.User.ID, group.ID), nil)
+	require.Equal(http.StatusConflict, res.StatusCode)
+	require.Equal("member_exists", res.Error().Code)
+	res = alice.Post(fmt.Sprintf("/member?userId=9999&groupId=%d", group.ID), nil)
+	require.Equal(http.StatusNotFound, res.StatusCode)
+	require.Equal("reference_not_found", res.Error().Code)
+
+	// Validation failures of the handlers list every field
+	res = server.Anonymous().Post("/user/register", request.RegisterRequest{
+		UserDTO:  dto.UserDTO{Username: "carol", DisplayName: "C"},
+		Password: "short",
+	})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	require.Equal("validation_failed", res.Error().Code)
+	var fields []string
+	for _, fieldError := range res.Error().Errors {
+		fields = append(fields, fieldError.Field)
+	}
+	require.ElementsMatch([]string{"username", "password", "displayName"}, fields)
+	res = alice.Put(fmt.Sprintf("/expense/%d", 1), map[string]string{"status": "paid"})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	require.Equal("status", res.Error().Errors[0].Field)
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	require := testifyRequire.New(t)
+	cfg := e2e.Config()
+	cfg.RateLimitAuth = "3/1m"
+	server := e2e.NewServerWithConfig(t, cfg)
+
+	// Registering counts against the same policy as logging in
+	server.Register("alice.smith", e2e.Password)
+	for i := 0; i < 2; i++ {
+		res := server.Anonymous().Post("/user/login", request.LoginRequest{Username: "alice.smith", Password: "wrong_password"})
+		require.Equal(http.StatusUnauthorized, res.StatusCode)
+	}
+	res := server.Anonymous().Post("/user/login", request.LoginRequest{Username: "alice.smith", Password: e2e.Password})
+	require.Equal(http.StatusTooManyRequests, res.StatusCode)
+	require.NotEmpty(res.Header.Get("Retry-After"))
+	require.Equal("0", res.Header.Get("RateLimit-Remaining"))
+
+	// Other routes have their own budget
+	server.Anonymous().Get("/user/checkUsername/bob.jones").RequireStatus(http.StatusOK)
+}
+
+func TestRequestValidation(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := e2e.NewServer(t)
+	alice := server.NewUser("alice.smith")
+	group := alice.CreateGroup("Flatmates")
+
+	// Every failing body field is reported
+	res := alice.Post("/expense", map[string]interface{}{
+		"amount": -5, "purchaseTime": "yesterday", "groupID": group.ID, "memberID": alice.User.ID,
+	})
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	fields := map[string]string{}
+	for _, fieldError := range res.Error().Errors {
+		fields[fieldError.Field] = fieldError.Message
+	}
+	require.Len(fields, 3)
+	require.Contains(fields, "title")
+	require.Contains(fields, "amount")
+	require.Contains(fields, "purchaseTime")
+
+	// Parameters are reported by name
+	res = alice.Get("/member?userId=abc&groupId=1")
+	require.Equal(http.StatusBadRequest, res.StatusCode)
+	require.Len(res.Error().Errors, 1)
+	require.Equal("userId", res.Error().Errors[0].Field)
+
+	// A zero amount is valid and returned
+	expense := alice.CreateExpense(dto.ExpenseDTO{
+		Title: "Free coffee", PurchaseTime: "2024-01-01 10:00:00", GroupID: group.ID, MemberID: alice.User.ID,
+	})
+	res = alice.Get(fmt.Sprintf("/expense/%d", expense.ID)).RequireStatus(http.StatusOK)
+	require.Contains(string(res.Body), `"amount":0`)
+
+	// Anonymous callers are told to authenticate before their body is looked at
+	res = server.Anonymous().Post("/expense", map[string]interface{}{"amount": -5})
+	require.Equal(http.StatusUnauthorized, res.StatusCode)
+	require.Equal("missing_token", res.Error().Code)
+
+	// Bodies over the JSON limit are rejected without being read to the end
+	res = alice.Post("/expense", map[string]interface{}{
+		"title": strings.Repeat("a", 2<<20), "purchaseTime": "2024-01-01 10:00:00", "groupID": group.ID, "memberID": alice.User.ID,
+	})
+	require.Equal(http.StatusRequestEntityTooLarge, res.StatusCode)
+	require.Equal("body_too_large", res.Error().Code)
+}
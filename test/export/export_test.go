@@ -0,0 +1,256 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/csv"
+	"fmt"
+	testifyRequire "github.com/stretchr/testify/require"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"io"
+	"money_share/pkg/export"
+	"money_share/pkg/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func member(userID uint, name string) *model.Member {
+	return &model.Member{UserID: userID, User: model.User{Username: name}}
+}
+
+func expense(memberID uint, amount float32, status string) *model.Expense {
+	return &model.Expense{Title: "Dinner", Amount: amount, Status: status, MemberID: memberID}
+}
+
+// ledger of a trip of alice, bob and carol, dave paid for the car before leaving the group
+func ledger() *export.Ledger {
+	ledger := export.NewLedger([]*model.Member{member(2, "bob"), member(1, "alice"), member(3, "carol")})
+	ledger.AddPayer(4, "dave")
+	for _, e := range []*model.Expense{
+		expense(1, 100, model.StatusApproved),
+		expense(1, 20.5, model.StatusPending),
+		expense(2, 50, model.StatusApproved),
+		expense(3, 8, model.StatusDenied),
+		expense(4, 30.01, model.StatusApproved),
+	} {
+		ledger.Add(e)
+	}
+	return ledger
+}
+
+func TestSummary(t *testing.T) {
+	require := testifyRequire.New(t)
+	summary := ledger().Summary()
+
+	// 180.01 split between the three members, the odd cent goes to the first
+	require.Equal(int64(18001), summary.Total)
+	require.Equal([]export.MemberTotal{
+		{Name: "alice", Expenses: 2, Approved: 10000, Pending: 2050, Share: 6001, Balance: 3999},
+		{Name: "bob", Expenses: 1, Approved: 5000, Share: 6000, Balance: -1000},
+		{Name: "carol", Expenses: 1, Denied: 800, Share: 6000, Balance: -6000},
+		{Name: "dave", Expenses: 1, Approved: 3001, Balance: 3001},
+	}, summary.Members)
+	require.Equal([]export.Transfer{
+		{From: "carol", To: "alice", Amount: 3999},
+		{From: "carol", To: "dave", Amount: 2001},
+		{From: "bob", To: "dave", Amount: 1000},
+	}, summary.Transfers)
+}
+
+func write(t *testing.T, format string, expenses int) []byte {
+	require := testifyRequire.New(t)
+	buf := new(bytes.Buffer)
+	writer, err := export.NewWriter(format, buf, "Trip to Lisbon expenses")
+	require.NoError(err)
+	for i := 0; i < expenses; i++ {
+		require.NoError(writer.WriteExpense(export.Expense{
+			Title:        fmt.Sprintf("=Dinner %d", i),
+			Description:  "Fish (grilled) & wine, 10€",
+			Amount:       12.3,
+			Payer:        "alice",
+			PurchaseTime: time.Date(2024, 1, 31, 18, 30, 0, 0, time.UTC),
+			Status:       model.StatusApproved,
+		}))
+	}
+	require.NoError(writer.WriteSummary(ledger().Summary()))
+	require.NoError(writer.Close())
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	require := testifyRequire.New(t)
+	reader := csv.NewReader(bytes.NewReader(write(t, export.FormatCSV, 2)))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	require.NoError(err)
+
+	require.Equal([]string{"Expenses"}, rows[0])
+	require.Equal([]string{"Title", "Description", "Amount", "Paid by", "Purchase time", "Status"}, rows[1])
+	// Text is not evaluated as formulas
+	require.Equal([]string{"'=Dinner 0", "Fish (grilled) & wine, 10€", "12.30", "alice", "2024-01-31 18:30:00", "approved"}, rows[2])
+	// Sections are separated by blank lines, which readers skip
+	require.Equal([]string{"Member totals"}, rows[4])
+	require.Equal([]string{"bob", "1", "50.00", "0.00", "0.00", "60.00", "-10.00"}, rows[7])
+	require.Equal([]string{"Total", "", "180.01"}, rows[10])
+	require.Equal([]string{"Settlement"}, rows[11])
+	require.Equal([]string{"carol", "alice", "39.99"}, rows[13])
+	require.Len(rows, 16)
+}
+
+func TestXLSX(t *testing.T) {
+	require := testifyRequire.New(t)
+	file := write(t, export.FormatXLSX, 2)
+	archive, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+	require.NoError(err)
+	parts := make(map[string]string)
+	for _, part := range archive.File {
+		reader, err := part.Open()
+		require.NoError(err)
+		content, err := io.ReadAll(reader)
+		require.NoError(err)
+		parts[part.Name] = string(content)
+	}
+	require.Contains(parts, "[Content_Types].xml")
+	require.Contains(parts["xl/workbook.xml"], `<sheet name="Settlement" sheetId="3" r:id="rId3"/>`)
+
+	expenses := parts["xl/worksheets/sheet1.xml"]
+	require.Equal(3, strings.Count(expenses, "<row "))
+	require.Contains(expenses, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">=Dinner 0</t></is></c>`)
+	require.Contains(expenses, `<t xml:space="preserve">Fish (grilled) &amp; wine, 10€</t>`)
+	require.Contains(expenses, `<c r="C2" s="2"><v>12.3</v></c>`)
+	// Dates are days since the spreadsheet epoch
+	require.Contains(expenses, `<c r="E2" s="3"><v>45322.770833333336</v></c>`)
+	require.Contains(parts["xl/worksheets/sheet2.xml"], `<c r="G3" s="2"><v>-10</v></c>`)
+	require.Contains(parts["xl/worksheets/sheet3.xml"], `<c r="C2" s="2"><v>39.99</v></c>`)
+}
+
+func TestPDF(t *testing.T) {
+	require := testifyRequire.New(t)
+	file := write(t, export.FormatPDF, 200)
+	require.True(bytes.HasPrefix(file, []byte("%PDF-1.4\n")))
+	require.True(bytes.HasSuffix(file, []byte("%%EOF\n")))
+
+	// The cross-reference table points at every object
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(file)
+	require.NotNil(match)
+	xref, err := strconv.Atoi(string(match[1]))
+	require.NoError(err)
+	require.True(bytes.HasPrefix(file[xref:], []byte("xref\n0 ")))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(file[xref:], -1)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(err)
+		require.True(bytes.HasPrefix(file[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+
+	// Rows which do not fit continue on new pages
+	pages := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(file)
+	require.NotNil(pages)
+	count, err := strconv.Atoi(string(pages[1]))
+	require.NoError(err)
+	require.Greater(count, 5)
+	require.Equal(count, bytes.Count(file, []byte("/Type /Page /Parent")))
+	text := pdfText(t, file)
+	require.Contains(text, "Trip to Lisbon expenses")
+	require.Contains(text, "Fish (grilled) & wine, 10€")
+	require.Contains(text, "Settlement")
+}
+
+func TestPDFUnicode(t *testing.T) {
+	require := testifyRequire.New(t)
+	buf := new(bytes.Buffer)
+	writer, err := export.NewWriter(export.FormatPDF, buf, "Chuyến đi Đà Lạt")
+	require.NoError(err)
+	require.NoError(writer.WriteExpense(export.Expense{
+		Title:        "Phở bò",
+		Description:  "Bánh mì và cà phê sữa đá",
+		Amount:       95,
+		Payer:        "Nguyễn Thị Hương",
+		PurchaseTime: time.Date(2024, 1, 31, 18, 30, 0, 0, time.UTC),
+		Status:       model.StatusApproved,
+	}))
+	require.NoError(writer.WriteSummary(ledger().Summary()))
+	require.NoError(writer.Close())
+
+	// Vietnamese text is kept rather than replaced with question marks
+	text := pdfText(t, buf.Bytes())
+	require.Contains(text, "Chuyến đi Đà Lạt")
+	require.Contains(text, "Phở bò")
+	require.Contains(text, "Bánh mì và cà phê sữa đá")
+	require.Contains(text, "Nguyễn Thị Hương")
+	for _, line := range text {
+		require.NotContains(line, "?")
+	}
+
+	// The embedded subsets are valid fonts with only the outlines of the glyphs used, the regular
+	// font is written first
+	streams := regexp.MustCompile(`/Length (\d+) /Length1 \d+ /Filter /FlateDecode >>\nstream\n`).FindAllSubmatchIndex(buf.Bytes(), -1)
+	require.Len(streams, 2)
+	for i, stream := range streams {
+		length, err := strconv.Atoi(string(buf.Bytes()[stream[2]:stream[3]]))
+		require.NoError(err)
+		reader, err := zlib.NewReader(bytes.NewReader(buf.Bytes()[stream[1] : stream[1]+length]))
+		require.NoError(err)
+		ttf, err := io.ReadAll(reader)
+		require.NoError(err)
+		font, err := sfnt.Parse(ttf)
+		require.NoError(err)
+		used, unused := []rune{'ở', 'ạ'}[i], []rune{'ạ', 'ở'}[i]
+		glyph, err := font.GlyphIndex(nil, used)
+		require.NoError(err)
+		segments, err := font.LoadGlyph(nil, glyph, fixed.I(12), nil)
+		require.NoError(err)
+		require.NotEmpty(segments)
+		glyph, err = font.GlyphIndex(nil, unused)
+		require.NoError(err)
+		segments, err = font.LoadGlyph(nil, glyph, fixed.I(12), nil)
+		require.NoError(err)
+		require.Empty(segments)
+	}
+}
+
+// pdfText returns the text shown on the pages of a PDF, decoded with the ToUnicode CMaps of its fonts
+func pdfText(t *testing.T, file []byte) []string {
+	require := testifyRequire.New(t)
+	objects := make(map[string]string)
+	for _, object := range regexp.MustCompile(`(?s)(\d+) 0 obj\n(.*?)\nendobj\n`).FindAllSubmatch(file, -1) {
+		objects[string(object[1])] = string(object[2])
+	}
+	resources := regexp.MustCompile(`/(F\d) (\d+) 0 R`).FindAllSubmatch(file, -1)
+	require.NotEmpty(resources)
+	cmaps := make(map[string]map[string]string)
+	for _, resource := range resources {
+		toUnicode := regexp.MustCompile(`/ToUnicode (\d+) 0 R`).FindStringSubmatch(objects[string(resource[2])])
+		require.NotNil(toUnicode, "font %s", resource[1])
+		cmap := make(map[string]string)
+		for _, char := range regexp.MustCompile(`<([0-9A-F]{4})> <([0-9A-F]+)>`).FindAllStringSubmatch(objects[toUnicode[1]], -1) {
+			units := make([]uint16, len(char[2])/4)
+			for i := range units {
+				unit, err := strconv.ParseUint(char[2][4*i:4*i+4], 16, 16)
+				require.NoError(err)
+				units[i] = uint16(unit)
+			}
+			cmap[char[1]] = string(utf16.Decode(units))
+		}
+		cmaps[string(resource[1])] = cmap
+	}
+
+	var text []string
+	for _, show := range regexp.MustCompile(`/(F\d) [\d.]+ Tf [\d.]+ [\d.]+ Td <([0-9A-F]*)> Tj`).FindAllSubmatch(file, -1) {
+		cmap := cmaps[string(show[1])]
+		line := new(strings.Builder)
+		for i := 0; i < len(show[2]); i += 4 {
+			char, ok := cmap[string(show[2][i:i+4])]
+			require.True(ok, "glyph %s of %s is not mapped", show[2][i:i+4], show[1])
+			line.WriteString(char)
+		}
+		text = append(text, line.String())
+	}
+	return text
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"context"
+	"errors"
+	testifyRequire "github.com/stretchr/testify/require"
+	"money_share/pkg/health"
+	"testing"
+	"time"
+)
+
+func TestCheckerReady(t *testing.T) {
+	require := testifyRequire.New(t)
+	checker := health.NewChecker()
+	checker.Register("ok", time.Second, func(ctx context.Context) error { return nil })
+
+	report := checker.Check(context.Background())
+	require.True(report.Ready, "should be ready when all checks pass")
+	require.Len(report.Checks, 1)
+	require.True(report.Checks[0].Healthy)
+}
+
+func TestCheckerFailingDependency(t *testing.T) {
+	require := testifyRequire.New(t)
+	checker := health.NewChecker()
+	checker.Register("ok", time.Second, func(ctx context.Context) error { return nil })
+	checker.Register("broken", time.Second, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := checker.Check(context.Background())
+	require.False(report.Ready, "should not be ready when a check fails")
+	require.Equal("connection refused", report.Checks[1].Error)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	require := testifyRequire.New(t)
+	checker := health.NewChecker()
+	// A check that ignores its context must still be bounded by the timeout
+	checker.Register("hanging", 50*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+	require.Less(time.Since(start), 500*time.Millisecond, "should not wait for hanging check")
+	require.False(report.Ready)
+	require.Equal(context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestCheckerDraining(t *testing.T) {
+	require := testifyRequire.New(t)
+	checker := health.NewChecker()
+	checker.SetDraining()
+
+	report := checker.Check(context.Background())
+	require.False(report.Ready, "should not be ready while draining")
+}
@@ -0,0 +1,109 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	testifyRequire "github.com/stretchr/testify/require"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"money_share/pkg/imaging"
+	"testing"
+)
+
+func newImage(width int, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+// withEXIF inserts an APP1 EXIF segment carrying marker right after the JPEG SOI marker
+func withEXIF(jpegBytes []byte, marker string) []byte {
+	payload := append([]byte("Exif\x00\x00"), []byte(marker)...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	result := append([]byte{}, jpegBytes[:2]...)
+	result = append(result, segment...)
+	return append(result, jpegBytes[2:]...)
+}
+
+func TestProcessAvatarJPEG(t *testing.T) {
+	require := testifyRequire.New(t)
+	buf := &bytes.Buffer{}
+	require.Nil(jpeg.Encode(buf, newImage(800, 600), nil))
+	upload := withEXIF(buf.Bytes(), "GPSLatitude=48.8584")
+
+	avatar, err := imaging.ProcessAvatar(bytes.NewReader(upload))
+	require.Nil(err)
+	require.Equal("jpg", avatar.Extension)
+	require.Len(avatar.ID, 32)
+	require.Regexp(`^[0-9a-f]{32}\.jpg$`, avatar.FileName())
+	for _, size := range imaging.AvatarSizes {
+		encoded := avatar.Images[size]
+		require.NotContains(string(encoded), "GPSLatitude")
+		require.NotContains(string(encoded), "Exif")
+		config, format, err := image.DecodeConfig(bytes.NewReader(encoded))
+		require.Nil(err)
+		require.Equal("jpeg", format)
+		require.Equal(size, config.Width)
+		require.Equal(size, config.Height)
+	}
+
+	// Same upload, new ID, so that users uploading the same image don't share it
+	again, err := imaging.ProcessAvatar(bytes.NewReader(upload))
+	require.Nil(err)
+	require.NotEqual(avatar.ID, again.ID)
+}
+
+func TestProcessAvatarPNG(t *testing.T) {
+	require := testifyRequire.New(t)
+	buf := &bytes.Buffer{}
+	require.Nil(png.Encode(buf, newImage(100, 300)))
+
+	avatar, err := imaging.ProcessAvatar(buf)
+	require.Nil(err)
+	require.Equal("png", avatar.Extension)
+	config, format, err := image.DecodeConfig(bytes.NewReader(avatar.Images[512]))
+	require.Nil(err)
+	require.Equal("png", format)
+	require.Equal(512, config.Width)
+}
+
+func TestProcessAvatarRejectsOtherContent(t *testing.T) {
+	require := testifyRequire.New(t)
+	gifBuf := &bytes.Buffer{}
+	require.Nil(gif.Encode(gifBuf, newImage(10, 10), nil))
+
+	_, err := imaging.ProcessAvatar(gifBuf)
+	require.ErrorIs(err, imaging.ErrUnsupportedFormat)
+	_, err = imaging.ProcessAvatar(bytes.NewReader([]byte("<html><script>alert(1)</script></html>")))
+	require.ErrorIs(err, imaging.ErrUnsupportedFormat)
+
+	// Valid signature with a truncated body
+	pngBuf := &bytes.Buffer{}
+	require.Nil(png.Encode(pngBuf, newImage(10, 10)))
+	_, err = imaging.ProcessAvatar(bytes.NewReader(pngBuf.Bytes()[:40]))
+	require.ErrorIs(err, imaging.ErrInvalidImage)
+}
+
+func TestProcessAvatarRejectsHugeDimensions(t *testing.T) {
+	require := testifyRequire.New(t)
+	buf := &bytes.Buffer{}
+	require.Nil(png.Encode(buf, newImage(1, 1)))
+	// Patch the IHDR width and height, the decoder must refuse before allocating
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := imaging.ProcessAvatar(bytes.NewReader(data))
+	require.ErrorIs(err, imaging.ErrImageTooLarge)
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	testifyRequire "github.com/stretchr/testify/require"
+	"money_share/pkg/auth"
+	"money_share/pkg/controller"
+	"money_share/pkg/logging"
+	"money_share/pkg/middleware"
+	"money_share/pkg/model"
+	"money_share/pkg/repository/memory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerRedactsCredentials(t *testing.T) {
+	require := testifyRequire.New(t)
+	buf := &bytes.Buffer{}
+	logger := logging.New(buf, "json", "info")
+
+	logger.Info("login", "username", "someone", "password", "hunter22", "accessToken", "eyJhbGciOi")
+
+	entry := map[string]interface{}{}
+	require.NoError(json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal("someone", entry["username"])
+	require.Equal("[REDACTED]", entry["password"])
+	require.Equal("[REDACTED]", entry["accessToken"])
+}
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	require := testifyRequire.New(t)
+	buf := &bytes.Buffer{}
+	logger := logging.New(buf, "json", "info")
+
+	var seenRequestID string
+	r := mux.NewRouter()
+	r.Use(middleware.RequestID, middleware.AccessLog(logger))
+	r.HandleFunc("/group/{groupId:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		seenRequestID = logging.RequestIDFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	// Incoming request ID is propagated
+	req := httptest.NewRequest("GET", "/group/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal("abc-123", rec.Header().Get(middleware.RequestIDHeader))
+	require.Equal("abc-123", seenRequestID)
+
+	entry := map[string]interface{}{}
+	require.NoError(json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal("/group/{groupId:[0-9]+}", entry["route"], "should log route template, not raw path")
+	require.Equal(float64(http.StatusTeapot), entry["status"])
+	require.Equal("abc-123", entry["request_id"])
+
+	// Missing or malformed request ID gets replaced
+	req = httptest.NewRequest("GET", "/group/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "bad id\nwith newline")
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Len(rec.Header().Get(middleware.RequestIDHeader), 32)
+}
+
+func TestAccessLogUserIDComesFromAuthentication(t *testing.T) {
+	require := testifyRequire.New(t)
+	auth.JWTKey = []byte("test-key")
+	buf := &bytes.Buffer{}
+	logger := logging.New(buf, "json", "info")
+
+	r := mux.NewRouter()
+	r.Use(middleware.AccessLog(logger))
+	r.HandleFunc("/user/login", func(w http.ResponseWriter, r *http.Request) {})
+	authSub := r.PathPrefix("/user").Subrouter()
+	store := memory.NewStore()
+	user := &model.User{Username: "alice.smith", Password: "secret"}
+	require.NoError(memory.NewUserRepository(store).Create(user))
+	authSub.Use(middleware.Authenticate(&controller.App{Logger: logger, UserRepository: memory.NewUserRepository(store)}))
+	authSub.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {})
+
+	logged := func(req *http.Request) map[string]interface{} {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		entry := map[string]interface{}{}
+		require.NoError(json.Unmarshal(buf.Bytes(), &entry))
+		return entry
+	}
+
+	// A client cannot put a user into the log of an unauthenticated route
+	req := httptest.NewRequest("POST", "/user/login", strings.NewReader("{}"))
+	req.Header.Set("userID", "7")
+	require.NotContains(logged(req), "user_id")
+
+	token, err := auth.GenerateAccessToken(user.ID, user.Username)
+	require.NoError(err)
+	req = httptest.NewRequest("GET", "/user/me", nil)
+	req.Header.Set("Authorization", token)
+	req.Header.Set("userID", "7")
+	require.Equal(float64(user.ID), logged(req)["user_id"])
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/controller"
+	"money_share/pkg/metrics"
+	"money_share/pkg/middleware"
+	"money_share/pkg/model"
+	"money_share/pkg/repository/memory"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsMiddlewareLabelsByRouteTemplate(t *testing.T) {
+	require := testifyRequire.New(t)
+	r := mux.NewRouter()
+	r.Use(middleware.Metrics)
+	r.HandleFunc("/expense/{expenseId:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+	r.Handle("/metrics", metrics.Handler())
+
+	for _, path := range []string{"/expense/1", "/expense/2", "/expense/3"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	counter := metrics.HTTPRequests.WithLabelValues("/expense/{expenseId:[0-9]+}", "GET", "404")
+	require.Equal(float64(3), testutil.ToFloat64(counter))
+
+	// Exposed in Prometheus text format
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.True(strings.Contains(rec.Body.String(), `money_share_http_requests_total{method="GET",route="/expense/{expenseId:[0-9]+}",status="404"} 3`))
+}
+
+func TestExpensesApprovedCountsApprovalsOnly(t *testing.T) {
+	require := testifyRequire.New(t)
+	store := memory.NewStore()
+	app := &controller.App{
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ExpenseRepository: memory.NewExpenseRepository(store),
+		MemberRepository:  memory.NewMemberRepository(store),
+	}
+	user := &model.User{Username: "alice.smith", Password: "secret"}
+	require.NoError(memory.NewUserRepository(store).Create(user))
+	group := &model.Group{Name: "Trip"}
+	require.NoError(memory.NewGroupRepository(store).Create(group, user.ID))
+	expense := &model.Expense{Title: "Dinner", Amount: 30, PurchaseTime: time.Now(), MemberID: user.ID, GroupID: group.ID}
+	require.NoError(app.ExpenseRepository.Create(expense))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/expense/{expenseId:[0-9]+}", app.UpdateExpense).Methods("PUT")
+	// The creator of the group manages it and approves the expense
+	update := func(body string) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/expense/%d", expense.ID), strings.NewReader(body))
+		req.Header.Set("userID", fmt.Sprint(user.ID))
+		r.ServeHTTP(rec, req)
+		require.Equal(http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	approved := testutil.ToFloat64(metrics.ExpensesApproved)
+	update(`{"status": "approved"}`)
+	require.Equal(approved+1, testutil.ToFloat64(metrics.ExpensesApproved))
+
+	// Editing or re-sending the status of an approved expense is not another approval
+	update(`{"title": "Dinner at the beach", "status": "approved"}`)
+	update(`{"amount": 35}`)
+	require.Equal(approved+1, testutil.ToFloat64(metrics.ExpensesApproved))
+}
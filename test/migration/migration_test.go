@@ -0,0 +1,51 @@
+package migration
+
+import (
+	testifyRequire "github.com/stretchr/testify/require"
+	"money_share/pkg/migration"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadOrdersAndPairsMigrations(t *testing.T) {
+	require := testifyRequire.New(t)
+	fsys := fstest.MapFS{
+		"postgres/000002_add_index.up.sql":   {Data: []byte("CREATE INDEX a ON b (c);")},
+		"postgres/000002_add_index.down.sql": {Data: []byte("DROP INDEX a;")},
+		"postgres/000001_baseline.up.sql":    {Data: []byte("CREATE TABLE b (c int);")},
+		"postgres/README.md":                 {Data: []byte("ignored")},
+	}
+
+	migrations, err := migration.Load(fsys, "postgres")
+	require.NoError(err)
+	require.Len(migrations, 2)
+	require.Equal(uint64(1), migrations[0].Version)
+	require.Equal("baseline", migrations[0].Name)
+	require.Empty(migrations[0].DownSQL, "baseline has no down file")
+	require.Equal(uint64(2), migrations[1].Version)
+	require.Equal("DROP INDEX a;", migrations[1].DownSQL)
+	require.NotEmpty(migrations[1].Checksum)
+}
+
+func TestLoadRejectsMissingUpFile(t *testing.T) {
+	require := testifyRequire.New(t)
+	fsys := fstest.MapFS{
+		"postgres/000001_baseline.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+
+	_, err := migration.Load(fsys, "postgres")
+	require.Error(err)
+}
+
+func TestCreateUsesNextVersion(t *testing.T) {
+	require := testifyRequire.New(t)
+	dir := t.TempDir()
+	require.NoError(os.WriteFile(filepath.Join(dir, "000007_existing.up.sql"), []byte("SELECT 1;"), 0644))
+
+	upPath, downPath, err := migration.Create(dir, "Add Expense Index")
+	require.NoError(err)
+	require.Equal(filepath.Join(dir, "000008_add_expense_index.up.sql"), upPath)
+	require.Equal(filepath.Join(dir, "000008_add_expense_index.down.sql"), downPath)
+}
@@ -0,0 +1,158 @@
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/controller"
+	"money_share/pkg/dto"
+	"money_share/pkg/dto/request"
+	"money_share/pkg/dto/response"
+	"money_share/pkg/health"
+	"money_share/pkg/middleware"
+	"money_share/pkg/openapi"
+	"money_share/pkg/route"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func newRouter() *mux.Router {
+	app := &controller.App{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	return route.NewRouter(app, middleware.RateLimitConfig{})
+}
+
+func TestDocumentDescribesEveryRoute(t *testing.T) {
+	require := testifyRequire.New(t)
+	doc, err := openapi.Load()
+	require.NoError(err)
+
+	var routes []string
+	err = newRouter().Walk(func(r *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := r.GetMethods()
+		if err != nil {
+			// Subrouters and catch-all handlers have no methods
+			return nil
+		}
+		template, err := r.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routes = append(routes, method+" "+openapi.PathFromTemplate(template))
+		}
+		return nil
+	})
+	require.NoError(err)
+
+	var documented []string
+	for path, pathItem := range doc.Paths.Map() {
+		for method := range pathItem.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+	sort.Strings(routes)
+	sort.Strings(documented)
+	require.Equal(routes, documented, "every route in pkg/route must be described in pkg/openapi/openapi.json")
+}
+
+// jsonFields returns the JSON names of the fields of t, including embedded structs
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			fields = append(fields, jsonFields(field.Type)...)
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestSchemasMatchDTOs(t *testing.T) {
+	require := testifyRequire.New(t)
+	doc, err := openapi.Load()
+	require.NoError(err)
+
+	types := map[string]interface{}{
+		"UserDTO":                   dto.UserDTO{},
+		"MemberDTO":                 dto.MemberDTO{},
+		"ExpenseDTO":                dto.ExpenseDTO{},
+		"GroupDTO":                  dto.GroupDTO{},
+		"GroupPage":                 response.PageResponse[dto.GroupDTO]{},
+		"MemberPage":                response.PageResponse[dto.MemberDTO]{},
+		"ExpensePage":               response.PageResponse[dto.ExpenseDTO]{},
+		"ExpenseMatch":              dto.ExpenseMatchDTO{},
+		"ExpenseMatchPage":          response.PageResponse[dto.ExpenseMatchDTO]{},
+		"Activity":                  dto.ActivityDTO{},
+		"ActivityPage":              response.PageResponse[dto.ActivityDTO]{},
+		"Notification":              dto.NotificationDTO{},
+		"NotificationPage":          response.NotificationPageResponse{},
+		"NotificationPreferences":   dto.NotificationPreferencesDTO{},
+		"Device":                    dto.DeviceDTO{},
+		"DeviceRegistrationRequest": request.DeviceRegistrationRequest{},
+		"Webhook":                   dto.WebhookDTO{},
+		"WebhookRequest":            request.WebhookRequest{},
+		"WebhookDelivery":           dto.WebhookDeliveryDTO{},
+		"WebhookDeliveryPage":       response.PageResponse[dto.WebhookDeliveryDTO]{},
+		"GroupCreationRequest":      request.GroupCreationRequest{},
+		"LoginRequest":              request.LoginRequest{},
+		"LoginResponse":             response.LoginResponse{},
+		"RegisterRequest":           request.RegisterRequest{},
+		"UpdateUserRequest":         request.UpdateUserRequest{},
+		"SimpleResponse":            response.SimpleResponse{},
+		"FieldError":                response.FieldError{},
+		"ErrorResponse":             response.ErrorResponse{},
+		"ReadinessReport":           health.Report{},
+	}
+	for name, value := range types {
+		schema, ok := doc.Components.Schemas[name]
+		require.True(ok, "schema %s is missing", name)
+		var properties []string
+		for property := range schema.Value.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		require.Equal(jsonFields(reflect.TypeOf(value)), properties, "properties of schema %s", name)
+	}
+
+	// Creating an expense takes the fields of an expense except the ID
+	creation := doc.Components.Schemas["ExpenseCreationRequest"].Value
+	expense := doc.Components.Schemas["ExpenseDTO"].Value
+	require.Len(creation.Properties, len(expense.Properties)-1)
+	for property := range creation.Properties {
+		require.Contains(expense.Properties, property)
+	}
+}
+
+func TestServeDocument(t *testing.T) {
+	require := testifyRequire.New(t)
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	var document map[string]interface{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &document))
+	require.Equal("3.0.3", document["openapi"])
+}
+
+func TestZeroAmountIsSerialised(t *testing.T) {
+	require := testifyRequire.New(t)
+	encoded, err := json.Marshal(dto.ExpenseDTO{ID: 1, Title: "Free coffee"})
+	require.NoError(err)
+	require.Contains(string(encoded), `"amount":0`)
+}
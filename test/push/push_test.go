@@ -0,0 +1,339 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/auth"
+	"money_share/pkg/model"
+	"money_share/pkg/push"
+	"money_share/pkg/repository/memory"
+	testmodel "money_share/test_tool/model"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func createUser(t *testing.T, store *memory.Store, username string) model.User {
+	user := testmodel.GenerateRandomUser()
+	user.Username = username
+	testifyRequire.NoError(t, memory.NewUserRepository(store).Create(&user))
+	return user
+}
+
+func TestDispatcherRetriesAndPrunes(t *testing.T) {
+	require := testifyRequire.New(t)
+	store := memory.NewStore()
+	devices := memory.NewDeviceRepository(store)
+	alice := createUser(t, store, "alice.smith")
+	for _, token := range []string{"ok", "flaky", "down", "uninstalled"} {
+		require.NoError(devices.Register(&model.Device{UserID: alice.ID, Platform: model.PlatformAndroid, Token: token}))
+	}
+	require.NoError(devices.Register(&model.Device{UserID: alice.ID, Platform: model.PlatformIOS, Token: "no-sender"}))
+
+	fake := push.NewFake()
+	fake.FailTimes("flaky", 1)
+	fake.FailTimes("down", 5)
+	fake.Invalidate("uninstalled")
+	dispatcher := &push.Dispatcher{
+		Devices:       devices,
+		Senders:       map[string]push.Sender{model.PlatformAndroid: fake},
+		MaxAttempts:   3,
+		Backoff:       time.Second,
+		MaxRetryDelay: time.Minute,
+		Logger:        logger,
+	}
+	ctx := context.Background()
+	message := push.Message{Title: "Flatmates", Body: "Hello"}
+	retries, err := dispatcher.Send(ctx, alice.ID, message)
+	require.NoError(err)
+
+	// Every device is attempted once, temporary failures are returned to be retried
+	require.Len(fake.Sent(), 1)
+	require.Equal("ok", fake.Sent()[0].Token)
+	require.Len(retries, 2)
+	var tokens []string
+	for _, retry := range retries {
+		tokens = append(tokens, retry.Device.Token)
+		require.Equal(1, retry.Attempts)
+		require.Equal(time.Second, retry.Delay)
+	}
+	require.ElementsMatch([]string{"flaky", "down"}, tokens)
+
+	byToken := make(map[string]*model.Device)
+	for _, retry := range retries {
+		byToken[retry.Device.Token] = retry.Device
+	}
+
+	// Retries back off exponentially until the attempts run out
+	retry, ok := dispatcher.SendToDevice(ctx, byToken["down"], message, 2)
+	require.True(ok)
+	require.Equal(2, retry.Attempts)
+	require.Equal(2*time.Second, retry.Delay)
+	_, ok = dispatcher.SendToDevice(ctx, byToken["down"], message, 3)
+	require.False(ok)
+	_, ok = dispatcher.SendToDevice(ctx, byToken["flaky"], message, 2)
+	require.False(ok)
+	require.Len(fake.Sent(), 2)
+
+	// A provider asking for a long delay is retried after the maximum delay
+	fake.RetryAfter = time.Hour
+	retry, ok = dispatcher.SendToDevice(ctx, byToken["down"], message, 1)
+	require.True(ok)
+	require.Equal(time.Minute, retry.Delay)
+
+	// Invalid tokens are removed, the others are kept
+	remaining, err := devices.ListByUser(alice.ID)
+	require.NoError(err)
+	tokens = nil
+	for _, device := range remaining {
+		tokens = append(tokens, device.Token)
+	}
+	require.Equal([]string{"ok", "flaky", "down", "no-sender"}, tokens)
+}
+
+func TestWorkerPushesOnce(t *testing.T) {
+	require := testifyRequire.New(t)
+	store := memory.NewStore()
+	alice := createUser(t, store, "alice.smith")
+	bob := createUser(t, store, "bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	require.NoError(memory.NewGroupRepository(store).Create(&group, alice.ID))
+	require.NoError(memory.NewDeviceRepository(store).Register(&model.Device{UserID: bob.ID, Platform: model.PlatformIOS, Token: "bob-phone"}))
+	ctx := auth.WithUserID(context.Background(), alice.ID)
+	require.NoError(memory.NewMemberRepository(store).WithContext(ctx).AddMemberToGroup(bob.ID, group.ID))
+
+	fake := push.NewFake()
+	newWorker := func() *push.Worker {
+		return &push.Worker{
+			Notifications: memory.NewNotificationRepository(store),
+			Groups:        memory.NewGroupRepository(store),
+			Users:         memory.NewUserRepository(store),
+			Dispatcher: &push.Dispatcher{
+				Devices:     memory.NewDeviceRepository(store),
+				Senders:     map[string]push.Sender{model.PlatformIOS: fake},
+				MaxAttempts: 1,
+				Logger:      logger,
+			},
+			BatchSize: 10,
+			MaxAge:    time.Hour,
+			Logger:    logger,
+		}
+	}
+
+	// Workers of several instances claim each notification once
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(worker *push.Worker) {
+			defer wg.Done()
+			_, err := worker.Flush(context.Background())
+			require.NoError(err)
+		}(newWorker())
+	}
+	wg.Wait()
+	sent := fake.Sent()
+	require.Len(sent, 1)
+	require.Equal("bob-phone", sent[0].Token)
+	require.Equal(group.Name, sent[0].Message.Title)
+	require.Equal(alice.DisplayName+" added you to the group", sent[0].Message.Body)
+	require.Equal(model.NotificationMemberAdded, sent[0].Message.Data["type"])
+
+	pushed, err := newWorker().Flush(context.Background())
+	require.NoError(err)
+	require.Zero(pushed)
+}
+
+func TestWorkerStoresRetries(t *testing.T) {
+	require := testifyRequire.New(t)
+	store := memory.NewStore()
+	alice := createUser(t, store, "alice.smith")
+	bob := createUser(t, store, "bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	require.NoError(memory.NewGroupRepository(store).Create(&group, alice.ID))
+	devices := memory.NewDeviceRepository(store)
+	require.NoError(devices.Register(&model.Device{UserID: bob.ID, Platform: model.PlatformIOS, Token: "bob-phone"}))
+	require.NoError(devices.Register(&model.Device{UserID: bob.ID, Platform: model.PlatformIOS, Token: "bob-tablet"}))
+	ctx := auth.WithUserID(context.Background(), alice.ID)
+	require.NoError(memory.NewMemberRepository(store).WithContext(ctx).AddMemberToGroup(bob.ID, group.ID))
+
+	fake := push.NewFake()
+	fake.FailTimes("bob-tablet", 1)
+	fake.RetryAfter = time.Hour
+	newWorker := func() *push.Worker {
+		return &push.Worker{
+			Notifications: memory.NewNotificationRepository(store),
+			Groups:        memory.NewGroupRepository(store),
+			Users:         memory.NewUserRepository(store),
+			Dispatcher: &push.Dispatcher{
+				Devices:       devices,
+				Senders:       map[string]push.Sender{model.PlatformIOS: fake},
+				MaxAttempts:   3,
+				MaxRetryDelay: time.Minute,
+				Logger:        logger,
+			},
+			BatchSize: 10,
+			MaxAge:    time.Hour,
+			Logger:    logger,
+		}
+	}
+
+	// The failed device doesn't hold the batch, its retry is stored rather than waited for
+	_, err := newWorker().Flush(context.Background())
+	require.NoError(err)
+	require.Len(fake.Sent(), 1)
+	require.Equal("bob-phone", fake.Sent()[0].Token)
+	pushed, err := newWorker().Flush(context.Background())
+	require.NoError(err)
+	require.Zero(pushed)
+
+	// Another worker retries it once due, after the maximum delay rather than the hour asked for
+	store.Clock = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	pushed, err = newWorker().Flush(context.Background())
+	require.NoError(err)
+	require.Equal(1, pushed)
+	require.Len(fake.Sent(), 2)
+	require.Equal("bob-tablet", fake.Sent()[1].Token)
+	require.Equal(model.NotificationMemberAdded, fake.Sent()[1].Message.Data["type"])
+	store.Clock = func() time.Time { return time.Now().Add(time.Hour) }
+	pushed, err = newWorker().Flush(context.Background())
+	require.NoError(err)
+	require.Zero(pushed)
+}
+
+func TestFCMSender(t *testing.T) {
+	require := testifyRequire.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(err)
+	tokenRequests := 0
+	var sent map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			// The assertion is signed with the key of the service account
+			assertion, err := jwt.Parse(r.FormValue("assertion"), func(token *jwt.Token) (interface{}, error) {
+				return &key.PublicKey, nil
+			})
+			require.NoError(err)
+			require.Equal("push@money-share.iam.gserviceaccount.com", assertion.Claims.(jwt.MapClaims)["iss"])
+			_, _ = w.Write([]byte(`{"access_token":"access","expires_in":3600}`))
+		case "/v1/projects/money-share/messages:send":
+			require.Equal("Bearer access", r.Header.Get("Authorization"))
+			require.NoError(json.NewDecoder(r.Body).Decode(&sent))
+			message := sent["message"].(map[string]interface{})
+			switch message["token"] {
+			case "uninstalled":
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+			case "busy":
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				_, _ = w.Write([]byte(`{"name":"projects/money-share/messages/1"}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	credentials, err := json.Marshal(push.FCMCredentials{
+		ProjectID:   "money-share",
+		ClientEmail: "push@money-share.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		TokenURI:    server.URL + "/token",
+	})
+	require.NoError(err)
+	sender, err := push.NewFCMSender(credentials)
+	require.NoError(err)
+	sender.Endpoint = server.URL
+	ctx := context.Background()
+	message := push.Message{Title: "Flatmates", Body: "Hello", Data: map[string]string{"type": "member.added"}}
+
+	require.NoError(sender.Send(ctx, "phone", message))
+	require.Equal(map[string]interface{}{
+		"token":        "phone",
+		"notification": map[string]interface{}{"title": "Flatmates", "body": "Hello"},
+		"data":         map[string]interface{}{"type": "member.added"},
+		"android":      map[string]interface{}{"priority": "high"},
+	}, sent["message"])
+
+	require.ErrorIs(sender.Send(ctx, "uninstalled", message), push.ErrInvalidToken)
+	var temporary *push.TemporaryError
+	require.True(errors.As(sender.Send(ctx, "busy", message), &temporary))
+	require.Equal(7*time.Second, temporary.RetryAfter)
+	// The access token is reused until it expires
+	require.Equal(1, tokenRequests)
+}
+
+func TestAPNsSender(t *testing.T) {
+	require := testifyRequire.New(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	encoded, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(err)
+
+	var payload map[string]interface{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal("com.example.moneyshare", r.Header.Get("apns-topic"))
+		require.Equal("alert", r.Header.Get("apns-push-type"))
+		// Requests are authenticated with a provider token signed with the key of the team
+		token, err := jwt.Parse(r.Header.Get("Authorization")[len("bearer "):], func(token *jwt.Token) (interface{}, error) {
+			require.Equal("KEY123", token.Header["kid"])
+			return &key.PublicKey, nil
+		})
+		require.NoError(err)
+		require.Equal("TEAM456", token.Claims.(jwt.MapClaims)["iss"])
+		require.NoError(json.NewDecoder(r.Body).Decode(&payload))
+
+		switch r.URL.Path {
+		case "/3/device/uninstalled":
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"reason":"Unregistered"}`))
+		case "/3/device/malformed":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+		case "/3/device/throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"reason":"TooManyRequests"}`))
+		}
+	}))
+	defer server.Close()
+
+	sender, err := push.NewAPNsSender(push.APNsConfig{
+		Key:      pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encoded}),
+		KeyID:    "KEY123",
+		TeamID:   "TEAM456",
+		Topic:    "com.example.moneyshare",
+		Endpoint: server.URL,
+	})
+	require.NoError(err)
+	sender.Client = server.Client()
+	ctx := context.Background()
+	message := push.Message{Title: "Flatmates", Body: "Hello", Data: map[string]string{"type": "member.added"}}
+
+	require.NoError(sender.Send(ctx, "phone", message))
+	require.Equal(map[string]interface{}{
+		"aps":  map[string]interface{}{"alert": map[string]interface{}{"title": "Flatmates", "body": "Hello"}, "sound": "default"},
+		"type": "member.added",
+	}, payload)
+	require.ErrorIs(sender.Send(ctx, "uninstalled", message), push.ErrInvalidToken)
+	require.ErrorIs(sender.Send(ctx, "malformed", message), push.ErrInvalidToken)
+	var temporary *push.TemporaryError
+	require.True(errors.As(sender.Send(ctx, "throttled", message), &temporary))
+}
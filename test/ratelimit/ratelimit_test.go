@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/gorilla/mux"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/middleware"
+	"money_share/pkg/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRedisLimiterTokenBucket(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	limiter := ratelimit.NewRedisLimiter(client)
+	policy := ratelimit.Policy{Name: "test", Limit: 3, Window: time.Minute}
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(context.Background(), "ip:10.0.0.1", policy)
+		require.NoError(err)
+		require.True(result.Allowed)
+		require.Equal(i, result.Remaining)
+	}
+	result, err := limiter.Allow(context.Background(), "ip:10.0.0.1", policy)
+	require.NoError(err)
+	require.False(result.Allowed, "bucket should be empty")
+	require.Greater(result.RetryAfter, time.Duration(0))
+
+	// Buckets are independent per key
+	result, err = limiter.Allow(context.Background(), "ip:10.0.0.2", policy)
+	require.NoError(err)
+	require.True(result.Allowed)
+}
+
+func TestFallbackWhenRedisUnavailable(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	server.Close()
+
+	limiter := &ratelimit.FallbackLimiter{
+		Primary:  ratelimit.NewRedisLimiter(client),
+		Fallback: ratelimit.NewMemoryLimiter(),
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	policy := ratelimit.Policy{Name: "test", Limit: 1, Window: time.Minute}
+	result, err := limiter.Allow(context.Background(), "ip:10.0.0.1", policy)
+	require.NoError(err)
+	require.True(result.Allowed)
+	result, err = limiter.Allow(context.Background(), "ip:10.0.0.1", policy)
+	require.NoError(err)
+	require.False(result.Allowed, "in-process limits should still apply")
+}
+
+func TestParsePolicy(t *testing.T) {
+	require := testifyRequire.New(t)
+	policy, err := ratelimit.ParsePolicy("login", "5/1m")
+	require.NoError(err)
+	require.Equal(ratelimit.Policy{Name: "login", Limit: 5, Window: time.Minute}, policy)
+
+	for _, value := range []string{"5", "0/1m", "5/0s", "5/-1s", "5/999us", "5/1ns"} {
+		_, err = ratelimit.ParsePolicy("login", value)
+		require.Error(err, value)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	require := testifyRequire.New(t)
+	trusted, err := ratelimit.ParseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	require.NoError(err)
+
+	// Port is not part of the key
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.9:51234"
+	require.Equal("203.0.113.9", ratelimit.ClientIP(req, trusted))
+
+	// Forwarded header from an untrusted peer is ignored
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	require.Equal("203.0.113.9", ratelimit.ClientIP(req, trusted))
+
+	// Behind trusted proxies the rightmost untrusted hop is the client
+	req.RemoteAddr = "10.1.2.3:443"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.1, 192.168.1.1")
+	require.Equal("198.51.100.1", ratelimit.ClientIP(req, trusted))
+}
+
+func TestRateLimitMiddlewareHeadersAndRoutePolicies(t *testing.T) {
+	require := testifyRequire.New(t)
+	r := mux.NewRouter()
+	r.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Limiter: ratelimit.NewMemoryLimiter(),
+		Default: ratelimit.Policy{Name: "default", Limit: 10, Window: 10 * time.Second},
+		Routes: map[string]ratelimit.Policy{
+			"/user/login": {Name: "auth", Limit: 1, Window: time.Minute},
+		},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r.HandleFunc("/user/login", ok)
+	r.HandleFunc("/group", ok)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		req.RemoteAddr = "203.0.113.9:1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("/user/login")
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal("1", rec.Header().Get("RateLimit-Limit"))
+	require.Equal("0", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal("1;w=60", rec.Header().Get("RateLimit-Policy"))
+
+	rec = send("/user/login")
+	require.Equal(http.StatusTooManyRequests, rec.Code)
+	require.Equal("60", rec.Header().Get("Retry-After"))
+
+	// Other routes use the default policy and their own bucket
+	rec = send("/group")
+	require.Equal(http.StatusOK, rec.Code)
+	require.Equal("9", rec.Header().Get("RateLimit-Remaining"))
+}
@@ -0,0 +1,215 @@
+package realtime
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/realtime"
+	"testing"
+	"time"
+)
+
+// newHub starts a hub, several hubs on one server act as instances of the API
+func newHub(t *testing.T, server *miniredis.Miniredis) *realtime.Hub {
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	hub := realtime.NewHub(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	t.Cleanup(func() {
+		hub.Close()
+		cancel()
+		_ = client.Close()
+	})
+	return hub
+}
+
+func receive(t *testing.T, subscription *realtime.Subscription) realtime.Event {
+	t.Helper()
+	select {
+	case event, ok := <-subscription.Events:
+		testifyRequire.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return realtime.Event{}
+}
+
+func TestFanOutAcrossInstances(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := miniredis.RunT(t)
+	first, second := newHub(t, server), newHub(t, server)
+	ctx := context.Background()
+
+	subscription, err := second.Subscribe(ctx, 1)
+	require.NoError(err)
+	other, err := second.Subscribe(ctx, 2)
+	require.NoError(err)
+
+	published, err := first.Publish(ctx, 1, realtime.EventExpenseCreated, map[string]int{"id": 7})
+	require.NoError(err)
+	event := receive(t, subscription)
+	require.Equal(published.ID, event.ID)
+	require.Equal(uint(1), event.GroupID)
+	require.Equal(realtime.EventExpenseCreated, event.Type)
+	require.JSONEq(`{"id":7}`, string(event.Data))
+
+	// Events go to the subscribers of their group only
+	require.Empty(other.Events)
+
+	// Closed subscriptions receive nothing
+	subscription.Close()
+	_, ok := <-subscription.Events
+	require.False(ok)
+}
+
+func TestRunReturnsWhenCancelled(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	hub := realtime.NewHub(client, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	// Wait for the subscription, then shut down while it waits for messages
+	subscription, err := hub.Subscribe(context.Background(), 1)
+	testifyRequire.NoError(t, err)
+	subscription.Close()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
+
+func TestResumeFromHistory(t *testing.T) {
+	require := testifyRequire.New(t)
+	hub := newHub(t, miniredis.RunT(t))
+	hub.History = 3
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		event, err := hub.Publish(ctx, 1, realtime.EventTotalsUpdated, i)
+		require.NoError(err)
+		if len(ids) > 0 {
+			require.Equal(1, realtime.CompareIDs(event.ID, ids[len(ids)-1]))
+		}
+		ids = append(ids, event.ID)
+	}
+
+	// Events after one still kept are resumed
+	events, complete, latest, err := hub.Since(ctx, 1, ids[2])
+	require.NoError(err)
+	require.True(complete)
+	require.Equal(ids[4], latest)
+	require.Len(events, 2)
+	require.Equal(ids[3], events[0].ID)
+	require.Equal("3", string(events[0].Data))
+
+	// Events after one no longer kept are lost
+	events, complete, latest, err = hub.Since(ctx, 1, ids[0])
+	require.NoError(err)
+	require.False(complete)
+	require.Empty(events)
+	require.Equal(ids[4], latest)
+	_, complete, latest, err = hub.Since(ctx, 2, ids[0])
+	require.NoError(err)
+	require.False(complete)
+	require.Empty(latest)
+
+	require.True(realtime.ValidID(ids[0]))
+	require.False(realtime.ValidID("42"))
+	require.False(realtime.ValidID("a-1"))
+}
+
+func TestLaggingSubscriberIsDropped(t *testing.T) {
+	require := testifyRequire.New(t)
+	hub := newHub(t, miniredis.RunT(t))
+	ctx := context.Background()
+	subscription, err := hub.Subscribe(ctx, 1)
+	require.NoError(err)
+	marker, err := hub.Subscribe(ctx, 2)
+	require.NoError(err)
+
+	// Nobody reads the events, the subscription is closed once its buffer is full
+	for i := 0; i <= cap(subscription.Events); i++ {
+		_, err = hub.Publish(ctx, 1, realtime.EventTotalsUpdated, i)
+		require.NoError(err)
+	}
+	// Events are dispatched in order, the earlier ones are dispatched once the marker is
+	_, err = hub.Publish(ctx, 2, realtime.EventTotalsUpdated, 0)
+	require.NoError(err)
+	receive(t, marker)
+	received := 0
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-subscription.Events:
+			closed = !ok
+			if ok {
+				received++
+			}
+		case <-time.After(time.Second):
+			t.Fatal("subscription not dropped")
+		}
+	}
+	require.Equal(cap(subscription.Events), received)
+
+	// Closing the hub ends every subscription and refuses new ones
+	subscription, err = hub.Subscribe(ctx, 1)
+	require.NoError(err)
+	hub.Close()
+	_, ok := <-subscription.Events
+	require.False(ok)
+	_, err = hub.Subscribe(ctx, 1)
+	require.ErrorIs(err, realtime.ErrClosed)
+}
+
+func TestConnectionLimit(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := miniredis.RunT(t)
+	first, second := newHub(t, server), newHub(t, server)
+	ctx := context.Background()
+
+	// The limit holds across instances
+	connection, ok, err := first.Connect(ctx, 1, 2, time.Minute)
+	require.NoError(err)
+	require.True(ok)
+	_, ok, err = second.Connect(ctx, 1, 2, time.Minute)
+	require.NoError(err)
+	require.True(ok)
+	_, ok, err = second.Connect(ctx, 1, 2, time.Minute)
+	require.NoError(err)
+	require.False(ok)
+	_, ok, err = second.Connect(ctx, 2, 2, time.Minute)
+	require.NoError(err)
+	require.True(ok, "other users have their own limit")
+
+	connection.Close()
+	_, ok, err = second.Connect(ctx, 1, 2, time.Minute)
+	require.NoError(err)
+	require.True(ok)
+
+	// Connections of crashed instances expire with their lease unless refreshed
+	refreshed, ok, err := first.Connect(ctx, 3, 2, 50*time.Millisecond)
+	require.NoError(err)
+	require.True(ok)
+	_, ok, err = first.Connect(ctx, 3, 2, 50*time.Millisecond)
+	require.NoError(err)
+	require.True(ok)
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(refreshed.Refresh(ctx))
+	time.Sleep(30 * time.Millisecond)
+	_, ok, err = first.Connect(ctx, 3, 2, time.Minute)
+	require.NoError(err)
+	require.True(ok)
+	_, ok, err = first.Connect(ctx, 3, 2, time.Minute)
+	require.NoError(err)
+	require.False(ok)
+}
@@ -0,0 +1,1020 @@
+package repostory
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"money_share/pkg/apperror"
+	"money_share/pkg/auth"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"money_share/pkg/repository/memory"
+	"money_share/test_tool/database"
+	testmodel "money_share/test_tool/model"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Repositories under test, either all gorm or all in-memory
+type Repositories struct {
+	User         repository.UserRepository
+	Group        repository.GroupRepository
+	Member       repository.MemberRepository
+	Expense      repository.ExpenseRepository
+	Activity     repository.ActivityRepository
+	Notification repository.NotificationRepository
+	Device       repository.DeviceRepository
+	Webhook      repository.WebhookRepository
+	Payment      repository.PaymentRepository
+}
+
+// RepositoryContractSuite describes the behaviour every repository implementation must share
+type RepositoryContractSuite struct {
+	suite.Suite
+	// NewRepositories returns repositories over an empty store, it is called before every test
+	NewRepositories func() (Repositories, error)
+	Repositories
+}
+
+func (suite *RepositoryContractSuite) SetupTest() {
+	repositories, err := suite.NewRepositories()
+	if err != nil {
+		suite.T().Skipf("repositories not available: %s", err)
+	}
+	suite.Repositories = repositories
+}
+
+// assertError asserts err is an apperror of kind with code
+func (suite *RepositoryContractSuite) assertError(err error, kind apperror.Kind, code string, msgAndArgs ...interface{}) {
+	appErr, ok := apperror.As(err)
+	if suite.True(ok, msgAndArgs...) {
+		suite.Equal(kind, appErr.Kind, msgAndArgs...)
+		suite.Equal(code, appErr.Code, msgAndArgs...)
+	}
+}
+
+func (suite *RepositoryContractSuite) createUser(username string) model.User {
+	user := testmodel.GenerateRandomUser()
+	user.Username = username
+	suite.Require().NoError(suite.User.Create(&user))
+	suite.Require().Greater(user.ID, uint(0), "created user must have id greater than 0")
+	return user
+}
+
+func (suite *RepositoryContractSuite) createExpense(groupID uint, memberID uint, amount float32, status string) model.Expense {
+	expense := model.Expense{
+		Title:        "Dinner",
+		Amount:       amount,
+		PurchaseTime: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Status:       status,
+		GroupID:      groupID,
+		MemberID:     memberID,
+	}
+	suite.Require().NoError(suite.Expense.Create(&expense))
+	suite.Require().Greater(expense.ID, uint(0), "created expense must have id greater than 0")
+	return expense
+}
+
+// assertTotals compares the group total and average and the total of each member
+func (suite *RepositoryContractSuite) assertTotals(groupID uint, total float32, average float32, memberTotals map[uint]float32) {
+	group, err := suite.Group.GetById(groupID)
+	suite.Require().NoError(err)
+	suite.InDelta(total, group.TotalExpense, 0.001, "group total")
+	suite.InDelta(average, group.AverageExpense, 0.001, "group average")
+	for userID, memberTotal := range memberTotals {
+		member, err := suite.Member.GetByID(userID, groupID)
+		suite.Require().NoError(err)
+		suite.InDelta(memberTotal, member.TotalExpense, 0.001, "total of member %d", userID)
+	}
+}
+
+func (suite *RepositoryContractSuite) TestUser() {
+	alice := suite.createUser("alice.smith")
+	suite.createUser("bob.jones")
+
+	// Usernames are unique
+	duplicate := testmodel.GenerateRandomUser()
+	duplicate.Username = alice.Username
+	suite.assertError(suite.User.Create(&duplicate), apperror.KindConflict, "username_taken")
+
+	user, err := suite.User.GetById(alice.ID)
+	suite.NoError(err)
+	suite.Equal(alice.Username, user.Username)
+	suite.Equal(alice.DisplayName, user.DisplayName)
+	user, err = suite.User.GetByUsername("alice.smith")
+	suite.NoError(err)
+	suite.Equal(alice.ID, user.ID)
+	_, err = suite.User.GetByUsername("no_such_username")
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+	suite.assertError(err, apperror.KindNotFound, "user_not_found")
+
+	// Find is case insensitive, ordered and limited
+	users, err := suite.User.Find("SMITH", 10)
+	suite.NoError(err)
+	suite.Len(users, 1)
+	suite.Equal(alice.ID, users[0].ID)
+	users, err = suite.User.Find("", 1)
+	suite.NoError(err)
+	suite.Len(users, 1)
+	suite.Equal(alice.ID, users[0].ID)
+
+	available, err := suite.User.CheckUsernameAvailability("alice.smith")
+	suite.NoError(err)
+	suite.False(available)
+	validated, err := suite.User.ValidateUsernameAndUserID("bob.jones", alice.ID)
+	suite.NoError(err)
+	suite.False(validated)
+
+	// Update by field name, including setting and clearing a nullable column
+	disabledAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated, err := suite.User.Update(alice.ID, map[string]interface{}{"DisplayName": "Alice", "DisabledAt": disabledAt})
+	suite.NoError(err)
+	suite.Equal("Alice", updated.DisplayName)
+	suite.Equal(alice.Username, updated.Username)
+	suite.True(updated.IsDisabled())
+	updated, err = suite.User.Update(alice.ID, map[string]interface{}{"DisabledAt": nil})
+	suite.NoError(err)
+	suite.False(updated.IsDisabled())
+	_, err = suite.User.Update(alice.ID+100, map[string]interface{}{"DisplayName": "Nobody"})
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+
+	// Deleted users are gone but keep their username taken
+	suite.NoError(suite.User.Delete(alice.ID))
+	_, err = suite.User.GetById(alice.ID)
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+	suite.Error(suite.User.Delete(alice.ID))
+	suite.Error(suite.User.Create(&duplicate))
+}
+
+func (suite *RepositoryContractSuite) TestGroup() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+
+	group := testmodel.GenerateRandomGroup()
+	suite.NoError(suite.Group.Create(&group, alice.ID))
+	suite.Greater(group.ID, uint(0))
+	missingCreator := testmodel.GenerateRandomGroup()
+	suite.ErrorIs(suite.Group.Create(&missingCreator, alice.ID+100), gorm.ErrRecordNotFound)
+
+	// The creator manages the group
+	saved, err := suite.Group.GetById(group.ID)
+	suite.NoError(err)
+	suite.Equal(group.Name, saved.Name)
+	suite.Len(saved.Members, 1)
+	suite.Equal(alice.Username, saved.Members[0].User.Username)
+	suite.Equal(model.RoleManager, saved.Members[0].Role)
+	role, err := suite.Group.GetMemberRole(alice.ID, group.ID)
+	suite.NoError(err)
+	suite.Equal(model.RoleManager, role)
+	role, err = suite.Group.GetMemberRole(bob.ID, group.ID)
+	suite.NoError(err)
+	suite.Empty(role)
+
+	groups, err := suite.Group.GetByUser(alice.ID)
+	suite.NoError(err)
+	suite.Len(groups, 1)
+	groups, err = suite.Group.GetByUser(bob.ID)
+	suite.NoError(err)
+	suite.Empty(groups)
+
+	// Only non-zero fields are updated
+	suite.NoError(suite.Group.Update(&model.Group{Model: gorm.Model{ID: group.ID}, Name: "Renamed"}))
+	saved, err = suite.Group.GetById(group.ID)
+	suite.NoError(err)
+	suite.Equal("Renamed", saved.Name)
+	suite.Equal(group.GroupImageUrl, saved.GroupImageUrl)
+	suite.ErrorIs(suite.Group.Update(&model.Group{Model: gorm.Model{ID: group.ID + 100}, Name: "x"}), gorm.ErrRecordNotFound)
+
+	suite.NoError(suite.Group.Delete(group.ID))
+	_, err = suite.Group.GetById(group.ID)
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+	groups, err = suite.Group.GetByUser(alice.ID)
+	suite.NoError(err)
+	suite.Empty(groups)
+}
+
+func (suite *RepositoryContractSuite) TestMember() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+
+	suite.NoError(suite.Member.AddMemberToGroup(bob.ID, group.ID))
+	suite.assertError(suite.Member.AddMemberToGroup(bob.ID, group.ID), apperror.KindConflict, "member_exists", "members are unique")
+	suite.assertError(suite.Member.AddMemberToGroup(bob.ID+100, group.ID), apperror.KindNotFound, "reference_not_found", "user must exist")
+
+	member, err := suite.Member.GetByID(bob.ID, group.ID)
+	suite.NoError(err)
+	suite.Equal(model.RoleMember, member.Role)
+	suite.Equal(bob.Username, member.User.Username)
+	members, err := suite.Member.GetByGroup(group.ID)
+	suite.NoError(err)
+	suite.Len(members, 2)
+
+	suite.NoError(suite.Member.UpdateRole(bob.ID, group.ID, model.RoleManager))
+	member, err = suite.Member.GetByID(bob.ID, group.ID)
+	suite.NoError(err)
+	suite.Equal(model.RoleManager, member.Role)
+	suite.assertError(suite.Member.UpdateRole(bob.ID, group.ID, "owner"), apperror.KindValidation, apperror.CodeValidationFailed)
+	suite.ErrorIs(suite.Member.UpdateRole(bob.ID+100, group.ID, model.RoleMember), gorm.ErrRecordNotFound)
+	suite.assertError(suite.Member.UpdateRole(bob.ID+100, group.ID, model.RoleMember), apperror.KindNotFound, "member_not_found")
+
+	suite.NoError(suite.Member.RemoveMemberFromGroup(bob.ID, group.ID))
+	_, err = suite.Member.GetByID(bob.ID, group.ID)
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+}
+
+func (suite *RepositoryContractSuite) TestExpenseTotals() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+	suite.Require().NoError(suite.Member.AddMemberToGroup(bob.ID, group.ID))
+
+	// Only approved expenses count
+	aliceDinner := suite.createExpense(group.ID, alice.ID, 10, "approved")
+	suite.createExpense(group.ID, bob.ID, 20.5, "approved")
+	pending := suite.createExpense(group.ID, alice.ID, 100, "")
+	suite.Equal("pending", pending.Status, "status defaults to pending")
+	suite.assertTotals(group.ID, 30.5, 15.25, map[uint]float32{alice.ID: 10, bob.ID: 20.5})
+
+	// Approving, changing amounts and denying recompute totals
+	// Updates report the action recorded
+	action, err := suite.Expense.Update(&model.Expense{Model: gorm.Model{ID: pending.ID}, Status: "approved"})
+	suite.NoError(err)
+	suite.Equal(model.ActionExpenseApproved, action)
+	suite.assertTotals(group.ID, 130.5, 65.25, map[uint]float32{alice.ID: 110, bob.ID: 20.5})
+	action, err = suite.Expense.Update(&model.Expense{Model: gorm.Model{ID: pending.ID}, Amount: 50, Status: "approved"})
+	suite.NoError(err)
+	suite.Equal(model.ActionExpenseUpdated, action, "approving an approved expense again is an update")
+	suite.assertTotals(group.ID, 80.5, 40.25, map[uint]float32{alice.ID: 60, bob.ID: 20.5})
+	action, err = suite.Expense.Update(&model.Expense{Model: gorm.Model{ID: pending.ID}, Status: "denied"})
+	suite.NoError(err)
+	suite.Equal(model.ActionExpenseDenied, action)
+	suite.assertTotals(group.ID, 30.5, 15.25, map[uint]float32{alice.ID: 10, bob.ID: 20.5})
+
+	// Deleting removes the expense from the totals
+	suite.NoError(suite.Expense.Delete(aliceDinner.ID))
+	_, err = suite.Expense.GetById(aliceDinner.ID)
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+	suite.ErrorIs(suite.Expense.Delete(aliceDinner.ID), gorm.ErrRecordNotFound)
+	suite.assertError(suite.Expense.Delete(aliceDinner.ID), apperror.KindNotFound, "expense_not_found")
+	suite.assertTotals(group.ID, 20.5, 10.25, map[uint]float32{alice.ID: 0, bob.ID: 20.5})
+
+	// The average is split between all members
+	carol := suite.createUser("carol.white")
+	suite.NoError(suite.Member.AddMemberToGroup(carol.ID, group.ID))
+	suite.assertTotals(group.ID, 20.5, 20.5/3, map[uint]float32{carol.ID: 0})
+
+	// Removing a member detaches their expenses from the group
+	suite.NoError(suite.Member.RemoveMemberFromGroup(bob.ID, group.ID))
+	suite.assertTotals(group.ID, 0, 0, map[uint]float32{alice.ID: 0, carol.ID: 0})
+	expenses, err := suite.Expense.GetByGroup(group.ID)
+	suite.NoError(err)
+	suite.Len(expenses, 1, "only the denied expense of alice remains")
+	expenses, err = suite.Expense.GetByMember(alice.ID, group.ID)
+	suite.NoError(err)
+	suite.Len(expenses, 1)
+}
+
+func (suite *RepositoryContractSuite) TestExpenseValidation() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+
+	invalid := []model.Expense{
+		{Amount: 1, PurchaseTime: time.Now(), GroupID: group.ID, MemberID: alice.ID},
+		{Title: "Negative", Amount: -1, PurchaseTime: time.Now(), GroupID: group.ID, MemberID: alice.ID},
+		{Title: "No time", Amount: 1, GroupID: group.ID, MemberID: alice.ID},
+	}
+	for _, expense := range invalid {
+		expense := expense
+		suite.assertError(suite.Expense.Create(&expense), apperror.KindValidation, apperror.CodeValidationFailed, expense.Title)
+	}
+	notMember := model.Expense{Title: "Not a member", Amount: 1, PurchaseTime: time.Now(), GroupID: group.ID, MemberID: bob.ID}
+	suite.assertError(suite.Expense.Create(&notMember), apperror.KindNotFound, "reference_not_found")
+
+	// Every invalid field is reported
+	err := suite.Expense.Create(&model.Expense{Amount: -1, GroupID: group.ID, MemberID: alice.ID})
+	appErr, ok := apperror.As(err)
+	suite.Require().True(ok)
+	var fields []string
+	for _, field := range appErr.Fields {
+		fields = append(fields, field.Field)
+	}
+	suite.ElementsMatch([]string{"title", "amount", "purchaseTime"}, fields)
+
+	expense := suite.createExpense(group.ID, alice.ID, 10, "pending")
+	_, err = suite.Expense.Update(&model.Expense{Model: gorm.Model{ID: expense.ID}, Status: "accepted"})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	_, err = suite.Expense.Update(&model.Expense{Model: gorm.Model{ID: expense.ID + 100}, Title: "x"})
+	suite.ErrorIs(err, gorm.ErrRecordNotFound)
+
+	saved, err := suite.Expense.GetById(expense.ID)
+	suite.NoError(err)
+	suite.Equal("Dinner", saved.Title)
+	suite.Equal("pending", saved.Status)
+}
+
+// listAll follows the cursors of the expense list and returns the titles in order
+func (suite *RepositoryContractSuite) listAll(filter repository.ExpenseFilter, limit int) []string {
+	var titles []string
+	page := repository.PageRequest{Limit: limit}
+	for {
+		result, err := suite.Expense.List(filter, page)
+		suite.Require().NoError(err)
+		suite.Require().LessOrEqual(len(result.Items), limit)
+		for _, expense := range result.Items {
+			titles = append(titles, expense.Title)
+		}
+		if result.NextCursor == "" {
+			return titles
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+func (suite *RepositoryContractSuite) TestExpenseList() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+	suite.Require().NoError(suite.Member.AddMemberToGroup(bob.ID, group.ID))
+
+	// Equal purchase times and amounts are ordered by ID
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	for _, expense := range []model.Expense{
+		{Title: "A", Amount: 30, PurchaseTime: day(3), Status: "approved", MemberID: alice.ID},
+		{Title: "B", Amount: 10, PurchaseTime: day(1), Status: "pending", MemberID: bob.ID},
+		{Title: "C", Amount: 20, PurchaseTime: day(2), Status: "approved", MemberID: bob.ID},
+		{Title: "D", Amount: 10, PurchaseTime: day(2), Status: "denied", MemberID: alice.ID},
+		{Title: "E", Amount: 50, PurchaseTime: day(5), Status: "approved", MemberID: bob.ID},
+	} {
+		expense := expense
+		expense.GroupID = group.ID
+		suite.Require().NoError(suite.Expense.Create(&expense))
+	}
+
+	filter := repository.ExpenseFilter{GroupID: group.ID}
+	for _, limit := range []int{1, 2, 10} {
+		suite.Equal([]string{"B", "C", "D", "A", "E"}, suite.listAll(filter, limit), "purchase time, limit %d", limit)
+	}
+	filter.Descending = true
+	suite.Equal([]string{"E", "A", "D", "C", "B"}, suite.listAll(filter, 2))
+	filter.Sort = repository.SortAmount
+	suite.Equal([]string{"E", "A", "C", "D", "B"}, suite.listAll(filter, 2))
+	filter.Descending = false
+	suite.Equal([]string{"B", "D", "C", "A", "E"}, suite.listAll(filter, 2))
+	filter.Sort = repository.SortCreatedAt
+	suite.Equal([]string{"A", "B", "C", "D", "E"}, suite.listAll(filter, 3))
+
+	// Filters are combined and their bounds are inclusive
+	from, to := day(2), day(3)
+	minAmount, maxAmount := float32(10), float32(20)
+	suite.Equal([]string{"C", "D"}, suite.listAll(repository.ExpenseFilter{GroupID: group.ID, PurchasedFrom: &from, PurchasedTo: &to,
+		MinAmount: &minAmount, MaxAmount: &maxAmount}, 10))
+	suite.Equal([]string{"B", "C", "E"}, suite.listAll(repository.ExpenseFilter{GroupID: group.ID, MemberID: bob.ID}, 10))
+	suite.Equal([]string{"C", "E"}, suite.listAll(repository.ExpenseFilter{GroupID: group.ID, MemberID: bob.ID, Status: "approved"}, 1))
+	suite.Empty(suite.listAll(repository.ExpenseFilter{GroupID: group.ID + 100}, 10))
+
+	// Invalid filters, limits and cursors are rejected
+	_, err := suite.Expense.List(repository.ExpenseFilter{GroupID: group.ID, Status: "accepted", Sort: "title"}, repository.PageRequest{})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	_, err = suite.Expense.List(filter, repository.PageRequest{Limit: repository.MaxPageLimit + 1})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	page, err := suite.Expense.List(repository.ExpenseFilter{GroupID: group.ID}, repository.PageRequest{Limit: 1})
+	suite.Require().NoError(err)
+	_, err = suite.Expense.List(repository.ExpenseFilter{GroupID: group.ID, Sort: repository.SortAmount}, repository.PageRequest{Cursor: page.NextCursor})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed, "cursor of another order")
+	_, err = suite.Expense.List(filter, repository.PageRequest{Cursor: "not a cursor"})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+}
+
+func (suite *RepositoryContractSuite) TestGroupAndMemberPages() {
+	alice := suite.createUser("alice.smith")
+	var groupIDs []uint
+	for i := 0; i < 3; i++ {
+		group := testmodel.GenerateRandomGroup()
+		suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+		groupIDs = append(groupIDs, group.ID)
+	}
+
+	var listed []uint
+	page := repository.PageRequest{Limit: 2}
+	for {
+		groups, err := suite.Group.ListByUser(alice.ID, page)
+		suite.Require().NoError(err)
+		for _, group := range groups.Items {
+			listed = append(listed, group.ID)
+		}
+		if groups.NextCursor == "" {
+			break
+		}
+		page.Cursor = groups.NextCursor
+	}
+	suite.Equal(groupIDs, listed)
+
+	var userIDs []uint
+	for _, username := range []string{"bob.jones", "carol.white"} {
+		user := suite.createUser(username)
+		suite.Require().NoError(suite.Member.AddMemberToGroup(user.ID, groupIDs[0]))
+		userIDs = append(userIDs, user.ID)
+	}
+	members, err := suite.Member.ListByGroup(groupIDs[0], repository.PageRequest{Limit: 2})
+	suite.Require().NoError(err)
+	suite.Require().Len(members.Items, 2)
+	suite.Equal(alice.ID, members.Items[0].UserID)
+	suite.Equal(alice.Username, members.Items[0].User.Username)
+	suite.NotEmpty(members.NextCursor)
+	members, err = suite.Member.ListByGroup(groupIDs[0], repository.PageRequest{Limit: 2, Cursor: members.NextCursor})
+	suite.Require().NoError(err)
+	suite.Require().Len(members.Items, 1)
+	suite.Equal(userIDs[1], members.Items[0].UserID)
+	suite.Empty(members.NextCursor)
+
+	// Only the group with its expenses loads them
+	suite.createExpense(groupIDs[0], alice.ID, 10, "approved")
+	group, err := suite.Group.GetById(groupIDs[0])
+	suite.Require().NoError(err)
+	suite.Empty(group.Expenses)
+	group, err = suite.Group.GetWithExpenses(groupIDs[0])
+	suite.Require().NoError(err)
+	suite.Len(group.Expenses, 1)
+}
+
+func (suite *RepositoryContractSuite) TestExpenseSearch() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	trip := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&trip, alice.ID))
+	suite.Require().NoError(suite.Member.AddMemberToGroup(bob.ID, trip.ID))
+	flat := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&flat, bob.ID))
+
+	create := func(groupID uint, title string, description string, day int) model.Expense {
+		expense := model.Expense{Title: title, Description: description, Amount: 10, Status: "approved",
+			PurchaseTime: time.Date(2024, 3, day, 12, 0, 0, 0, time.UTC), GroupID: groupID, MemberID: bob.ID}
+		suite.Require().NoError(suite.Expense.Create(&expense))
+		return expense
+	}
+	taxi := create(trip.ID, "Taxi to the market", "Night market in Da Lat", 10)
+	dinner := create(trip.ID, "Dinner", "Shared a taxi back to the hotel", 12)
+	create(trip.ID, "Coffee", "Egg coffee", 11)
+	flatTaxi := create(flat.ID, "Taxi from the airport", "", 1)
+
+	// Title matches rank above description matches, ties are newest first
+	search := func(userID uint, query string, groupID uint) []uint {
+		page, err := suite.Expense.Search(repository.ExpenseSearch{UserID: userID, Query: query, GroupID: groupID}, repository.PageRequest{})
+		suite.Require().NoError(err)
+		var ids []uint
+		for _, match := range page.Items {
+			ids = append(ids, match.Expense.ID)
+		}
+		return ids
+	}
+	suite.Equal([]uint{taxi.ID, flatTaxi.ID, dinner.ID}, search(bob.ID, "TAXI", 0))
+	suite.Equal([]uint{taxi.ID, dinner.ID}, search(alice.ID, "taxi", 0), "only the groups of the user are searched")
+	suite.Equal([]uint{flatTaxi.ID}, search(bob.ID, "taxi", flat.ID))
+	suite.Equal([]uint{taxi.ID}, search(bob.ID, "taxi da lat", 0), "every word must match")
+	suite.Empty(search(alice.ID, "taxi", flat.ID))
+	suite.Empty(search(bob.ID, "100%", 0))
+
+	// Snippets highlight the matched words
+	page, err := suite.Expense.Search(repository.ExpenseSearch{UserID: alice.ID, Query: "market"}, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Contains(page.Items[0].Snippet, repository.HighlightStart+"market"+repository.HighlightStop)
+	suite.Greater(page.Items[0].Rank, float32(0))
+
+	// The text of snippets is escaped, only the highlights are markup
+	create(flat.ID, "<img src=x onerror=alert(1)> Picnic", "Bread & \x02cheese\x03", 2)
+	page, err = suite.Expense.Search(repository.ExpenseSearch{UserID: bob.ID, Query: "picnic"}, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Contains(page.Items[0].Snippet, "&lt;img src=x onerror=alert(1)&gt; "+repository.HighlightStart+"Picnic"+repository.HighlightStop)
+	suite.NotContains(page.Items[0].Snippet, "<img")
+	suite.NotContains(page.Items[0].Snippet, repository.HighlightStart+"cheese")
+
+	// Pages follow each other
+	page, err = suite.Expense.Search(repository.ExpenseSearch{UserID: bob.ID, Query: "taxi"}, repository.PageRequest{Limit: 2})
+	suite.Require().NoError(err)
+	suite.Len(page.Items, 2)
+	suite.Require().NotEmpty(page.NextCursor)
+	page, err = suite.Expense.Search(repository.ExpenseSearch{UserID: bob.ID, Query: "taxi"}, repository.PageRequest{Limit: 2, Cursor: page.NextCursor})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Equal(dinner.ID, page.Items[0].Expense.ID)
+	suite.Empty(page.NextCursor)
+
+	_, err = suite.Expense.Search(repository.ExpenseSearch{UserID: bob.ID, Query: "  "}, repository.PageRequest{})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	_, err = suite.Expense.Search(repository.ExpenseSearch{UserID: bob.ID, Query: "taxi"}, repository.PageRequest{Cursor: "bad"})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+}
+
+func (suite *RepositoryContractSuite) TestActivityLog() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+
+	// Changes are made by alice
+	ctx := auth.WithUserID(context.Background(), alice.ID)
+	members := suite.Member.WithContext(ctx)
+	expenses := suite.Expense.WithContext(ctx)
+	suite.Require().NoError(members.AddMemberToGroup(bob.ID, group.ID))
+	suite.Require().NoError(members.UpdateRole(bob.ID, group.ID, model.RoleManager))
+	// Updates which change nothing are not recorded
+	suite.Require().NoError(members.UpdateRole(bob.ID, group.ID, model.RoleManager))
+	expense := model.Expense{
+		Title:        "Dinner",
+		Amount:       30,
+		PurchaseTime: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Status:       model.StatusPending,
+		GroupID:      group.ID,
+		MemberID:     bob.ID,
+	}
+	suite.Require().NoError(expenses.Create(&expense))
+	_, err := expenses.Update(&model.Expense{Model: gorm.Model{ID: expense.ID}, Amount: 40})
+	suite.Require().NoError(err)
+	_, err = expenses.Update(&model.Expense{Model: gorm.Model{ID: expense.ID}, Status: model.StatusApproved})
+	suite.Require().NoError(err)
+	suite.Require().NoError(expenses.Delete(expense.ID))
+	suite.Require().NoError(suite.Group.WithContext(ctx).Update(&model.Group{Model: gorm.Model{ID: group.ID}, Name: "Renamed"}))
+	// Changes without a user in the context have no actor
+	suite.Require().NoError(suite.Member.RemoveMemberFromGroup(bob.ID, group.ID))
+	suite.Require().NoError(suite.Member.RemoveMemberFromGroup(bob.ID, group.ID))
+
+	var activities []*model.Activity
+	page := repository.PageRequest{Limit: 3}
+	for {
+		result, err := suite.Activity.ListByGroup(group.ID, page)
+		suite.Require().NoError(err)
+		suite.Require().LessOrEqual(len(result.Items), 3)
+		activities = append(activities, result.Items...)
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+
+	// Newest first
+	var actions []string
+	for _, activity := range activities {
+		actions = append(actions, activity.Action)
+		suite.Equal(group.ID, activity.GroupID)
+		suite.False(activity.CreatedAt.IsZero())
+	}
+	suite.Equal([]string{
+		model.ActionMemberRemoved,
+		model.ActionGroupRenamed,
+		model.ActionExpenseDeleted,
+		model.ActionExpenseApproved,
+		model.ActionExpenseUpdated,
+		model.ActionExpenseCreated,
+		model.ActionMemberRoleChanged,
+		model.ActionMemberAdded,
+	}, actions)
+
+	removed, renamed, updated, created := activities[0], activities[1], activities[4], activities[5]
+	suite.Nil(removed.ActorID)
+	suite.Equal(model.TargetMember, removed.TargetType)
+	suite.Equal(bob.ID, removed.TargetID)
+	suite.JSONEq(`{"userID":`+strconv.Itoa(int(bob.ID))+`,"role":"manager"}`, removed.Before)
+	suite.Empty(removed.After)
+
+	suite.Require().NotNil(renamed.ActorID)
+	suite.Equal(alice.ID, *renamed.ActorID)
+	suite.Contains(renamed.After, `"name":"Renamed"`)
+
+	suite.Equal(model.TargetExpense, updated.TargetType)
+	suite.Equal(expense.ID, updated.TargetID)
+	suite.Contains(updated.Before, `"amount":30`)
+	suite.Contains(updated.After, `"amount":40`)
+	suite.Empty(created.Before)
+	suite.Contains(created.After, `"status":"pending"`)
+
+	_, err = suite.Activity.ListByGroup(group.ID, repository.PageRequest{Cursor: "bad"})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+}
+
+func (suite *RepositoryContractSuite) TestNotifications() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	carol := suite.createUser("carol.white")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+
+	// Carol turns notifications of pending expenses off
+	suite.Require().NoError(suite.Notification.UpdatePreferences(carol.ID, map[string]bool{model.NotificationExpensePending: false}))
+	err := suite.Notification.UpdatePreferences(carol.ID, map[string]bool{"expense.paid": false})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	preferences, err := suite.Notification.GetPreferences(carol.ID)
+	suite.Require().NoError(err)
+	suite.Len(preferences, len(model.NotificationTypes))
+	suite.False(preferences[model.NotificationExpensePending])
+	suite.True(preferences[model.NotificationExpenseApproved])
+
+	// Alice adds bob and carol and makes carol a manager
+	aliceCtx := auth.WithUserID(context.Background(), alice.ID)
+	suite.Require().NoError(suite.Member.WithContext(aliceCtx).AddMemberToGroup(bob.ID, group.ID))
+	suite.Require().NoError(suite.Member.WithContext(aliceCtx).AddMemberToGroup(carol.ID, group.ID))
+	suite.Require().NoError(suite.Member.WithContext(aliceCtx).UpdateRole(carol.ID, group.ID, model.RoleManager))
+	// Bob's expense waits for the approval of alice, carol turned it off, then alice denies it
+	bobCtx := auth.WithUserID(context.Background(), bob.ID)
+	expense := model.Expense{
+		Title:        "Dinner",
+		Amount:       30,
+		PurchaseTime: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Status:       model.StatusPending,
+		GroupID:      group.ID,
+		MemberID:     bob.ID,
+	}
+	suite.Require().NoError(suite.Expense.WithContext(bobCtx).Create(&expense))
+	_, err = suite.Expense.WithContext(aliceCtx).Update(&model.Expense{Model: gorm.Model{ID: expense.ID}, Status: model.StatusDenied})
+	suite.Require().NoError(err)
+	// Nobody is notified of their own expenses
+	suite.createExpense(group.ID, alice.ID, 10, model.StatusApproved)
+	_, err = suite.Expense.WithContext(aliceCtx).Update(&model.Expense{Model: gorm.Model{ID: expense.ID}, Status: model.StatusApproved})
+	suite.Require().NoError(err)
+
+	types := func(userID uint) []string {
+		page, err := suite.Notification.ListByUser(userID, false, repository.PageRequest{})
+		suite.Require().NoError(err)
+		var types []string
+		for _, notification := range page.Items {
+			types = append(types, notification.Type)
+			suite.Equal(group.ID, notification.GroupID)
+		}
+		return types
+	}
+	suite.Equal([]string{model.NotificationExpenseApproved, model.NotificationExpenseDenied, model.NotificationMemberAdded}, types(bob.ID))
+	suite.Equal([]string{model.NotificationExpensePending}, types(alice.ID))
+	suite.Equal([]string{model.NotificationMemberAdded}, types(carol.ID))
+
+	// Newest first, in pages
+	page, err := suite.Notification.ListByUser(bob.ID, false, repository.PageRequest{Limit: 2})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 2)
+	suite.Require().NotEmpty(page.NextCursor)
+	denied := page.Items[1]
+	suite.Equal(expense.ID, denied.TargetID)
+	suite.Require().NotNil(denied.ActorID)
+	suite.Equal(alice.ID, *denied.ActorID)
+	suite.Contains(denied.Data, `"status":"denied"`)
+	page, err = suite.Notification.ListByUser(bob.ID, false, repository.PageRequest{Limit: 2, Cursor: page.NextCursor})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Empty(page.NextCursor)
+	suite.Equal(model.NotificationMemberAdded, page.Items[0].Type)
+
+	// Reading
+	count, err := suite.Notification.CountUnread(bob.ID)
+	suite.Require().NoError(err)
+	suite.Equal(int64(3), count)
+	suite.Require().NoError(suite.Notification.MarkRead(bob.ID, denied.ID))
+	suite.Require().NoError(suite.Notification.MarkRead(bob.ID, denied.ID))
+	count, err = suite.Notification.CountUnread(bob.ID)
+	suite.Require().NoError(err)
+	suite.Equal(int64(2), count)
+	unread, err := suite.Notification.ListByUser(bob.ID, true, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Len(unread.Items, 2)
+	read, err := suite.Notification.ListByUser(bob.ID, false, repository.PageRequest{Limit: 2})
+	suite.Require().NoError(err)
+	suite.NotNil(read.Items[1].ReadAt)
+	// Other users cannot read the notifications of bob
+	err = suite.Notification.MarkRead(alice.ID, denied.ID)
+	suite.assertError(err, apperror.KindNotFound, "notification_not_found")
+
+	suite.Require().NoError(suite.Notification.MarkAllRead(bob.ID))
+	count, err = suite.Notification.CountUnread(bob.ID)
+	suite.Require().NoError(err)
+	suite.Zero(count)
+	count, err = suite.Notification.CountUnread(alice.ID)
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), count)
+}
+
+func (suite *RepositoryContractSuite) TestDevices() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+
+	phone := model.Device{UserID: alice.ID, Platform: model.PlatformAndroid, Token: "token-1", AppVersion: "1.0.0"}
+	suite.Require().NoError(suite.Device.Register(&phone))
+	suite.Greater(phone.ID, uint(0))
+	tablet := model.Device{UserID: alice.ID, Platform: model.PlatformIOS, Token: "token-2"}
+	suite.Require().NoError(suite.Device.Register(&tablet))
+
+	// Registering a token again updates its device, also for another user
+	update := model.Device{UserID: alice.ID, Platform: model.PlatformAndroid, Token: "token-1", AppVersion: "1.1.0"}
+	suite.Require().NoError(suite.Device.Register(&update))
+	suite.Equal(phone.ID, update.ID)
+	moved := model.Device{UserID: bob.ID, Platform: model.PlatformIOS, Token: "token-2"}
+	suite.Require().NoError(suite.Device.Register(&moved))
+	suite.Equal(tablet.ID, moved.ID)
+
+	devices, err := suite.Device.ListByUser(alice.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(devices, 1)
+	suite.Equal("1.1.0", devices[0].AppVersion)
+	devices, err = suite.Device.ListByUser(bob.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(devices, 1)
+	suite.Equal(tablet.ID, devices[0].ID)
+
+	// Validation and ownership
+	err = suite.Device.Register(&model.Device{UserID: alice.ID, Platform: "windows"})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	suite.Len(err.(*apperror.Error).Fields, 2)
+	err = suite.Device.Delete(alice.ID, tablet.ID)
+	suite.assertError(err, apperror.KindNotFound, "device_not_found")
+
+	suite.Require().NoError(suite.Device.Delete(bob.ID, tablet.ID))
+	suite.Require().NoError(suite.Device.DeleteByToken("token-1"))
+	devices, err = suite.Device.ListByUser(alice.ID)
+	suite.Require().NoError(err)
+	suite.Empty(devices)
+}
+
+func (suite *RepositoryContractSuite) TestPushRetries() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+	aliceCtx := auth.WithUserID(context.Background(), alice.ID)
+	suite.Require().NoError(suite.Member.WithContext(aliceCtx).AddMemberToGroup(bob.ID, group.ID))
+	notifications, err := suite.Notification.ListByUser(bob.ID, false, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Require().Len(notifications.Items, 1)
+	notification := notifications.Items[0]
+	phone := model.Device{UserID: bob.ID, Platform: model.PlatformAndroid, Token: "token-1"}
+	suite.Require().NoError(suite.Device.Register(&phone))
+	tablet := model.Device{UserID: bob.ID, Platform: model.PlatformIOS, Token: "token-2"}
+	suite.Require().NoError(suite.Device.Register(&tablet))
+
+	due := model.PushRetry{NotificationID: notification.ID, DeviceID: phone.ID, Attempts: 1, RetryAt: time.Now().Add(-time.Minute)}
+	suite.Require().NoError(suite.Notification.SavePushRetry(&due))
+	suite.Greater(due.ID, uint(0))
+	later := model.PushRetry{NotificationID: notification.ID, DeviceID: tablet.ID, Attempts: 1, RetryAt: time.Now().Add(time.Hour)}
+	suite.Require().NoError(suite.Notification.SavePushRetry(&later))
+	err = suite.Notification.SavePushRetry(&model.PushRetry{NotificationID: notification.ID, DeviceID: 9999, RetryAt: time.Now()})
+	suite.assertError(err, apperror.KindNotFound, "reference_not_found")
+
+	// Due retries are claimed once until their lease expires, with their notification and device
+	claimed, err := suite.Notification.ClaimPushRetries(10, time.Hour)
+	suite.Require().NoError(err)
+	suite.Require().Len(claimed, 1)
+	suite.Equal(due.ID, claimed[0].ID)
+	suite.Require().NotNil(claimed[0].Notification)
+	suite.Equal(model.NotificationMemberAdded, claimed[0].Notification.Type)
+	suite.Require().NotNil(claimed[0].Device)
+	suite.Equal("token-1", claimed[0].Device.Token)
+	claimed, err = suite.Notification.ClaimPushRetries(10, time.Hour)
+	suite.Require().NoError(err)
+	suite.Empty(claimed)
+
+	// Rescheduled retries are due again
+	due.Attempts, due.RetryAt = 2, time.Now().Add(-time.Second)
+	suite.Require().NoError(suite.Notification.SavePushRetry(&due))
+	claimed, err = suite.Notification.ClaimPushRetries(10, time.Hour)
+	suite.Require().NoError(err)
+	suite.Require().Len(claimed, 1)
+	suite.Equal(2, claimed[0].Attempts)
+
+	// Retries are removed once done, and with their device
+	suite.Require().NoError(suite.Notification.DeletePushRetry(due.ID))
+	suite.Require().NoError(suite.Device.Delete(bob.ID, tablet.ID))
+	later.RetryAt = time.Now().Add(-time.Second)
+	suite.Require().NoError(suite.Notification.SavePushRetry(&later))
+	due.RetryAt = time.Now().Add(-time.Second)
+	suite.Require().NoError(suite.Notification.SavePushRetry(&due))
+	claimed, err = suite.Notification.ClaimPushRetries(10, time.Hour)
+	suite.Require().NoError(err)
+	suite.Empty(claimed)
+}
+
+func (suite *RepositoryContractSuite) TestWebhooks() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+	other := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&other, alice.ID))
+
+	err := suite.Webhook.Create(&model.Webhook{GroupID: group.ID, URL: "ftp://hooks.example.com", Secret: "secret", Events: "expense.paid"})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	suite.Len(err.(*apperror.Error).Fields, 2)
+	expenses := model.Webhook{GroupID: group.ID, URL: "https://hooks.example.com/expenses", Secret: "secret"}
+	expenses.SetEvents([]string{model.ActionExpenseCreated, model.ActionExpenseApproved})
+	suite.Require().NoError(suite.Webhook.Create(&expenses))
+	suite.True(expenses.Active)
+	members := model.Webhook{GroupID: group.ID, URL: "https://hooks.example.com/members", Secret: "secret", Events: model.ActionMemberAdded}
+	suite.Require().NoError(suite.Webhook.Create(&members))
+	webhooks, err := suite.Webhook.ListByGroup(group.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(webhooks, 2)
+	suite.Equal(expenses.ID, webhooks[0].ID)
+	_, err = suite.Webhook.GetByID(other.ID, expenses.ID)
+	suite.assertError(err, apperror.KindNotFound, "webhook_not_found")
+
+	// Changes are queued for the webhooks subscribing to them
+	aliceCtx := auth.WithUserID(context.Background(), alice.ID)
+	suite.Require().NoError(suite.Member.WithContext(aliceCtx).AddMemberToGroup(bob.ID, group.ID))
+	expense := suite.createExpense(group.ID, alice.ID, 10, model.StatusApproved)
+	suite.createExpense(other.ID, alice.ID, 10, model.StatusApproved)
+	page, err := suite.Webhook.ListDeliveries(expenses.ID, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	created := page.Items[0]
+	suite.Equal(model.ActionExpenseCreated, created.Event)
+	suite.Equal(model.DeliveryPending, created.Status)
+	suite.Contains(created.Payload, `"event":"expense.created"`)
+	suite.Contains(created.Payload, `"targetID":`+strconv.Itoa(int(expense.ID)))
+	page, err = suite.Webhook.ListDeliveries(members.ID, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Contains(page.Items[0].Payload, `"actorID":`+strconv.Itoa(int(alice.ID)))
+
+	// Due deliveries are claimed once until their lease expires
+	claimed, err := suite.Webhook.ClaimDue(10, time.Hour)
+	suite.Require().NoError(err)
+	suite.Require().Len(claimed, 2)
+	suite.Require().NotNil(claimed[0].Webhook)
+	suite.Equal("secret", claimed[0].Webhook.Secret)
+	claimed, err = suite.Webhook.ClaimDue(10, time.Hour)
+	suite.Require().NoError(err)
+	suite.Empty(claimed)
+
+	// The webhook is disabled once deliveries failed in a row, a success resets the count
+	attempt := func(delivery *model.WebhookDelivery, status string) bool {
+		now := time.Now()
+		delivery.Status = status
+		delivery.Attempts++
+		delivery.LastAttemptAt = &now
+		delivery.ResponseStatus = 500
+		delivery.Error = "endpoint responded 500"
+		disabled, err := suite.Webhook.SaveAttempt(delivery, 2)
+		suite.Require().NoError(err)
+		return disabled
+	}
+	suite.False(attempt(created, model.DeliveryFailed))
+	redelivered, err := suite.Webhook.Redeliver(expenses.ID, created.ID)
+	suite.Require().NoError(err)
+	suite.Equal(model.DeliveryPending, redelivered.Status)
+	suite.Equal(created.Payload, redelivered.Payload)
+	suite.False(attempt(redelivered, model.DeliverySucceeded))
+	suite.False(attempt(created, model.DeliveryFailed))
+	suite.True(attempt(created, model.DeliveryFailed))
+	disabled, err := suite.Webhook.GetByID(group.ID, expenses.ID)
+	suite.Require().NoError(err)
+	suite.False(disabled.Active)
+	suite.NotNil(disabled.DisabledAt)
+	suite.Equal(2, disabled.ConsecutiveFailures)
+	// Disabled webhooks receive nothing
+	suite.Require().NoError(suite.Expense.WithContext(aliceCtx).Delete(expense.ID))
+	page, err = suite.Webhook.ListDeliveries(expenses.ID, repository.PageRequest{Limit: 1})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Equal(redelivered.ID, page.Items[0].ID)
+	suite.Equal(model.DeliverySucceeded, page.Items[0].Status)
+	page, err = suite.Webhook.ListDeliveries(expenses.ID, repository.PageRequest{Limit: 1, Cursor: page.NextCursor})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Equal(model.DeliveryFailed, page.Items[0].Status)
+	suite.Equal(3, page.Items[0].Attempts)
+	suite.Equal(500, page.Items[0].ResponseStatus)
+	suite.Empty(page.NextCursor)
+
+	// Enabling resets the failures
+	disabled.Active = true
+	disabled.URL = "https://hooks.example.com/v2"
+	suite.Require().NoError(suite.Webhook.Update(disabled))
+	suite.True(disabled.Active)
+	suite.Zero(disabled.ConsecutiveFailures)
+	suite.Nil(disabled.DisabledAt)
+	suite.Equal("secret", disabled.Secret)
+	_, err = suite.Webhook.Redeliver(members.ID, created.ID)
+	suite.assertError(err, apperror.KindNotFound, "delivery_not_found")
+
+	suite.Require().NoError(suite.Webhook.Delete(group.ID, expenses.ID))
+	err = suite.Webhook.Delete(group.ID, expenses.ID)
+	suite.assertError(err, apperror.KindNotFound, "webhook_not_found")
+	page, err = suite.Webhook.ListDeliveries(expenses.ID, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Empty(page.Items)
+}
+
+func (suite *RepositoryContractSuite) TestPayments() {
+	alice := suite.createUser("alice.smith")
+	bob := suite.createUser("bob.jones")
+	group := testmodel.GenerateRandomGroup()
+	suite.Require().NoError(suite.Group.Create(&group, alice.ID))
+	aliceCtx := auth.WithUserID(context.Background(), alice.ID)
+	suite.Require().NoError(suite.Member.WithContext(aliceCtx).AddMemberToGroup(bob.ID, group.ID))
+
+	// Bob pays alice twice, she is notified of each payment
+	bobCtx := auth.WithUserID(context.Background(), bob.ID)
+	first := model.Payment{GroupID: group.ID, PayerID: bob.ID, PayeeID: alice.ID, Amount: 12.5}
+	suite.Require().NoError(suite.Payment.WithContext(bobCtx).Create(&first))
+	suite.NotZero(first.ID)
+	suite.False(first.CreatedAt.IsZero())
+	second := model.Payment{GroupID: group.ID, PayerID: bob.ID, PayeeID: alice.ID, Amount: 7}
+	suite.Require().NoError(suite.Payment.WithContext(bobCtx).Create(&second))
+
+	notifications, err := suite.Notification.ListByUser(alice.ID, false, repository.PageRequest{})
+	suite.Require().NoError(err)
+	suite.Require().Len(notifications.Items, 2)
+	suite.Equal(model.NotificationPaymentReceived, notifications.Items[1].Type)
+	suite.Equal(first.ID, notifications.Items[1].TargetID)
+	suite.Require().NotNil(notifications.Items[1].ActorID)
+	suite.Equal(bob.ID, *notifications.Items[1].ActorID)
+	suite.Contains(notifications.Items[1].Data, `"amount":12.5`)
+	activities, err := suite.Activity.ListByGroup(group.ID, repository.PageRequest{Limit: 1})
+	suite.Require().NoError(err)
+	suite.Equal(model.ActionPaymentRecorded, activities.Items[0].Action)
+	suite.Equal(model.TargetPayment, activities.Items[0].TargetType)
+	suite.Equal(second.ID, activities.Items[0].TargetID)
+
+	// Newest first, in pages
+	page, err := suite.Payment.ListByGroup(group.ID, repository.PageRequest{Limit: 1})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Equal(second.ID, page.Items[0].ID)
+	suite.Require().NotEmpty(page.NextCursor)
+	page, err = suite.Payment.ListByGroup(group.ID, repository.PageRequest{Limit: 1, Cursor: page.NextCursor})
+	suite.Require().NoError(err)
+	suite.Require().Len(page.Items, 1)
+	suite.Equal(first.ID, page.Items[0].ID)
+	suite.InDelta(12.5, page.Items[0].Amount, 0.001)
+	suite.Empty(page.NextCursor)
+
+	// Invalid payments and missing references are rejected
+	err = suite.Payment.Create(&model.Payment{GroupID: group.ID, PayerID: bob.ID, PayeeID: bob.ID, Amount: 1})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	err = suite.Payment.Create(&model.Payment{GroupID: group.ID, PayerID: bob.ID, PayeeID: alice.ID})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+	err = suite.Payment.Create(&model.Payment{GroupID: group.ID, PayerID: bob.ID, PayeeID: 9999, Amount: 1})
+	suite.assertError(err, apperror.KindNotFound, "reference_not_found")
+	_, err = suite.Payment.ListByGroup(0, repository.PageRequest{})
+	suite.assertError(err, apperror.KindValidation, apperror.CodeValidationFailed)
+}
+
+func TestGormRepositoryContract(t *testing.T) {
+	suite.Run(t, &RepositoryContractSuite{NewRepositories: func() (Repositories, error) {
+		db, err := database.Connect()
+		if err != nil {
+			return Repositories{}, err
+		}
+		return Repositories{
+			User:         repository.NewUserRepository(db),
+			Group:        repository.NewGroupRepository(db),
+			Member:       repository.NewMemberRepository(db),
+			Expense:      repository.NewExpenseRepository(db),
+			Activity:     repository.NewActivityRepository(db),
+			Notification: repository.NewNotificationRepository(db),
+			Device:       repository.NewDeviceRepository(db),
+			Webhook:      repository.NewWebhookRepository(db),
+			Payment:      repository.NewPaymentRepository(db),
+		}, nil
+	}})
+}
+
+func TestSQLiteRepositoryContract(t *testing.T) {
+	suite.Run(t, &RepositoryContractSuite{NewRepositories: func() (Repositories, error) {
+		db, err := database.ConnectSQLite()
+		if err != nil {
+			return Repositories{}, err
+		}
+		return Repositories{
+			User:         repository.NewUserRepository(db),
+			Group:        repository.NewGroupRepository(db),
+			Member:       repository.NewMemberRepository(db),
+			Expense:      repository.NewExpenseRepository(db),
+			Activity:     repository.NewActivityRepository(db),
+			Notification: repository.NewNotificationRepository(db),
+			Device:       repository.NewDeviceRepository(db),
+			Webhook:      repository.NewWebhookRepository(db),
+			Payment:      repository.NewPaymentRepository(db),
+		}, nil
+	}})
+}
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	suite.Run(t, &RepositoryContractSuite{NewRepositories: func() (Repositories, error) {
+		store := memory.NewStore()
+		return Repositories{
+			User:         memory.NewUserRepository(store),
+			Group:        memory.NewGroupRepository(store),
+			Member:       memory.NewMemberRepository(store),
+			Expense:      memory.NewExpenseRepository(store),
+			Activity:     memory.NewActivityRepository(store),
+			Notification: memory.NewNotificationRepository(store),
+			Device:       memory.NewDeviceRepository(store),
+			Webhook:      memory.NewWebhookRepository(store),
+			Payment:      memory.NewPaymentRepository(store),
+		}, nil
+	}})
+}
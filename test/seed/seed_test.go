@@ -0,0 +1,87 @@
+package seed
+
+import (
+	testifyRequire "github.com/stretchr/testify/require"
+	"money_share/pkg/model"
+	"money_share/pkg/seed"
+	"testing"
+	"time"
+)
+
+func newConfig() seed.Config {
+	return seed.Config{
+		Seed:             42,
+		Users:            30,
+		Groups:           5,
+		MinMembers:       2,
+		MaxMembers:       6,
+		ExpensesPerMonth: 10,
+		Months:           3,
+		End:              time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Password:         "password123",
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	require := testifyRequire.New(t)
+	first, err := seed.Generate(newConfig())
+	require.Nil(err)
+	second, err := seed.Generate(newConfig())
+	require.Nil(err)
+	require.Equal(first, second)
+
+	config := newConfig()
+	config.Seed = 43
+	other, err := seed.Generate(config)
+	require.Nil(err)
+	require.NotEqual(first, other)
+}
+
+func TestGenerateProducesValidData(t *testing.T) {
+	require := testifyRequire.New(t)
+	config := newConfig()
+	data, err := seed.Generate(config)
+	require.Nil(err)
+
+	require.Len(data.Users, config.Users)
+	usernames := make(map[string]bool)
+	for _, user := range data.Users {
+		require.Nil(user.ValidateFields(), user.Username)
+		require.False(usernames[user.Username], user.Username)
+		usernames[user.Username] = true
+		require.Equal(config.Password, user.Password)
+	}
+
+	require.Len(data.Groups, config.Groups)
+	start := config.End.AddDate(0, -config.Months, 0)
+	for _, group := range data.Groups {
+		require.GreaterOrEqual(len(group.Members), config.MinMembers)
+		require.LessOrEqual(len(group.Members), config.MaxMembers)
+		require.Equal(model.RoleManager, group.Members[0].Role)
+		memberIndexes := make(map[int]bool)
+		for _, member := range group.Members {
+			require.False(memberIndexes[member.UserIndex])
+			memberIndexes[member.UserIndex] = true
+		}
+		for _, expense := range group.Expenses {
+			require.True(memberIndexes[expense.UserIndex])
+			require.Greater(expense.Expense.Amount, float32(0))
+			require.False(expense.Expense.PurchaseTime.Before(start))
+			require.False(expense.Expense.PurchaseTime.After(config.End))
+			require.Contains([]string{"approved", "pending", "denied"}, expense.Expense.Status)
+		}
+	}
+}
+
+func TestGenerateRejectsInvalidConfig(t *testing.T) {
+	require := testifyRequire.New(t)
+	config := newConfig()
+	config.MaxMembers = config.Users + 1
+	_, err := seed.Generate(config)
+	require.NotNil(err)
+
+	config = newConfig()
+	config.Password = "short"
+	_, err = seed.Generate(config)
+	require.NotNil(err)
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"money_share/pkg/storage"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStorageContract runs the behaviour every backend must share
+func testStorageContract(t *testing.T, s storage.Storage) {
+	require := testifyRequire.New(t)
+	ctx := context.Background()
+
+	content := "profile image"
+	err := s.Put(ctx, "userProfileImage/user_1.png", strings.NewReader(content), int64(len(content)), "image/png")
+	require.Nil(err)
+
+	reader, info, err := s.Get(ctx, "userProfileImage/user_1.png")
+	require.Nil(err)
+	data, err := io.ReadAll(reader)
+	require.Nil(err)
+	require.Nil(reader.Close())
+	require.Equal(content, string(data))
+	require.Equal(int64(len(content)), info.Size)
+	require.Equal("image/png", info.ContentType)
+	require.NotEmpty(info.ETag)
+
+	signedURL, err := s.SignedURL(ctx, "userProfileImage/user_1.png", time.Minute)
+	require.Nil(err)
+	require.Contains(signedURL, "userProfileImage/user_1.png")
+
+	require.Nil(s.Delete(ctx, "userProfileImage/user_1.png"))
+	_, _, err = s.Get(ctx, "userProfileImage/user_1.png")
+	require.ErrorIs(err, storage.ErrNotFound)
+
+	for _, key := range []string{"", "../secret", "userProfileImage/../../secret", "/etc/passwd", "a//b", "a\\b"} {
+		_, _, err = s.Get(ctx, key)
+		require.ErrorIs(err, storage.ErrInvalidKey, key)
+		err = s.Put(ctx, key, strings.NewReader(content), int64(len(content)), "")
+		require.ErrorIs(err, storage.ErrInvalidKey, key)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	testStorageContract(t, storage.NewLocalStorage(t.TempDir(), "http://localhost/file", []byte("key")))
+}
+
+func TestLocalStorageSignedURL(t *testing.T) {
+	require := testifyRequire.New(t)
+	s := storage.NewLocalStorage(t.TempDir(), "http://localhost/file", []byte("key"))
+
+	signedURL, err := s.SignedURL(context.Background(), "userProfileImage/user_1.png", time.Minute)
+	require.Nil(err)
+	parsed, err := url.Parse(signedURL)
+	require.Nil(err)
+	require.Equal("/file/userProfileImage/user_1.png", parsed.Path)
+	require.True(s.VerifySignedURL("userProfileImage/user_1.png", parsed.Query()))
+
+	// Signature is bound to the key and expiry
+	require.False(s.VerifySignedURL("userProfileImage/user_2.png", parsed.Query()))
+	tampered := parsed.Query()
+	tampered.Set("expires", "9999999999")
+	require.False(s.VerifySignedURL("userProfileImage/user_1.png", tampered))
+
+	expired, err := s.SignedURL(context.Background(), "userProfileImage/user_1.png", -time.Minute)
+	require.Nil(err)
+	parsed, err = url.Parse(expired)
+	require.Nil(err)
+	require.False(s.VerifySignedURL("userProfileImage/user_1.png", parsed.Query()))
+}
+
+func TestS3Storage(t *testing.T) {
+	require := testifyRequire.New(t)
+	server := httptest.NewServer(gofakes3.New(s3mem.New()).Server())
+	defer server.Close()
+
+	s, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		Region:    "us-east-1",
+		Bucket:    "money-share",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	require.Nil(err)
+	require.Nil(s.EnsureBucket(context.Background()))
+	// Creating the bucket again is a no-op
+	require.Nil(s.EnsureBucket(context.Background()))
+
+	testStorageContract(t, s)
+}
+
+func TestMigrateDirectory(t *testing.T) {
+	require := testifyRequire.New(t)
+	src := t.TempDir()
+	require.Nil(os.WriteFile(filepath.Join(src, "user_1.png"), []byte("one"), 0644))
+	require.Nil(os.MkdirAll(filepath.Join(src, "nested"), os.ModePerm))
+	require.Nil(os.WriteFile(filepath.Join(src, "nested", "user_2.jpg"), []byte("two"), 0644))
+
+	dst := storage.NewLocalStorage(t.TempDir(), "", nil)
+	var keys []string
+	count, err := storage.MigrateDirectory(context.Background(), src, "userProfileImage", dst, func(key string) {
+		keys = append(keys, key)
+	})
+	require.Nil(err)
+	require.Equal(2, count)
+	require.ElementsMatch([]string{"userProfileImage/user_1.png", "userProfileImage/nested/user_2.jpg"}, keys)
+
+	reader, _, err := dst.Get(context.Background(), "userProfileImage/nested/user_2.jpg")
+	require.Nil(err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.Nil(err)
+	require.Equal("two", string(data))
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	testifyRequire "github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"money_share/pkg/middleware"
+	"money_share/pkg/model"
+	"money_share/pkg/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupProvider(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(tracing.Propagator())
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return exporter
+}
+
+func TestHTTPSpanContinuesIncomingTrace(t *testing.T) {
+	require := testifyRequire.New(t)
+	exporter := setupProvider(t)
+
+	r := mux.NewRouter()
+	r.Use(middleware.Tracing)
+	r.HandleFunc("/group/{groupId:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/group/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(spans, 1)
+	require.Equal("GET /group/{groupId:[0-9]+}", spans[0].Name)
+	require.Equal("4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	require.Equal("00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+}
+
+func TestGormPluginCreatesChildSpans(t *testing.T) {
+	require := testifyRequire.New(t)
+	exporter := setupProvider(t)
+
+	// Dry run builds statements without needing a database server
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(err)
+	require.NoError(db.Use(tracing.GormPlugin{}))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "handler")
+	db.WithContext(ctx).Where("group_id = ?", 1).Find(&[]model.Expense{})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(spans, 2)
+	query := spans[0]
+	require.Equal("gorm.query expenses", query.Name)
+	require.Equal(trace.SpanKindClient, query.SpanKind)
+	require.Equal(parent.SpanContext().SpanID(), query.Parent.SpanID())
+	statementFound := false
+	for _, attr := range query.Attributes {
+		if attr.Key == "db.statement" {
+			statementFound = true
+			require.Contains(attr.Value.AsString(), `SELECT * FROM "expenses" WHERE group_id = $1`)
+		}
+	}
+	require.True(statementFound, "should record the statement")
+}
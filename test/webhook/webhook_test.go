@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	testifyRequire "github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"money_share/pkg/auth"
+	"money_share/pkg/model"
+	"money_share/pkg/repository"
+	"money_share/pkg/repository/memory"
+	"money_share/pkg/webhook"
+	testmodel "money_share/test_tool/model"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// receiver records the requests of deliveries and answers with the statuses it is given, then 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (rec *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, string(body))
+	status := http.StatusOK
+	if len(rec.statuses) > 0 {
+		status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("received"))
+}
+
+// setup creates a group with a webhook posting to url and adds a member to queue a delivery
+func setup(t *testing.T, url string) (*memory.Store, model.Webhook) {
+	require := testifyRequire.New(t)
+	store := memory.NewStore()
+	alice := testmodel.GenerateRandomUser()
+	require.NoError(memory.NewUserRepository(store).Create(&alice))
+	bob := testmodel.GenerateRandomUser()
+	require.NoError(memory.NewUserRepository(store).Create(&bob))
+	group := testmodel.GenerateRandomGroup()
+	require.NoError(memory.NewGroupRepository(store).Create(&group, alice.ID))
+	hook := model.Webhook{GroupID: group.ID, URL: url, Secret: "top-secret", Events: model.ActionMemberAdded}
+	require.NoError(memory.NewWebhookRepository(store).Create(&hook))
+	ctx := auth.WithUserID(context.Background(), alice.ID)
+	require.NoError(memory.NewMemberRepository(store).WithContext(ctx).AddMemberToGroup(bob.ID, group.ID))
+	return store, hook
+}
+
+func newDeliverer(store *memory.Store, allowPrivateNetworks bool) *webhook.Deliverer {
+	return &webhook.Deliverer{
+		Webhooks:     memory.NewWebhookRepository(store),
+		Client:       webhook.NewClient(time.Second, allowPrivateNetworks),
+		MaxAttempts:  3,
+		Backoff:      time.Millisecond,
+		DisableAfter: 1,
+		BatchSize:    10,
+		Lease:        time.Minute,
+		Logger:       logger,
+	}
+}
+
+func TestSignature(t *testing.T) {
+	require := testifyRequire.New(t)
+	// Known HMAC-SHA256 test vector
+	signature := webhook.Sign("It's a Secret to Everybody", []byte("Hello, World!"))
+	require.Equal("sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", signature)
+	require.True(webhook.Verify("It's a Secret to Everybody", []byte("Hello, World!"), signature))
+	require.False(webhook.Verify("another secret", []byte("Hello, World!"), signature))
+
+	secret, err := webhook.NewSecret()
+	require.NoError(err)
+	require.Len(secret, 64)
+}
+
+func TestDelivererRetriesAndDisables(t *testing.T) {
+	require := testifyRequire.New(t)
+	rec := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	store, hook := setup(t, server.URL+"/hook")
+	deliverer := newDeliverer(store, true)
+	ctx := context.Background()
+
+	// Failed attempts are retried after the backoff, then the delivery succeeds
+	for i := 0; i < 3; i++ {
+		attempted, err := deliverer.Flush(ctx)
+		require.NoError(err)
+		require.Equal(1, attempted)
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.Len(rec.requests, 3)
+	request := rec.requests[2]
+	require.Equal(model.ActionMemberAdded, request.Header.Get(webhook.HeaderEvent))
+	require.Equal("application/json", request.Header.Get("Content-Type"))
+	require.Equal(webhook.Sign("top-secret", []byte(rec.bodies[2])), request.Header.Get(webhook.HeaderSignature))
+	require.Contains(rec.bodies[2], `"event":"member.added"`)
+
+	deliveries, err := memory.NewWebhookRepository(store).ListDeliveries(hook.ID, repository.PageRequest{})
+	require.NoError(err)
+	require.Len(deliveries.Items, 1)
+	delivery := deliveries.Items[0]
+	require.Equal(model.DeliverySucceeded, delivery.Status)
+	require.Equal(3, delivery.Attempts)
+	require.Equal(http.StatusOK, delivery.ResponseStatus)
+	require.Equal("received", delivery.ResponseBody)
+
+	// A delivery failing every attempt fails and disables the webhook
+	rec.statuses = []int{http.StatusGone, http.StatusGone, http.StatusGone}
+	_, err = memory.NewWebhookRepository(store).Redeliver(hook.ID, delivery.ID)
+	require.NoError(err)
+	for i := 0; i < 3; i++ {
+		_, err := deliverer.Flush(ctx)
+		require.NoError(err)
+		time.Sleep(5 * time.Millisecond)
+	}
+	deliveries, err = memory.NewWebhookRepository(store).ListDeliveries(hook.ID, repository.PageRequest{})
+	require.NoError(err)
+	require.Equal(model.DeliveryFailed, deliveries.Items[0].Status)
+	require.Equal("endpoint responded 410", deliveries.Items[0].Error)
+	disabled, err := memory.NewWebhookRepository(store).GetByID(hook.GroupID, hook.ID)
+	require.NoError(err)
+	require.False(disabled.Active)
+
+	// Deliveries of disabled webhooks wait until they are enabled
+	_, err = memory.NewWebhookRepository(store).Redeliver(hook.ID, delivery.ID)
+	require.NoError(err)
+	attempted, err := deliverer.Flush(ctx)
+	require.NoError(err)
+	require.Zero(attempted)
+}
+
+func TestPrivateNetworksAreRefused(t *testing.T) {
+	require := testifyRequire.New(t)
+	rec := &receiver{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	store, hook := setup(t, server.URL)
+
+	attempted, err := newDeliverer(store, false).Flush(context.Background())
+	require.NoError(err)
+	require.Equal(1, attempted)
+	require.Empty(rec.requests)
+	deliveries, err := memory.NewWebhookRepository(store).ListDeliveries(hook.ID, repository.PageRequest{})
+	require.NoError(err)
+	require.Equal(model.DeliveryPending, deliveries.Items[0].Status)
+	require.Contains(deliveries.Items[0].Error, webhook.ErrForbiddenAddress.Error())
+
+	_, err = webhook.NewClient(time.Second, false).Get("http://169.254.169.254/latest/meta-data")
+	require.True(errors.Is(err, webhook.ErrForbiddenAddress))
+}
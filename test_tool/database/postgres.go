@@ -5,7 +5,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"money_share/pkg/model"
+	"money_share/pkg/migration"
 )
 
 const (
@@ -25,14 +25,18 @@ func Connect() (db *gorm.DB, err error) {
 		return
 	}
 
-	// Drop old database schema
-	err = db.Migrator().DropTable(&model.User{}, &model.Group{}, &model.Member{}, &model.Expense{})
+	// Drop old database schema, with every table, index and function the migrations created
+	err = db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public").Error
 	if err != nil {
 		return
 	}
 
-	// Auto migrate new database schema
-	err = db.AutoMigrate(&model.User{}, &model.Group{}, &model.Member{}, &model.Expense{})
+	// Apply versioned migrations to create new database schema
+	migrator, err := migration.New(db)
+	if err != nil {
+		return
+	}
+	_, err = migrator.Up()
 	if err != nil {
 		return
 	}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	appdatabase "money_share/pkg/database"
+	"money_share/pkg/migration"
+)
+
+// ConnectSQLite opens a new in-memory SQLite database with the schema migrated. Each call
+// returns an empty database which is discarded when the connection is closed.
+func ConnectSQLite() (db *gorm.DB, err error) {
+	db, err = appdatabase.Open(appdatabase.DriverSQLite, ":memory:", &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return
+	}
+
+	migrator, err := migration.New(db)
+	if err != nil {
+		return
+	}
+	_, err = migrator.Up()
+	return
+}
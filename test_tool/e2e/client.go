@@ -0,0 +1,216 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"money_share/pkg/dto"
+	"money_share/pkg/dto/request"
+	"money_share/pkg/dto/response"
+	"net/http"
+	"testing"
+)
+
+// Password of every user created with NewUser
+const Password = "password123"
+
+// Client sends requests to the server, authenticated when Token is set
+type Client struct {
+	server *Server
+	// Token is sent in the Authorization header
+	Token string
+	// User is the logged in user
+	User dto.UserDTO
+}
+
+// Response is a completed response with its body read
+type Response struct {
+	t          testing.TB
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Anonymous returns a client without credentials
+func (s *Server) Anonymous() *Client {
+	return &Client{server: s}
+}
+
+// Register creates a user through the register endpoint and fails the test if it is rejected
+func (s *Server) Register(username string, password string) {
+	s.t.Helper()
+	s.Anonymous().Post("/user/register", request.RegisterRequest{
+		UserDTO: dto.UserDTO{
+			Username:     username,
+			DisplayName:  username,
+			EmailAddress: username + "@example.com",
+			DateOfBirth:  "2000-01-01",
+		},
+		Password: password,
+	}).RequireStatus(http.StatusOK)
+}
+
+// Login logs in through the login endpoint and returns a client acting as the user
+func (s *Server) Login(username string, password string) *Client {
+	s.t.Helper()
+	loginResponse := response.LoginResponse{}
+	s.Anonymous().Post("/user/login", request.LoginRequest{Username: username, Password: password}).
+		RequireStatus(http.StatusOK).Decode(&loginResponse)
+	return &Client{server: s, Token: loginResponse.AccessToken, User: loginResponse.UserDTO}
+}
+
+// NewUser registers username with Password and returns a client acting as the user
+func (s *Server) NewUser(username string) *Client {
+	s.t.Helper()
+	s.Register(username, Password)
+	return s.Login(username, Password)
+}
+
+// Do sends a request with body encoded as JSON, a nil body sends no body
+func (c *Client) Do(method string, path string, body interface{}) *Response {
+	t := c.server.t
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Cannot encode request body: %s", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, c.server.URL+path, reader)
+	if err != nil {
+		t.Fatalf("Cannot create request: %s", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", c.Token)
+	}
+
+	res, err := c.server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %s", method, path, err)
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Cannot read response of %s %s: %s", method, path, err)
+	}
+	return &Response{t: t, StatusCode: res.StatusCode, Header: res.Header, Body: resBody}
+}
+
+func (c *Client) Get(path string) *Response {
+	c.server.t.Helper()
+	return c.Do(http.MethodGet, path, nil)
+}
+
+func (c *Client) Post(path string, body interface{}) *Response {
+	c.server.t.Helper()
+	return c.Do(http.MethodPost, path, body)
+}
+
+func (c *Client) Put(path string, body interface{}) *Response {
+	c.server.t.Helper()
+	return c.Do(http.MethodPut, path, body)
+}
+
+func (c *Client) Delete(path string) *Response {
+	c.server.t.Helper()
+	return c.Do(http.MethodDelete, path, nil)
+}
+
+// CreateGroup creates a group managed by the user
+func (c *Client) CreateGroup(name string) dto.GroupDTO {
+	c.server.t.Helper()
+	group := dto.GroupDTO{}
+	c.Post("/group", request.GroupCreationRequest{Name: name}).RequireStatus(http.StatusOK).Decode(&group)
+	return group
+}
+
+// Group returns the group with its members
+func (c *Client) Group(groupID uint) dto.GroupDTO {
+	c.server.t.Helper()
+	group := dto.GroupDTO{}
+	c.Get(fmt.Sprintf("/group/%d", groupID)).RequireStatus(http.StatusOK).Decode(&group)
+	return group
+}
+
+// Expenses returns a page of the expenses of the group, query holds the filter and page parameters
+func (c *Client) Expenses(groupID uint, query string) response.PageResponse[dto.ExpenseDTO] {
+	c.server.t.Helper()
+	page := response.PageResponse[dto.ExpenseDTO]{}
+	c.Get(fmt.Sprintf("/expense/group/%d?%s", groupID, query)).RequireStatus(http.StatusOK).Decode(&page)
+	return page
+}
+
+// Activity returns a page of the activity log of the group, query holds the page parameters
+func (c *Client) Activity(groupID uint, query string) response.PageResponse[dto.ActivityDTO] {
+	c.server.t.Helper()
+	page := response.PageResponse[dto.ActivityDTO]{}
+	c.Get(fmt.Sprintf("/group/%d/activity?%s", groupID, query)).RequireStatus(http.StatusOK).Decode(&page)
+	return page
+}
+
+// Notifications returns a page of the notifications of the user, query holds the filter and page parameters
+func (c *Client) Notifications(query string) response.NotificationPageResponse {
+	c.server.t.Helper()
+	page := response.NotificationPageResponse{}
+	c.Get("/notification?" + query).RequireStatus(http.StatusOK).Decode(&page)
+	return page
+}
+
+// AddMember adds the user to the group
+func (c *Client) AddMember(groupID uint, userID uint) {
+	c.server.t.Helper()
+	c.Post(fmt.Sprintf("/member?userId=%d&groupId=%d", userID, groupID), nil).RequireStatus(http.StatusOK)
+}
+
+// Member returns the user as a member of the group
+func (c *Client) Member(groupID uint, userID uint) dto.MemberDTO {
+	c.server.t.Helper()
+	member := dto.MemberDTO{}
+	c.Get(fmt.Sprintf("/member?userId=%d&groupId=%d", userID, groupID)).RequireStatus(http.StatusOK).Decode(&member)
+	return member
+}
+
+// CreateExpense submits an expense, the response tells whether it is pending or approved
+func (c *Client) CreateExpense(expense dto.ExpenseDTO) dto.ExpenseDTO {
+	c.server.t.Helper()
+	saved := dto.ExpenseDTO{}
+	c.Post("/expense", expense).RequireStatus(http.StatusOK).Decode(&saved)
+	return saved
+}
+
+// SetExpenseStatus approves or denies an expense
+func (c *Client) SetExpenseStatus(expenseID uint, status string) {
+	c.server.t.Helper()
+	c.Put(fmt.Sprintf("/expense/%d", expenseID), map[string]string{"status": status}).RequireStatus(http.StatusOK)
+}
+
+// RequireStatus fails the test unless the response has the status code
+func (r *Response) RequireStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Fatalf("Expected status %d, got %d: %s", code, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// Decode decodes the JSON body into v and fails the test if it cannot
+func (r *Response) Decode(v interface{}) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("Cannot decode response body %s: %s", r.Body, err)
+	}
+}
+
+// Error decodes an error response
+func (r *Response) Error() response.ErrorResponse {
+	r.t.Helper()
+	errorResponse := response.ErrorResponse{}
+	r.Decode(&errorResponse)
+	return errorResponse
+}
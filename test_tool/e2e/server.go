@@ -0,0 +1,113 @@
+// Package e2e runs the whole API in process for end-to-end tests. Requests go through the
+// real router and middleware chain over HTTP, backed by an in-memory SQLite database, an
+// in-memory Redis and a temporary file storage directory which are discarded after the test.
+package e2e
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"gorm.io/gorm"
+	"io"
+	"log/slog"
+	"money_share/pkg/auth"
+	"money_share/pkg/background"
+	"money_share/pkg/config"
+	"money_share/pkg/controller"
+	appdatabase "money_share/pkg/database"
+	"money_share/pkg/model"
+	"money_share/pkg/push"
+	appserver "money_share/pkg/server"
+	"money_share/test_tool/database"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// JWTKey signs the tokens of every test server
+const JWTKey = "e2e-test-jwt-key"
+
+// Server is a running API with its dependencies
+type Server struct {
+	*httptest.Server
+	App   *controller.App
+	DB    *gorm.DB
+	Redis *miniredis.Miniredis
+	// Push records the push notifications sent to every platform
+	Push *push.Fake
+	t    testing.TB
+}
+
+// Config returns the config test servers start with, rate limits are the production defaults
+func Config() config.Config {
+	cfg := config.Default()
+	cfg.JWTKey = JWTKey
+	cfg.DatabaseDriver = appdatabase.DriverSQLite
+	cfg.PushPollInterval = 10 * time.Millisecond
+	cfg.PushBackoff = time.Millisecond
+	// Test receivers listen on loopback
+	cfg.WebhookPollInterval = 10 * time.Millisecond
+	cfg.WebhookBackoff = time.Millisecond
+	cfg.WebhookAllowPrivateNetworks = true
+	return cfg
+}
+
+// NewServer starts a server with Config, it is closed when the test finishes
+func NewServer(t testing.TB) *Server {
+	return NewServerWithConfig(t, Config())
+}
+
+// NewServerWithConfig starts a server with cfg, it is closed when the test finishes
+func NewServerWithConfig(t testing.TB, cfg config.Config) *Server {
+	t.Helper()
+	// Tokens are signed with a package level key
+	auth.JWTKey = []byte(cfg.JWTKey)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	db, err := database.ConnectSQLite()
+	if err != nil {
+		t.Fatalf("Cannot create database: %s", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	redisServer := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	// The listener is created first so signed file URLs can point at the server
+	httpServer := httptest.NewUnstartedServer(nil)
+	cfg.StorageBackend = "local"
+	cfg.StorageLocalDir = t.TempDir()
+	cfg.StoragePublicURL = "http://" + httpServer.Listener.Addr().String() + "/file"
+	fileStorage, err := appserver.NewStorage(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Cannot set up file storage: %s", err)
+	}
+	pushFake := push.NewFake()
+	srv, err := appserver.New(cfg, db, rdb, appserver.Options{
+		Storage:     fileStorage,
+		PushSenders: map[string]push.Sender{model.PlatformAndroid: pushFake, model.PlatformIOS: pushFake},
+		Logger:      logger,
+	})
+	if err != nil {
+		t.Fatalf("Cannot set up server: %s", err)
+	}
+	workers := background.NewGroup(logger)
+	srv.Start(workers)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		_ = workers.Shutdown(ctx)
+	})
+	httpServer.Config.Handler = srv.Handler
+	httpServer.Start()
+	t.Cleanup(httpServer.Close)
+	// Open event streams would keep the server from closing
+	t.Cleanup(srv.App.Events.Close)
+
+	return &Server{Server: httpServer, App: srv.App, DB: db, Redis: redisServer, Push: pushFake, t: t}
+}
@@ -0,0 +1,135 @@
+package e2e
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// How long reading a stream waits for the next event
+const streamTimeout = 2 * time.Second
+
+// Event is an event or comment read from an event stream
+type Event struct {
+	ID   string
+	Type string
+	Data string
+	// Comment is set for comments such as heartbeats, which have no other field
+	Comment string
+}
+
+// EventStream is an open event stream of a group
+type EventStream struct {
+	t      testing.TB
+	res    *http.Response
+	events chan Event
+}
+
+// Stream opens the event stream of the group like a browser, with the token in the query.
+// lastEventID resumes after that event when set.
+func (c *Client) Stream(groupID uint, lastEventID string) *EventStream {
+	t := c.server.t
+	t.Helper()
+	path := fmt.Sprintf("/group/%d/events?access_token=%s", groupID, url.QueryEscape(c.Token))
+	req, err := http.NewRequest(http.MethodGet, c.server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("Cannot create request: %s", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := c.server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Cannot open event stream: %s", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		t.Fatalf("Event stream responded %d", res.StatusCode)
+	}
+
+	stream := &EventStream{t: t, res: res, events: make(chan Event, 100)}
+	go stream.read()
+	t.Cleanup(stream.Close)
+	return stream
+}
+
+// read parses the stream until it ends, fields other than id, event and data are ignored
+func (s *EventStream) read() {
+	defer close(s.events)
+	scanner := bufio.NewScanner(s.res.Body)
+	event := Event{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if event != (Event{}) {
+				s.events <- event
+			}
+			event = Event{}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			event.Comment = value
+		case "id":
+			event.ID = value
+		case "event":
+			event.Type = value
+		case "data":
+			event.Data = value
+		}
+	}
+}
+
+// Next returns the next event or comment, it fails the test when none arrives in time
+func (s *EventStream) Next() Event {
+	s.t.Helper()
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			s.t.Fatalf("Event stream ended")
+		}
+		return event
+	case <-time.After(streamTimeout):
+		s.t.Fatalf("No event received within %s", streamTimeout)
+	}
+	return Event{}
+}
+
+// NextEvent returns the next event, skipping comments
+func (s *EventStream) NextEvent() Event {
+	s.t.Helper()
+	for {
+		if event := s.Next(); event.Comment == "" {
+			return event
+		}
+	}
+}
+
+// RequireEnd waits for the server to end the stream, skipping what is still sent, and fails the
+// test when it does not end in time
+func (s *EventStream) RequireEnd() {
+	s.t.Helper()
+	timeout := time.After(streamTimeout)
+	for {
+		select {
+		case _, ok := <-s.events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			s.t.Fatalf("Event stream did not end within %s", streamTimeout)
+		}
+	}
+}
+
+// Close disconnects the stream
+func (s *EventStream) Close() {
+	s.res.Body.Close()
+}